@@ -4,9 +4,13 @@
 // 	protoc        v6.33.0
 // source: api/EventService.proto
 
+// Requests are made on behalf of the user passed in x-user-id metadata.
+
 package proto
 
 import (
+	_ "github.com/grpc-ecosystem/grpc-gateway/v2/protoc-gen-openapiv2/options"
+	_ "google.golang.org/genproto/googleapis/api/annotations"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
@@ -22,6 +26,63 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type Role int32
+
+const (
+	Role_ROLE_UNSPECIFIED Role = 0
+	// only time and duration of events are visible
+	Role_ROLE_FREE_BUSY Role = 1
+	Role_ROLE_VIEWER    Role = 2
+	Role_ROLE_EDITOR    Role = 3
+	// may rename the calendar and manage its shares
+	Role_ROLE_OWNER Role = 4
+)
+
+// Enum value maps for Role.
+var (
+	Role_name = map[int32]string{
+		0: "ROLE_UNSPECIFIED",
+		1: "ROLE_FREE_BUSY",
+		2: "ROLE_VIEWER",
+		3: "ROLE_EDITOR",
+		4: "ROLE_OWNER",
+	}
+	Role_value = map[string]int32{
+		"ROLE_UNSPECIFIED": 0,
+		"ROLE_FREE_BUSY":   1,
+		"ROLE_VIEWER":      2,
+		"ROLE_EDITOR":      3,
+		"ROLE_OWNER":       4,
+	}
+)
+
+func (x Role) Enum() *Role {
+	p := new(Role)
+	*p = x
+	return p
+}
+
+func (x Role) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Role) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_EventService_proto_enumTypes[0].Descriptor()
+}
+
+func (Role) Type() protoreflect.EnumType {
+	return &file_api_EventService_proto_enumTypes[0]
+}
+
+func (x Role) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Role.Descriptor instead.
+func (Role) EnumDescriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{0}
+}
+
 type CreateRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	EventData     *EventData             `protobuf:"bytes,1,opt,name=event_data,json=eventData,proto3" json:"event_data,omitempty"`
@@ -385,8 +446,11 @@ type EventData struct {
 	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
 	UpdatedAt      *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
 	RemindSentTime *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=remind_sent_time,json=remindSentTime,proto3" json:"remind_sent_time,omitempty"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+	Tags           []string               `protobuf:"bytes,10,rep,name=tags,proto3" json:"tags,omitempty"`
+	// the requesting user's first own calendar if empty
+	CalendarId    string `protobuf:"bytes,11,opt,name=calendar_id,json=calendarId,proto3" json:"calendar_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *EventData) Reset() {
@@ -482,6 +546,20 @@ func (x *EventData) GetRemindSentTime() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *EventData) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *EventData) GetCalendarId() string {
+	if x != nil {
+		return x.CalendarId
+	}
+	return ""
+}
+
 type EventId struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -527,8 +605,10 @@ func (x *EventId) GetId() string {
 }
 
 type StartDate struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	StartDate     *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=start_date,json=startDate,proto3" json:"start_date,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	StartDate *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=start_date,json=startDate,proto3" json:"start_date,omitempty"`
+	// events having any of tags are returned, all events if empty
+	Tags          []string `protobuf:"bytes,2,rep,name=tags,proto3" json:"tags,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -570,56 +650,1201 @@ func (x *StartDate) GetStartDate() *timestamppb.Timestamp {
 	return nil
 }
 
-var File_api_EventService_proto protoreflect.FileDescriptor
+func (x *StartDate) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
 
-const file_api_EventService_proto_rawDesc = "" +
-	"\n" +
-	"\x16api/EventService.proto\x12\x05event\x1a\x1fgoogle/protobuf/timestamp.proto\"@\n" +
-	"\rCreateRequest\x12/\n" +
-	"\n" +
-	"event_data\x18\x01 \x01(\v2\x10.event.EventDataR\teventData\"k\n" +
-	"\rUpdateRequest\x12)\n" +
-	"\bevent_id\x18\x01 \x01(\v2\x0e.event.EventIdR\aeventId\x12/\n" +
-	"\n" +
-	"event_data\x18\x02 \x01(\v2\x10.event.EventDataR\teventData\":\n" +
-	"\rDeleteRequest\x12)\n" +
-	"\bevent_id\x18\x01 \x01(\v2\x0e.event.EventIdR\aeventId\";\n" +
-	"\x0eCreateResponse\x12)\n" +
-	"\bevent_id\x18\x01 \x01(\v2\x0e.event.EventIdR\aeventId\"\x10\n" +
-	"\x0eUpdateResponse\"\x10\n" +
-	"\x0eDeleteResponse\".\n" +
-	"\x06Events\x12$\n" +
-	"\x06events\x18\x01 \x03(\v2\f.event.EventR\x06events\"c\n" +
-	"\x05Event\x12)\n" +
-	"\bevent_id\x18\x01 \x01(\v2\x0e.event.EventIdR\aeventId\x12/\n" +
-	"\n" +
-	"event_data\x18\x02 \x01(\v2\x10.event.EventDataR\teventData\"\xaa\x03\n" +
-	"\tEventData\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x14\n" +
-	"\x05title\x18\x02 \x01(\tR\x05title\x127\n" +
-	"\tdate_time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\bdateTime\x12 \n" +
-	"\vdescription\x18\x04 \x01(\tR\vdescription\x12\x1a\n" +
-	"\bduration\x18\x05 \x01(\tR\bduration\x12;\n" +
-	"\vremind_time\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
-	"remindTime\x129\n" +
-	"\n" +
-	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
-	"\n" +
-	"updated_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12D\n" +
-	"\x10remind_sent_time\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\x0eremindSentTime\"\x19\n" +
-	"\aEventId\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\"F\n" +
-	"\tStartDate\x129\n" +
+type SearchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// words and "quoted phrases" that all must match
+	Query string `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	// optional half-open period [start, end)
+	Start *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=start,proto3" json:"start,omitempty"`
+	End   *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=end,proto3" json:"end,omitempty"`
+	// 50 if not set
+	Limit int32 `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	// events having any of tags are returned, all matches if empty
+	Tags          []string `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_api_EventService_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SearchRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetEnd() *timestamppb.Timestamp {
+	if x != nil {
+		return x.End
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type Tag struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Name   string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// optional, #RRGGBB
+	Color         string `protobuf:"bytes,3,opt,name=color,proto3" json:"color,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Tag) Reset() {
+	*x = Tag{}
+	mi := &file_api_EventService_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Tag) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Tag) ProtoMessage() {}
+
+func (x *Tag) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Tag.ProtoReflect.Descriptor instead.
+func (*Tag) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Tag) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *Tag) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Tag) GetColor() string {
+	if x != nil {
+		return x.Color
+	}
+	return ""
+}
+
+type Tags struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tags          []*Tag                 `protobuf:"bytes,1,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Tags) Reset() {
+	*x = Tags{}
+	mi := &file_api_EventService_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Tags) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Tags) ProtoMessage() {}
+
+func (x *Tags) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Tags.ProtoReflect.Descriptor instead.
+func (*Tags) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Tags) GetTags() []*Tag {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type UserId struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserId) Reset() {
+	*x = UserId{}
+	mi := &file_api_EventService_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserId) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserId) ProtoMessage() {}
+
+func (x *UserId) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserId.ProtoReflect.Descriptor instead.
+func (*UserId) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *UserId) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type SaveTagResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SaveTagResponse) Reset() {
+	*x = SaveTagResponse{}
+	mi := &file_api_EventService_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SaveTagResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SaveTagResponse) ProtoMessage() {}
+
+func (x *SaveTagResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SaveTagResponse.ProtoReflect.Descriptor instead.
+func (*SaveTagResponse) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{15}
+}
+
+type DeleteTagRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteTagRequest) Reset() {
+	*x = DeleteTagRequest{}
+	mi := &file_api_EventService_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteTagRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteTagRequest) ProtoMessage() {}
+
+func (x *DeleteTagRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteTagRequest.ProtoReflect.Descriptor instead.
+func (*DeleteTagRequest) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *DeleteTagRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *DeleteTagRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type DeleteTagResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteTagResponse) Reset() {
+	*x = DeleteTagResponse{}
+	mi := &file_api_EventService_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteTagResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteTagResponse) ProtoMessage() {}
+
+func (x *DeleteTagResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteTagResponse.ProtoReflect.Descriptor instead.
+func (*DeleteTagResponse) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{17}
+}
+
+// Digest subscribes a user to the daily agenda of the user's events.
+type Digest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// local time to send the digest at, HH:MM
+	Time string `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
+	// IANA time zone, UTC if not set
+	TimeZone string `protobuf:"bytes,3,opt,name=time_zone,json=timeZone,proto3" json:"time_zone,omitempty"`
+	// local date of the last sent digest, read only
+	LastSent      string `protobuf:"bytes,4,opt,name=last_sent,json=lastSent,proto3" json:"last_sent,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Digest) Reset() {
+	*x = Digest{}
+	mi := &file_api_EventService_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Digest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Digest) ProtoMessage() {}
+
+func (x *Digest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Digest.ProtoReflect.Descriptor instead.
+func (*Digest) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *Digest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *Digest) GetTime() string {
+	if x != nil {
+		return x.Time
+	}
+	return ""
+}
+
+func (x *Digest) GetTimeZone() string {
+	if x != nil {
+		return x.TimeZone
+	}
+	return ""
+}
+
+func (x *Digest) GetLastSent() string {
+	if x != nil {
+		return x.LastSent
+	}
+	return ""
+}
+
+type SaveDigestResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SaveDigestResponse) Reset() {
+	*x = SaveDigestResponse{}
+	mi := &file_api_EventService_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SaveDigestResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SaveDigestResponse) ProtoMessage() {}
+
+func (x *SaveDigestResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SaveDigestResponse.ProtoReflect.Descriptor instead.
+func (*SaveDigestResponse) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{19}
+}
+
+type DeleteDigestResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteDigestResponse) Reset() {
+	*x = DeleteDigestResponse{}
+	mi := &file_api_EventService_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteDigestResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteDigestResponse) ProtoMessage() {}
+
+func (x *DeleteDigestResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteDigestResponse.ProtoReflect.Descriptor instead.
+func (*DeleteDigestResponse) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{20}
+}
+
+type Calendar struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CalendarId    *CalendarId            `protobuf:"bytes,1,opt,name=calendar_id,json=calendarId,proto3" json:"calendar_id,omitempty"`
+	OwnerId       int64                  `protobuf:"varint,2,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Calendar) Reset() {
+	*x = Calendar{}
+	mi := &file_api_EventService_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Calendar) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Calendar) ProtoMessage() {}
+
+func (x *Calendar) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Calendar.ProtoReflect.Descriptor instead.
+func (*Calendar) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *Calendar) GetCalendarId() *CalendarId {
+	if x != nil {
+		return x.CalendarId
+	}
+	return nil
+}
+
+func (x *Calendar) GetOwnerId() int64 {
+	if x != nil {
+		return x.OwnerId
+	}
+	return 0
+}
+
+func (x *Calendar) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Calendar) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Calendar) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type Calendars struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Calendars     []*Calendar            `protobuf:"bytes,1,rep,name=calendars,proto3" json:"calendars,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Calendars) Reset() {
+	*x = Calendars{}
+	mi := &file_api_EventService_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Calendars) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Calendars) ProtoMessage() {}
+
+func (x *Calendars) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Calendars.ProtoReflect.Descriptor instead.
+func (*Calendars) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *Calendars) GetCalendars() []*Calendar {
+	if x != nil {
+		return x.Calendars
+	}
+	return nil
+}
+
+type CalendarId struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CalendarId) Reset() {
+	*x = CalendarId{}
+	mi := &file_api_EventService_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CalendarId) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CalendarId) ProtoMessage() {}
+
+func (x *CalendarId) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CalendarId.ProtoReflect.Descriptor instead.
+func (*CalendarId) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *CalendarId) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type CreateCalendarResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CalendarId    *CalendarId            `protobuf:"bytes,1,opt,name=calendar_id,json=calendarId,proto3" json:"calendar_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateCalendarResponse) Reset() {
+	*x = CreateCalendarResponse{}
+	mi := &file_api_EventService_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateCalendarResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateCalendarResponse) ProtoMessage() {}
+
+func (x *CreateCalendarResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateCalendarResponse.ProtoReflect.Descriptor instead.
+func (*CreateCalendarResponse) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *CreateCalendarResponse) GetCalendarId() *CalendarId {
+	if x != nil {
+		return x.CalendarId
+	}
+	return nil
+}
+
+type UpdateCalendarResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateCalendarResponse) Reset() {
+	*x = UpdateCalendarResponse{}
+	mi := &file_api_EventService_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateCalendarResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateCalendarResponse) ProtoMessage() {}
+
+func (x *UpdateCalendarResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateCalendarResponse.ProtoReflect.Descriptor instead.
+func (*UpdateCalendarResponse) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{25}
+}
+
+type DeleteCalendarResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteCalendarResponse) Reset() {
+	*x = DeleteCalendarResponse{}
+	mi := &file_api_EventService_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteCalendarResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteCalendarResponse) ProtoMessage() {}
+
+func (x *DeleteCalendarResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteCalendarResponse.ProtoReflect.Descriptor instead.
+func (*DeleteCalendarResponse) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{26}
+}
+
+type GetCalendarsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCalendarsRequest) Reset() {
+	*x = GetCalendarsRequest{}
+	mi := &file_api_EventService_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCalendarsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCalendarsRequest) ProtoMessage() {}
+
+func (x *GetCalendarsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCalendarsRequest.ProtoReflect.Descriptor instead.
+func (*GetCalendarsRequest) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{27}
+}
+
+type Share struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CalendarId    *CalendarId            `protobuf:"bytes,1,opt,name=calendar_id,json=calendarId,proto3" json:"calendar_id,omitempty"`
+	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Role          Role                   `protobuf:"varint,3,opt,name=role,proto3,enum=event.Role" json:"role,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Share) Reset() {
+	*x = Share{}
+	mi := &file_api_EventService_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Share) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Share) ProtoMessage() {}
+
+func (x *Share) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Share.ProtoReflect.Descriptor instead.
+func (*Share) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *Share) GetCalendarId() *CalendarId {
+	if x != nil {
+		return x.CalendarId
+	}
+	return nil
+}
+
+func (x *Share) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *Share) GetRole() Role {
+	if x != nil {
+		return x.Role
+	}
+	return Role_ROLE_UNSPECIFIED
+}
+
+type Shares struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Shares        []*Share               `protobuf:"bytes,1,rep,name=shares,proto3" json:"shares,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Shares) Reset() {
+	*x = Shares{}
+	mi := &file_api_EventService_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Shares) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Shares) ProtoMessage() {}
+
+func (x *Shares) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Shares.ProtoReflect.Descriptor instead.
+func (*Shares) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *Shares) GetShares() []*Share {
+	if x != nil {
+		return x.Shares
+	}
+	return nil
+}
+
+type ShareResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShareResponse) Reset() {
+	*x = ShareResponse{}
+	mi := &file_api_EventService_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShareResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShareResponse) ProtoMessage() {}
+
+func (x *ShareResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShareResponse.ProtoReflect.Descriptor instead.
+func (*ShareResponse) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{30}
+}
+
+type UnshareRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CalendarId    *CalendarId            `protobuf:"bytes,1,opt,name=calendar_id,json=calendarId,proto3" json:"calendar_id,omitempty"`
+	UserId        int64                  `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnshareRequest) Reset() {
+	*x = UnshareRequest{}
+	mi := &file_api_EventService_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnshareRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnshareRequest) ProtoMessage() {}
+
+func (x *UnshareRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnshareRequest.ProtoReflect.Descriptor instead.
+func (*UnshareRequest) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *UnshareRequest) GetCalendarId() *CalendarId {
+	if x != nil {
+		return x.CalendarId
+	}
+	return nil
+}
+
+func (x *UnshareRequest) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+type UnshareResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnshareResponse) Reset() {
+	*x = UnshareResponse{}
+	mi := &file_api_EventService_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnshareResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnshareResponse) ProtoMessage() {}
+
+func (x *UnshareResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_EventService_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnshareResponse.ProtoReflect.Descriptor instead.
+func (*UnshareResponse) Descriptor() ([]byte, []int) {
+	return file_api_EventService_proto_rawDescGZIP(), []int{32}
+}
+
+var File_api_EventService_proto protoreflect.FileDescriptor
+
+const file_api_EventService_proto_rawDesc = "" +
+	"\n" +
+	"\x16api/EventService.proto\x12\x05event\x1a\x1cgoogle/api/annotations.proto\x1a\x1fgoogle/protobuf/timestamp.proto\x1a.protoc-gen-openapiv2/options/annotations.proto\"@\n" +
+	"\rCreateRequest\x12/\n" +
+	"\n" +
+	"event_data\x18\x01 \x01(\v2\x10.event.EventDataR\teventData\"k\n" +
+	"\rUpdateRequest\x12)\n" +
+	"\bevent_id\x18\x01 \x01(\v2\x0e.event.EventIdR\aeventId\x12/\n" +
+	"\n" +
+	"event_data\x18\x02 \x01(\v2\x10.event.EventDataR\teventData\":\n" +
+	"\rDeleteRequest\x12)\n" +
+	"\bevent_id\x18\x01 \x01(\v2\x0e.event.EventIdR\aeventId\";\n" +
+	"\x0eCreateResponse\x12)\n" +
+	"\bevent_id\x18\x01 \x01(\v2\x0e.event.EventIdR\aeventId\"\x10\n" +
+	"\x0eUpdateResponse\"\x10\n" +
+	"\x0eDeleteResponse\".\n" +
+	"\x06Events\x12$\n" +
+	"\x06events\x18\x01 \x03(\v2\f.event.EventR\x06events\"c\n" +
+	"\x05Event\x12)\n" +
+	"\bevent_id\x18\x01 \x01(\v2\x0e.event.EventIdR\aeventId\x12/\n" +
+	"\n" +
+	"event_data\x18\x02 \x01(\v2\x10.event.EventDataR\teventData\"\xdf\x03\n" +
+	"\tEventData\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x127\n" +
+	"\tdate_time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\bdateTime\x12 \n" +
+	"\vdescription\x18\x04 \x01(\tR\vdescription\x12\x1a\n" +
+	"\bduration\x18\x05 \x01(\tR\bduration\x12;\n" +
+	"\vremind_time\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"remindTime\x129\n" +
+	"\n" +
+	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12D\n" +
+	"\x10remind_sent_time\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\x0eremindSentTime\x12\x12\n" +
+	"\x04tags\x18\n" +
+	" \x03(\tR\x04tags\x12\x1f\n" +
+	"\vcalendar_id\x18\v \x01(\tR\n" +
+	"calendarId\"\x19\n" +
+	"\aEventId\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"Z\n" +
+	"\tStartDate\x129\n" +
+	"\n" +
+	"start_date\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\tstartDate\x12\x12\n" +
+	"\x04tags\x18\x02 \x03(\tR\x04tags\"\xaf\x01\n" +
+	"\rSearchRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x120\n" +
+	"\x05start\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x05start\x12,\n" +
+	"\x03end\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x03end\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\x12\x12\n" +
+	"\x04tags\x18\x05 \x03(\tR\x04tags\"H\n" +
+	"\x03Tag\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05color\x18\x03 \x01(\tR\x05color\"&\n" +
+	"\x04Tags\x12\x1e\n" +
+	"\x04tags\x18\x01 \x03(\v2\n" +
+	".event.TagR\x04tags\"\x18\n" +
+	"\x06UserId\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\"\x11\n" +
+	"\x0fSaveTagResponse\"?\n" +
+	"\x10DeleteTagRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\"\x13\n" +
+	"\x11DeleteTagResponse\"o\n" +
+	"\x06Digest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x12\n" +
+	"\x04time\x18\x02 \x01(\tR\x04time\x12\x1b\n" +
+	"\ttime_zone\x18\x03 \x01(\tR\btimeZone\x12\x1b\n" +
+	"\tlast_sent\x18\x04 \x01(\tR\blastSent\"\x14\n" +
+	"\x12SaveDigestResponse\"\x16\n" +
+	"\x14DeleteDigestResponse\"\xe3\x01\n" +
+	"\bCalendar\x122\n" +
+	"\vcalendar_id\x18\x01 \x01(\v2\x11.event.CalendarIdR\n" +
+	"calendarId\x12\x19\n" +
+	"\bowner_id\x18\x02 \x01(\x03R\aownerId\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x129\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\":\n" +
+	"\tCalendars\x12-\n" +
+	"\tcalendars\x18\x01 \x03(\v2\x0f.event.CalendarR\tcalendars\"\x1c\n" +
+	"\n" +
+	"CalendarId\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"L\n" +
+	"\x16CreateCalendarResponse\x122\n" +
+	"\vcalendar_id\x18\x01 \x01(\v2\x11.event.CalendarIdR\n" +
+	"calendarId\"\x18\n" +
+	"\x16UpdateCalendarResponse\"\x18\n" +
+	"\x16DeleteCalendarResponse\"\x15\n" +
+	"\x13GetCalendarsRequest\"u\n" +
+	"\x05Share\x122\n" +
+	"\vcalendar_id\x18\x01 \x01(\v2\x11.event.CalendarIdR\n" +
+	"calendarId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\x03R\x06userId\x12\x1f\n" +
+	"\x04role\x18\x03 \x01(\x0e2\v.event.RoleR\x04role\".\n" +
+	"\x06Shares\x12$\n" +
+	"\x06shares\x18\x01 \x03(\v2\f.event.ShareR\x06shares\"\x0f\n" +
+	"\rShareResponse\"]\n" +
+	"\x0eUnshareRequest\x122\n" +
+	"\vcalendar_id\x18\x01 \x01(\v2\x11.event.CalendarIdR\n" +
+	"calendarId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\x03R\x06userId\"\x11\n" +
+	"\x0fUnshareResponse*b\n" +
+	"\x04Role\x12\x14\n" +
+	"\x10ROLE_UNSPECIFIED\x10\x00\x12\x12\n" +
+	"\x0eROLE_FREE_BUSY\x10\x01\x12\x0f\n" +
+	"\vROLE_VIEWER\x10\x02\x12\x0f\n" +
+	"\vROLE_EDITOR\x10\x03\x12\x0e\n" +
+	"\n" +
+	"ROLE_OWNER\x10\x042\xee\x0e\n" +
+	"\fEventService\x12Z\n" +
+	"\vCreateEvent\x12\x14.event.CreateRequest\x1a\x15.event.CreateResponse\"\x1e\x82\xd3\xe4\x93\x02\x18:\n" +
+	"event_data\"\n" +
+	"/v1/events\x12h\n" +
+	"\vUpdateEvent\x12\x14.event.UpdateRequest\x1a\x15.event.UpdateResponse\",\x82\xd3\xe4\x93\x02&:\n" +
+	"event_data\x1a\x18/v1/events/{event_id.id}\x12\\\n" +
+	"\vDeleteEvent\x12\x14.event.DeleteRequest\x1a\x15.event.DeleteResponse\" \x82\xd3\xe4\x93\x02\x1a*\x18/v1/events/{event_id.id}\x12A\n" +
+	"\bGetEvent\x12\x0e.event.EventId\x1a\f.event.Event\"\x17\x82\xd3\xe4\x93\x02\x11\x12\x0f/v1/events/{id}\x12G\n" +
+	"\fGetDayEvents\x12\x10.event.StartDate\x1a\r.event.Events\"\x16\x82\xd3\xe4\x93\x02\x10\x12\x0e/v1/events:day\x12I\n" +
+	"\rGetWeekEvents\x12\x10.event.StartDate\x1a\r.event.Events\"\x17\x82\xd3\xe4\x93\x02\x11\x12\x0f/v1/events:week\x12K\n" +
+	"\x0eGetMonthEvents\x12\x10.event.StartDate\x1a\r.event.Events\"\x18\x82\xd3\xe4\x93\x02\x12\x12\x10/v1/events:month\x12N\n" +
+	"\fSearchEvents\x12\x14.event.SearchRequest\x1a\r.event.Events\"\x19\x82\xd3\xe4\x93\x02\x13\x12\x11/v1/events:search\x12Y\n" +
+	"\aSaveTag\x12\n" +
+	".event.Tag\x1a\x16.event.SaveTagResponse\"*\x82\xd3\xe4\x93\x02$:\x01*\x1a\x1f/v1/users/{user_id}/tags/{name}\x12g\n" +
+	"\tDeleteTag\x12\x17.event.DeleteTagRequest\x1a\x18.event.DeleteTagResponse\"'\x82\xd3\xe4\x93\x02!*\x1f/v1/users/{user_id}/tags/{name}\x12B\n" +
+	"\aGetTags\x12\r.event.UserId\x1a\v.event.Tags\"\x1b\x82\xd3\xe4\x93\x02\x15\x12\x13/v1/users/{id}/tags\x12Z\n" +
+	"\x0eCreateCalendar\x12\x0f.event.Calendar\x1a\x1d.event.CreateCalendarResponse\"\x18\x82\xd3\xe4\x93\x02\x12:\x01*\"\r/v1/calendars\x12k\n" +
+	"\x0eUpdateCalendar\x12\x0f.event.Calendar\x1a\x1d.event.UpdateCalendarResponse\")\x82\xd3\xe4\x93\x02#:\x01*\x1a\x1e/v1/calendars/{calendar_id.id}\x12^\n" +
+	"\x0eDeleteCalendar\x12\x11.event.CalendarId\x1a\x1d.event.DeleteCalendarResponse\"\x1a\x82\xd3\xe4\x93\x02\x14*\x12/v1/calendars/{id}\x12S\n" +
+	"\fGetCalendars\x12\x1a.event.GetCalendarsRequest\x1a\x10.event.Calendars\"\x15\x82\xd3\xe4\x93\x02\x0f\x12\r/v1/calendars\x12o\n" +
+	"\rShareCalendar\x12\f.event.Share\x1a\x14.event.ShareResponse\":\x82\xd3\xe4\x93\x024:\x01*\x1a//v1/calendars/{calendar_id.id}/shares/{user_id}\x12y\n" +
+	"\x0fUnshareCalendar\x12\x15.event.UnshareRequest\x1a\x16.event.UnshareResponse\"7\x82\xd3\xe4\x93\x021*//v1/calendars/{calendar_id.id}/shares/{user_id}\x12P\n" +
+	"\tGetShares\x12\x11.event.CalendarId\x1a\r.event.Shares\"!\x82\xd3\xe4\x93\x02\x1b\x12\x19/v1/calendars/{id}/shares\x12]\n" +
+	"\n" +
+	"SaveDigest\x12\r.event.Digest\x1a\x19.event.SaveDigestResponse\"%\x82\xd3\xe4\x93\x02\x1f:\x01*\x1a\x1a/v1/users/{user_id}/digest\x12Y\n" +
+	"\fDeleteDigest\x12\r.event.UserId\x1a\x1b.event.DeleteDigestResponse\"\x1d\x82\xd3\xe4\x93\x02\x17*\x15/v1/users/{id}/digest\x12H\n" +
+	"\tGetDigest\x12\r.event.UserId\x1a\r.event.Digest\"\x1d\x82\xd3\xe4\x93\x02\x17\x12\x15/v1/users/{id}/digestB\xb8\x01\x92Ap\x12\x12\n" +
+	"\fCalendar API2\x02v1ZL\n" +
+	"J\n" +
+	"\x06UserId\x12@\b\x02\x12/ID of the user the request is made on behalf of\x1a\tx-user-id \x02b\f\n" +
+	"\n" +
 	"\n" +
-	"start_date\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\tstartDate2\x90\x03\n" +
-	"\fEventService\x12<\n" +
-	"\vCreateEvent\x12\x14.event.CreateRequest\x1a\x15.event.CreateResponse\"\x00\x12<\n" +
-	"\vUpdateEvent\x12\x14.event.UpdateRequest\x1a\x15.event.UpdateResponse\"\x00\x12<\n" +
-	"\vDeleteEvent\x12\x14.event.DeleteRequest\x1a\x15.event.DeleteResponse\"\x00\x12*\n" +
-	"\bGetEvent\x12\x0e.event.EventId\x1a\f.event.Event\"\x00\x121\n" +
-	"\fGetDayEvents\x12\x10.event.StartDate\x1a\r.event.Events\"\x00\x122\n" +
-	"\rGetWeekEvents\x12\x10.event.StartDate\x1a\r.event.Events\"\x00\x123\n" +
-	"\x0eGetMonthEvents\x12\x10.event.StartDate\x1a\r.event.Events\"\x00BEZCgithub.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/protob\x06proto3"
+	"\x06UserId\x12\x00ZCgithub.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/protob\x06proto3"
 
 var (
 	file_api_EventService_proto_rawDescOnce sync.Once
@@ -633,55 +1858,119 @@ func file_api_EventService_proto_rawDescGZIP() []byte {
 	return file_api_EventService_proto_rawDescData
 }
 
-var file_api_EventService_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_api_EventService_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_api_EventService_proto_msgTypes = make([]protoimpl.MessageInfo, 33)
 var file_api_EventService_proto_goTypes = []any{
-	(*CreateRequest)(nil),         // 0: event.CreateRequest
-	(*UpdateRequest)(nil),         // 1: event.UpdateRequest
-	(*DeleteRequest)(nil),         // 2: event.DeleteRequest
-	(*CreateResponse)(nil),        // 3: event.CreateResponse
-	(*UpdateResponse)(nil),        // 4: event.UpdateResponse
-	(*DeleteResponse)(nil),        // 5: event.DeleteResponse
-	(*Events)(nil),                // 6: event.Events
-	(*Event)(nil),                 // 7: event.Event
-	(*EventData)(nil),             // 8: event.EventData
-	(*EventId)(nil),               // 9: event.EventId
-	(*StartDate)(nil),             // 10: event.StartDate
-	(*timestamppb.Timestamp)(nil), // 11: google.protobuf.Timestamp
+	(Role)(0),                      // 0: event.Role
+	(*CreateRequest)(nil),          // 1: event.CreateRequest
+	(*UpdateRequest)(nil),          // 2: event.UpdateRequest
+	(*DeleteRequest)(nil),          // 3: event.DeleteRequest
+	(*CreateResponse)(nil),         // 4: event.CreateResponse
+	(*UpdateResponse)(nil),         // 5: event.UpdateResponse
+	(*DeleteResponse)(nil),         // 6: event.DeleteResponse
+	(*Events)(nil),                 // 7: event.Events
+	(*Event)(nil),                  // 8: event.Event
+	(*EventData)(nil),              // 9: event.EventData
+	(*EventId)(nil),                // 10: event.EventId
+	(*StartDate)(nil),              // 11: event.StartDate
+	(*SearchRequest)(nil),          // 12: event.SearchRequest
+	(*Tag)(nil),                    // 13: event.Tag
+	(*Tags)(nil),                   // 14: event.Tags
+	(*UserId)(nil),                 // 15: event.UserId
+	(*SaveTagResponse)(nil),        // 16: event.SaveTagResponse
+	(*DeleteTagRequest)(nil),       // 17: event.DeleteTagRequest
+	(*DeleteTagResponse)(nil),      // 18: event.DeleteTagResponse
+	(*Digest)(nil),                 // 19: event.Digest
+	(*SaveDigestResponse)(nil),     // 20: event.SaveDigestResponse
+	(*DeleteDigestResponse)(nil),   // 21: event.DeleteDigestResponse
+	(*Calendar)(nil),               // 22: event.Calendar
+	(*Calendars)(nil),              // 23: event.Calendars
+	(*CalendarId)(nil),             // 24: event.CalendarId
+	(*CreateCalendarResponse)(nil), // 25: event.CreateCalendarResponse
+	(*UpdateCalendarResponse)(nil), // 26: event.UpdateCalendarResponse
+	(*DeleteCalendarResponse)(nil), // 27: event.DeleteCalendarResponse
+	(*GetCalendarsRequest)(nil),    // 28: event.GetCalendarsRequest
+	(*Share)(nil),                  // 29: event.Share
+	(*Shares)(nil),                 // 30: event.Shares
+	(*ShareResponse)(nil),          // 31: event.ShareResponse
+	(*UnshareRequest)(nil),         // 32: event.UnshareRequest
+	(*UnshareResponse)(nil),        // 33: event.UnshareResponse
+	(*timestamppb.Timestamp)(nil),  // 34: google.protobuf.Timestamp
 }
 var file_api_EventService_proto_depIdxs = []int32{
-	8,  // 0: event.CreateRequest.event_data:type_name -> event.EventData
-	9,  // 1: event.UpdateRequest.event_id:type_name -> event.EventId
-	8,  // 2: event.UpdateRequest.event_data:type_name -> event.EventData
-	9,  // 3: event.DeleteRequest.event_id:type_name -> event.EventId
-	9,  // 4: event.CreateResponse.event_id:type_name -> event.EventId
-	7,  // 5: event.Events.events:type_name -> event.Event
-	9,  // 6: event.Event.event_id:type_name -> event.EventId
-	8,  // 7: event.Event.event_data:type_name -> event.EventData
-	11, // 8: event.EventData.date_time:type_name -> google.protobuf.Timestamp
-	11, // 9: event.EventData.remind_time:type_name -> google.protobuf.Timestamp
-	11, // 10: event.EventData.created_at:type_name -> google.protobuf.Timestamp
-	11, // 11: event.EventData.updated_at:type_name -> google.protobuf.Timestamp
-	11, // 12: event.EventData.remind_sent_time:type_name -> google.protobuf.Timestamp
-	11, // 13: event.StartDate.start_date:type_name -> google.protobuf.Timestamp
-	0,  // 14: event.EventService.CreateEvent:input_type -> event.CreateRequest
-	1,  // 15: event.EventService.UpdateEvent:input_type -> event.UpdateRequest
-	2,  // 16: event.EventService.DeleteEvent:input_type -> event.DeleteRequest
-	9,  // 17: event.EventService.GetEvent:input_type -> event.EventId
-	10, // 18: event.EventService.GetDayEvents:input_type -> event.StartDate
-	10, // 19: event.EventService.GetWeekEvents:input_type -> event.StartDate
-	10, // 20: event.EventService.GetMonthEvents:input_type -> event.StartDate
-	3,  // 21: event.EventService.CreateEvent:output_type -> event.CreateResponse
-	4,  // 22: event.EventService.UpdateEvent:output_type -> event.UpdateResponse
-	5,  // 23: event.EventService.DeleteEvent:output_type -> event.DeleteResponse
-	7,  // 24: event.EventService.GetEvent:output_type -> event.Event
-	6,  // 25: event.EventService.GetDayEvents:output_type -> event.Events
-	6,  // 26: event.EventService.GetWeekEvents:output_type -> event.Events
-	6,  // 27: event.EventService.GetMonthEvents:output_type -> event.Events
-	21, // [21:28] is the sub-list for method output_type
-	14, // [14:21] is the sub-list for method input_type
-	14, // [14:14] is the sub-list for extension type_name
-	14, // [14:14] is the sub-list for extension extendee
-	0,  // [0:14] is the sub-list for field type_name
+	9,  // 0: event.CreateRequest.event_data:type_name -> event.EventData
+	10, // 1: event.UpdateRequest.event_id:type_name -> event.EventId
+	9,  // 2: event.UpdateRequest.event_data:type_name -> event.EventData
+	10, // 3: event.DeleteRequest.event_id:type_name -> event.EventId
+	10, // 4: event.CreateResponse.event_id:type_name -> event.EventId
+	8,  // 5: event.Events.events:type_name -> event.Event
+	10, // 6: event.Event.event_id:type_name -> event.EventId
+	9,  // 7: event.Event.event_data:type_name -> event.EventData
+	34, // 8: event.EventData.date_time:type_name -> google.protobuf.Timestamp
+	34, // 9: event.EventData.remind_time:type_name -> google.protobuf.Timestamp
+	34, // 10: event.EventData.created_at:type_name -> google.protobuf.Timestamp
+	34, // 11: event.EventData.updated_at:type_name -> google.protobuf.Timestamp
+	34, // 12: event.EventData.remind_sent_time:type_name -> google.protobuf.Timestamp
+	34, // 13: event.StartDate.start_date:type_name -> google.protobuf.Timestamp
+	34, // 14: event.SearchRequest.start:type_name -> google.protobuf.Timestamp
+	34, // 15: event.SearchRequest.end:type_name -> google.protobuf.Timestamp
+	13, // 16: event.Tags.tags:type_name -> event.Tag
+	24, // 17: event.Calendar.calendar_id:type_name -> event.CalendarId
+	34, // 18: event.Calendar.created_at:type_name -> google.protobuf.Timestamp
+	34, // 19: event.Calendar.updated_at:type_name -> google.protobuf.Timestamp
+	22, // 20: event.Calendars.calendars:type_name -> event.Calendar
+	24, // 21: event.CreateCalendarResponse.calendar_id:type_name -> event.CalendarId
+	24, // 22: event.Share.calendar_id:type_name -> event.CalendarId
+	0,  // 23: event.Share.role:type_name -> event.Role
+	29, // 24: event.Shares.shares:type_name -> event.Share
+	24, // 25: event.UnshareRequest.calendar_id:type_name -> event.CalendarId
+	1,  // 26: event.EventService.CreateEvent:input_type -> event.CreateRequest
+	2,  // 27: event.EventService.UpdateEvent:input_type -> event.UpdateRequest
+	3,  // 28: event.EventService.DeleteEvent:input_type -> event.DeleteRequest
+	10, // 29: event.EventService.GetEvent:input_type -> event.EventId
+	11, // 30: event.EventService.GetDayEvents:input_type -> event.StartDate
+	11, // 31: event.EventService.GetWeekEvents:input_type -> event.StartDate
+	11, // 32: event.EventService.GetMonthEvents:input_type -> event.StartDate
+	12, // 33: event.EventService.SearchEvents:input_type -> event.SearchRequest
+	13, // 34: event.EventService.SaveTag:input_type -> event.Tag
+	17, // 35: event.EventService.DeleteTag:input_type -> event.DeleteTagRequest
+	15, // 36: event.EventService.GetTags:input_type -> event.UserId
+	22, // 37: event.EventService.CreateCalendar:input_type -> event.Calendar
+	22, // 38: event.EventService.UpdateCalendar:input_type -> event.Calendar
+	24, // 39: event.EventService.DeleteCalendar:input_type -> event.CalendarId
+	28, // 40: event.EventService.GetCalendars:input_type -> event.GetCalendarsRequest
+	29, // 41: event.EventService.ShareCalendar:input_type -> event.Share
+	32, // 42: event.EventService.UnshareCalendar:input_type -> event.UnshareRequest
+	24, // 43: event.EventService.GetShares:input_type -> event.CalendarId
+	19, // 44: event.EventService.SaveDigest:input_type -> event.Digest
+	15, // 45: event.EventService.DeleteDigest:input_type -> event.UserId
+	15, // 46: event.EventService.GetDigest:input_type -> event.UserId
+	4,  // 47: event.EventService.CreateEvent:output_type -> event.CreateResponse
+	5,  // 48: event.EventService.UpdateEvent:output_type -> event.UpdateResponse
+	6,  // 49: event.EventService.DeleteEvent:output_type -> event.DeleteResponse
+	8,  // 50: event.EventService.GetEvent:output_type -> event.Event
+	7,  // 51: event.EventService.GetDayEvents:output_type -> event.Events
+	7,  // 52: event.EventService.GetWeekEvents:output_type -> event.Events
+	7,  // 53: event.EventService.GetMonthEvents:output_type -> event.Events
+	7,  // 54: event.EventService.SearchEvents:output_type -> event.Events
+	16, // 55: event.EventService.SaveTag:output_type -> event.SaveTagResponse
+	18, // 56: event.EventService.DeleteTag:output_type -> event.DeleteTagResponse
+	14, // 57: event.EventService.GetTags:output_type -> event.Tags
+	25, // 58: event.EventService.CreateCalendar:output_type -> event.CreateCalendarResponse
+	26, // 59: event.EventService.UpdateCalendar:output_type -> event.UpdateCalendarResponse
+	27, // 60: event.EventService.DeleteCalendar:output_type -> event.DeleteCalendarResponse
+	23, // 61: event.EventService.GetCalendars:output_type -> event.Calendars
+	31, // 62: event.EventService.ShareCalendar:output_type -> event.ShareResponse
+	33, // 63: event.EventService.UnshareCalendar:output_type -> event.UnshareResponse
+	30, // 64: event.EventService.GetShares:output_type -> event.Shares
+	20, // 65: event.EventService.SaveDigest:output_type -> event.SaveDigestResponse
+	21, // 66: event.EventService.DeleteDigest:output_type -> event.DeleteDigestResponse
+	19, // 67: event.EventService.GetDigest:output_type -> event.Digest
+	47, // [47:68] is the sub-list for method output_type
+	26, // [26:47] is the sub-list for method input_type
+	26, // [26:26] is the sub-list for extension type_name
+	26, // [26:26] is the sub-list for extension extendee
+	0,  // [0:26] is the sub-list for field type_name
 }
 
 func init() { file_api_EventService_proto_init() }
@@ -694,13 +1983,14 @@ func file_api_EventService_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_EventService_proto_rawDesc), len(file_api_EventService_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   11,
+			NumEnums:      1,
+			NumMessages:   33,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_api_EventService_proto_goTypes,
 		DependencyIndexes: file_api_EventService_proto_depIdxs,
+		EnumInfos:         file_api_EventService_proto_enumTypes,
 		MessageInfos:      file_api_EventService_proto_msgTypes,
 	}.Build()
 	File_api_EventService_proto = out.File
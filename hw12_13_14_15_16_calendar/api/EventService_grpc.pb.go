@@ -4,6 +4,8 @@
 // - protoc             v6.33.0
 // source: api/EventService.proto
 
+// Requests are made on behalf of the user passed in x-user-id metadata.
+
 package proto
 
 import (
@@ -19,13 +21,27 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	EventService_CreateEvent_FullMethodName    = "/event.EventService/CreateEvent"
-	EventService_UpdateEvent_FullMethodName    = "/event.EventService/UpdateEvent"
-	EventService_DeleteEvent_FullMethodName    = "/event.EventService/DeleteEvent"
-	EventService_GetEvent_FullMethodName       = "/event.EventService/GetEvent"
-	EventService_GetDayEvents_FullMethodName   = "/event.EventService/GetDayEvents"
-	EventService_GetWeekEvents_FullMethodName  = "/event.EventService/GetWeekEvents"
-	EventService_GetMonthEvents_FullMethodName = "/event.EventService/GetMonthEvents"
+	EventService_CreateEvent_FullMethodName     = "/event.EventService/CreateEvent"
+	EventService_UpdateEvent_FullMethodName     = "/event.EventService/UpdateEvent"
+	EventService_DeleteEvent_FullMethodName     = "/event.EventService/DeleteEvent"
+	EventService_GetEvent_FullMethodName        = "/event.EventService/GetEvent"
+	EventService_GetDayEvents_FullMethodName    = "/event.EventService/GetDayEvents"
+	EventService_GetWeekEvents_FullMethodName   = "/event.EventService/GetWeekEvents"
+	EventService_GetMonthEvents_FullMethodName  = "/event.EventService/GetMonthEvents"
+	EventService_SearchEvents_FullMethodName    = "/event.EventService/SearchEvents"
+	EventService_SaveTag_FullMethodName         = "/event.EventService/SaveTag"
+	EventService_DeleteTag_FullMethodName       = "/event.EventService/DeleteTag"
+	EventService_GetTags_FullMethodName         = "/event.EventService/GetTags"
+	EventService_CreateCalendar_FullMethodName  = "/event.EventService/CreateCalendar"
+	EventService_UpdateCalendar_FullMethodName  = "/event.EventService/UpdateCalendar"
+	EventService_DeleteCalendar_FullMethodName  = "/event.EventService/DeleteCalendar"
+	EventService_GetCalendars_FullMethodName    = "/event.EventService/GetCalendars"
+	EventService_ShareCalendar_FullMethodName   = "/event.EventService/ShareCalendar"
+	EventService_UnshareCalendar_FullMethodName = "/event.EventService/UnshareCalendar"
+	EventService_GetShares_FullMethodName       = "/event.EventService/GetShares"
+	EventService_SaveDigest_FullMethodName      = "/event.EventService/SaveDigest"
+	EventService_DeleteDigest_FullMethodName    = "/event.EventService/DeleteDigest"
+	EventService_GetDigest_FullMethodName       = "/event.EventService/GetDigest"
 )
 
 // EventServiceClient is the client API for EventService service.
@@ -39,6 +55,20 @@ type EventServiceClient interface {
 	GetDayEvents(ctx context.Context, in *StartDate, opts ...grpc.CallOption) (*Events, error)
 	GetWeekEvents(ctx context.Context, in *StartDate, opts ...grpc.CallOption) (*Events, error)
 	GetMonthEvents(ctx context.Context, in *StartDate, opts ...grpc.CallOption) (*Events, error)
+	SearchEvents(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*Events, error)
+	SaveTag(ctx context.Context, in *Tag, opts ...grpc.CallOption) (*SaveTagResponse, error)
+	DeleteTag(ctx context.Context, in *DeleteTagRequest, opts ...grpc.CallOption) (*DeleteTagResponse, error)
+	GetTags(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*Tags, error)
+	CreateCalendar(ctx context.Context, in *Calendar, opts ...grpc.CallOption) (*CreateCalendarResponse, error)
+	UpdateCalendar(ctx context.Context, in *Calendar, opts ...grpc.CallOption) (*UpdateCalendarResponse, error)
+	DeleteCalendar(ctx context.Context, in *CalendarId, opts ...grpc.CallOption) (*DeleteCalendarResponse, error)
+	GetCalendars(ctx context.Context, in *GetCalendarsRequest, opts ...grpc.CallOption) (*Calendars, error)
+	ShareCalendar(ctx context.Context, in *Share, opts ...grpc.CallOption) (*ShareResponse, error)
+	UnshareCalendar(ctx context.Context, in *UnshareRequest, opts ...grpc.CallOption) (*UnshareResponse, error)
+	GetShares(ctx context.Context, in *CalendarId, opts ...grpc.CallOption) (*Shares, error)
+	SaveDigest(ctx context.Context, in *Digest, opts ...grpc.CallOption) (*SaveDigestResponse, error)
+	DeleteDigest(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*DeleteDigestResponse, error)
+	GetDigest(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*Digest, error)
 }
 
 type eventServiceClient struct {
@@ -119,6 +149,146 @@ func (c *eventServiceClient) GetMonthEvents(ctx context.Context, in *StartDate,
 	return out, nil
 }
 
+func (c *eventServiceClient) SearchEvents(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*Events, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Events)
+	err := c.cc.Invoke(ctx, EventService_SearchEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServiceClient) SaveTag(ctx context.Context, in *Tag, opts ...grpc.CallOption) (*SaveTagResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SaveTagResponse)
+	err := c.cc.Invoke(ctx, EventService_SaveTag_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServiceClient) DeleteTag(ctx context.Context, in *DeleteTagRequest, opts ...grpc.CallOption) (*DeleteTagResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteTagResponse)
+	err := c.cc.Invoke(ctx, EventService_DeleteTag_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServiceClient) GetTags(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*Tags, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Tags)
+	err := c.cc.Invoke(ctx, EventService_GetTags_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServiceClient) CreateCalendar(ctx context.Context, in *Calendar, opts ...grpc.CallOption) (*CreateCalendarResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CreateCalendarResponse)
+	err := c.cc.Invoke(ctx, EventService_CreateCalendar_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServiceClient) UpdateCalendar(ctx context.Context, in *Calendar, opts ...grpc.CallOption) (*UpdateCalendarResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateCalendarResponse)
+	err := c.cc.Invoke(ctx, EventService_UpdateCalendar_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServiceClient) DeleteCalendar(ctx context.Context, in *CalendarId, opts ...grpc.CallOption) (*DeleteCalendarResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteCalendarResponse)
+	err := c.cc.Invoke(ctx, EventService_DeleteCalendar_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServiceClient) GetCalendars(ctx context.Context, in *GetCalendarsRequest, opts ...grpc.CallOption) (*Calendars, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Calendars)
+	err := c.cc.Invoke(ctx, EventService_GetCalendars_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServiceClient) ShareCalendar(ctx context.Context, in *Share, opts ...grpc.CallOption) (*ShareResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ShareResponse)
+	err := c.cc.Invoke(ctx, EventService_ShareCalendar_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServiceClient) UnshareCalendar(ctx context.Context, in *UnshareRequest, opts ...grpc.CallOption) (*UnshareResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UnshareResponse)
+	err := c.cc.Invoke(ctx, EventService_UnshareCalendar_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServiceClient) GetShares(ctx context.Context, in *CalendarId, opts ...grpc.CallOption) (*Shares, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Shares)
+	err := c.cc.Invoke(ctx, EventService_GetShares_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServiceClient) SaveDigest(ctx context.Context, in *Digest, opts ...grpc.CallOption) (*SaveDigestResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SaveDigestResponse)
+	err := c.cc.Invoke(ctx, EventService_SaveDigest_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServiceClient) DeleteDigest(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*DeleteDigestResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteDigestResponse)
+	err := c.cc.Invoke(ctx, EventService_DeleteDigest_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *eventServiceClient) GetDigest(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*Digest, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Digest)
+	err := c.cc.Invoke(ctx, EventService_GetDigest_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // EventServiceServer is the server API for EventService service.
 // All implementations must embed UnimplementedEventServiceServer
 // for forward compatibility.
@@ -130,6 +300,20 @@ type EventServiceServer interface {
 	GetDayEvents(context.Context, *StartDate) (*Events, error)
 	GetWeekEvents(context.Context, *StartDate) (*Events, error)
 	GetMonthEvents(context.Context, *StartDate) (*Events, error)
+	SearchEvents(context.Context, *SearchRequest) (*Events, error)
+	SaveTag(context.Context, *Tag) (*SaveTagResponse, error)
+	DeleteTag(context.Context, *DeleteTagRequest) (*DeleteTagResponse, error)
+	GetTags(context.Context, *UserId) (*Tags, error)
+	CreateCalendar(context.Context, *Calendar) (*CreateCalendarResponse, error)
+	UpdateCalendar(context.Context, *Calendar) (*UpdateCalendarResponse, error)
+	DeleteCalendar(context.Context, *CalendarId) (*DeleteCalendarResponse, error)
+	GetCalendars(context.Context, *GetCalendarsRequest) (*Calendars, error)
+	ShareCalendar(context.Context, *Share) (*ShareResponse, error)
+	UnshareCalendar(context.Context, *UnshareRequest) (*UnshareResponse, error)
+	GetShares(context.Context, *CalendarId) (*Shares, error)
+	SaveDigest(context.Context, *Digest) (*SaveDigestResponse, error)
+	DeleteDigest(context.Context, *UserId) (*DeleteDigestResponse, error)
+	GetDigest(context.Context, *UserId) (*Digest, error)
 	mustEmbedUnimplementedEventServiceServer()
 }
 
@@ -161,6 +345,48 @@ func (UnimplementedEventServiceServer) GetWeekEvents(context.Context, *StartDate
 func (UnimplementedEventServiceServer) GetMonthEvents(context.Context, *StartDate) (*Events, error) {
 	return nil, status.Error(codes.Unimplemented, "method GetMonthEvents not implemented")
 }
+func (UnimplementedEventServiceServer) SearchEvents(context.Context, *SearchRequest) (*Events, error) {
+	return nil, status.Error(codes.Unimplemented, "method SearchEvents not implemented")
+}
+func (UnimplementedEventServiceServer) SaveTag(context.Context, *Tag) (*SaveTagResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SaveTag not implemented")
+}
+func (UnimplementedEventServiceServer) DeleteTag(context.Context, *DeleteTagRequest) (*DeleteTagResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteTag not implemented")
+}
+func (UnimplementedEventServiceServer) GetTags(context.Context, *UserId) (*Tags, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetTags not implemented")
+}
+func (UnimplementedEventServiceServer) CreateCalendar(context.Context, *Calendar) (*CreateCalendarResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreateCalendar not implemented")
+}
+func (UnimplementedEventServiceServer) UpdateCalendar(context.Context, *Calendar) (*UpdateCalendarResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method UpdateCalendar not implemented")
+}
+func (UnimplementedEventServiceServer) DeleteCalendar(context.Context, *CalendarId) (*DeleteCalendarResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteCalendar not implemented")
+}
+func (UnimplementedEventServiceServer) GetCalendars(context.Context, *GetCalendarsRequest) (*Calendars, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetCalendars not implemented")
+}
+func (UnimplementedEventServiceServer) ShareCalendar(context.Context, *Share) (*ShareResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ShareCalendar not implemented")
+}
+func (UnimplementedEventServiceServer) UnshareCalendar(context.Context, *UnshareRequest) (*UnshareResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method UnshareCalendar not implemented")
+}
+func (UnimplementedEventServiceServer) GetShares(context.Context, *CalendarId) (*Shares, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetShares not implemented")
+}
+func (UnimplementedEventServiceServer) SaveDigest(context.Context, *Digest) (*SaveDigestResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SaveDigest not implemented")
+}
+func (UnimplementedEventServiceServer) DeleteDigest(context.Context, *UserId) (*DeleteDigestResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DeleteDigest not implemented")
+}
+func (UnimplementedEventServiceServer) GetDigest(context.Context, *UserId) (*Digest, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetDigest not implemented")
+}
 func (UnimplementedEventServiceServer) mustEmbedUnimplementedEventServiceServer() {}
 func (UnimplementedEventServiceServer) testEmbeddedByValue()                      {}
 
@@ -308,6 +534,258 @@ func _EventService_GetMonthEvents_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _EventService_SearchEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).SearchEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_SearchEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).SearchEvents(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventService_SaveTag_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Tag)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).SaveTag(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_SaveTag_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).SaveTag(ctx, req.(*Tag))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventService_DeleteTag_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteTagRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).DeleteTag(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_DeleteTag_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).DeleteTag(ctx, req.(*DeleteTagRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventService_GetTags_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).GetTags(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_GetTags_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).GetTags(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventService_CreateCalendar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Calendar)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).CreateCalendar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_CreateCalendar_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).CreateCalendar(ctx, req.(*Calendar))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventService_UpdateCalendar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Calendar)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).UpdateCalendar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_UpdateCalendar_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).UpdateCalendar(ctx, req.(*Calendar))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventService_DeleteCalendar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CalendarId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).DeleteCalendar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_DeleteCalendar_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).DeleteCalendar(ctx, req.(*CalendarId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventService_GetCalendars_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCalendarsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).GetCalendars(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_GetCalendars_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).GetCalendars(ctx, req.(*GetCalendarsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventService_ShareCalendar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Share)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).ShareCalendar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_ShareCalendar_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).ShareCalendar(ctx, req.(*Share))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventService_UnshareCalendar_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnshareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).UnshareCalendar(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_UnshareCalendar_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).UnshareCalendar(ctx, req.(*UnshareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventService_GetShares_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CalendarId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).GetShares(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_GetShares_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).GetShares(ctx, req.(*CalendarId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventService_SaveDigest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Digest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).SaveDigest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_SaveDigest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).SaveDigest(ctx, req.(*Digest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventService_DeleteDigest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).DeleteDigest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_DeleteDigest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).DeleteDigest(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EventService_GetDigest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EventServiceServer).GetDigest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EventService_GetDigest_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EventServiceServer).GetDigest(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // EventService_ServiceDesc is the grpc.ServiceDesc for EventService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -343,6 +821,62 @@ var EventService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetMonthEvents",
 			Handler:    _EventService_GetMonthEvents_Handler,
 		},
+		{
+			MethodName: "SearchEvents",
+			Handler:    _EventService_SearchEvents_Handler,
+		},
+		{
+			MethodName: "SaveTag",
+			Handler:    _EventService_SaveTag_Handler,
+		},
+		{
+			MethodName: "DeleteTag",
+			Handler:    _EventService_DeleteTag_Handler,
+		},
+		{
+			MethodName: "GetTags",
+			Handler:    _EventService_GetTags_Handler,
+		},
+		{
+			MethodName: "CreateCalendar",
+			Handler:    _EventService_CreateCalendar_Handler,
+		},
+		{
+			MethodName: "UpdateCalendar",
+			Handler:    _EventService_UpdateCalendar_Handler,
+		},
+		{
+			MethodName: "DeleteCalendar",
+			Handler:    _EventService_DeleteCalendar_Handler,
+		},
+		{
+			MethodName: "GetCalendars",
+			Handler:    _EventService_GetCalendars_Handler,
+		},
+		{
+			MethodName: "ShareCalendar",
+			Handler:    _EventService_ShareCalendar_Handler,
+		},
+		{
+			MethodName: "UnshareCalendar",
+			Handler:    _EventService_UnshareCalendar_Handler,
+		},
+		{
+			MethodName: "GetShares",
+			Handler:    _EventService_GetShares_Handler,
+		},
+		{
+			MethodName: "SaveDigest",
+			Handler:    _EventService_SaveDigest_Handler,
+		},
+		{
+			MethodName: "DeleteDigest",
+			Handler:    _EventService_DeleteDigest_Handler,
+		},
+		{
+			MethodName: "GetDigest",
+			Handler:    _EventService_GetDigest_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/EventService.proto",
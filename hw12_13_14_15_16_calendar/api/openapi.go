@@ -0,0 +1,9 @@
+package proto
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI v2 document of the HTTP gateway, generated from
+// EventService.proto along with the gateway.
+//
+//go:embed EventService.swagger.json
+var OpenAPI []byte
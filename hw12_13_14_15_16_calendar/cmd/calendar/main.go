@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -11,18 +13,26 @@ import (
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/app"
 	common "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/app/_common"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/lifecycle"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server"
 	serverGRPC "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/grpc"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/grpc/ratelimit"
 	serverHTTP "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/http"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/http/health"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/service/calendar"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage"
+	cachestorage "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage/cache"
 )
 
-var configFile string
+var (
+	configFile  string
+	checkConfig bool
+)
 
 func init() {
 	flag.StringVar(&configFile, "config", "/configs/config.yml", "Path to configuration file")
+	flag.BoolVar(&checkConfig, "check-config", false, "Validate the configuration file and exit")
 }
 
 func main() {
@@ -38,23 +48,30 @@ func run() int {
 	}
 
 	ctx, cancel := signal.NotifyContext(context.Background(),
-		syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+		syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	configR, err := os.Open(configFile)
+	cfg, err := config.Load(configFile)
 	if err != nil {
-		log.Printf("%s", "Error opening config file: "+err.Error())
+		log.Printf("Error loading config: %v", err)
 		return 1
 	}
-
-	cfg, err := config.New(configR)
-	if err != nil {
-		log.Printf("%s", "Error parsing config file: "+err.Error())
+	if err = cfg.Validate(config.SectionServer, config.SectionStorage); err != nil {
+		log.Printf("Invalid config %s:\n%v", configFile, err)
 		return 1
 	}
+	if checkConfig {
+		log.Printf("Config %s is valid", configFile)
+		fmt.Print(cfg)
+		return 0
+	}
 	ctx = cfg.WithContext(ctx)
 
 	logg := logger.New(common.LevelMap[cfg.Logger.Level], os.Stdout)
+	logg.Debug("Config:\n%s", cfg)
+
+	reloader := config.NewReloader(configFile, cfg, logg, config.SectionServer, config.SectionStorage)
+	go reloader.Watch(ctx)
 
 	st, err := storage.Get(cfg.Storage)
 	if err != nil {
@@ -62,13 +79,44 @@ func run() int {
 		return 1
 	}
 
-	err = st.Connect(ctx)
+	if cfg.Cache.Enabled {
+		cache := cachestorage.New(st, cachestorage.Options{TTL: cfg.Cache.TTL, Size: cfg.Cache.Size})
+		// served at /debug/vars
+		expvar.Publish("storage_cache", cache.Var())
+		defer func() {
+			stats := cache.Stats()
+			logg.Info("Storage cache stats: hits %d, misses %d, evictions %d", stats.Hits, stats.Misses, stats.Evictions)
+		}()
+		st = cache
+	}
+
+	application := app.New(logg, st)
+	application.Quotas = common.Quotas{
+		MaxEventsPerUser: cfg.Quota.MaxEventsPerUser,
+		MaxEventsPerDay:  cfg.Quota.MaxEventsPerDay,
+	}
+	application.IdempotencyWindow = cfg.Idempotency.Window
+
+	manager := lifecycle.New(lifecycle.Options{
+		Timeout:    cfg.Shutdown.Timeout,
+		DrainDelay: cfg.Shutdown.DrainDelay,
+	}, logg)
+
+	tlsConfigs, err := loadTLS(cfg, logg)
+	if err != nil {
+		logg.Error("Error loading TLS certificates: %v", err)
+		return 1
+	}
+
+	trustedProxies, err := ratelimit.ParseProxies(cfg.RateLimit.TrustedProxies)
 	if err != nil {
-		logg.Error("Error init storage: " + err.Error())
+		logg.Error("Error parsing trusted proxies: %v", err)
 		return 1
 	}
 
-	application := app.New(logg, st)
+	checker := health.NewChecker(health.DefaultTimeout)
+	checker.Add("storage", st.Ping)
+	checker.Add("shutdown", manager.Draining)
 
 	srv := server.New(
 		server.Options{
@@ -76,13 +124,27 @@ func run() int {
 				Host:           cfg.GRPC.Host,
 				Port:           cfg.GRPC.Port,
 				ConnectTimeout: cfg.GRPC.ConnectTimeout,
+				Health:         checker,
+				RateLimit: ratelimit.Options{
+					UserRate:       cfg.RateLimit.User.Rate,
+					UserBurst:      cfg.RateLimit.User.Burst,
+					IPRate:         cfg.RateLimit.IP.Rate,
+					IPBurst:        cfg.RateLimit.IP.Burst,
+					TrustedProxies: trustedProxies,
+				},
+				TLS: tlsConfigs.grpc,
 			},
 			HTTP: serverHTTP.Options{
 				Host:         cfg.HTTP.Host,
 				Port:         cfg.HTTP.Port,
 				ReadTimeout:  cfg.HTTP.ReadTimeout,
 				WriteTimeout: cfg.HTTP.WriteTimeout,
+				Health:       checker,
+				TLS:          tlsConfigs.http,
+				GatewayTLS:   tlsConfigs.gateway,
 			},
+			ListenMode: cfg.Listen.Mode,
+			CalDAV:     cfg.HTTP.CalDAV.Enabled,
 		},
 		logg,
 		application,
@@ -90,11 +152,15 @@ func run() int {
 
 	service := calendar.New(srv, logg)
 
-	err = service.Run(ctx)
+	manager.Add(lifecycle.Component{Name: "storage", Start: st.Connect, Stop: st.Close})
+	manager.Add(service.Components()...)
+
+	err = manager.Run(ctx)
 	if err != nil {
-		logg.Error("Error starting calendar: %v", err)
+		logg.Error("Error running calendar: %v", err)
 		return 1
 	}
+	logg.Info("Calendar stopped.")
 
 	return 0
 }
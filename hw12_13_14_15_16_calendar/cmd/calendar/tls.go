@@ -0,0 +1,34 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/tlsconfig"
+)
+
+// tlsConfigs are the TLS configs of the listeners and of the gateway's
+// connection to the gRPC server, nil ones are plaintext.
+type tlsConfigs struct {
+	grpc, http, gateway *tls.Config
+}
+
+func loadTLS(cfg *config.Config, logg logger.Logger) (configs tlsConfigs, err error) {
+	if cfg.GRPC.TLS.Enabled {
+		if configs.grpc, err = tlsconfig.Server(cfg.GRPC.TLS, logg); err != nil {
+			return configs, fmt.Errorf("grpc.tls: %w", err)
+		}
+		if configs.gateway, err = tlsconfig.Client(cfg.GRPC.ClientTLS, logg); err != nil {
+			return configs, fmt.Errorf("grpc.clientTls: %w", err)
+		}
+	}
+	if cfg.HTTP.TLS.Enabled {
+		if configs.http, err = tlsconfig.Server(cfg.HTTP.TLS, logg); err != nil {
+			return configs, fmt.Errorf("http.tls: %w", err)
+		}
+	}
+
+	return configs, nil
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	proto "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/api"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type client struct {
+	api     proto.EventServiceClient
+	timeout time.Duration
+	json    bool
+	out     io.Writer
+}
+
+type command func(ctx context.Context, c *client, args []string) error
+
+var commands = map[string]command{
+	"create":    createEvent,
+	"update":    updateEvent,
+	"delete":    deleteEvent,
+	"get":       getEvent,
+	"day":       listEvents("day"),
+	"week":      listEvents("week"),
+	"month":     listEvents("month"),
+	"export":    exportEvents,
+	"import":    importEvents,
+	"reminders": showReminders,
+}
+
+// call limits a single request by the timeout.
+func (c *client) call(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// eventFlags are fields of an event passed as flags, only set ones are
+// applied.
+type eventFlags struct {
+	title, description, duration string
+	at, remind                   string
+	calendar, tags               string
+}
+
+func (f *eventFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.title, "title", "", "Event title")
+	fs.StringVar(&f.description, "description", "", "Event description")
+	fs.StringVar(&f.duration, "duration", "", "Event duration, HH:MM:SS")
+	fs.StringVar(&f.at, "at", "", "Event start, RFC 3339 or \"2006-01-02 15:04\" in local time")
+	fs.StringVar(&f.remind, "remind", "", "Reminder time, same formats as -at")
+	fs.StringVar(&f.calendar, "calendar", "", "Calendar ID, your first calendar by default")
+	fs.StringVar(&f.tags, "tags", "", "Comma separated tags")
+}
+
+func (f *eventFlags) apply(fs *flag.FlagSet, data *proto.EventData) error {
+	var err error
+
+	fs.Visit(func(set *flag.Flag) {
+		switch set.Name {
+		case "title":
+			data.Title = f.title
+		case "description":
+			data.Description = f.description
+		case "duration":
+			data.Duration = f.duration
+		case "calendar":
+			data.CalendarId = f.calendar
+		case "tags":
+			data.Tags = splitList(f.tags)
+		case "at", "remind":
+			var t time.Time
+			if t, err = parseTime(set.Value.String()); err != nil {
+				return
+			}
+			if set.Name == "at" {
+				data.DateTime = timestamppb.New(t)
+			} else {
+				data.RemindTime = timestamppb.New(t)
+			}
+		}
+	})
+
+	return err
+}
+
+func createEvent(ctx context.Context, c *client, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	var fields eventFlags
+	fields.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fields.title == "" || fields.at == "" {
+		return fmt.Errorf("%w: -title and -at are required", errUsage)
+	}
+
+	data := &proto.EventData{}
+	if err := fields.apply(fs, data); err != nil {
+		return err
+	}
+
+	ctx, cancel := c.call(ctx)
+	defer cancel()
+
+	resp, err := c.api.CreateEvent(ctx, &proto.CreateRequest{EventData: data})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out, resp.GetEventId().GetId())
+
+	return nil
+}
+
+func updateEvent(ctx context.Context, c *client, args []string) error {
+	fs := flag.NewFlagSet("update", flag.ContinueOnError)
+	var fields eventFlags
+	fields.register(fs)
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	id, err := singleArg(args, "event id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := c.call(ctx)
+	defer cancel()
+
+	event, err := c.api.GetEvent(ctx, &proto.EventId{Id: id})
+	if err != nil {
+		return err
+	}
+	data := event.GetEventData()
+	if err = fields.apply(fs, data); err != nil {
+		return err
+	}
+
+	_, err = c.api.UpdateEvent(ctx, &proto.UpdateRequest{EventId: event.GetEventId(), EventData: data})
+
+	return err
+}
+
+func deleteEvent(ctx context.Context, c *client, args []string) error {
+	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	id, err := singleArg(args, "event id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := c.call(ctx)
+	defer cancel()
+
+	_, err = c.api.DeleteEvent(ctx, &proto.DeleteRequest{EventId: &proto.EventId{Id: id}})
+
+	return err
+}
+
+func getEvent(ctx context.Context, c *client, args []string) error {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	id, err := singleArg(args, "event id")
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := c.call(ctx)
+	defer cancel()
+
+	event, err := c.api.GetEvent(ctx, &proto.EventId{Id: id})
+	if err != nil {
+		return err
+	}
+
+	return c.printEvents(&proto.Events{Events: []*proto.Event{event}})
+}
+
+func listEvents(period string) command {
+	return func(ctx context.Context, c *client, args []string) error {
+		fs := flag.NewFlagSet(period, flag.ContinueOnError)
+		tags := fs.String("tags", "", "Comma separated tags, events having any of them are listed")
+		args, err := parse(fs, args)
+		if err != nil {
+			return err
+		}
+
+		events, err := c.fetch(ctx, period, optionalArg(args), splitList(*tags))
+		if err != nil {
+			return err
+		}
+
+		return c.printEvents(events)
+	}
+}
+
+func exportEvents(ctx context.Context, c *client, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	period := fs.String("period", "month", "Period to export: day, week or month")
+	out := fs.String("out", "-", "File to write, - for stdout")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	events, err := c.fetch(ctx, *period, optionalArg(args), nil)
+	if err != nil {
+		return err
+	}
+
+	data, err := protojson.MarshalOptions{Multiline: true}.Marshal(events)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if *out == "-" {
+		_, err = c.out.Write(data)
+		return err
+	}
+
+	return os.WriteFile(*out, data, 0o640)
+}
+
+// importEvents creates every event of the file, events failing to be created
+// are reported and skipped.
+func importEvents(ctx context.Context, c *client, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	calendar := fs.String("calendar", "", "Calendar ID to import to, calendars of the file by default")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+	path, err := singleArg(args, "file")
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var events proto.Events
+	if err = protojson.Unmarshal(data, &events); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	var errs []error
+	for _, event := range events.GetEvents() {
+		if *calendar != "" {
+			event.GetEventData().CalendarId = *calendar
+		}
+		callCtx, cancel := c.call(ctx)
+		resp, err := c.api.CreateEvent(callCtx, &proto.CreateRequest{EventData: event.GetEventData()})
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("event %q: %w", event.GetEventData().GetTitle(), err))
+			continue
+		}
+		fmt.Fprintf(c.out, "%s\t%s\n", resp.GetEventId().GetId(), event.GetEventData().GetTitle())
+	}
+
+	return errors.Join(errs...)
+}
+
+func showReminders(ctx context.Context, c *client, args []string) error {
+	fs := flag.NewFlagSet("reminders", flag.ContinueOnError)
+	period := fs.String("period", "week", "Period to show: day, week or month")
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	events, err := c.fetch(ctx, *period, optionalArg(args), nil)
+	if err != nil {
+		return err
+	}
+
+	return c.printReminders(events, time.Now())
+}
+
+// fetch lists events of the period starting at date, today if date is empty.
+func (c *client) fetch(ctx context.Context, period, date string, tags []string) (*proto.Events, error) {
+	start := time.Now().UTC().Truncate(24 * time.Hour)
+	if date != "" {
+		var err error
+		if start, err = parseTime(date); err != nil {
+			return nil, err
+		}
+	}
+	req := &proto.StartDate{StartDate: timestamppb.New(start), Tags: tags}
+
+	ctx, cancel := c.call(ctx)
+	defer cancel()
+
+	switch period {
+	case "day":
+		return c.api.GetDayEvents(ctx, req)
+	case "week":
+		return c.api.GetWeekEvents(ctx, req)
+	case "month":
+		return c.api.GetMonthEvents(ctx, req)
+	default:
+		return nil, fmt.Errorf("%w: unknown period %q", errUsage, period)
+	}
+}
+
+// parse parses flags given before or after positional arguments, the flag
+// package stops at the first argument otherwise.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func singleArg(args []string, name string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("%w: expected %s", errUsage, name)
+	}
+
+	return args[0], nil
+}
+
+// parseTime accepts RFC 3339, a local "2006-01-02 15:04" time or a date,
+// which is taken as a UTC day as the API does.
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("%w: can't parse time %q", errUsage, value)
+}
+
+func optionalArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	return args[0]
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	items := strings.Split(value, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+
+	return items
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+	"time"
+
+	proto "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/api"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		positional []string
+		tags       string
+	}{
+		{name: "flags first", args: []string{"-tags", "a,b", "2026-10-20"}, positional: []string{"2026-10-20"}, tags: "a,b"},
+		{name: "flags last", args: []string{"2026-10-20", "-tags", "a"}, positional: []string{"2026-10-20"}, tags: "a"},
+		{name: "flags between", args: []string{"x", "-tags=a", "y"}, positional: []string{"x", "y"}, tags: "a"},
+		{name: "no args", args: nil, positional: nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			tags := fs.String("tags", "", "")
+
+			positional, err := parse(fs, tc.args)
+			require.NoError(t, err)
+			require.Equal(t, tc.positional, positional)
+			require.Equal(t, tc.tags, *tags)
+		})
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(&bytes.Buffer{})
+	_, err := parse(fs, []string{"-unknown"})
+	require.Error(t, err)
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Time
+	}{
+		{value: "2026-10-20T08:30:00Z", expected: time.Date(2026, 10, 20, 8, 30, 0, 0, time.UTC)},
+		{value: "2026-10-20T11:30:00+03:00", expected: time.Date(2026, 10, 20, 8, 30, 0, 0, time.UTC)},
+		{value: "2026-10-20 08:30", expected: time.Date(2026, 10, 20, 8, 30, 0, 0, time.Local)},
+		{value: "2026-10-20", expected: time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.value, func(t *testing.T) {
+			parsed, err := parseTime(tc.value)
+			require.NoError(t, err)
+			require.True(t, tc.expected.Equal(parsed), "got %s", parsed)
+		})
+	}
+
+	for _, value := range []string{"", "tomorrow", "20.10.2026", "2026-13-01"} {
+		_, err := parseTime(value)
+		require.ErrorIs(t, err, errUsage, value)
+	}
+}
+
+func TestReminderState(t *testing.T) {
+	now := time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		data     *proto.EventData
+		expected string
+	}{
+		{name: "no reminder", data: &proto.EventData{}, expected: "none"},
+		{
+			name:     "zero server time",
+			data:     &proto.EventData{RemindTime: timestamppb.New(time.Time{})},
+			expected: "none",
+		},
+		{
+			name:     "pending",
+			data:     &proto.EventData{RemindTime: timestamppb.New(now.Add(time.Minute))},
+			expected: "pending",
+		},
+		{name: "due", data: &proto.EventData{RemindTime: timestamppb.New(now)}, expected: "due"},
+		{
+			name: "sent",
+			data: &proto.EventData{
+				RemindTime:     timestamppb.New(now.Add(-time.Hour)),
+				RemindSentTime: timestamppb.New(now.Add(-time.Minute)),
+			},
+			expected: "sent",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, reminderState(tc.data, now))
+		})
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	tests := map[string][]string{
+		"":            nil,
+		"work":        {"work"},
+		"work,home":   {"work", "home"},
+		" work , q4 ": {"work", "q4"},
+	}
+	for value, expected := range tests {
+		require.Equal(t, expected, splitList(value), value)
+	}
+}
+
+func TestPrintEvents(t *testing.T) {
+	at := time.Date(2026, 10, 20, 8, 30, 0, 0, time.UTC)
+	events := &proto.Events{Events: []*proto.Event{
+		{
+			EventId: &proto.EventId{Id: "1"},
+			EventData: &proto.EventData{
+				Title:      "Planning",
+				DateTime:   timestamppb.New(at),
+				Duration:   "01:00:00",
+				Tags:       []string{"work", "q4"},
+				CalendarId: "c1",
+			},
+		},
+		{EventId: &proto.EventId{Id: "2"}, EventData: &proto.EventData{Title: "Someday"}},
+	}}
+	start := at.Local().Format(timeLayout)
+
+	t.Run("table", func(t *testing.T) {
+		var out bytes.Buffer
+		c := &client{out: &out}
+
+		require.NoError(t, c.printEvents(events))
+		require.Equal(
+			t,
+			"ID  START             DURATION  TITLE     TAGS     CALENDAR\n"+
+				"1   "+start+"  01:00:00  Planning  work,q4  c1\n"+
+				"2   -                           Someday            \n",
+			out.String(),
+		)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var out bytes.Buffer
+		c := &client{out: &out, json: true}
+
+		require.NoError(t, c.printEvents(events))
+		var printed proto.Events
+		require.NoError(t, protojson.Unmarshal(out.Bytes(), &printed))
+		require.Len(t, printed.GetEvents(), 2)
+		require.Equal(t, "Planning", printed.GetEvents()[0].GetEventData().GetTitle())
+		require.True(t, at.Equal(printed.GetEvents()[0].GetEventData().GetDateTime().AsTime()))
+	})
+
+	t.Run("reminders", func(t *testing.T) {
+		var out bytes.Buffer
+		c := &client{out: &out}
+		events := &proto.Events{Events: []*proto.Event{{
+			EventId:   &proto.EventId{Id: "1"},
+			EventData: &proto.EventData{Title: "Planning", RemindTime: timestamppb.New(at)},
+		}}}
+
+		require.NoError(t, c.printReminders(events, at.Add(-time.Hour)))
+		require.Equal(
+			t,
+			"ID  TITLE     REMIND AT         STATE    SENT AT\n"+
+				"1   Planning  "+start+"  pending  -\n",
+			out.String(),
+		)
+	})
+}
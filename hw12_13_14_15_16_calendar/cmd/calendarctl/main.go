@@ -0,0 +1,171 @@
+// Command calendarctl manages calendar events through the gRPC API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	proto "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/api"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/grpc/identity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/tlsconfig"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+const usage = `Usage: calendarctl [flags] <command> [command flags] [args]
+
+Commands:
+  create               create an event
+  update <id>          change fields of an event passed by flags
+  delete <id>          delete an event
+  get <id>             show an event
+  day|week|month [date]
+                       list events of the period starting at date, today by default
+  export [date]        write events of a period to a JSON file
+  import <file>        create events read from a JSON file made by export
+  reminders [date]     show reminder state of events of a period
+
+Flags:
+`
+
+var errUsage = errors.New("invalid usage")
+
+// options are the global flags.
+type options struct {
+	configFile string
+	addr       string
+	userID     int
+	timeout    time.Duration
+	json       bool
+}
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	var opts options
+
+	flag.StringVar(&opts.configFile, "config", "", "Path to configuration file to read the gRPC address and TLS from")
+	flag.StringVar(&opts.addr, "addr", "", "gRPC server address, overrides the config (default localhost:50051)")
+	flag.IntVar(&opts.userID, "user", 0, "ID of the user to act as")
+	flag.DurationVar(&opts.timeout, "timeout", 10*time.Second, "Request timeout")
+	flag.BoolVar(&opts.json, "json", false, "Print JSON instead of tables")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		return 2
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	err := execute(ctx, opts, flag.Arg(0), flag.Args()[1:])
+	if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return 1
+	}
+
+	return 0
+}
+
+func execute(ctx context.Context, opts options, command string, args []string) error {
+	handler, ok := commands[command]
+	if !ok {
+		return fmt.Errorf("%w: unknown command %q", errUsage, command)
+	}
+
+	addr, creds, err := target(opts)
+	if err != nil {
+		return err
+	}
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	if opts.userID != 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, identity.Header, strconv.Itoa(opts.userID))
+	}
+
+	c := &client{
+		api:     proto.NewEventServiceClient(conn),
+		timeout: opts.timeout,
+		json:    opts.json,
+		out:     os.Stdout,
+	}
+
+	return handler(ctx, c, args)
+}
+
+// target returns the --addr flag or the gRPC address of the config file and
+// the credentials to dial it with, TLS ones if the config enables gRPC TLS.
+// In single listen mode gRPC is served on the HTTP address with its TLS.
+func target(opts options) (string, credentials.TransportCredentials, error) {
+	addr, creds := opts.addr, insecure.NewCredentials()
+	if opts.configFile == "" {
+		if addr == "" {
+			addr = "localhost:50051"
+		}
+		return addr, creds, nil
+	}
+
+	f, err := os.Open(opts.configFile)
+	if err != nil {
+		return "", nil, fmt.Errorf("open config file: %w", err)
+	}
+	defer f.Close()
+
+	cfg, err := config.New(f)
+	if err != nil {
+		return "", nil, fmt.Errorf("parse config file: %w", err)
+	}
+
+	host, port, tlsEnabled := cfg.GRPC.Host, cfg.GRPC.Port, cfg.GRPC.TLS.Enabled
+	if cfg.Listen.Mode == server.ListenSingle {
+		host, port, tlsEnabled = cfg.HTTP.Host, cfg.HTTP.Port, cfg.HTTP.TLS.Enabled
+	}
+
+	if tlsEnabled {
+		tlsConfig, err := tlsconfig.Client(cfg.GRPC.ClientTLS, logger.New(logger.Warning, os.Stderr))
+		if err != nil {
+			return "", nil, fmt.Errorf("load TLS certificates: %w", err)
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	if addr == "" {
+		if host == "" || host == "0.0.0.0" {
+			host = "localhost"
+		}
+		addr = net.JoinHostPort(host, port)
+	}
+
+	return addr, creds, nil
+}
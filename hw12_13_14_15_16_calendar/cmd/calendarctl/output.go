@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	proto "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/api"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const timeLayout = "2006-01-02 15:04"
+
+func (c *client) printJSON(events *proto.Events) error {
+	data, err := protojson.MarshalOptions{Multiline: true}.Marshal(events)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.out, string(data))
+
+	return err
+}
+
+func (c *client) printEvents(events *proto.Events) error {
+	if c.json {
+		return c.printJSON(events)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTART\tDURATION\tTITLE\tTAGS\tCALENDAR")
+	for _, event := range events.GetEvents() {
+		data := event.GetEventData()
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			event.GetEventId().GetId(),
+			formatTime(data.GetDateTime()),
+			data.GetDuration(),
+			data.GetTitle(),
+			strings.Join(data.GetTags(), ","),
+			data.GetCalendarId(),
+		)
+	}
+
+	return w.Flush()
+}
+
+// printReminders shows whether reminders of events are pending, due or sent
+// at now.
+func (c *client) printReminders(events *proto.Events, now time.Time) error {
+	if c.json {
+		return c.printJSON(events)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTITLE\tREMIND AT\tSTATE\tSENT AT")
+	for _, event := range events.GetEvents() {
+		data := event.GetEventData()
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			event.GetEventId().GetId(),
+			data.GetTitle(),
+			formatTime(data.GetRemindTime()),
+			reminderState(data, now),
+			formatTime(data.GetRemindSentTime()),
+		)
+	}
+
+	return w.Flush()
+}
+
+func reminderState(data *proto.EventData, now time.Time) string {
+	switch {
+	case isZero(data.GetRemindTime()):
+		return "none"
+	case !isZero(data.GetRemindSentTime()):
+		return "sent"
+	case data.GetRemindTime().AsTime().After(now):
+		return "pending"
+	default:
+		return "due"
+	}
+}
+
+// isZero tells unset timestamps, zero times of the server included.
+func isZero(t *timestamppb.Timestamp) bool {
+	return t == nil || t.AsTime().IsZero() || t.AsTime().Unix() <= 0
+}
+
+func formatTime(t *timestamppb.Timestamp) string {
+	if isZero(t) {
+		return "-"
+	}
+
+	return t.AsTime().Local().Format(timeLayout)
+}
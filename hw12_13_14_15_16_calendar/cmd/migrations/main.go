@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 
@@ -11,10 +12,14 @@ import (
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
 )
 
-var configFile string
+var (
+	configFile  string
+	checkConfig bool
+)
 
 func init() {
 	flag.StringVar(&configFile, "config", "configs/config.yml", "Path to configuration file")
+	flag.BoolVar(&checkConfig, "check-config", false, "Validate the configuration file and exit")
 }
 
 func main() {
@@ -24,6 +29,20 @@ func main() {
 func run() int {
 	flag.Parse()
 
+	if checkConfig {
+		cfg, err := config.Load(configFile)
+		if err == nil {
+			err = cfg.Validate(config.SectionMigrations)
+		}
+		if err != nil {
+			log.Printf("Invalid config %s:\n%v", configFile, err)
+			return 1
+		}
+		log.Printf("Config %s is valid", configFile)
+		fmt.Print(cfg)
+		return 0
+	}
+
 	args := os.Args[1:]
 	if len(args) < 2 {
 		flag.Usage()
@@ -31,19 +50,17 @@ func run() int {
 	}
 	command := args[1]
 
-	configR, err := os.Open(configFile)
+	cfg, err := config.Load(configFile)
 	if err != nil {
-		log.Printf("%s", "Error opening config file: "+err.Error())
+		log.Printf("Error loading config: %v", err)
 		return 1
 	}
-
-	cfg, err := config.New(configR)
-	if err != nil {
-		log.Printf("%s", "Error parsing config file: "+err.Error())
+	if err = cfg.Validate(config.SectionMigrations); err != nil {
+		log.Printf("Invalid config %s:\n%v", configFile, err)
 		return 1
 	}
 
-	db, err := goose.OpenDBWithDriver("pgx", cfg.DB.Dsn)
+	db, err := goose.OpenDBWithDriver("pgx", cfg.DSN())
 	if err != nil {
 		log.Printf("goose: failed to open DB: %s", err.Error())
 		return 1
@@ -2,25 +2,38 @@ package main
 
 import (
 	"context"
+	"errors"
+	"expvar"
 	"flag"
+	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/app"
 	common "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/app/_common"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/leader"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/lifecycle"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/queue"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/http/health"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/service/scheduler"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage"
 )
 
-var configFile string
+var (
+	configFile  string
+	checkConfig bool
+)
 
 func init() {
 	flag.StringVar(&configFile, "config", "configs/config.yml", "Path to configuration file")
+	flag.BoolVar(&checkConfig, "check-config", false, "Validate the configuration file and exit")
 }
 
 func main() {
@@ -31,23 +44,31 @@ func run() int {
 	flag.Parse()
 
 	ctx, cancel := signal.NotifyContext(context.Background(),
-		syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+		syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	configR, err := os.Open(configFile)
+	cfg, err := config.Load(configFile)
 	if err != nil {
-		log.Printf("Error opening config file: %v", err)
+		log.Printf("Error loading config: %v", err)
 		return 1
 	}
-
-	cfg, err := config.New(configR)
+	err = errors.Join(
+		cfg.Validate(config.SectionStorage, config.SectionQueue, config.SectionScheduler),
+		scheduler.CheckConfig(cfg),
+	)
 	if err != nil {
-		log.Printf("Error parsing config file: %v", err)
+		log.Printf("Invalid config %s:\n%v", configFile, err)
 		return 1
 	}
+	if checkConfig {
+		log.Printf("Config %s is valid", configFile)
+		fmt.Print(cfg)
+		return 0
+	}
 	ctx = cfg.WithContext(ctx)
 
 	logg := logger.New(common.LevelMap[cfg.Logger.Level], os.Stdout)
+	logg.Debug("Config:\n%s", cfg)
 
 	st, err := storage.Get(cfg.Storage)
 	if err != nil {
@@ -55,40 +76,103 @@ func run() int {
 		return 1
 	}
 
-	err = st.Connect(ctx)
-	if err != nil {
-		logg.Error("Error init storage: %v", err)
-		return 1
-	}
-
 	qManager := queue.NewRabbitManager(logg)
-	err = qManager.Connect(ctx)
+
+	elector, err := leader.Get(ctx, logg)
 	if err != nil {
-		logg.Error("Error connecting to RabbitMQ server: %v", err)
+		logg.Error("Error getting leader elector: %v", err)
 		return 1
 	}
 
 	application := app.New(logg, st)
+	application.IdempotencyWindow = cfg.Idempotency.Window
 
 	service := scheduler.New(
 		application,
 		logg,
 		qManager,
+		elector,
 	)
-	err = service.Run(ctx)
-	if err != nil {
-		logg.Error("Error starting scheduler: %v", err)
-		return 1
+
+	reloader := config.NewReloader(configFile, cfg, logg,
+		config.SectionStorage, config.SectionQueue, config.SectionScheduler)
+	reloader.OnReload(service.Reload)
+	go reloader.Watch(ctx)
+
+	manager := lifecycle.New(lifecycle.Options{
+		Timeout:    cfg.Shutdown.Timeout,
+		DrainDelay: cfg.Shutdown.DrainDelay,
+	}, logg)
+
+	checker := health.NewChecker(health.DefaultTimeout)
+	checker.Add("storage", st.Ping)
+	checker.Add("broker", qManager.Ping)
+	checker.Add("jobs", func(context.Context) error {
+		return service.Jobs().Check(time.Now(), cfg.Scheduler.StaleAfter)
+	})
+	checker.Add("shutdown", manager.Draining)
+
+	// the scheduler stops first, publishing in flight before the broker closes
+	manager.Add(
+		lifecycle.Component{Name: "storage", Start: st.Connect, Stop: st.Close},
+		lifecycle.Component{
+			Name:  "broker",
+			Start: qManager.Connect,
+			Stop:  func(context.Context) error { return qManager.Close() },
+		},
+		lifecycle.Component{
+			Name: "leader elector",
+			Stop: func(context.Context) error { return elector.Close() },
+		},
+	)
+	if cfg.Scheduler.HealthPort != "" {
+		manager.Add(lifecycle.Component{
+			Name: "health server",
+			Run: func(ctx context.Context) error {
+				return serveHealth(ctx, net.JoinHostPort("", cfg.Scheduler.HealthPort), service, checker)
+			},
+		})
 	}
+	manager.Add(lifecycle.Component{Name: "scheduler", Run: service.Run})
 
-	err = qManager.Close()
+	err = manager.Run(ctx)
 	if err != nil {
-		logg.Error("Error closing RabbitMQ connection: %v", err)
-
+		logg.Error("Error running scheduler: %v", err)
 		return 1
 	}
-
-	logg.Info("Connection closed.")
+	logg.Info("Scheduler stopped.")
 
 	return 0
 }
+
+// serveHealth reports whether the replica is the leader at GET /health, the
+// probes at GET /health/live and /health/ready, metrics at GET /debug/vars and
+// job states at GET /admin/jobs.
+func serveHealth(
+	ctx context.Context,
+	addr string,
+	service *scheduler.Scheduler,
+	checker *health.Checker,
+) error {
+	mux := http.NewServeMux()
+	checker.Routes(mux)
+	mux.Handle("GET /health", health.NewStatusHandler(func() any {
+		return struct {
+			Leader bool `json:"leader"`
+		}{service.IsLeader()}
+	}))
+	mux.Handle("GET /debug/vars", expvar.Handler())
+	mux.Handle("GET /admin/jobs", service.Jobs().Handler())
+
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: time.Second}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
@@ -2,23 +2,34 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	common "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/app/_common"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/lifecycle"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/queue"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/http/health"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/service/sender"
 )
 
-var configFile string
+var (
+	configFile  string
+	checkConfig bool
+)
 
 func init() {
 	flag.StringVar(&configFile, "config", "configs/config.yml", "Path to configuration file")
+	flag.BoolVar(&checkConfig, "check-config", false, "Validate the configuration file and exit")
 }
 
 func main() {
@@ -29,46 +40,84 @@ func run() int {
 	flag.Parse()
 
 	ctx, cancel := signal.NotifyContext(context.Background(),
-		syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+		syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	configR, err := os.Open(configFile)
+	cfg, err := config.Load(configFile)
 	if err != nil {
-		log.Printf("Error opening config file: %v", err)
+		log.Printf("Error loading config: %v", err)
 		return 1
 	}
-
-	cfg, err := config.New(configR)
-	if err != nil {
-		log.Printf("Error parsing config file: %v", err)
+	if err = cfg.Validate(config.SectionQueue); err != nil {
+		log.Printf("Invalid config %s:\n%v", configFile, err)
 		return 1
 	}
+	if checkConfig {
+		log.Printf("Config %s is valid", configFile)
+		fmt.Print(cfg)
+		return 0
+	}
 	ctx = cfg.WithContext(ctx)
 
 	logg := logger.New(common.LevelMap[cfg.Logger.Level], os.Stdout)
+	logg.Debug("Config:\n%s", cfg)
+
+	reloader := config.NewReloader(configFile, cfg, logg, config.SectionQueue)
+	go reloader.Watch(ctx)
 
 	qManager := queue.NewRabbitManager(logg)
-	err = qManager.Connect(ctx)
-	if err != nil {
-		logg.Error("Error connecting to RabbitMQ server: %v", err)
-		return 1
-	}
 
 	service := sender.New(logg, qManager)
 
-	err = service.Run(ctx)
-	if err != nil {
-		logg.Error("Error starting sender: %v", err)
-		return 1
+	manager := lifecycle.New(lifecycle.Options{
+		Timeout:    cfg.Shutdown.Timeout,
+		DrainDelay: cfg.Shutdown.DrainDelay,
+	}, logg)
+
+	// the sender stops first, finishing the message in flight before the
+	// broker closes and redelivers the unacked ones
+	manager.Add(lifecycle.Component{
+		Name:  "broker",
+		Start: qManager.Connect,
+		Stop:  func(context.Context) error { return qManager.Close() },
+	})
+	if cfg.Sender.HealthPort != "" {
+		checker := health.NewChecker(health.DefaultTimeout)
+		checker.Add("broker", qManager.Ping)
+		checker.Add("shutdown", manager.Draining)
+		manager.Add(lifecycle.Component{
+			Name: "health server",
+			Run: func(ctx context.Context) error {
+				return serveHealth(ctx, net.JoinHostPort("", cfg.Sender.HealthPort), checker)
+			},
+		})
 	}
+	manager.Add(lifecycle.Component{Name: "sender", Run: service.Run})
 
-	err = qManager.Close()
+	err = manager.Run(ctx)
 	if err != nil {
-		logg.Error("Error closing RabbitMQ connection: %v", err)
+		logg.Error("Error running sender: %v", err)
 		return 1
 	}
-
-	logg.Info("Connection closed.")
+	logg.Info("Sender stopped.")
 
 	return 0
 }
+
+// serveHealth serves the probes at GET /health/live and /health/ready.
+func serveHealth(ctx context.Context, addr string, checker *health.Checker) error {
+	mux := http.NewServeMux()
+	checker.Routes(mux)
+
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: time.Second}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
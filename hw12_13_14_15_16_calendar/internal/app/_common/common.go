@@ -1,6 +1,8 @@
 package common
 
 import (
+	"time"
+
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage"
 )
@@ -8,6 +10,18 @@ import (
 type Deps struct {
 	Storage storage.Storage
 	Logger  logger.Logger
+	Quotas  Quotas
+	// IdempotencyWindow is how long results of requests with idempotency
+	// keys are returned to retries, keys are ignored if it is zero.
+	IdempotencyWindow time.Duration
+}
+
+// Quotas limit the events a user creates, zero values don't limit.
+type Quotas struct {
+	// MaxEventsPerUser limits events a user has.
+	MaxEventsPerUser int
+	// MaxEventsPerDay limits events a user creates in a UTC day.
+	MaxEventsPerDay int
 }
 
 var LevelMap = map[string]logger.Level{
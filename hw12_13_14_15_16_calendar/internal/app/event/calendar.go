@@ -0,0 +1,305 @@
+package event
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/identity"
+)
+
+// defaultCalendarName names the calendar created for a user's first event.
+const defaultCalendarName = "Default"
+
+var (
+	ErrNoUser          = errors.New("requesting user is not identified")
+	ErrForbidden       = errors.New("access denied")
+	ErrInvalidCalendar = errors.New("calendar name must not be empty")
+	ErrInvalidRole     = errors.New("unknown calendar role")
+	ErrShareToOwner    = errors.New("calendar can't be shared with its owner")
+)
+
+// CreateCalendar creates a calendar owned by the requesting user.
+func (a App) CreateCalendar(ctx context.Context, calendar entity.Calendar) (string, error) {
+	userID, err := a.userID(ctx)
+	if err != nil {
+		return "", err
+	}
+	if calendar.Name == "" {
+		return "", ErrInvalidCalendar
+	}
+
+	calendar.OwnerID = userID
+	id, err := a.Storage.CreateCalendar(ctx, calendar)
+	if err != nil {
+		a.Logger.Error(err.Error())
+
+		return "", err
+	}
+
+	return id, nil
+}
+
+// UpdateCalendar renames the calendar, the owner is kept.
+func (a App) UpdateCalendar(ctx context.Context, calendar entity.Calendar) error {
+	userID, err := a.userID(ctx)
+	if err != nil {
+		return err
+	}
+	if calendar.Name == "" {
+		return ErrInvalidCalendar
+	}
+
+	existing, err := a.Storage.GetCalendar(ctx, calendar.ID)
+	if err != nil {
+		return err
+	}
+	if err = a.authorize(ctx, userID, calendar.ID, entity.RoleOwner); err != nil {
+		return err
+	}
+
+	calendar.OwnerID = existing.OwnerID
+	if err = a.Storage.UpdateCalendar(ctx, calendar); err != nil {
+		a.Logger.Error(err.Error())
+
+		return err
+	}
+
+	return nil
+}
+
+// DeleteCalendar deletes the calendar with its events, only its owner can.
+func (a App) DeleteCalendar(ctx context.Context, id string) error {
+	userID, err := a.userID(ctx)
+	if err != nil {
+		return err
+	}
+
+	calendar, err := a.Storage.GetCalendar(ctx, id)
+	if err != nil {
+		return err
+	}
+	if calendar.OwnerID != userID {
+		return ErrForbidden
+	}
+
+	if err = a.Storage.DeleteCalendar(ctx, id); err != nil {
+		a.Logger.Error(err.Error())
+
+		return err
+	}
+
+	return nil
+}
+
+// GetCalendars returns calendars owned by or shared with the requesting user.
+func (a App) GetCalendars(ctx context.Context) (*entity.Calendars, error) {
+	userID, err := a.userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	calendars, err := a.Storage.GetCalendars(ctx, userID)
+	if err != nil {
+		a.Logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	return calendars, nil
+}
+
+// ShareCalendar grants a role on the calendar to another user or changes it.
+func (a App) ShareCalendar(ctx context.Context, share entity.Share) error {
+	userID, err := a.userID(ctx)
+	if err != nil {
+		return err
+	}
+	if !share.Role.Valid() {
+		return ErrInvalidRole
+	}
+
+	calendar, err := a.Storage.GetCalendar(ctx, share.CalendarID)
+	if err != nil {
+		return err
+	}
+	if share.UserID == calendar.OwnerID {
+		return ErrShareToOwner
+	}
+	if err = a.authorize(ctx, userID, share.CalendarID, entity.RoleOwner); err != nil {
+		return err
+	}
+
+	if err = a.Storage.SaveShare(ctx, share); err != nil {
+		a.Logger.Error(err.Error())
+
+		return err
+	}
+
+	return nil
+}
+
+// UnshareCalendar revokes the user's access. Users may revoke their own
+// access to calendars shared with them.
+func (a App) UnshareCalendar(ctx context.Context, calendarID string, shareUserID int) error {
+	userID, err := a.userID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if shareUserID != userID {
+		if err = a.authorize(ctx, userID, calendarID, entity.RoleOwner); err != nil {
+			return err
+		}
+	}
+
+	if err = a.Storage.DeleteShare(ctx, calendarID, shareUserID); err != nil {
+		a.Logger.Error(err.Error())
+
+		return err
+	}
+
+	return nil
+}
+
+func (a App) GetShares(ctx context.Context, calendarID string) (*entity.Shares, error) {
+	userID, err := a.userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = a.authorize(ctx, userID, calendarID, entity.RoleOwner); err != nil {
+		return nil, err
+	}
+
+	shares, err := a.Storage.GetShares(ctx, calendarID)
+	if err != nil {
+		a.Logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	return shares, nil
+}
+
+func (a App) userID(ctx context.Context) (int, error) {
+	userID, ok := identity.UserID(ctx)
+	if !ok {
+		return 0, ErrNoUser
+	}
+
+	return userID, nil
+}
+
+// checkUser allows users to manage only their own data.
+func (a App) checkUser(ctx context.Context, userID int) error {
+	requesting, err := a.userID(ctx)
+	if err != nil {
+		return err
+	}
+	if requesting != userID {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// authorize checks the user has at least the required role on the calendar.
+func (a App) authorize(ctx context.Context, userID int, calendarID string, required entity.Role) error {
+	role, err := a.role(ctx, userID, calendarID)
+	if err != nil {
+		return err
+	}
+	if !role.Includes(required) {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+func (a App) authorizeEvent(ctx context.Context, userID int, event *entity.Event, required entity.Role) error {
+	role, err := a.eventRole(ctx, userID, event)
+	if err != nil {
+		return err
+	}
+	if !role.Includes(required) {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// eventRole returns the user's role on the event's calendar. Events created
+// before calendars were added belong to their user only.
+func (a App) eventRole(ctx context.Context, userID int, event *entity.Event) (entity.Role, error) {
+	if event.CalendarID == "" {
+		if event.UserID == userID {
+			return entity.RoleOwner, nil
+		}
+
+		return "", nil
+	}
+
+	return a.role(ctx, userID, event.CalendarID)
+}
+
+// role returns the user's role on the calendar, empty if the user has no
+// access.
+func (a App) role(ctx context.Context, userID int, calendarID string) (entity.Role, error) {
+	calendar, err := a.Storage.GetCalendar(ctx, calendarID)
+	if err != nil {
+		return "", err
+	}
+	if calendar.OwnerID == userID {
+		return entity.RoleOwner, nil
+	}
+
+	shares, err := a.Storage.GetShares(ctx, calendarID)
+	if err != nil {
+		return "", err
+	}
+	for _, share := range *shares {
+		if share.UserID == userID {
+			return share.Role, nil
+		}
+	}
+
+	return "", nil
+}
+
+// scope returns the user's events and events of calendars the user has at
+// least the required role on.
+func scope(roles map[string]entity.Role, userID int, required entity.Role) entity.Scope {
+	scope := entity.Scope{UserID: userID, CalendarIDs: make([]string, 0, len(roles))}
+	for id, role := range roles {
+		if role.Includes(required) {
+			scope.CalendarIDs = append(scope.CalendarIDs, id)
+		}
+	}
+
+	return scope
+}
+
+// roleIn returns the user's role on the event given roles of Storage.GetRoles.
+func roleIn(roles map[string]entity.Role, userID int, event *entity.Event) entity.Role {
+	if event.CalendarID == "" && event.UserID == userID {
+		return entity.RoleOwner
+	}
+
+	return roles[event.CalendarID]
+}
+
+// defaultCalendar returns the oldest calendar owned by the user, creating one
+// if there is none.
+func (a App) defaultCalendar(ctx context.Context, userID int) (string, error) {
+	calendars, err := a.Storage.GetCalendars(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	for _, calendar := range *calendars {
+		if calendar.OwnerID == userID {
+			return calendar.ID, nil
+		}
+	}
+
+	return a.Storage.CreateCalendar(ctx, entity.Calendar{OwnerID: userID, Name: defaultCalendarName})
+}
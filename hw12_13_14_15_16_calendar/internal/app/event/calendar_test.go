@@ -0,0 +1,245 @@
+package event
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/identity"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendars(t *testing.T) {
+	app := createApp(t)
+	owner := identity.WithUserID(context.Background(), 1)
+	other := identity.WithUserID(context.Background(), 2)
+
+	_, err := app.CreateCalendar(context.Background(), entity.Calendar{Name: "work"})
+	require.ErrorIs(t, err, ErrNoUser)
+	_, err = app.CreateCalendar(owner, entity.Calendar{})
+	require.ErrorIs(t, err, ErrInvalidCalendar)
+
+	id, err := app.CreateCalendar(owner, entity.Calendar{Name: "work", OwnerID: 2})
+	require.NoError(t, err)
+
+	calendars, err := app.GetCalendars(owner)
+	require.NoError(t, err)
+	require.Len(t, *calendars, 1)
+	require.Equal(t, 1, (*calendars)[0].OwnerID)
+
+	calendars, err = app.GetCalendars(other)
+	require.NoError(t, err)
+	require.Empty(t, *calendars)
+
+	require.ErrorIs(t, app.UpdateCalendar(other, entity.Calendar{ID: id, Name: "mine"}), ErrForbidden)
+	require.NoError(t, app.UpdateCalendar(owner, entity.Calendar{ID: id, Name: "job"}))
+
+	require.ErrorIs(t, app.ShareCalendar(owner, entity.Share{CalendarID: id, UserID: 2, Role: "admin"}), ErrInvalidRole)
+	require.ErrorIs(t, app.ShareCalendar(owner, entity.Share{CalendarID: id, UserID: 1, Role: entity.RoleViewer}), ErrShareToOwner)
+	require.ErrorIs(t, app.ShareCalendar(other, entity.Share{CalendarID: id, UserID: 2, Role: entity.RoleOwner}), ErrForbidden)
+
+	require.NoError(t, app.ShareCalendar(owner, entity.Share{CalendarID: id, UserID: 2, Role: entity.RoleViewer}))
+	calendars, err = app.GetCalendars(other)
+	require.NoError(t, err)
+	require.Len(t, *calendars, 1)
+
+	_, err = app.GetShares(other, id)
+	require.ErrorIs(t, err, ErrForbidden)
+	shares, err := app.GetShares(owner, id)
+	require.NoError(t, err)
+	require.Len(t, *shares, 1)
+
+	// shared users may leave
+	require.NoError(t, app.UnshareCalendar(other, id, 2))
+	calendars, err = app.GetCalendars(other)
+	require.NoError(t, err)
+	require.Empty(t, *calendars)
+
+	require.ErrorIs(t, app.DeleteCalendar(other, id), ErrForbidden)
+	require.NoError(t, app.DeleteCalendar(owner, id))
+	_, err = app.GetCalendars(owner)
+	require.NoError(t, err)
+}
+
+func TestCalendarAccess(t *testing.T) {
+	app := createApp(t)
+	owner := identity.WithUserID(context.Background(), 1)
+	day := time.Date(2025, 12, 5, 0, 0, 0, 0, time.UTC)
+
+	// the first event without a calendar gets a default one
+	_, err := app.CreateEvent(owner, entity.Event{Title: "personal", DateTime: day.Add(time.Hour * 2)})
+	require.NoError(t, err)
+	calendars, err := app.GetCalendars(owner)
+	require.NoError(t, err)
+	require.Len(t, *calendars, 1)
+	require.Equal(t, defaultCalendarName, (*calendars)[0].Name)
+
+	calendarID, err := app.CreateCalendar(owner, entity.Calendar{Name: "team"})
+	require.NoError(t, err)
+	eventID, err := app.CreateEvent(owner, entity.Event{
+		Title:       "planning",
+		Description: "sprint planning",
+		CalendarID:  calendarID,
+		DateTime:    day.Add(time.Hour),
+		Duration:    "01:00:00",
+	})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		role                    entity.Role
+		read, details, mutation bool
+	}{
+		{role: "", read: false},
+		{role: entity.RoleFreeBusy, read: true},
+		{role: entity.RoleViewer, read: true, details: true},
+		{role: entity.RoleEditor, read: true, details: true, mutation: true},
+		{role: entity.RoleOwner, read: true, details: true, mutation: true},
+	} {
+		t.Run(string(tc.role), func(t *testing.T) {
+			userID := 10 + len(tc.role)
+			ctx := identity.WithUserID(context.Background(), userID)
+			if tc.role != "" {
+				require.NoError(t, app.ShareCalendar(owner, entity.Share{CalendarID: calendarID, UserID: userID, Role: tc.role}))
+			}
+
+			event, err := app.GetEvent(ctx, eventID)
+			events, listErr := app.GetDayEvents(ctx, day)
+			require.NoError(t, listErr)
+			if !tc.read {
+				require.ErrorIs(t, err, ErrForbidden)
+				require.Empty(t, *events)
+			} else {
+				require.NoError(t, err)
+				listed := findEvent(t, events, eventID)
+				require.True(t, event.DateTime.Equal(day.Add(time.Hour)))
+				if tc.details {
+					require.Equal(t, "planning", event.Title)
+					require.Equal(t, "planning", listed.Title)
+				} else {
+					require.Empty(t, event.Title)
+					require.Empty(t, event.Description)
+					require.Empty(t, listed.Title)
+				}
+			}
+
+			update := entity.Event{Title: "planning", DateTime: day.Add(time.Hour), Duration: "02:00:00"}
+			_, createErr := app.CreateEvent(ctx, entity.Event{
+				Title:      "created",
+				CalendarID: calendarID,
+				DateTime:   day.Add(time.Hour * time.Duration(3+len(tc.role))),
+			})
+			if tc.mutation {
+				require.NoError(t, app.UpdateEvent(ctx, eventID, update))
+				require.NoError(t, createErr)
+			} else {
+				require.ErrorIs(t, app.UpdateEvent(ctx, eventID, update), ErrForbidden)
+				require.ErrorIs(t, app.DeleteEvent(ctx, eventID), ErrForbidden)
+				require.ErrorIs(t, createErr, ErrForbidden)
+			}
+		})
+	}
+
+	// moving an event requires editing rights on the target calendar
+	other := identity.WithUserID(context.Background(), 2)
+	otherCalendarID, err := app.CreateCalendar(other, entity.Calendar{Name: "private"})
+	require.NoError(t, err)
+	err = app.UpdateEvent(owner, eventID, entity.Event{CalendarID: otherCalendarID, DateTime: day.Add(time.Hour)})
+	require.ErrorIs(t, err, ErrForbidden)
+
+	require.NoError(t, app.DeleteEvent(owner, eventID))
+}
+
+func TestSharedEventTags(t *testing.T) {
+	app := createApp(t)
+	owner := identity.WithUserID(context.Background(), 1)
+	editor := identity.WithUserID(context.Background(), 2)
+	day := time.Date(2025, 12, 5, 0, 0, 0, 0, time.UTC)
+
+	for _, tag := range []entity.Tag{{UserID: 1, Name: "work"}, {UserID: 1, Name: "q4"}, {UserID: 2, Name: "mine"}} {
+		require.NoError(t, app.SaveTag(identity.WithUserID(context.Background(), tag.UserID), tag))
+	}
+	calendarID, err := app.CreateCalendar(owner, entity.Calendar{Name: "team"})
+	require.NoError(t, err)
+	require.NoError(t, app.ShareCalendar(owner, entity.Share{CalendarID: calendarID, UserID: 2, Role: entity.RoleEditor}))
+	eventID, err := app.CreateEvent(owner, entity.Event{
+		Title: "planning", CalendarID: calendarID, DateTime: day.Add(time.Hour), Tags: []string{"work"},
+	})
+	require.NoError(t, err)
+
+	// tags are checked against the owner's catalog
+	update := entity.Event{Title: "planning", DateTime: day.Add(time.Hour), Tags: []string{"work"}}
+	require.NoError(t, app.UpdateEvent(editor, eventID, update))
+	update.Tags = []string{"work", "q4"}
+	require.NoError(t, app.UpdateEvent(editor, eventID, update))
+	update.Tags = []string{"work", "mine"}
+	require.ErrorIs(t, app.UpdateEvent(editor, eventID, update), ErrUnknownTag)
+
+	event, err := app.GetEvent(owner, eventID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"work", "q4"}, event.Tags)
+}
+
+func findEvent(t *testing.T, events *entity.Events, id string) *entity.Event {
+	t.Helper()
+
+	for _, event := range *events {
+		if event.ID == id {
+			return event
+		}
+	}
+	require.Failf(t, "event not listed", "id %s", id)
+
+	return nil
+}
+
+func TestCalendarEvents(t *testing.T) {
+	app := createApp(t)
+	owner := identity.WithUserID(context.Background(), 1)
+	other := identity.WithUserID(context.Background(), 2)
+	day := time.Date(2025, 12, 5, 0, 0, 0, 0, time.UTC)
+
+	workID, err := app.CreateCalendar(owner, entity.Calendar{Name: "work"})
+	require.NoError(t, err)
+	homeID, err := app.CreateCalendar(owner, entity.Calendar{Name: "home"})
+	require.NoError(t, err)
+	planningID, err := app.CreateEvent(owner, entity.Event{
+		Title: "planning", DateTime: day.Add(time.Hour), CalendarID: workID, UID: "planning@example.com",
+	})
+	require.NoError(t, err)
+	_, err = app.CreateEvent(owner, entity.Event{Title: "review", DateTime: day.AddDate(0, 1, 0), CalendarID: workID})
+	require.NoError(t, err)
+	_, err = app.CreateEvent(owner, entity.Event{Title: "dinner", DateTime: day.Add(time.Hour * 2), CalendarID: homeID})
+	require.NoError(t, err)
+
+	events, err := app.GetCalendarEvents(owner, workID, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, *events, 2)
+	events, err = app.GetCalendarEvents(owner, workID, day, day.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	require.Len(t, *events, 1)
+	require.Equal(t, planningID, (*events)[0].ID)
+
+	event, err := app.GetEventByUID(owner, workID, "planning@example.com")
+	require.NoError(t, err)
+	require.Equal(t, planningID, event.ID)
+	_, err = app.GetEventByUID(owner, homeID, "planning@example.com")
+	require.ErrorIs(t, err, entity.ErrEventNotFound)
+
+	// updates without a UID keep it
+	require.NoError(t, app.UpdateEvent(owner, planningID, entity.Event{Title: "retro", DateTime: day.Add(time.Hour * 3)}))
+	event, err = app.GetEventByUID(owner, workID, "planning@example.com")
+	require.NoError(t, err)
+	require.Equal(t, "retro", event.Title)
+
+	_, err = app.GetCalendarEvents(other, workID, time.Time{}, time.Time{})
+	require.ErrorIs(t, err, ErrForbidden)
+	_, err = app.GetEventByUID(other, workID, "planning@example.com")
+	require.ErrorIs(t, err, ErrForbidden)
+
+	// free/busy users get events without details
+	require.NoError(t, app.ShareCalendar(owner, entity.Share{CalendarID: workID, UserID: 2, Role: entity.RoleFreeBusy}))
+	event, err = app.GetEventByUID(other, workID, "planning@example.com")
+	require.NoError(t, err)
+	require.Empty(t, event.Title)
+}
@@ -0,0 +1,71 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+)
+
+const digestTimeLayout = "15:04"
+
+var (
+	ErrInvalidDigestTime = errors.New("digest time must be in HH:MM format")
+	ErrInvalidTimeZone   = errors.New("unknown time zone")
+)
+
+// SaveDigest subscribes the user to the daily agenda digest or changes the
+// subscription. Time zone defaults to UTC.
+func (a App) SaveDigest(ctx context.Context, digest entity.Digest) error {
+	if err := a.checkUser(ctx, digest.UserID); err != nil {
+		return err
+	}
+
+	at, err := time.Parse(digestTimeLayout, digest.Time)
+	if err != nil {
+		return ErrInvalidDigestTime
+	}
+	digest.Time = at.Format(digestTimeLayout)
+
+	if digest.TimeZone == "" {
+		digest.TimeZone = time.UTC.String()
+	}
+	if _, err = time.LoadLocation(digest.TimeZone); err != nil {
+		return ErrInvalidTimeZone
+	}
+
+	if err = a.Storage.SaveDigest(ctx, digest); err != nil {
+		a.Logger.Error(err.Error())
+
+		return err
+	}
+
+	return nil
+}
+
+func (a App) DeleteDigest(ctx context.Context, userID int) error {
+	if err := a.checkUser(ctx, userID); err != nil {
+		return err
+	}
+
+	return a.Storage.DeleteDigest(ctx, userID)
+}
+
+func (a App) GetDigest(ctx context.Context, userID int) (*entity.Digest, error) {
+	if err := a.checkUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return a.Storage.GetDigest(ctx, userID)
+}
+
+// GetDigests and MarkDigestSent are called by the scheduler, so access is not
+// checked.
+func (a App) GetDigests(ctx context.Context) (*entity.Digests, error) {
+	return a.Storage.GetDigests(ctx)
+}
+
+func (a App) MarkDigestSent(ctx context.Context, userID int, date string) error {
+	return a.Storage.MarkDigestSent(ctx, userID, date)
+}
@@ -1,11 +1,17 @@
 package event
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	common "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/app/_common"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/idempotency"
 )
 
 type App struct {
@@ -16,11 +22,58 @@ var (
 	ErrNotFound      = errors.New("event not found")
 	ErrDateBusy      = errors.New("time not available")
 	ErrEventIsActive = errors.New("can't modify active event")
+	ErrUnknownTag    = errors.New("tag is not in user's catalog")
+	ErrInvalidTag    = errors.New("tag name must not be empty")
+	ErrInvalidColor  = errors.New("tag color must be in #RRGGBB format")
+	ErrEmptyQuery    = errors.New("search query must not be empty")
 )
 
-// CreateEvent create event if requested time is not busy.
-func (a App) CreateEvent(event entity.Event) (string, error) {
-	existingEvent, getErr := a.Storage.GetForTime(event.DateTime)
+// defaultSearchLimit caps search results when the request doesn't.
+const defaultSearchLimit = 50
+
+// maxTime ends periods left open.
+var maxTime = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+var colorRe = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// CreateEvent create event if requested time is not busy. The event is created
+// by the requesting user, in their first own calendar if none is passed.
+// Requests with an idempotency key in ctx are done once, see createOnce.
+func (a App) CreateEvent(ctx context.Context, event entity.Event) (string, error) {
+	userID, err := a.userID(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if key, ok := idempotency.Key(ctx); ok && key != "" && a.IdempotencyWindow > 0 {
+		return a.createOnce(ctx, userID, key, event)
+	}
+
+	return a.createEvent(ctx, userID, event)
+}
+
+func (a App) createEvent(ctx context.Context, userID int, event entity.Event) (string, error) {
+	var err error
+	event.UserID = userID
+
+	if event.CalendarID == "" {
+		if event.CalendarID, err = a.defaultCalendar(ctx, userID); err != nil {
+			return "", err
+		}
+	}
+	if err = a.authorize(ctx, userID, event.CalendarID, entity.RoleEditor); err != nil {
+		return "", err
+	}
+
+	if err = a.checkTags(ctx, userID, event.Tags); err != nil {
+		return "", err
+	}
+
+	if err = a.checkQuotas(ctx, userID, time.Now()); err != nil {
+		return "", err
+	}
+
+	existingEvent, getErr := a.Storage.GetForTime(ctx, busyScope(event), event.DateTime)
 	if existingEvent != nil {
 		return "", ErrDateBusy
 	}
@@ -31,7 +84,7 @@ func (a App) CreateEvent(event entity.Event) (string, error) {
 		return "", getErr
 	}
 
-	id, createErr := a.Storage.Create(event)
+	id, createErr := a.Storage.Create(ctx, event)
 	if createErr != nil {
 		a.Logger.Error(createErr.Error())
 
@@ -41,16 +94,70 @@ func (a App) CreateEvent(event entity.Event) (string, error) {
 	return id, nil
 }
 
+// checkQuotas fails with entity.QuotaError if the user can't create more
+// events. Concurrent creates may exceed a quota by a few events.
+func (a App) checkQuotas(ctx context.Context, userID int, now time.Time) error {
+	perUser, perDay := a.Quotas.MaxEventsPerUser, a.Quotas.MaxEventsPerDay
+	if perUser <= 0 && perDay <= 0 {
+		return nil
+	}
+
+	dayStart := now.UTC().Truncate(24 * time.Hour)
+	total, today, err := a.Storage.CountUserEvents(ctx, userID, dayStart)
+	if err != nil {
+		return err
+	}
+
+	if perUser > 0 && total >= perUser {
+		return entity.QuotaError{Quota: "events per user", Limit: perUser}
+	}
+	if perDay > 0 && today >= perDay {
+		return entity.QuotaError{
+			Quota:      "events per day",
+			Limit:      perDay,
+			RetryAfter: dayStart.Add(24 * time.Hour).Sub(now),
+		}
+	}
+
+	return nil
+}
+
 // UpdateEvent updates event if it is not active and requested time is not busy.
-func (a App) UpdateEvent(id string, event entity.Event) error {
+// The user must be an editor of the event's calendar and of the calendar the
+// event is moved to.
+func (a App) UpdateEvent(ctx context.Context, id string, event entity.Event) error {
+	userID, err := a.userID(ctx)
+	if err != nil {
+		return err
+	}
+
 	// check has event
-	existingEvent, readErr := a.Storage.GetByID(id)
+	existingEvent, readErr := a.Storage.GetByID(ctx, id)
 	if readErr != nil {
 		a.Logger.Error(readErr.Error())
 
 		if errors.Is(readErr, entity.ErrEventNotFound) {
 			return ErrNotFound
 		}
+
+		return readErr
+	}
+
+	if err = a.authorizeEvent(ctx, userID, existingEvent, entity.RoleEditor); err != nil {
+		return err
+	}
+
+	event.ID = id
+	event.UserID = existingEvent.UserID
+	if event.UID == "" {
+		event.UID = existingEvent.UID
+	}
+	if event.CalendarID == "" {
+		event.CalendarID = existingEvent.CalendarID
+	} else if event.CalendarID != existingEvent.CalendarID {
+		if err = a.authorize(ctx, userID, event.CalendarID, entity.RoleEditor); err != nil {
+			return err
+		}
 	}
 
 	// check not active
@@ -58,9 +165,15 @@ func (a App) UpdateEvent(id string, event entity.Event) error {
 		return ErrEventIsActive
 	}
 
+	// tags are from the catalog of the event owner, unchanged ones aren't
+	// checked again
+	if err = a.checkTags(ctx, existingEvent.UserID, addedTags(existingEvent.Tags, event.Tags)); err != nil {
+		return err
+	}
+
 	// check new time not busy
-	eventWithRequestedTime, getErr := a.Storage.GetForTime(event.DateTime)
-	if eventWithRequestedTime != nil {
+	eventWithRequestedTime, getErr := a.Storage.GetForTime(ctx, busyScope(event), event.DateTime)
+	if eventWithRequestedTime != nil && eventWithRequestedTime.ID != id {
 		return ErrDateBusy
 	}
 
@@ -71,7 +184,7 @@ func (a App) UpdateEvent(id string, event entity.Event) error {
 	}
 
 	// update
-	updateErr := a.Storage.Update(event)
+	updateErr := a.Storage.Update(ctx, event)
 	if updateErr != nil {
 		return updateErr
 	}
@@ -80,19 +193,28 @@ func (a App) UpdateEvent(id string, event entity.Event) error {
 }
 
 // DeleteEvent deletes event if it is not active.
-func (a App) DeleteEvent(id string) error {
-	event, readErr := a.Storage.GetByID(id)
+func (a App) DeleteEvent(ctx context.Context, id string) error {
+	userID, err := a.userID(ctx)
+	if err != nil {
+		return err
+	}
+
+	event, readErr := a.Storage.GetByID(ctx, id)
 	if readErr != nil {
 		a.Logger.Error(readErr.Error())
 
 		return readErr
 	}
 
+	if err = a.authorizeEvent(ctx, userID, event, entity.RoleEditor); err != nil {
+		return err
+	}
+
 	if event.DateTime.Round(time.Minute) == time.Now().Round(time.Minute) {
 		return ErrEventIsActive
 	}
 
-	deleteErr := a.Storage.Delete(id)
+	deleteErr := a.Storage.Delete(ctx, id)
 	if deleteErr != nil {
 		a.Logger.Error(deleteErr.Error())
 
@@ -102,42 +224,103 @@ func (a App) DeleteEvent(id string) error {
 	return nil
 }
 
-// GetDayEvents returns events for passed day. Use UTC time format.
-func (a App) GetDayEvents(day time.Time) (*entity.Events, error) {
-	events, err := a.Storage.GetForPeriod(
-		day.Truncate(time.Hour*24),
-		day.Round(time.Hour*24),
-	)
-	if err != nil {
-		a.Logger.Error(err.Error())
-
-		return nil, err
-	}
+// GetDayEvents returns events for passed day having any of tags, all events
+// if tags are empty. Use UTC time format.
+func (a App) GetDayEvents(ctx context.Context, day time.Time, tags ...string) (*entity.Events, error) {
+	dayStart := day.Truncate(time.Hour * 24)
 
-	return events, nil
+	return a.getPeriodEvents(ctx, dayStart, dayStart.Add(time.Hour*24), tags)
 }
 
-// GetWeekEvents returns events for week starts with weekStart. Use UTC time format.
-func (a App) GetWeekEvents(weekStart time.Time) (*entity.Events, error) {
+// GetWeekEvents returns events for week starts with weekStart having any of
+// tags. Use UTC time format.
+func (a App) GetWeekEvents(ctx context.Context, weekStart time.Time, tags ...string) (*entity.Events, error) {
 	weekStart = weekStart.Truncate(time.Hour * 24)
 	weekEnd := weekStart.Add(time.Hour * 24 * 7)
 
-	events, err := a.Storage.GetForPeriod(weekStart, weekEnd)
+	return a.getPeriodEvents(ctx, weekStart, weekEnd, tags)
+}
+
+// GetMonthEvents returns events for month starts with monthStart having any of
+// tags. Use UTC time format.
+func (a App) GetMonthEvents(ctx context.Context, monthStart time.Time, tags ...string) (*entity.Events, error) {
+	monthStart = monthStart.Truncate(time.Hour * 24)
+	monthEnd := time.Date(monthStart.Year(), monthStart.Month()+1, monthStart.Day(), 0, 0, 0, 0, monthStart.Location())
+
+	return a.getPeriodEvents(ctx, monthStart, monthEnd, tags)
+}
+
+// GetPeriodEvents returns events of [start, end) having any of tags. Unlike
+// the day, week and month getters it keeps the bounds, so periods of other
+// time zones can be requested.
+func (a App) GetPeriodEvents(ctx context.Context, start, end time.Time, tags ...string) (*entity.Events, error) {
+	return a.getPeriodEvents(ctx, start, end, tags)
+}
+
+// getPeriodEvents returns events of calendars available to the user, events
+// of free/busy calendars are returned without details.
+func (a App) getPeriodEvents(
+	ctx context.Context,
+	start time.Time,
+	end time.Time,
+	tags []string,
+) (*entity.Events, error) {
+	userID, err := a.userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := a.Storage.GetRoles(ctx, userID)
 	if err != nil {
 		a.Logger.Error(err.Error())
 
 		return nil, err
 	}
 
-	return events, nil
+	events, err := a.Storage.GetForScope(ctx, scope(roles, userID, entity.RoleFreeBusy), start, end, tags...)
+	if err != nil {
+		a.Logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	visible := make(entity.Events, 0, len(*events))
+	for _, event := range *events {
+		visible = append(visible, restrict(event, roleIn(roles, userID, event)))
+	}
+
+	return &visible, nil
 }
 
-// GetMonthEvents returns events for month starts with monthStart. Use UTC time format.
-func (a App) GetMonthEvents(monthStart time.Time) (*entity.Events, error) {
-	monthStart = monthStart.Truncate(time.Hour * 24)
-	monthEnd := time.Date(monthStart.Year(), monthStart.Month()+1, monthStart.Day(), 0, 0, 0, 0, monthStart.Location())
+// SearchEvents returns up to limit events of [start, end) matching the query
+// and having any of the tags, best matches first. Zero times leave the period
+// open. Events of calendars shared as free/busy only are not searched.
+func (a App) SearchEvents(
+	ctx context.Context,
+	query string,
+	start, end time.Time,
+	limit int,
+	tags ...string,
+) (*entity.Events, error) {
+	userID, err := a.userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(query) == "" {
+		return nil, ErrEmptyQuery
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
 
-	events, err := a.Storage.GetForPeriod(monthStart, monthEnd)
+	roles, err := a.Storage.GetRoles(ctx, userID)
+	if err != nil {
+		a.Logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	events, err := a.Storage.Search(ctx, scope(roles, userID, entity.RoleViewer), query, start, end, limit, tags...)
 	if err != nil {
 		a.Logger.Error(err.Error())
 
@@ -147,28 +330,60 @@ func (a App) GetMonthEvents(monthStart time.Time) (*entity.Events, error) {
 	return events, nil
 }
 
-func (a App) DeleteEventsOlderThan(t time.Time) error {
-	return a.Storage.DeleteOlderThan(t)
+// DeleteEventsOlderThan, the remind methods and MarkEventAsReminded are called
+// by the service itself, so access is not checked.
+func (a App) DeleteEventsOlderThan(ctx context.Context, t time.Time) error {
+	return a.Storage.DeleteOlderThan(ctx, t)
+}
+
+// GetEventsOlderThan returns events starting before t, oldest first.
+func (a App) GetEventsOlderThan(ctx context.Context, t time.Time) (*entity.Events, error) {
+	return a.Storage.GetOlderThan(ctx, t)
 }
 
-func (a App) GetEventsForRemind() (*entity.Events, error) {
-	events, err := a.Storage.GetForRemind()
+// PurgeEvent deletes the event, even an active one.
+func (a App) PurgeEvent(ctx context.Context, id string) error {
+	return a.Storage.Delete(ctx, id)
+}
+
+func (a App) GetEventsForRemind(ctx context.Context) (*entity.Events, error) {
+	events, err := a.Storage.GetForRemind(ctx)
 	if err != nil {
 		return nil, err
 	}
 	return events, nil
 }
 
-func (a App) MarkEventAsReminded(id string) error {
-	err := a.Storage.MarkAsReminded(id)
+// ClaimEventsForRemind leases up to limit due reminders to the caller until
+// they are marked as reminded, released or the lease expires.
+func (a App) ClaimEventsForRemind(ctx context.Context, limit int, lease time.Duration) (*entity.Events, error) {
+	return a.Storage.ClaimForRemind(ctx, limit, lease)
+}
+
+func (a App) ReleaseEventClaim(ctx context.Context, id string) error {
+	return a.Storage.ReleaseClaim(ctx, id)
+}
+
+func (a App) CountEventsForRemind(ctx context.Context) (int, error) {
+	return a.Storage.CountForRemind(ctx)
+}
+
+func (a App) MarkEventAsReminded(ctx context.Context, id string) error {
+	err := a.Storage.MarkAsReminded(ctx, id)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (a App) GetEvent(id string) (*entity.Event, error) {
+func (a App) GetEvent(ctx context.Context, id string) (*entity.Event, error) {
+	userID, err := a.userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	event, err := a.Storage.GetByID(
+		ctx,
 		id,
 	)
 	if err != nil {
@@ -177,5 +392,194 @@ func (a App) GetEvent(id string) (*entity.Event, error) {
 		return nil, err
 	}
 
-	return event, nil
+	role, err := a.eventRole(ctx, userID, event)
+	if err != nil {
+		return nil, err
+	}
+	if !role.Includes(entity.RoleFreeBusy) {
+		return nil, ErrForbidden
+	}
+
+	return restrict(event, role), nil
+}
+
+// GetEventByUID returns the event of the calendar having the iCalendar UID.
+func (a App) GetEventByUID(ctx context.Context, calendarID, uid string) (*entity.Event, error) {
+	userID, err := a.userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := a.role(ctx, userID, calendarID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.Includes(entity.RoleFreeBusy) {
+		return nil, ErrForbidden
+	}
+
+	event, err := a.Storage.GetByUID(ctx, calendarID, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	return restrict(event, role), nil
+}
+
+// GetCalendarEvents returns events of the calendar starting in [start, end).
+// Zero times leave the period open.
+func (a App) GetCalendarEvents(
+	ctx context.Context,
+	calendarID string,
+	start time.Time,
+	end time.Time,
+) (*entity.Events, error) {
+	userID, err := a.userID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := a.role(ctx, userID, calendarID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.Includes(entity.RoleFreeBusy) {
+		return nil, ErrForbidden
+	}
+
+	if end.IsZero() {
+		end = maxTime
+	}
+	scope := entity.Scope{CalendarIDs: []string{calendarID}}
+	events, err := a.Storage.GetForScope(ctx, scope, start, end)
+	if err != nil {
+		a.Logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	calendarEvents := make(entity.Events, 0, len(*events))
+	for _, event := range *events {
+		calendarEvents = append(calendarEvents, restrict(event, role))
+	}
+
+	return &calendarEvents, nil
+}
+
+// SaveTag adds the tag to the user's catalog or changes its color. Color is
+// optional.
+func (a App) SaveTag(ctx context.Context, tag entity.Tag) error {
+	if err := a.checkUser(ctx, tag.UserID); err != nil {
+		return err
+	}
+	if tag.Name == "" {
+		return ErrInvalidTag
+	}
+	if tag.Color != "" && !colorRe.MatchString(tag.Color) {
+		return ErrInvalidColor
+	}
+
+	if err := a.Storage.SaveTag(ctx, tag); err != nil {
+		a.Logger.Error(err.Error())
+
+		return err
+	}
+
+	return nil
+}
+
+// DeleteTag removes the tag from the user's catalog and events.
+func (a App) DeleteTag(ctx context.Context, userID int, name string) error {
+	if err := a.checkUser(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := a.Storage.DeleteTag(ctx, userID, name); err != nil {
+		a.Logger.Error(err.Error())
+
+		return err
+	}
+
+	return nil
+}
+
+func (a App) GetTags(ctx context.Context, userID int) (*entity.Tags, error) {
+	if err := a.checkUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	tags, err := a.Storage.GetTags(ctx, userID)
+	if err != nil {
+		a.Logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// checkTags makes sure only tags of the user's catalog are used.
+func (a App) checkTags(ctx context.Context, userID int, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	tags, err := a.Storage.GetTags(ctx, userID)
+	if err != nil {
+		a.Logger.Error(err.Error())
+
+		return err
+	}
+
+	known := make(map[string]struct{}, len(*tags))
+	for _, tag := range *tags {
+		known[tag.Name] = struct{}{}
+	}
+	for _, name := range names {
+		if _, ok := known[name]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownTag, name)
+		}
+	}
+
+	return nil
+}
+
+// busyScope returns the events which may make the time of the event busy:
+// the ones of its calendar and, for legacy ones without a calendar, of its
+// owner.
+func busyScope(event entity.Event) entity.Scope {
+	scope := entity.Scope{UserID: event.UserID}
+	if event.CalendarID != "" {
+		scope.CalendarIDs = []string{event.CalendarID}
+	}
+
+	return scope
+}
+
+// addedTags returns the names which aren't in tags.
+func addedTags(tags, names []string) []string {
+	var added []string
+	for _, name := range names {
+		if !slices.Contains(tags, name) {
+			added = append(added, name)
+		}
+	}
+
+	return added
+}
+
+// restrict hides event details from users having free/busy access only.
+func restrict(event *entity.Event, role entity.Role) *entity.Event {
+	if role.Includes(entity.RoleViewer) {
+		return event
+	}
+
+	return &entity.Event{
+		ID:         event.ID,
+		CalendarID: event.CalendarID,
+		UserID:     event.UserID,
+		DateTime:   event.DateTime,
+		Duration:   event.Duration,
+		UID:        event.UID,
+	}
 }
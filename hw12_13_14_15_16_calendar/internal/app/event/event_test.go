@@ -9,6 +9,8 @@ import (
 
 	common "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/app/_common"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/idempotency"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/identity"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage"
 	"github.com/stretchr/testify/require"
@@ -34,6 +36,7 @@ func createApp(t *testing.T) *App {
 }
 
 func TestCreateEvent(t *testing.T) {
+	ctx := identity.WithUserID(context.Background(), 1)
 	app := createApp(t)
 	dateTime := time.Now()
 	event := entity.Event{
@@ -44,15 +47,16 @@ func TestCreateEvent(t *testing.T) {
 		RemindTime:  dateTime.Add(-time.Minute * 15),
 		UserID:      1,
 	}
-	id1, err := app.CreateEvent(event)
+	id1, err := app.CreateEvent(ctx, event)
 	require.NoError(t, err)
 	require.NotEmpty(t, id1)
 
-	eventFromStorage, err := app.Storage.GetByID(id1)
+	eventFromStorage, err := app.Storage.GetByID(ctx, id1)
 	require.NoError(t, err)
 	require.NotNil(t, eventFromStorage)
 
 	_, err = app.CreateEvent(
+		ctx,
 		entity.Event{
 			Title:       "event 2",
 			Description: "this is event 2",
@@ -65,7 +69,186 @@ func TestCreateEvent(t *testing.T) {
 	require.ErrorIs(t, err, ErrDateBusy)
 }
 
+func TestCreateEvent_Quotas(t *testing.T) {
+	ctx := identity.WithUserID(context.Background(), 1)
+	app := createApp(t)
+	app.Quotas = common.Quotas{MaxEventsPerUser: 3, MaxEventsPerDay: 2}
+
+	create := func(i int) error {
+		_, err := app.CreateEvent(ctx, entity.Event{
+			Title:    "event",
+			DateTime: time.Now().Add(time.Duration(i) * time.Hour),
+			Duration: "01:00:00",
+		})
+		return err
+	}
+	require.NoError(t, create(1))
+	require.NoError(t, create(2))
+
+	err := create(3)
+	var quotaErr entity.QuotaError
+	require.ErrorAs(t, err, &quotaErr)
+	require.ErrorIs(t, err, entity.ErrQuotaExceeded)
+	require.Equal(t, "events per day", quotaErr.Quota)
+	require.Positive(t, quotaErr.RetryAfter)
+	require.LessOrEqual(t, quotaErr.RetryAfter, 24*time.Hour)
+
+	app.Quotas.MaxEventsPerDay = 0
+	require.NoError(t, create(3))
+	require.ErrorAs(t, create(4), &quotaErr)
+	require.Equal(t, "events per user", quotaErr.Quota)
+	require.Zero(t, quotaErr.RetryAfter)
+
+	// quotas are per user
+	_, err = app.CreateEvent(identity.WithUserID(context.Background(), 2), entity.Event{
+		Title:    "other",
+		DateTime: time.Now().Add(10 * time.Hour),
+		Duration: "01:00:00",
+	})
+	require.NoError(t, err)
+}
+
+func TestCreateEvent_BusyPerCalendar(t *testing.T) {
+	app := createApp(t)
+	first := identity.WithUserID(context.Background(), 1)
+	second := identity.WithUserID(context.Background(), 2)
+	dateTime := time.Now().Add(10 * time.Hour).Truncate(time.Minute)
+
+	// other users' events don't make the time busy
+	_, err := app.CreateEvent(first, entity.Event{Title: "first", DateTime: dateTime, Duration: "01:00:00"})
+	require.NoError(t, err)
+	secondID, err := app.CreateEvent(second, entity.Event{Title: "second", DateTime: dateTime, Duration: "01:00:00"})
+	require.NoError(t, err)
+
+	_, err = app.CreateEvent(first, entity.Event{Title: "again", DateTime: dateTime, Duration: "01:00:00"})
+	require.ErrorIs(t, err, ErrDateBusy)
+
+	// the time of another calendar of the user is free
+	calendarID, err := app.CreateCalendar(first, entity.Calendar{Name: "home"})
+	require.NoError(t, err)
+	homeID, err := app.CreateEvent(first, entity.Event{
+		Title: "home", CalendarID: calendarID, DateTime: dateTime.Add(time.Hour), Duration: "01:00:00",
+	})
+	require.NoError(t, err)
+	require.NoError(t, app.UpdateEvent(first, homeID, entity.Event{Title: "home", DateTime: dateTime}))
+
+	require.NoError(t, app.UpdateEvent(second, secondID, entity.Event{Title: "moved", DateTime: dateTime}))
+}
+
+func TestCreateEvent_Idempotency(t *testing.T) {
+	ctx := identity.WithUserID(context.Background(), 1)
+	app := createApp(t)
+	app.IdempotencyWindow = time.Hour
+
+	dateTime := time.Now().Add(time.Hour)
+	event := entity.Event{Title: "event", DateTime: dateTime, Duration: "01:00:00"}
+	keyCtx := idempotency.WithKey(ctx, "key-1")
+
+	id, err := app.CreateEvent(keyCtx, event)
+	require.NoError(t, err)
+
+	// a retry returns the created event instead of failing as busy
+	replayed, err := app.CreateEvent(keyCtx, event)
+	require.NoError(t, err)
+	require.Equal(t, id, replayed)
+
+	other := event
+	other.DateTime = dateTime.Add(time.Hour)
+	_, err = app.CreateEvent(keyCtx, other)
+	require.ErrorIs(t, err, entity.ErrIdempotencyKeyReused)
+
+	// keys are per user
+	otherID, err := app.CreateEvent(idempotency.WithKey(identity.WithUserID(context.Background(), 2), "key-1"), other)
+	require.NoError(t, err)
+	require.NotEqual(t, id, otherID)
+
+	// failed requests don't keep the key
+	_, err = app.CreateEvent(idempotency.WithKey(ctx, "key-2"), event)
+	require.ErrorIs(t, err, ErrDateBusy)
+	_, err = app.CreateEvent(idempotency.WithKey(ctx, "key-2"), entity.Event{
+		Title: "event", DateTime: dateTime.Add(2 * time.Hour), Duration: "01:00:00",
+	})
+	require.NoError(t, err)
+
+	// expired keys are new requests
+	require.NoError(t, app.PurgeIdempotencyKeys(ctx, time.Now().Add(2*time.Hour)))
+	_, err = app.Storage.GetIdempotencyKey(ctx, 1, "key-1")
+	require.ErrorIs(t, err, entity.ErrIdempotencyKeyNotFound)
+	require.NoError(t, app.Storage.CreateIdempotencyKey(ctx, entity.IdempotencyKey{
+		UserID: 1, Key: "key-3", Hash: "stale", EventID: id, CreatedAt: time.Now().Add(-2 * time.Hour),
+	}))
+	_, err = app.CreateEvent(idempotency.WithKey(ctx, "key-3"), event)
+	require.ErrorIs(t, err, ErrDateBusy)
+
+	// a reservation is in progress until its lease runs out
+	later := entity.Event{Title: "later", DateTime: dateTime.Add(5 * time.Hour), Duration: "01:00:00"}
+	reserved := entity.IdempotencyKey{UserID: 1, Key: "key-4", CreatedAt: time.Now()}
+	reserved.Hash, err = requestHash(later)
+	require.NoError(t, err)
+	require.NoError(t, app.Storage.CreateIdempotencyKey(ctx, reserved))
+	_, err = app.CreateEvent(idempotency.WithKey(ctx, "key-4"), later)
+	require.ErrorIs(t, err, entity.ErrIdempotencyKeyInProgress)
+
+	reserved.CreatedAt = time.Now().Add(-2 * reservationLease)
+	require.NoError(t, app.Storage.SaveIdempotencyKey(ctx, reserved))
+	takenID, err := app.CreateEvent(idempotency.WithKey(ctx, "key-4"), later)
+	require.NoError(t, err)
+	saved, err := app.Storage.GetIdempotencyKey(ctx, 1, "key-4")
+	require.NoError(t, err)
+	require.Equal(t, takenID, saved.EventID)
+}
+
+// interleavedStorage runs interleave once, after the first read of a key, as
+// if a concurrent request ran at that moment.
+type interleavedStorage struct {
+	storage.Storage
+	interleave func()
+}
+
+func (s *interleavedStorage) GetIdempotencyKey(
+	ctx context.Context, userID int, key string,
+) (*entity.IdempotencyKey, error) {
+	result, err := s.Storage.GetIdempotencyKey(ctx, userID, key)
+	if interleave := s.interleave; interleave != nil {
+		s.interleave = nil
+		interleave()
+	}
+
+	return result, err
+}
+
+func TestCreateEvent_IdempotencyTakeover(t *testing.T) {
+	ctx := idempotency.WithKey(identity.WithUserID(context.Background(), 1), "key-1")
+	app := createApp(t)
+	app.IdempotencyWindow = time.Hour
+
+	event := entity.Event{Title: "event", DateTime: time.Now().Add(time.Hour), Duration: "01:00:00"}
+	stale := entity.IdempotencyKey{UserID: 1, Key: "key-1", CreatedAt: time.Now().Add(-2 * reservationLease)}
+	var err error
+	stale.Hash, err = requestHash(event)
+	require.NoError(t, err)
+	require.NoError(t, app.Storage.CreateIdempotencyKey(ctx, stale))
+
+	// both retries see the stale reservation, the second one takes it over
+	// first
+	var takenID string
+	var takenErr error
+	st := &interleavedStorage{Storage: app.Storage}
+	st.interleave = func() { takenID, takenErr = app.CreateEvent(ctx, event) }
+	app.Storage = st
+
+	id, err := app.CreateEvent(ctx, event)
+	require.NoError(t, err)
+	require.NoError(t, takenErr)
+	require.Equal(t, takenID, id)
+
+	saved, err := app.Storage.GetIdempotencyKey(ctx, 1, "key-1")
+	require.NoError(t, err)
+	require.Equal(t, id, saved.EventID)
+}
+
 func TestUpdateEvent(t *testing.T) {
+	ctx := identity.WithUserID(context.Background(), 1)
 	app := createApp(t)
 	dateTime := time.Now()
 	event := entity.Event{
@@ -78,39 +261,41 @@ func TestUpdateEvent(t *testing.T) {
 	}
 
 	// update unknown
-	err := app.UpdateEvent("random id", event)
+	err := app.UpdateEvent(ctx, "random id", event)
 	require.ErrorIs(t, err, ErrNotFound)
 
 	// fill storage
-	id1, err := app.CreateEvent(event)
+	id1, err := app.CreateEvent(ctx, event)
 	require.NoError(t, err)
 	event2 := event
 	event2.DateTime = dateTime.AddDate(0, 0, 1)
-	_, err = app.CreateEvent(event2)
+	_, err = app.CreateEvent(ctx, event2)
 	require.NoError(t, err)
 
 	// update to busy date
 	event.DateTime = event2.DateTime
-	err = app.UpdateEvent(id1, event)
+	err = app.UpdateEvent(ctx, id1, event)
 	require.ErrorIs(t, err, ErrDateBusy)
 
 	// successful update
 	event.ID = id1
 	event.DateTime = dateTime
-	err = app.UpdateEvent(id1, event)
+	err = app.UpdateEvent(ctx, id1, event)
 	require.NoError(t, err)
 
 	// update active
 	event.Title = "event 2"
-	err = app.UpdateEvent(id1, event)
+	err = app.UpdateEvent(ctx, id1, event)
 	require.ErrorIs(t, err, ErrEventIsActive)
 }
 
 func TestDeleteEvent(t *testing.T) {
+	ctx := identity.WithUserID(context.Background(), 1)
 	app := createApp(t)
 	dateTime := time.Now()
 
 	id1, err := app.CreateEvent(
+		ctx,
 		entity.Event{
 			Title:       "event 1",
 			Description: "this is event 1",
@@ -121,10 +306,11 @@ func TestDeleteEvent(t *testing.T) {
 		},
 	)
 	require.NoError(t, err)
-	err = app.DeleteEvent(id1)
+	err = app.DeleteEvent(ctx, id1)
 	require.NoError(t, err)
 
 	id2, err := app.CreateEvent(
+		ctx,
 		entity.Event{
 			Title:       "event 2",
 			Description: "this is event 2",
@@ -135,6 +321,122 @@ func TestDeleteEvent(t *testing.T) {
 		},
 	)
 	require.NoError(t, err)
-	deleteErr2 := app.DeleteEvent(id2)
+	deleteErr2 := app.DeleteEvent(ctx, id2)
 	require.ErrorIs(t, deleteErr2, ErrEventIsActive)
 }
+
+func TestGetDayEvents(t *testing.T) {
+	ctx := identity.WithUserID(context.Background(), 1)
+	app := createApp(t)
+	day := time.Date(2025, 12, 5, 0, 0, 0, 0, time.UTC)
+
+	for _, dateTime := range []time.Time{day, day.Add(time.Hour * 12), day.Add(time.Hour * 24)} {
+		_, err := app.CreateEvent(ctx, entity.Event{Title: dateTime.String(), DateTime: dateTime, UserID: 1})
+		require.NoError(t, err)
+	}
+
+	events, err := app.GetDayEvents(ctx, day)
+	require.NoError(t, err)
+	require.Len(t, *events, 2)
+
+	events, err = app.GetDayEvents(ctx, day.Add(time.Hour*18))
+	require.NoError(t, err)
+	require.Len(t, *events, 2)
+}
+
+func TestTags(t *testing.T) {
+	ctx := identity.WithUserID(context.Background(), 1)
+	app := createApp(t)
+	day := time.Date(2025, 12, 5, 0, 0, 0, 0, time.UTC)
+
+	require.ErrorIs(t, app.SaveTag(ctx, entity.Tag{UserID: 1, Name: "work", Color: "red"}), ErrInvalidColor)
+	require.ErrorIs(t, app.SaveTag(ctx, entity.Tag{UserID: 1, Color: "#ff0000"}), ErrInvalidTag)
+	require.NoError(t, app.SaveTag(ctx, entity.Tag{UserID: 1, Name: "work", Color: "#ff0000"}))
+
+	_, err := app.CreateEvent(ctx, entity.Event{Title: "1", DateTime: day, UserID: 1, Tags: []string{"home"}})
+	require.ErrorIs(t, err, ErrUnknownTag)
+	// tags are looked up in the requesting user's catalog
+	otherCtx := identity.WithUserID(context.Background(), 2)
+	_, err = app.CreateEvent(otherCtx, entity.Event{Title: "1", DateTime: day, UserID: 1, Tags: []string{"work"}})
+	require.ErrorIs(t, err, ErrUnknownTag)
+	require.ErrorIs(t, app.SaveTag(otherCtx, entity.Tag{UserID: 1, Name: "home"}), ErrForbidden)
+
+	_, err = app.CreateEvent(ctx, entity.Event{Title: "1", DateTime: day, UserID: 1, Tags: []string{"work"}})
+	require.NoError(t, err)
+	_, err = app.CreateEvent(ctx, entity.Event{Title: "2", DateTime: day.Add(time.Hour), UserID: 1})
+	require.NoError(t, err)
+
+	events, err := app.GetDayEvents(ctx, day, "work")
+	require.NoError(t, err)
+	require.Len(t, *events, 1)
+	require.Equal(t, "1", (*events)[0].Title)
+
+	events, err = app.GetWeekEvents(ctx, day)
+	require.NoError(t, err)
+	require.Len(t, *events, 2)
+
+	require.NoError(t, app.DeleteTag(ctx, 1, "work"))
+	events, err = app.GetMonthEvents(ctx, day, "work")
+	require.NoError(t, err)
+	require.Empty(t, *events)
+
+	tags, err := app.GetTags(ctx, 1)
+	require.NoError(t, err)
+	require.Empty(t, *tags)
+}
+
+func TestSearchEvents(t *testing.T) {
+	ctx := identity.WithUserID(context.Background(), 1)
+	app := createApp(t)
+	day := time.Date(2025, 12, 5, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, app.SaveTag(ctx, entity.Tag{UserID: 1, Name: "legal"}))
+	for i, title := range []string{"vendor call", "vendor contract", "standup"} {
+		event := entity.Event{Title: title, DateTime: day.Add(time.Hour * time.Duration(i))}
+		if title == "vendor contract" {
+			event.Tags = []string{"legal"}
+		}
+		_, err := app.CreateEvent(ctx, event)
+		require.NoError(t, err)
+	}
+
+	_, err := app.SearchEvents(ctx, " ", time.Time{}, time.Time{}, 0)
+	require.ErrorIs(t, err, ErrEmptyQuery)
+
+	events, err := app.SearchEvents(ctx, "vendor", time.Time{}, time.Time{}, 0)
+	require.NoError(t, err)
+	require.Len(t, *events, 2)
+
+	events, err = app.SearchEvents(ctx, "vendor", time.Time{}, time.Time{}, 1)
+	require.NoError(t, err)
+	require.Len(t, *events, 1)
+
+	events, err = app.SearchEvents(ctx, "vendor", time.Time{}, time.Time{}, 0, "legal")
+	require.NoError(t, err)
+	require.Len(t, *events, 1)
+	require.Equal(t, "vendor contract", (*events)[0].Title)
+
+	// events of other users' calendars are not found
+	events, err = app.SearchEvents(identity.WithUserID(context.Background(), 2), "vendor", time.Time{}, time.Time{}, 0)
+	require.NoError(t, err)
+	require.Empty(t, *events)
+}
+
+func TestDigests(t *testing.T) {
+	ctx := identity.WithUserID(context.Background(), 1)
+	app := createApp(t)
+
+	require.ErrorIs(t, app.SaveDigest(ctx, entity.Digest{UserID: 1, Time: "7 am"}), ErrInvalidDigestTime)
+	require.ErrorIs(t, app.SaveDigest(ctx, entity.Digest{UserID: 1, Time: "07:00", TimeZone: "Mars/Base"}),
+		ErrInvalidTimeZone)
+	require.ErrorIs(t, app.SaveDigest(ctx, entity.Digest{UserID: 2, Time: "07:00"}), ErrForbidden)
+
+	require.NoError(t, app.SaveDigest(ctx, entity.Digest{UserID: 1, Time: "7:05"}))
+	digest, err := app.GetDigest(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, entity.Digest{UserID: 1, Time: "07:05", TimeZone: "UTC"}, *digest)
+
+	require.NoError(t, app.DeleteDigest(ctx, 1))
+	_, err = app.GetDigest(ctx, 1)
+	require.ErrorIs(t, err, entity.ErrDigestNotFound)
+}
@@ -0,0 +1,149 @@
+package event
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+)
+
+// reservationLease is how long a key reserved by a request which hasn't
+// created its event yet blocks retries. It outlasts a create, so a reservation
+// left by a crashed request is taken over only after that request is gone.
+const reservationLease = time.Minute
+
+// createOnce creates the event unless the user has made the request with the
+// key within the idempotency window, then the event created by it is returned.
+// A key is reserved before creating, so concurrent retries don't create
+// duplicates, and released if creating fails, so the request may be retried.
+func (a App) createOnce(ctx context.Context, userID int, key string, event entity.Event) (string, error) {
+	hash, err := requestHash(event)
+	if err != nil {
+		return "", err
+	}
+
+	// stored times have microsecond precision, the reservation is deleted by
+	// its creation time
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	reservation := entity.IdempotencyKey{UserID: userID, Key: key, Hash: hash, CreatedAt: now}
+	existing, err := a.reserveKey(ctx, reservation)
+	if err != nil {
+		a.Logger.Error(err.Error())
+
+		return "", err
+	}
+	if existing != nil {
+		switch {
+		case existing.Hash != hash:
+			return "", entity.ErrIdempotencyKeyReused
+		case existing.EventID == "":
+			return "", entity.ErrIdempotencyKeyInProgress
+		}
+
+		return existing.EventID, nil
+	}
+
+	id, err := a.createEvent(ctx, userID, event)
+	if err != nil {
+		a.releaseKey(context.WithoutCancel(ctx), reservation)
+
+		return "", err
+	}
+
+	reservation.EventID = id
+	if err = a.Storage.SaveIdempotencyKey(context.WithoutCancel(ctx), reservation); err != nil {
+		a.Logger.Error(err.Error())
+		// without the key a retry would create the event again
+		a.rollbackCreate(context.WithoutCancel(ctx), reservation, id)
+
+		return "", err
+	}
+
+	return id, nil
+}
+
+// rollbackCreate deletes the event created for the reservation and releases
+// it.
+func (a App) rollbackCreate(ctx context.Context, reservation entity.IdempotencyKey, id string) {
+	if err := a.Storage.Delete(ctx, id); err != nil {
+		a.Logger.Error(err.Error())
+	}
+	a.releaseKey(ctx, reservation)
+}
+
+// releaseKey deletes the reservation unless another request has taken the key
+// over since.
+func (a App) releaseKey(ctx context.Context, reservation entity.IdempotencyKey) {
+	if err := a.Storage.DeleteIdempotencyKey(ctx, reservation); err != nil &&
+		!errors.Is(err, entity.ErrIdempotencyKeyNotFound) {
+		a.Logger.Error(err.Error())
+	}
+}
+
+// reserveKey stores the reservation. If the key is already stored and
+// neither expired nor an abandoned reservation, it returns the stored key
+// instead.
+func (a App) reserveKey(ctx context.Context, reservation entity.IdempotencyKey) (*entity.IdempotencyKey, error) {
+	// an expired or released key is replaced on the second attempt
+	for range 2 {
+		err := a.Storage.CreateIdempotencyKey(ctx, reservation)
+		if !errors.Is(err, entity.ErrIdempotencyKeyExists) {
+			return nil, err
+		}
+
+		existing, err := a.Storage.GetIdempotencyKey(ctx, reservation.UserID, reservation.Key)
+		switch {
+		case errors.Is(err, entity.ErrIdempotencyKeyNotFound):
+			continue
+		case err != nil:
+			return nil, err
+		case a.isStale(*existing, reservation.CreatedAt):
+			// a key another request has replaced meanwhile is kept
+			err = a.Storage.DeleteIdempotencyKey(ctx, *existing)
+			if err != nil && !errors.Is(err, entity.ErrIdempotencyKeyNotFound) {
+				return nil, err
+			}
+			continue
+		}
+
+		return existing, nil
+	}
+
+	// the key is being replaced concurrently
+	return nil, entity.ErrIdempotencyKeyInProgress
+}
+
+// isStale reports whether the key may be replaced at now: it has left the
+// idempotency window, or its reservation lease has run out without an event.
+func (a App) isStale(key entity.IdempotencyKey, now time.Time) bool {
+	if key.EventID == "" {
+		return key.CreatedAt.Before(now.Add(-reservationLease))
+	}
+
+	return key.CreatedAt.Before(now.Add(-a.IdempotencyWindow))
+}
+
+// requestHash identifies the payload of a create request.
+func requestHash(event entity.Event) (string, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// PurgeIdempotencyKeys deletes keys which have left the idempotency window by
+// now. It is called by the service itself.
+func (a App) PurgeIdempotencyKeys(ctx context.Context, now time.Time) error {
+	if a.IdempotencyWindow <= 0 {
+		return nil
+	}
+
+	return a.Storage.DeleteIdempotencyKeysBefore(ctx, now.Add(-a.IdempotencyWindow))
+}
@@ -3,11 +3,13 @@ package config
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"os"
 	"time"
 
+	"github.com/caarlos0/env/v10"
 	"github.com/creasty/defaults"
-	gocfg "github.com/dsbasko/go-cfg"
 	"gopkg.in/yaml.v3"
 )
 
@@ -23,42 +25,186 @@ type Config struct {
 	Logger struct {
 		Level string `yaml:"level" env:"LOG_LEVEL" default:"debug"`
 	} `yaml:"logger"`
+	Shutdown struct {
+		// Timeout bounds draining and stopping all components of a service.
+		Timeout time.Duration `default:"15s" yaml:"timeout" env:"SHUTDOWN_TIMEOUT"`
+		// DrainDelay keeps serving with a failing readiness probe before
+		// stopping, so load balancers stop routing requests first.
+		DrainDelay time.Duration `yaml:"drainDelay" env:"SHUTDOWN_DRAIN_DELAY"`
+	} `yaml:"shutdown"`
 	HTTP struct {
 		Host         string        `yaml:"host" env:"HTTP_HOST"`
 		Port         string        `yaml:"port" env:"HTTP_PORT"`
 		ReadTimeout  time.Duration `default:"5s" yaml:"readTimeout" env:"HTTP_READ_TIMEOUT"`
 		WriteTimeout time.Duration `default:"5s" yaml:"writeTimeout" env:"HTTP_WRITE_TIMEOUT"`
+		TLS          ServerTLS     `yaml:"tls" envPrefix:"HTTP_TLS_"`
+		// CalDAV serves calendars to desktop and mobile clients under /dav/.
+		// It is off by default: users must be authenticated by a proxy
+		// setting x-user-id, which is taken only from loopback and
+		// rateLimit.trustedProxies.
+		CalDAV struct {
+			Enabled bool `yaml:"enabled" env:"HTTP_CALDAV_ENABLED"`
+		} `yaml:"caldav"`
 	} `yaml:"http"`
 	GRPC struct {
 		Host           string        `yaml:"host" env:"GRPC_HOST"`
 		Port           string        `yaml:"port" env:"GRPC_PORT"`
 		ConnectTimeout time.Duration `default:"5s" yaml:"connectTimeout" env:"GRPC_CONNECT_TIMEOUT"`
+		TLS            ServerTLS     `yaml:"tls" envPrefix:"GRPC_TLS_"`
+		// ClientTLS is used by the HTTP gateway and calendarctl to dial the
+		// server when its TLS is enabled.
+		ClientTLS ClientTLS `yaml:"clientTls" envPrefix:"GRPC_CLIENT_TLS_"`
 	} `yaml:"grpc"`
+	// Listen is "separate" to serve gRPC and HTTP on their own ports or
+	// "single" to serve both on the HTTP port.
+	Listen struct {
+		Mode string `default:"separate" yaml:"mode" env:"LISTEN_MODE"`
+	} `yaml:"listen"`
+	// RateLimit limits API requests per second with token buckets, zero rates
+	// don't limit.
+	RateLimit struct {
+		User struct {
+			Rate  float64 `yaml:"rate" env:"RATE_LIMIT_USER_RATE"`
+			Burst int     `yaml:"burst" env:"RATE_LIMIT_USER_BURST"`
+		} `yaml:"user"`
+		IP struct {
+			Rate  float64 `yaml:"rate" env:"RATE_LIMIT_IP_RATE"`
+			Burst int     `yaml:"burst" env:"RATE_LIMIT_IP_BURST"`
+		} `yaml:"ip"`
+		// TrustedProxies are CIDRs of proxies in front of the HTTP gateway,
+		// client addresses they put in X-Forwarded-For are limited instead
+		// of theirs.
+		TrustedProxies []string `yaml:"trustedProxies" env:"RATE_LIMIT_TRUSTED_PROXIES"`
+	} `yaml:"rateLimit"`
+	// Quota limits events users create, zero values don't limit.
+	Quota struct {
+		MaxEventsPerUser int `yaml:"maxEventsPerUser" env:"QUOTA_MAX_EVENTS_PER_USER"`
+		MaxEventsPerDay  int `yaml:"maxEventsPerDay" env:"QUOTA_MAX_EVENTS_PER_DAY"`
+	} `yaml:"quota"`
+	// Idempotency keeps the results of requests made with idempotency keys.
+	Idempotency struct {
+		// Window is how long a key is remembered, retries after it are new
+		// requests.
+		Window time.Duration `default:"24h" yaml:"window" env:"IDEMPOTENCY_WINDOW"`
+	} `yaml:"idempotency"`
+	// DB is set by a DSN or by the structured settings, see DSN.
 	DB struct {
-		Dsn           string `yaml:"dsn" env:"DB_DSN"`
-		MigrationsDir string `yaml:"migrationsDir" env:"DB_MIGRATIONS_DIR"`
-		Migrate       bool   `yaml:"migrate" env:"DB_MIGRATE"`
+		Dsn           string        `yaml:"dsn" env:"DB_DSN" secret:"true"`
+		Host          string        `yaml:"host" env:"DB_HOST"`
+		Port          string        `default:"5432" yaml:"port" env:"DB_PORT"`
+		User          string        `yaml:"user" env:"DB_USER"`
+		Password      string        `yaml:"password" env:"DB_PASSWORD" secret:"true"`
+		Name          string        `yaml:"name" env:"DB_NAME"`
+		SSLMode       string        `yaml:"sslMode" env:"DB_SSLMODE"`
+		MigrationsDir string        `yaml:"migrationsDir" env:"DB_MIGRATIONS_DIR"`
+		Migrate       bool          `yaml:"migrate" env:"DB_MIGRATE"`
+		QueryTimeout  time.Duration `default:"5s" yaml:"queryTimeout" env:"DB_QUERY_TIMEOUT"`
 	} `yaml:"db"`
-	Storage   string `yaml:"storage" env:"STORAGE"`
+	File struct {
+		Dir           string `default:"./data" yaml:"dir" env:"FILE_DIR"`
+		SnapshotEvery int    `default:"1000" yaml:"snapshotEvery" env:"FILE_SNAPSHOT_EVERY"`
+	} `yaml:"file"`
+	Storage string `yaml:"storage" env:"STORAGE"`
+	Cache   struct {
+		Enabled bool          `yaml:"enabled" env:"CACHE_ENABLED"`
+		TTL     time.Duration `default:"1m" yaml:"ttl" env:"CACHE_TTL"`
+		Size    int           `default:"10000" yaml:"size" env:"CACHE_SIZE"`
+	} `yaml:"cache"`
 	Scheduler struct {
 		Period          time.Duration `default:"3s" yaml:"period" env:"SCHEDULER_PERIOD"`
 		Queue           string        `yaml:"queue" env:"SCHEDULER_QUEUE"`
 		RetentionPeriod time.Duration `default:"8760h" yaml:"retentionPeriod" env:"SCHEDULER_RETENTION_PERIOD"`
+		Retention       struct {
+			Schedule    string            `default:"0 3 * * *" yaml:"schedule" env:"SCHEDULER_RETENTION_SCHEDULE"`
+			Archive     bool              `yaml:"archive" env:"SCHEDULER_RETENTION_ARCHIVE"`
+			ArchiveFile string            `default:"./data/archive.jsonl" yaml:"archiveFile" env:"SCHEDULER_RETENTION_ARCHIVE_FILE"`
+			Policies    []RetentionPolicy `yaml:"policies"`
+		} `yaml:"retention"`
+		HealthPort string `yaml:"healthPort" env:"SCHEDULER_HEALTH_PORT"`
+		// StaleAfter is how late a job may be before the replica isn't ready.
+		StaleAfter time.Duration `default:"5m" yaml:"staleAfter" env:"SCHEDULER_STALE_AFTER"`
+		Leader     struct {
+			Elector     string        `default:"local" yaml:"elector" env:"SCHEDULER_LEADER_ELECTOR"`
+			LockID      int64         `default:"8126930" yaml:"lockId" env:"SCHEDULER_LEADER_LOCK_ID"`
+			RetryPeriod time.Duration `default:"1s" yaml:"retryPeriod" env:"SCHEDULER_LEADER_RETRY_PERIOD"`
+		} `yaml:"leader"`
+		Digest struct {
+			Schedule string `default:"@every 1m" yaml:"schedule" env:"SCHEDULER_DIGEST_SCHEDULE"`
+			Template string `yaml:"template" env:"SCHEDULER_DIGEST_TEMPLATE"`
+		} `yaml:"digest"`
+		Dispatch struct {
+			Mode      string        `default:"leader" yaml:"mode" env:"SCHEDULER_DISPATCH_MODE"`
+			Workers   int           `default:"1" yaml:"workers" env:"SCHEDULER_DISPATCH_WORKERS"`
+			BatchSize int           `default:"100" yaml:"batchSize" env:"SCHEDULER_DISPATCH_BATCH_SIZE"`
+			Lease     time.Duration `default:"1m" yaml:"lease" env:"SCHEDULER_DISPATCH_LEASE"`
+		} `yaml:"dispatch"`
 	} `yaml:"scheduler"`
+	Sender struct {
+		HealthPort string `yaml:"healthPort" env:"SENDER_HEALTH_PORT"`
+	} `yaml:"sender"`
 	RMQ struct {
 		Host     string `yaml:"host" env:"RMQ_HOST"`
 		Port     string `yaml:"port" env:"RMQ_PORT"`
 		Login    string `yaml:"login" env:"RMQ_LOGIN"`
-		Password string `yaml:"password" env:"RMQ_PASSWORD"`
+		Password string `yaml:"password" env:"RMQ_PASSWORD" secret:"true"`
+		// Reconnect delays double from MinDelay up to MaxDelay between attempts.
+		Reconnect struct {
+			MinDelay time.Duration `default:"500ms" yaml:"minDelay" env:"RMQ_RECONNECT_MIN_DELAY"`
+			MaxDelay time.Duration `default:"30s" yaml:"maxDelay" env:"RMQ_RECONNECT_MAX_DELAY"`
+		} `yaml:"reconnect"`
+		// TLS connects by AMQPS when enabled.
+		TLS struct {
+			Enabled   bool `yaml:"enabled" env:"ENABLED"`
+			ClientTLS `yaml:",inline"`
+		} `yaml:"tls" envPrefix:"RMQ_TLS_"`
 	} `yaml:"rmq"`
 }
 
+// ServerTLS serves a listener over TLS when enabled. Files are read again
+// when they change, so certificates are rotated without a restart.
+type ServerTLS struct {
+	Enabled  bool   `yaml:"enabled" env:"ENABLED"`
+	CertFile string `yaml:"certFile" env:"CERT_FILE"`
+	KeyFile  string `yaml:"keyFile" env:"KEY_FILE"`
+	// ClientCAFile requires clients to present certificates signed by its
+	// CAs, for mutual TLS.
+	ClientCAFile string `yaml:"clientCaFile" env:"CLIENT_CA_FILE"`
+}
+
+// ClientTLS sets how a TLS server is verified and which certificate is
+// presented to it.
+type ClientTLS struct {
+	// CAFile verifies the server, system CAs are used if it is empty.
+	CAFile string `yaml:"caFile" env:"CA_FILE"`
+	// CertFile and KeyFile are the client certificate for mutual TLS, it is
+	// read again when the files change.
+	CertFile string `yaml:"certFile" env:"CERT_FILE"`
+	KeyFile  string `yaml:"keyFile" env:"KEY_FILE"`
+	// ServerName is expected in the server certificate instead of the
+	// dialed host.
+	ServerName string `yaml:"serverName" env:"SERVER_NAME"`
+}
+
+// RetentionPolicy overrides the retention period for events of a user or a
+// calendar. A zero KeepDays keeps events forever.
+type RetentionPolicy struct {
+	Name       string `yaml:"name"`
+	UserID     int    `yaml:"userId"`
+	CalendarID string `yaml:"calendarId"`
+	KeepDays   int    `yaml:"keepDays"`
+	Archive    bool   `yaml:"archive"`
+}
+
 func New(r io.Reader) (*Config, error) {
 	config := &Config{}
 	if err := yaml.NewDecoder(r).Decode(config); err != nil {
 		return nil, err
 	}
-	if err := gocfg.ReadEnv(config); err != nil {
+	// env overrides only variables which are set, defaults fill the rest
+	if err := env.Parse(config); err != nil {
+		return nil, err
+	}
+	if err := readSecretFiles(config); err != nil {
 		return nil, err
 	}
 	if err := defaults.Set(config); err != nil {
@@ -67,10 +213,28 @@ func New(r io.Reader) (*Config, error) {
 	return config, nil
 }
 
+// Load reads the config file at path.
+func Load(path string) (*Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open config file: %w", err)
+	}
+	defer file.Close()
+
+	config, err := New(file)
+	if err != nil {
+		return nil, fmt.Errorf("parse config file: %w", err)
+	}
+
+	return config, nil
+}
+
 func (c *Config) WithContext(ctx context.Context) context.Context {
 	return context.WithValue(ctx, ctxKey, c)
 }
 
+// GetFromContext returns the config put by WithContext, nil if there is none.
 func GetFromContext(ctx context.Context) *Config {
-	return ctx.Value(ctxKey).(*Config)
+	config, _ := ctx.Value(ctxKey).(*Config)
+	return config
 }
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+)
+
+// Reloader re-reads the config file on SIGHUP. Only the log level, the
+// scheduler period and retention settings are taken from the new file, other
+// changes need a restart.
+type Reloader struct {
+	path     string
+	sections []Section
+	logger   logger.Logger
+
+	mu      sync.Mutex
+	current *Config
+	hooks   []func(*Config) error
+}
+
+// NewReloader watches the file cfg was loaded from, new files are validated
+// for the sections before they are applied. The level of logg follows the
+// reloaded config.
+func NewReloader(path string, cfg *Config, logg logger.Logger, sections ...Section) *Reloader {
+	r := &Reloader{
+		path:     path,
+		sections: sections,
+		logger:   logg,
+		current:  cfg,
+	}
+	r.hooks = append(r.hooks, r.setLogLevel)
+
+	return r
+}
+
+func (r *Reloader) setLogLevel(cfg *Config) error {
+	level, err := logger.ParseLevel(cfg.Logger.Level)
+	if err != nil {
+		return err
+	}
+	if setter, ok := r.logger.(logger.LevelSetter); ok {
+		setter.SetLevel(level)
+	}
+
+	return nil
+}
+
+// OnReload registers a hook getting the reloaded config. Hooks are called in
+// registration order and must leave their component unchanged on error.
+func (r *Reloader) OnReload(hook func(*Config) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.hooks = append(r.hooks, hook)
+}
+
+// Current returns the config with the last reloaded settings applied.
+func (r *Reloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current
+}
+
+// Reload reads the file and applies its reloadable settings. An invalid file
+// changes nothing, neither does a failing hook: the new config is kept only if
+// every hook accepts it.
+func (r *Reloader) Reload() error {
+	loaded, err := Load(r.path)
+	if err != nil {
+		return err
+	}
+	if err = loaded.Validate(r.sections...); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := *r.current
+	next.Logger = loaded.Logger
+	next.Scheduler.Period = loaded.Scheduler.Period
+	next.Scheduler.RetentionPeriod = loaded.Scheduler.RetentionPeriod
+	next.Scheduler.Retention = loaded.Scheduler.Retention
+
+	if !reflect.DeepEqual(next, *loaded) {
+		r.logger.Warning("Config file has changes which are applied on restart only")
+	}
+
+	for i, hook := range r.hooks {
+		if err = hook(&next); err != nil {
+			return errors.Join(err, r.revert(r.hooks[:i]))
+		}
+	}
+	r.current = &next
+
+	return nil
+}
+
+// revert passes the current config to hooks which have got a reloaded one, so
+// components don't keep settings of a reload that failed.
+func (r *Reloader) revert(applied []func(*Config) error) error {
+	var errs []error
+	for _, hook := range applied {
+		if err := hook(r.current); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Watch reloads the config on every SIGHUP until ctx is done.
+func (r *Reloader) Watch(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-signals:
+			if err := r.Reload(); err != nil {
+				r.logger.Error("Error reloading config: %v", err)
+				continue
+			}
+			r.logger.Info("Config reloaded.")
+		case <-ctx.Done():
+			return
+		}
+	}
+}
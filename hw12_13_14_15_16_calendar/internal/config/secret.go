@@ -0,0 +1,140 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces secrets in dumped configs.
+const redacted = "[REDACTED]"
+
+var ErrSecretTwice = errors.New("secret is set both by variable and by file")
+
+// readSecretFiles sets secret fields, tagged `secret:"true"`, from files named
+// by their env variable with the _FILE suffix, as secrets are mounted in
+// containers.
+func readSecretFiles(config *Config) error {
+	return walkSecrets(reflect.ValueOf(config).Elem(), func(field reflect.Value, tag reflect.StructTag) error {
+		name, _, _ := strings.Cut(tag.Get("env"), ",")
+		path, ok := os.LookupEnv(name + "_FILE")
+		if !ok || name == "" {
+			return nil
+		}
+		if _, ok = os.LookupEnv(name); ok {
+			return fmt.Errorf("%w: %s and %s_FILE", ErrSecretTwice, name, name)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read %s_FILE: %w", name, err)
+		}
+		field.SetString(strings.TrimRight(string(data), "\r\n"))
+
+		return nil
+	})
+}
+
+func walkSecrets(v reflect.Value, visit func(reflect.Value, reflect.StructTag) error) error {
+	for i := range v.NumField() {
+		field, info := v.Field(i), v.Type().Field(i)
+		switch {
+		case field.Kind() == reflect.Struct:
+			if err := walkSecrets(field, visit); err != nil {
+				return err
+			}
+		case field.Kind() == reflect.String && info.Tag.Get("secret") == "true":
+			if err := visit(field, info.Tag); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// DSN returns db.dsn if it is set, otherwise it is built of the structured
+// db settings.
+func (c *Config) DSN() string {
+	if c.DB.Dsn != "" {
+		return c.DB.Dsn
+	}
+
+	dsn := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(c.DB.User, c.DB.Password),
+		Host:   net.JoinHostPort(c.DB.Host, c.DB.Port),
+		Path:   "/" + c.DB.Name,
+	}
+	if c.DB.SSLMode != "" {
+		dsn.RawQuery = url.Values{"sslmode": {c.DB.SSLMode}}.Encode()
+	}
+
+	return dsn.String()
+}
+
+// RMQURL returns the RabbitMQ connection URL, an AMQPS one if TLS is enabled.
+func (c *Config) RMQURL() string {
+	scheme := "amqp"
+	if c.RMQ.TLS.Enabled {
+		scheme = "amqps"
+	}
+
+	return (&url.URL{
+		Scheme: scheme,
+		User:   url.UserPassword(c.RMQ.Login, c.RMQ.Password),
+		Host:   net.JoinHostPort(c.RMQ.Host, c.RMQ.Port),
+		Path:   "/",
+	}).String()
+}
+
+// Redacted returns a copy of the config with secrets replaced, passwords of
+// DSNs included.
+func (c *Config) Redacted() *Config {
+	clone := *c
+	_ = walkSecrets(reflect.ValueOf(&clone).Elem(), func(field reflect.Value, _ reflect.StructTag) error {
+		if field.String() != "" {
+			field.SetString(redacted)
+		}
+		return nil
+	})
+	if c.DB.Dsn != "" {
+		clone.DB.Dsn = redactURL(c.DB.Dsn)
+	}
+
+	return &clone
+}
+
+// redactURL hides the password of URL DSNs, key/value ones are hidden whole.
+func redactURL(dsn string) string {
+	parsed, err := url.Parse(dsn)
+	if err != nil || parsed.Scheme == "" {
+		return redacted
+	}
+	if query := parsed.Query(); query.Has("password") {
+		query.Set("password", "xxxxx")
+		parsed.RawQuery = query.Encode()
+	}
+
+	return parsed.Redacted()
+}
+
+// String dumps the config as YAML with secrets redacted, so it is safe to log.
+func (c *Config) String() string {
+	data, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return "config: " + err.Error()
+	}
+
+	return string(data)
+}
+
+func (c *Config) GoString() string {
+	return c.String()
+}
@@ -0,0 +1,290 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+)
+
+var ErrInvalid = errors.New("invalid config")
+
+// FieldError tells what is wrong with a config field, named by its YAML path.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e FieldError) Unwrap() error {
+	return ErrInvalid
+}
+
+// Section is a part of the config used by a binary.
+type Section int
+
+const (
+	// SectionServer are the HTTP and gRPC listeners.
+	SectionServer Section = iota
+	// SectionStorage is the event storage with its cache.
+	SectionStorage
+	// SectionQueue is the RabbitMQ connection and the reminder queue.
+	SectionQueue
+	// SectionScheduler are the scheduler loops and leader election.
+	SectionScheduler
+	// SectionMigrations is the database migrated by the migrations binary.
+	SectionMigrations
+)
+
+// Values accepted by the packages reading the config, which import this one.
+var (
+	storageTypes  = []string{"memory", "db", "file"}
+	electorTypes  = []string{"local", "postgres"}
+	dispatchModes = []string{"leader", "claim"}
+	listenModes   = []string{"separate", "single"}
+)
+
+type validator struct {
+	errs    []error
+	checked map[string]bool
+}
+
+// once tells whether a group of fields shared by sections is checked first
+// time.
+func (v *validator) once(group string) bool {
+	if v.checked[group] {
+		return false
+	}
+	if v.checked == nil {
+		v.checked = make(map[string]bool)
+	}
+	v.checked[group] = true
+
+	return true
+}
+
+func (v *validator) fail(field, format string, args ...any) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) required(field, value string) {
+	if value == "" {
+		v.fail(field, "must be set")
+	}
+}
+
+func (v *validator) oneOf(field, value string, allowed []string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.fail(field, "must be one of %q, got %q", allowed, value)
+}
+
+func (v *validator) port(field, value string) {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		v.fail(field, "must be a port number, got %q", value)
+	}
+}
+
+func (v *validator) positive(field string, value time.Duration) {
+	if value <= 0 {
+		v.fail(field, "must be positive, got %s", value)
+	}
+}
+
+// serverTLS checks the key pair of an enabled listener is set.
+func (v *validator) serverTLS(field string, settings ServerTLS) {
+	if settings.Enabled {
+		v.required(field+".certFile", settings.CertFile)
+		v.required(field+".keyFile", settings.KeyFile)
+	}
+}
+
+// clientTLS checks a client certificate is set by both files or by none.
+func (v *validator) clientTLS(field string, settings ClientTLS) {
+	if (settings.CertFile == "") != (settings.KeyFile == "") {
+		v.fail(field+".keyFile", "must be set together with certFile")
+	}
+}
+
+func (v *validator) positiveInt(field string, value int) {
+	if value <= 0 {
+		v.fail(field, "must be positive, got %d", value)
+	}
+}
+
+// Validate checks the logger, shutdown and the given sections, every problem
+// found is reported in the joined error.
+func (c *Config) Validate(sections ...Section) error {
+	v := &validator{}
+
+	if _, err := logger.ParseLevel(c.Logger.Level); err != nil {
+		v.fail("logger.level", "must be one of debug, info, warning, error, got %q", c.Logger.Level)
+	}
+	v.positive("shutdown.timeout", c.Shutdown.Timeout)
+	if c.Shutdown.DrainDelay < 0 {
+		v.fail("shutdown.drainDelay", "must not be negative, got %s", c.Shutdown.DrainDelay)
+	}
+
+	for _, section := range sections {
+		switch section {
+		case SectionServer:
+			c.validateServer(v)
+		case SectionStorage:
+			c.validateStorage(v)
+		case SectionQueue:
+			c.validateQueue(v)
+		case SectionScheduler:
+			c.validateScheduler(v)
+		case SectionMigrations:
+			c.validateDB(v)
+			v.required("db.migrationsDir", c.DB.MigrationsDir)
+		}
+	}
+
+	return errors.Join(v.errs...)
+}
+
+func (c *Config) validateServer(v *validator) {
+	v.port("http.port", c.HTTP.Port)
+	v.positive("http.readTimeout", c.HTTP.ReadTimeout)
+	v.positive("http.writeTimeout", c.HTTP.WriteTimeout)
+	v.serverTLS("http.tls", c.HTTP.TLS)
+	v.oneOf("listen.mode", c.Listen.Mode, listenModes)
+	if c.Listen.Mode == "single" {
+		// gRPC is served on the HTTP listener with its TLS
+		if c.GRPC.TLS.Enabled {
+			v.fail("grpc.tls.enabled", "must be false in single listen mode, http.tls is used")
+		}
+	} else {
+		v.port("grpc.port", c.GRPC.Port)
+		v.serverTLS("grpc.tls", c.GRPC.TLS)
+	}
+	v.positive("grpc.connectTimeout", c.GRPC.ConnectTimeout)
+	v.clientTLS("grpc.clientTls", c.GRPC.ClientTLS)
+
+	for _, limit := range []struct {
+		name  string
+		rate  float64
+		burst int
+	}{
+		{"rateLimit.user", c.RateLimit.User.Rate, c.RateLimit.User.Burst},
+		{"rateLimit.ip", c.RateLimit.IP.Rate, c.RateLimit.IP.Burst},
+	} {
+		if limit.rate < 0 {
+			v.fail(limit.name+".rate", "must not be negative, got %g", limit.rate)
+		}
+		if limit.rate > 0 {
+			v.positiveInt(limit.name+".burst", limit.burst)
+		}
+	}
+	for i, cidr := range c.RateLimit.TrustedProxies {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			v.fail(fmt.Sprintf("rateLimit.trustedProxies[%d]", i), "must be a CIDR, got %q", cidr)
+		}
+	}
+	if c.Quota.MaxEventsPerUser < 0 {
+		v.fail("quota.maxEventsPerUser", "must not be negative, got %d", c.Quota.MaxEventsPerUser)
+	}
+	if c.Quota.MaxEventsPerDay < 0 {
+		v.fail("quota.maxEventsPerDay", "must not be negative, got %d", c.Quota.MaxEventsPerDay)
+	}
+}
+
+func (c *Config) validateStorage(v *validator) {
+	v.oneOf("storage", c.Storage, storageTypes)
+	v.positive("idempotency.window", c.Idempotency.Window)
+
+	switch c.Storage {
+	case "db":
+		c.validateDB(v)
+		v.positive("db.queryTimeout", c.DB.QueryTimeout)
+		if c.DB.Migrate {
+			v.required("db.migrationsDir", c.DB.MigrationsDir)
+		}
+	case "file":
+		v.required("file.dir", c.File.Dir)
+		v.positiveInt("file.snapshotEvery", c.File.SnapshotEvery)
+	}
+
+	if c.Cache.Enabled {
+		v.positive("cache.ttl", c.Cache.TTL)
+		v.positiveInt("cache.size", c.Cache.Size)
+	}
+}
+
+// validateDB checks the database is set either by a DSN or by host and name.
+func (c *Config) validateDB(v *validator) {
+	if !v.once("db") {
+		return
+	}
+
+	switch {
+	case c.DB.Dsn != "" && c.DB.Host != "":
+		v.fail("db.dsn", "must not be set together with db.host")
+	case c.DB.Dsn == "" && (c.DB.Host == "" || c.DB.Name == ""):
+		v.fail("db.dsn", "must be set, or db.host and db.name")
+	case c.DB.Dsn == "":
+		v.port("db.port", c.DB.Port)
+	}
+}
+
+func (c *Config) validateQueue(v *validator) {
+	v.required("rmq.host", c.RMQ.Host)
+	v.port("rmq.port", c.RMQ.Port)
+	v.positive("rmq.reconnect.minDelay", c.RMQ.Reconnect.MinDelay)
+	if c.RMQ.Reconnect.MaxDelay < c.RMQ.Reconnect.MinDelay {
+		v.fail("rmq.reconnect.maxDelay", "must not be less than minDelay %s, got %s",
+			c.RMQ.Reconnect.MinDelay, c.RMQ.Reconnect.MaxDelay)
+	}
+	v.clientTLS("rmq.tls", c.RMQ.TLS.ClientTLS)
+	v.required("scheduler.queue", c.Scheduler.Queue)
+	if c.Sender.HealthPort != "" {
+		v.port("sender.healthPort", c.Sender.HealthPort)
+	}
+}
+
+func (c *Config) validateScheduler(v *validator) {
+	s := c.Scheduler
+
+	v.positive("scheduler.period", s.Period)
+	if s.RetentionPeriod < 0 {
+		v.fail("scheduler.retentionPeriod", "must not be negative, got %s", s.RetentionPeriod)
+	}
+	v.required("scheduler.retention.schedule", s.Retention.Schedule)
+	for i, policy := range s.Retention.Policies {
+		if policy.KeepDays < 0 {
+			v.fail(fmt.Sprintf("scheduler.retention.policies[%d].keepDays", i),
+				"must not be negative, got %d", policy.KeepDays)
+		}
+		if policy.Archive && s.Retention.ArchiveFile == "" {
+			v.fail("scheduler.retention.archiveFile", "must be set to archive events of policy %q", policy.Name)
+		}
+	}
+	if s.HealthPort != "" {
+		v.port("scheduler.healthPort", s.HealthPort)
+	}
+	v.positive("scheduler.staleAfter", s.StaleAfter)
+
+	v.oneOf("scheduler.leader.elector", s.Leader.Elector, electorTypes)
+	if s.Leader.Elector == "postgres" {
+		c.validateDB(v)
+	}
+	v.positive("scheduler.leader.retryPeriod", s.Leader.RetryPeriod)
+
+	v.required("scheduler.digest.schedule", s.Digest.Schedule)
+
+	v.oneOf("scheduler.dispatch.mode", s.Dispatch.Mode, dispatchModes)
+	v.positiveInt("scheduler.dispatch.workers", s.Dispatch.Workers)
+	v.positiveInt("scheduler.dispatch.batchSize", s.Dispatch.BatchSize)
+	v.positive("scheduler.dispatch.lease", s.Dispatch.Lease)
+}
@@ -0,0 +1,79 @@
+package entity
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+var (
+	ErrCalendarNotFound = errors.New("calendar not found")
+	ErrShareNotFound    = errors.New("share not found")
+)
+
+// Role is an access level granted on a calendar. Every role includes the
+// rights of the roles below it.
+type Role string
+
+const (
+	RoleFreeBusy Role = "free_busy"
+	RoleViewer   Role = "viewer"
+	RoleEditor   Role = "editor"
+	RoleOwner    Role = "owner"
+)
+
+var roleRanks = map[Role]int{
+	RoleFreeBusy: 1,
+	RoleViewer:   2,
+	RoleEditor:   3,
+	RoleOwner:    4,
+}
+
+func (r Role) Valid() bool {
+	_, ok := roleRanks[r]
+
+	return ok
+}
+
+// Includes reports whether r grants the rights of required.
+func (r Role) Includes(required Role) bool {
+	return r.Valid() && roleRanks[r] >= roleRanks[required]
+}
+
+type Calendars []*Calendar
+
+// Calendar owns events. Its owner has the owner role implicitly, other users
+// get access through shares.
+type Calendar struct {
+	ID        string
+	OwnerID   int
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type Shares []*Share
+
+// Share grants the user a role on the calendar.
+type Share struct {
+	CalendarID string
+	UserID     int
+	Role       Role
+}
+
+// Scope selects events of the calendars and the user's events having no
+// calendar, which were created before calendars were added. A zero UserID
+// selects no such events.
+type Scope struct {
+	UserID      int
+	CalendarIDs []string
+}
+
+// Contains reports whether the event is in the scope.
+func (s Scope) Contains(e *Event) bool {
+	if e.CalendarID == "" {
+		return s.UserID != 0 && e.UserID == s.UserID
+	}
+
+	return slices.Contains(s.CalendarIDs, e.CalendarID)
+}
@@ -0,0 +1,30 @@
+package entity
+
+import "errors"
+
+var ErrDigestNotFound = errors.New("digest subscription not found")
+
+// Digest subscribes a user to the daily agenda, sent at Time ("15:04") of the
+// user's TimeZone.
+type Digest struct {
+	UserID   int
+	Time     string
+	TimeZone string
+	// LastSent is the local date ("2006-01-02") of the last sent digest, so a
+	// day's digest is sent once.
+	LastSent string
+}
+
+type Digests []*Digest
+
+// DigestMsgType is the type of digest messages in the reminder queue,
+// reminders are sent without a type.
+const DigestMsgType = "digest"
+
+// DigestMsg is a user's agenda for a day, Text is rendered for delivery.
+type DigestMsg struct {
+	UserID int
+	Date   string
+	Text   string
+	Events []EventMsg
+}
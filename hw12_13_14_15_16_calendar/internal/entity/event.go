@@ -2,15 +2,23 @@ package entity
 
 import (
 	"errors"
+	"slices"
 	"time"
 )
 
-var ErrEventNotFound = errors.New("event not found")
+var (
+	ErrEventNotFound = errors.New("event not found")
+	ErrTagNotFound   = errors.New("tag not found")
+	// ErrEventUIDExists is returned for an event with the UID of another
+	// event of its calendar.
+	ErrEventUIDExists = errors.New("event with the UID already exists in the calendar")
+)
 
 type Events []*Event
 
 type Event struct {
 	ID             string
+	CalendarID     string
 	UserID         int
 	Title          string
 	DateTime       time.Time
@@ -20,6 +28,34 @@ type Event struct {
 	RemindSentTime time.Time
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
+	Tags           []string
+	// UID is the iCalendar UID of an event created over CalDAV, events
+	// created otherwise have none and are identified by ID.
+	UID string
+}
+
+// Tag is an entry of a user's tag catalog, events refer to tags by name.
+type Tag struct {
+	UserID int
+	Name   string
+	Color  string
+}
+
+type Tags []*Tag
+
+// HasAnyTag reports whether the event has one of tags or tags are empty.
+func (e Event) HasAnyTag(tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+
+	for _, tag := range tags {
+		if slices.Contains(e.Tags, tag) {
+			return true
+		}
+	}
+
+	return false
 }
 
 type EventMsg struct {
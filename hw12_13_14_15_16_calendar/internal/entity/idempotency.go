@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+	ErrIdempotencyKeyExists   = errors.New("idempotency key exists")
+	// ErrIdempotencyKeyReused is returned for a key retried with another
+	// payload.
+	ErrIdempotencyKeyReused = errors.New("idempotency key is used for another request")
+	// ErrIdempotencyKeyInProgress is returned for a key retried before its
+	// request is done.
+	ErrIdempotencyKeyInProgress = errors.New("request with the idempotency key is in progress")
+)
+
+// IdempotencyKey remembers a request made with a client's key, so retries of
+// the request return its result instead of doing it again.
+type IdempotencyKey struct {
+	UserID int
+	Key    string
+	// Hash identifies the request payload, a key isn't reused for another one.
+	Hash string
+	// EventID is the created event, it is empty while the request is in
+	// progress.
+	EventID   string
+	CreatedAt time.Time
+}
@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaError tells which quota a request has exceeded. RetryAfter is zero if
+// waiting doesn't help, e.g. until the user deletes events.
+type QuotaError struct {
+	Quota      string
+	Limit      int
+	RetryAfter time.Duration
+}
+
+func (e QuotaError) Error() string {
+	return fmt.Sprintf("%s: %s is %d", ErrQuotaExceeded, e.Quota, e.Limit)
+}
+
+func (e QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
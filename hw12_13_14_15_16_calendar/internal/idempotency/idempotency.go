@@ -0,0 +1,23 @@
+// Package idempotency carries the idempotency key of a request through
+// context.
+package idempotency
+
+import "context"
+
+type key int
+
+const (
+	ctxKey key = iota
+)
+
+func WithKey(ctx context.Context, idempotencyKey string) context.Context {
+	return context.WithValue(ctx, ctxKey, idempotencyKey)
+}
+
+// Key returns the request's idempotency key, ok is false for requests which
+// aren't retried safely.
+func Key(ctx context.Context) (idempotencyKey string, ok bool) {
+	idempotencyKey, ok = ctx.Value(ctxKey).(string)
+
+	return idempotencyKey, ok
+}
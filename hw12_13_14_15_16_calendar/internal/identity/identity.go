@@ -0,0 +1,23 @@
+// Package identity carries the ID of the user making a request through
+// context.
+package identity
+
+import "context"
+
+type key int
+
+const (
+	ctxKey key = iota
+)
+
+func WithUserID(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, ctxKey, userID)
+}
+
+// UserID returns the requesting user, ok is false for calls made by the
+// service itself.
+func UserID(ctx context.Context) (userID int, ok bool) {
+	userID, ok = ctx.Value(ctxKey).(int)
+
+	return userID, ok
+}
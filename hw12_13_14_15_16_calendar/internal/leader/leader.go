@@ -0,0 +1,78 @@
+// Package leader elects a single active instance among service replicas.
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // driver import
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+)
+
+type Type string
+
+const (
+	Local    Type = "local"
+	Postgres Type = "postgres"
+)
+
+var ErrInvalidElectorValue = errors.New("invalid leader elector value in config")
+
+// Elector campaigns for leadership on behalf of one instance.
+type Elector interface {
+	// Campaign blocks until the instance becomes the leader. The returned
+	// context is canceled when leadership is lost or ctx is done.
+	Campaign(ctx context.Context) (context.Context, error)
+	// Resign gives up leadership, so another instance can take it at once.
+	Resign(ctx context.Context) error
+	Close() error
+}
+
+// Get returns the elector configured by Scheduler.Leader.
+func Get(ctx context.Context, logg logger.Logger) (Elector, error) {
+	cfg := config.GetFromContext(ctx)
+	if cfg == nil {
+		return nil, config.ErrNoConfigInContext
+	}
+
+	switch Type(cfg.Scheduler.Leader.Elector) {
+	case Local:
+		return NewLocal(), nil
+	case Postgres:
+		db, err := sql.Open("pgx", cfg.DSN())
+		if err != nil {
+			return nil, fmt.Errorf("open leader election db: %w", err)
+		}
+
+		return NewPostgres(db, cfg.Scheduler.Leader.LockID, cfg.Scheduler.Leader.RetryPeriod, logg), nil
+	default:
+		return nil, ErrInvalidElectorValue
+	}
+}
+
+// LocalElector makes the only instance the leader at once. It suits single
+// replica deployments and storages that can't be shared between replicas.
+type LocalElector struct{}
+
+func NewLocal() *LocalElector {
+	return &LocalElector{}
+}
+
+func (LocalElector) Campaign(ctx context.Context) (context.Context, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return ctx, nil
+}
+
+func (LocalElector) Resign(context.Context) error {
+	return nil
+}
+
+func (LocalElector) Close() error {
+	return nil
+}
@@ -0,0 +1,62 @@
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalElector(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	elector := NewLocal()
+
+	leaderCtx, err := elector.Campaign(ctx)
+	require.NoError(t, err)
+	require.NoError(t, leaderCtx.Err())
+
+	cancel()
+	require.Error(t, leaderCtx.Err())
+	_, err = elector.Campaign(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestPostgresElector(t *testing.T) {
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DB_DSN is not set")
+	}
+
+	newElector := func() *PostgresElector {
+		db, err := sql.Open("pgx", dsn)
+		require.NoError(t, err)
+
+		return NewPostgres(db, 42, 50*time.Millisecond, logger.New(logger.Debug, io.Discard))
+	}
+	first, second := newElector(), newElector()
+	defer first.Close()
+	defer second.Close()
+
+	ctx := context.Background()
+	leaderCtx, err := first.Campaign(ctx)
+	require.NoError(t, err)
+
+	// the lock is taken, so the second replica waits
+	waitCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	_, err = second.Campaign(waitCtx)
+	cancel()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NoError(t, leaderCtx.Err())
+
+	// resigning hands leadership over
+	require.NoError(t, first.Resign(ctx))
+	waitCtx, cancel = context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	_, err = second.Campaign(waitCtx)
+	require.NoError(t, err)
+}
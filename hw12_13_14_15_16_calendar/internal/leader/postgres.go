@@ -0,0 +1,130 @@
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+)
+
+// PostgresElector holds leadership as a session level advisory lock. The lock
+// is released by Postgres as soon as the leader's session ends, so a crashed
+// leader is replaced within one retry period.
+type PostgresElector struct {
+	db          *sql.DB
+	lockID      int64
+	retryPeriod time.Duration
+	logger      logger.Logger
+
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+func NewPostgres(db *sql.DB, lockID int64, retryPeriod time.Duration, logg logger.Logger) *PostgresElector {
+	return &PostgresElector{
+		db:          db,
+		lockID:      lockID,
+		retryPeriod: retryPeriod,
+		logger:      logg,
+	}
+}
+
+// Campaign tries to take the lock every retry period. Errors of a single
+// attempt, like the database being down, are logged and retried.
+func (e *PostgresElector) Campaign(ctx context.Context) (context.Context, error) {
+	for {
+		if conn, ok := e.tryLock(ctx); ok {
+			e.mu.Lock()
+			e.conn = conn
+			e.mu.Unlock()
+
+			return e.watch(ctx, conn), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(e.retryPeriod):
+		}
+	}
+}
+
+func (e *PostgresElector) tryLock(ctx context.Context) (*sql.Conn, bool) {
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		e.logger.Warning("Error connecting to take leader lock: %v", err)
+
+		return nil, false
+	}
+
+	var locked bool
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", e.lockID).Scan(&locked)
+	if err != nil || !locked {
+		if err != nil {
+			e.logger.Warning("Error taking leader lock: %v", err)
+		}
+		conn.Close()
+
+		return nil, false
+	}
+
+	return conn, true
+}
+
+// watch pings the session holding the lock, losing it loses leadership.
+func (e *PostgresElector) watch(ctx context.Context, conn *sql.Conn) context.Context {
+	leaderCtx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		defer cancel()
+
+		ticker := time.NewTicker(e.retryPeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-leaderCtx.Done():
+				return
+			case <-ticker.C:
+				pingCtx, pingCancel := context.WithTimeout(leaderCtx, e.retryPeriod)
+				err := conn.PingContext(pingCtx)
+				pingCancel()
+				if err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	return leaderCtx
+}
+
+func (e *PostgresElector) Resign(ctx context.Context) error {
+	e.mu.Lock()
+	conn := e.conn
+	e.conn = nil
+	e.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+
+	_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", e.lockID)
+	if closeErr := conn.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func (e *PostgresElector) Close() error {
+	if err := e.Resign(context.Background()); err != nil {
+		_ = e.db.Close()
+
+		return err
+	}
+
+	return e.db.Close()
+}
@@ -0,0 +1,177 @@
+// Package lifecycle starts the components of a service in order and stops them
+// in reverse order, letting each one drain what it has in flight.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+)
+
+var (
+	ErrShutdownTimeout = errors.New("shutdown timed out")
+	ErrDraining        = errors.New("shutting down")
+)
+
+// Component is a part of the service, any of its funcs may be nil.
+type Component struct {
+	Name string
+	// Start prepares the component, e.g. connects to a storage.
+	Start func(ctx context.Context) error
+	// Run serves until its ctx is done or Stop makes it return. A Run
+	// returning ends the service, Run errors are reported by Manager.Run.
+	Run func(ctx context.Context) error
+	// Stop drains the component. The ctx of Run is done once Stop returns.
+	Stop func(ctx context.Context) error
+}
+
+type Options struct {
+	// Timeout bounds stopping all of the components.
+	Timeout time.Duration
+	// DrainDelay is waited before stopping anything, while Draining fails,
+	// so load balancers stop sending requests first.
+	DrainDelay time.Duration
+}
+
+type Manager struct {
+	options    Options
+	logger     logger.Logger
+	components []Component
+
+	mu       sync.Mutex
+	err      error
+	draining bool
+}
+
+func New(options Options, logger logger.Logger) *Manager {
+	return &Manager{
+		options: options,
+		logger:  logger,
+	}
+}
+
+// Add appends components, they are started in the added order.
+func (m *Manager) Add(components ...Component) {
+	m.components = append(m.components, components...)
+}
+
+// Draining fails once shutdown has begun, it is meant for readiness checks.
+func (m *Manager) Draining(context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.draining {
+		return ErrDraining
+	}
+
+	return nil
+}
+
+// fail keeps the first error, the one which has stopped the service.
+func (m *Manager) fail(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err == nil {
+		m.err = err
+	}
+}
+
+// running is a started component.
+type running struct {
+	Component
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Run starts the components and runs them until ctx is done or one of them
+// returns from Run, then stops the started ones in reverse order. The first
+// error of starting, running or stopping is returned.
+func (m *Manager) Run(ctx context.Context) error {
+	ended := make(chan struct{}, len(m.components))
+	started := make([]*running, 0, len(m.components))
+
+	var startErr error
+	for _, component := range m.components {
+		m.logger.Info("Starting %s...", component.Name)
+		if component.Start != nil {
+			if startErr = component.Start(ctx); startErr != nil {
+				m.fail(fmt.Errorf("start %s: %w", component.Name, startErr))
+				break
+			}
+		}
+
+		// runs end by their own Stop, not by the shutdown signal
+		runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		r := &running{Component: component, cancel: cancel, done: make(chan struct{})}
+		started = append(started, r)
+
+		if r.Run == nil {
+			close(r.done)
+			continue
+		}
+		go func() {
+			defer close(r.done)
+			if err := r.Run(runCtx); err != nil {
+				m.fail(fmt.Errorf("run %s: %w", r.Name, err))
+			}
+			ended <- struct{}{}
+		}()
+	}
+
+	if startErr == nil {
+		select {
+		case <-ctx.Done():
+			m.logger.Info("Shutting down...")
+		case <-ended:
+			m.logger.Info("A component has stopped, shutting down...")
+		}
+	}
+
+	m.mu.Lock()
+	m.draining = true
+	m.mu.Unlock()
+
+	m.shutdown(started)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.err
+}
+
+// shutdown stops started components in reverse order within the timeout.
+func (m *Manager) shutdown(started []*running) {
+	if m.options.DrainDelay > 0 && len(started) == len(m.components) {
+		m.logger.Info("Draining for %s before stopping...", m.options.DrainDelay)
+		time.Sleep(m.options.DrainDelay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.options.Timeout)
+	defer cancel()
+
+	for i := len(started) - 1; i >= 0; i-- {
+		r := started[i]
+
+		m.logger.Info("Stopping %s...", r.Name)
+		if r.Stop != nil {
+			if err := r.Stop(ctx); err != nil {
+				m.fail(fmt.Errorf("stop %s: %w", r.Name, err))
+			}
+		}
+		r.cancel()
+
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			m.fail(fmt.Errorf("%w: %s is still running", ErrShutdownTimeout, r.Name))
+			// the rest is stopped anyway, they may not need the stuck one
+			continue
+		}
+		m.logger.Info("Stopped %s.", r.Name)
+	}
+}
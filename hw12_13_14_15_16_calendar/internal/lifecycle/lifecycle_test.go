@@ -0,0 +1,134 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	steps []string
+}
+
+func (r *recorder) add(step string) {
+	r.mu.Lock()
+	r.steps = append(r.steps, step)
+	r.mu.Unlock()
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.steps...)
+}
+
+// component records its steps, its Run serves until ctx is done.
+func (r *recorder) component(name string, startErr error) Component {
+	return Component{
+		Name: name,
+		Start: func(context.Context) error {
+			r.add("start " + name)
+			return startErr
+		},
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			r.add("ran " + name)
+			return nil
+		},
+		Stop: func(context.Context) error {
+			r.add("stop " + name)
+			return nil
+		},
+	}
+}
+
+func newManager(timeout time.Duration) *Manager {
+	return New(Options{Timeout: timeout}, logger.New(logger.Debug, io.Discard))
+}
+
+func TestRun(t *testing.T) {
+	r := &recorder{}
+	manager := newManager(time.Second)
+	manager.Add(r.component("storage", nil), r.component("broker", nil), r.component("server", nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- manager.Run(ctx) }()
+
+	require.Eventually(t, func() bool { return len(r.get()) == 3 }, time.Second, time.Millisecond)
+	require.NoError(t, manager.Draining(ctx))
+	cancel()
+
+	require.NoError(t, <-done)
+	require.ErrorIs(t, manager.Draining(ctx), ErrDraining)
+	require.Equal(t, []string{
+		"start storage", "start broker", "start server",
+		"stop server", "ran server",
+		"stop broker", "ran broker",
+		"stop storage", "ran storage",
+	}, r.get())
+}
+
+func TestRun_StartFails(t *testing.T) {
+	r := &recorder{}
+	broken := errors.New("no connection")
+	manager := newManager(time.Second)
+	manager.Add(r.component("storage", nil), r.component("broker", broken), r.component("server", nil))
+
+	err := manager.Run(context.Background())
+	require.ErrorIs(t, err, broken)
+	require.Contains(t, err.Error(), "start broker")
+	require.Equal(t, []string{"start storage", "start broker", "stop storage", "ran storage"}, r.get())
+}
+
+func TestRun_FirstError(t *testing.T) {
+	r := &recorder{}
+	crashed := errors.New("crashed")
+	manager := newManager(time.Second)
+	manager.Add(
+		r.component("storage", nil),
+		Component{
+			Name: "server",
+			Run:  func(context.Context) error { return crashed },
+			Stop: func(context.Context) error { return errors.New("not running") },
+		},
+	)
+
+	err := manager.Run(context.Background())
+	require.ErrorIs(t, err, crashed, "the error stopping the service is reported, not later ones")
+	require.Equal(t, []string{"start storage", "stop storage", "ran storage"}, r.get())
+}
+
+func TestRun_Timeout(t *testing.T) {
+	r := &recorder{}
+	release := make(chan struct{})
+	defer close(release)
+
+	manager := newManager(20 * time.Millisecond)
+	manager.Add(
+		r.component("storage", nil),
+		Component{
+			Name: "stuck",
+			Run: func(context.Context) error {
+				<-release
+				return nil
+			},
+		},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := manager.Run(ctx)
+	require.ErrorIs(t, err, ErrShutdownTimeout)
+	require.Contains(t, err.Error(), "stuck")
+	require.Contains(t, r.get(), "stop storage", "the rest is stopped anyway")
+}
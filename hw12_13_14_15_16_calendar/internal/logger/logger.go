@@ -1,8 +1,10 @@
 package logger
 
 import (
+	"errors"
 	"io"
 	"log"
+	"sync/atomic"
 )
 
 type Level int
@@ -14,47 +16,83 @@ const (
 	Error
 )
 
+var ErrInvalidLevel = errors.New("unknown log level")
+
+var levels = map[string]Level{
+	"debug":   Debug,
+	"info":    Info,
+	"warning": Warning,
+	"error":   Error,
+}
+
+// ParseLevel returns the level named as in the config.
+func ParseLevel(name string) (Level, error) {
+	level, ok := levels[name]
+	if !ok {
+		return 0, ErrInvalidLevel
+	}
+
+	return level, nil
+}
+
 type Logger interface {
 	Debug(string, ...interface{})
 	Info(string, ...interface{})
 	Warning(string, ...interface{})
 	Error(string, ...interface{})
 }
+
+// LevelSetter is implemented by loggers which level can be changed while
+// they are used.
+type LevelSetter interface {
+	SetLevel(Level)
+}
+
 type SLogger struct {
-	Level  Level
+	level  atomic.Int32
 	logger *log.Logger
 }
 
 func New(level Level, writer io.Writer) Logger {
-	return &SLogger{
-		Level:  level,
+	l := &SLogger{
 		logger: log.New(writer, "", 0),
 	}
+	l.SetLevel(level)
+
+	return l
+}
+
+func (l *SLogger) SetLevel(level Level) {
+	l.level.Store(int32(level))
+}
+
+func (l *SLogger) enabled(level Level) bool {
+	return Level(l.level.Load()) <= level
 }
 
 func (l *SLogger) Debug(msg string, args ...interface{}) {
-	if l.Level > Debug {
+	if !l.enabled(Debug) {
 		return
 	}
 	l.log("DEBUG: ", msg, args...)
 }
 
 func (l *SLogger) Info(msg string, args ...interface{}) {
-	if l.Level > Info {
+	if !l.enabled(Info) {
 		return
 	}
 	l.log("INFO: ", msg, args...)
 }
 
 func (l *SLogger) Warning(msg string, args ...interface{}) {
-	if l.Level > Warning {
+	if !l.enabled(Warning) {
 		return
 	}
 	l.log("WARN: ", msg, args...)
 }
 
 func (l *SLogger) Error(msg string, args ...interface{}) {
-	if l.Level > Error {
+	if !l.enabled(Error) {
 		return
 	}
 	l.log("ERROR: ", msg, args...)
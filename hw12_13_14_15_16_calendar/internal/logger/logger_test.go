@@ -27,4 +27,20 @@ func TestLogger(t *testing.T) {
 		log.Warning(msg)
 		require.Empty(t, out.String())
 	})
+	t.Run("change level", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		log := New(Error, out)
+		log.(LevelSetter).SetLevel(Debug)
+		log.Debug(msg)
+		require.Contains(t, out.String(), msg)
+	})
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("warning")
+	require.NoError(t, err)
+	require.Equal(t, Warning, level)
+
+	_, err = ParseLevel("verbose")
+	require.ErrorIs(t, err, ErrInvalidLevel)
 }
@@ -2,144 +2,246 @@ package queue
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
-	"fmt"
+	"sync"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/tlsconfig"
 )
 
 var (
 	ErrQueueNotConnected     = errors.New("need to connect first")
 	ErrQueueAlreadyConnected = errors.New("already exists")
+	ErrConnectionClosed      = errors.New("broker connection is closed")
+	ErrManagerClosed         = errors.New("queue manager is closed")
 )
 
-type RabbitQueueConnection struct {
-	Name        string
-	SendChannel *amqp.Channel
-	Queue       *amqp.Queue
-}
-
+// RabbitManager keeps the broker connection. A lost connection is dialed
+// again with exponential backoff, then queues are declared again and their
+// consumers subscribed again.
 type RabbitManager struct {
-	// sync.Mutex
-	connection *amqp.Connection
+	logger   logger.Logger
+	url      string
+	tls      *tls.Config
+	minDelay time.Duration
+	maxDelay time.Duration
+	// dialer opens broker connections, tests replace it.
+	dialer func() (brokerConnection, error)
+
+	mu         sync.Mutex
+	connection brokerConnection
 	queueMap   map[string]*RabbitQueueConnection
-	logger     logger.Logger
+	closed     bool
+	// done is closed by Close to stop reconnecting and waiting producers.
+	done chan struct{}
 }
 
 func NewRabbitManager(logger logger.Logger) *RabbitManager {
-	return &RabbitManager{
+	q := &RabbitManager{
 		logger:   logger,
 		queueMap: make(map[string]*RabbitQueueConnection),
+		done:     make(chan struct{}),
 	}
+	q.dialer = q.dial
+
+	return q
 }
 
+// Connect dials the broker once, so a wrong config fails at start, and
+// supervises the connection afterwards. With TLS enabled, AMQPS is dialed.
 func (q *RabbitManager) Connect(ctx context.Context) error {
 	cfg := config.GetFromContext(ctx)
 	if cfg == nil {
 		return config.ErrNoConfigInContext
 	}
-	var err error
-	q.connection, err = amqp.Dial(fmt.Sprintf(
-		"amqp://%s:%s@%s:%s/",
-		cfg.RMQ.Login,
-		cfg.RMQ.Password,
-		cfg.RMQ.Host,
-		cfg.RMQ.Port,
-	))
+
+	q.url = cfg.RMQURL()
+	q.minDelay, q.maxDelay = cfg.RMQ.Reconnect.MinDelay, cfg.RMQ.Reconnect.MaxDelay
+	if cfg.RMQ.TLS.Enabled {
+		tlsConfig, err := tlsconfig.Client(cfg.RMQ.TLS.ClientTLS, q.logger)
+		if err != nil {
+			return err
+		}
+		q.tls = tlsConfig
+	}
+
+	connection, err := q.dialer()
 	if err != nil {
 		return err
 	}
+
+	q.mu.Lock()
+	q.connection = connection
+	q.mu.Unlock()
+
+	go q.supervise(connection)
+
 	return nil
 }
 
-func (q *RabbitManager) CreateQueue(queueName string) (*RabbitQueueConnection, error) {
-	if q.connection == nil {
-		return nil, ErrQueueNotConnected
+// supervise reconnects whenever the connection is lost until Close.
+func (q *RabbitManager) supervise(connection brokerConnection) {
+	for connection != nil {
+		reason := <-connection.NotifyClose(make(chan *amqp.Error, 1))
+		if q.isClosed() {
+			return
+		}
+
+		q.logger.Warning("RabbitMQ connection lost: %v, reconnecting...", reason)
+		connection = q.reconnect()
 	}
-	if _, exists := q.queueMap[queueName]; exists {
-		return nil, ErrQueueAlreadyConnected
+}
+
+// reconnect dials until it succeeds and queues are restored, nil is returned
+// if the manager is closed meanwhile.
+func (q *RabbitManager) reconnect() brokerConnection {
+	delay := q.minDelay
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-time.After(delay):
+		case <-q.done:
+			return nil
+		}
+
+		connection, err := q.dialer()
+		if err == nil {
+			if err = q.restore(connection); err == nil {
+				q.logger.Info("Reconnected to RabbitMQ, attempt %d.", attempt)
+				return connection
+			}
+			_ = connection.Close()
+			if errors.Is(err, ErrManagerClosed) {
+				return nil
+			}
+		}
+
+		q.logger.Warning("Error reconnecting to RabbitMQ, attempt %d: %v", attempt, err)
+		delay = nextDelay(delay, q.maxDelay)
 	}
-	channel, err := q.connection.Channel()
-	if err != nil {
-		return nil, err
+}
+
+// restore makes the connection current and opens channels of all queues on it.
+func (q *RabbitManager) restore(connection brokerConnection) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrManagerClosed
 	}
 
-	ch := channel.NotifyPublish(make(chan amqp.Confirmation, 100))
+	for _, queue := range q.queueMap {
+		if err := queue.open(connection); err != nil {
+			return err
+		}
+	}
+	q.connection = connection
+
+	return nil
+}
 
-	queue, err := channel.QueueDeclare(
-		queueName,
-		false,
-		false,
-		false,
-		false,
-		nil,
+func (q *RabbitManager) dial() (brokerConnection, error) {
+	var (
+		conn *amqp.Connection
+		err  error
 	)
+	if q.tls != nil {
+		conn, err = amqp.DialTLS(q.url, q.tls)
+	} else {
+		conn, err = amqp.Dial(q.url)
+	}
 	if err != nil {
-		close(ch)
 		return nil, err
 	}
 
+	return amqpConnection{conn}, nil
+}
+
+// nextDelay doubles the reconnect delay up to max.
+func nextDelay(delay, maxDelay time.Duration) time.Duration {
+	return min(2*delay, maxDelay)
+}
+
+func (q *RabbitManager) isClosed() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.closed
+}
+
+// Ping checks the broker connection is open.
+func (q *RabbitManager) Ping(_ context.Context) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.connection == nil {
+		return ErrQueueNotConnected
+	}
+	if q.connection.IsClosed() {
+		return ErrConnectionClosed
+	}
+
+	return nil
+}
+
+// CreateQueue declares the queue, it is declared again after reconnects.
+func (q *RabbitManager) CreateQueue(queueName string) (*RabbitQueueConnection, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.connection == nil {
+		return nil, ErrQueueNotConnected
+	}
+	if _, exists := q.queueMap[queueName]; exists {
+		return nil, ErrQueueAlreadyConnected
+	}
+
 	rq := &RabbitQueueConnection{
-		Name:        queueName,
-		SendChannel: channel,
-		Queue:       &queue,
+		Name:    queueName,
+		manager: q,
+		ready:   make(chan struct{}),
+	}
+	if err := rq.open(q.connection); err != nil {
+		return nil, err
 	}
 	q.queueMap[queueName] = rq
+
 	return rq, nil
 }
 
+// Close stops reconnecting, closes queues and the connection. Consumers'
+// channels are closed and waiting producers get ErrManagerClosed.
 func (q *RabbitManager) Close() error {
-	err := q.connection.Close()
-	if err != nil {
-		return err
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
 	}
+	q.closed = true
+	close(q.done)
+
+	var errs []error
 	for queueName, conn := range q.queueMap {
-		err = conn.Close()
-		if err != nil {
-			return err
-		}
+		errs = append(errs, conn.Close())
 		delete(q.queueMap, queueName)
 	}
+	connection := q.connection
+	q.mu.Unlock()
 
-	return nil
-}
-
-func (q *RabbitQueueConnection) Close() error {
-	err := q.SendChannel.Close()
-	if err != nil {
-		return err
+	if connection != nil {
+		errs = append(errs, ignoreClosed(connection.Close()))
 	}
-	return nil
-}
 
-func (q *RabbitQueueConnection) Produce(jsonMsg []byte) error {
-	return q.SendChannel.Publish(
-		"",
-		q.Name,
-		false,
-		false,
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        jsonMsg,
-		},
-	)
+	return errors.Join(errs...)
 }
 
-func (q *RabbitQueueConnection) Consume() (<-chan amqp.Delivery, error) {
-	messages, consumeErr := q.SendChannel.Consume(
-		q.Name,
-		"",
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
-	if consumeErr != nil {
-		return nil, consumeErr
+func ignoreClosed(err error) error {
+	if errors.Is(err, amqp.ErrClosed) {
+		return nil
 	}
 
-	return messages, nil
+	return err
 }
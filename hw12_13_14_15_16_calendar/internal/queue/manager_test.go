@@ -0,0 +1,27 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextDelay(t *testing.T) {
+	delay := 500 * time.Millisecond
+	var delays []time.Duration
+	for range 7 {
+		delay = nextDelay(delay, 30*time.Second)
+		delays = append(delays, delay)
+	}
+
+	require.Equal(t, []time.Duration{
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		16 * time.Second,
+		30 * time.Second,
+		30 * time.Second,
+	}, delays)
+}
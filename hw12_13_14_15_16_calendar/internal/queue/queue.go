@@ -0,0 +1,252 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// RabbitQueueConnection is a queue with its own channel. Producers wait while
+// the channel is reopened, which gives back-pressure during broker outages.
+type RabbitQueueConnection struct {
+	Name    string
+	manager *RabbitManager
+
+	mu      sync.Mutex
+	channel brokerChannel
+	// ready is closed while channel is open.
+	ready chan struct{}
+}
+
+// open declares the queue on a new channel of the connection and watches the
+// channel for closing.
+func (q *RabbitQueueConnection) open(connection brokerConnection) error {
+	channel, err := connection.Channel()
+	if err != nil {
+		return err
+	}
+
+	_, err = channel.QueueDeclare(
+		q.Name,
+		false,
+		false,
+		false,
+		false,
+		nil,
+	)
+	if err != nil {
+		_ = channel.Close()
+		return err
+	}
+
+	closes := channel.NotifyClose(make(chan *amqp.Error, 1))
+
+	q.mu.Lock()
+	q.channel = channel
+	// the old channel's watch may not have marked the queue down yet
+	select {
+	case <-q.ready:
+	default:
+		close(q.ready)
+	}
+	q.mu.Unlock()
+
+	go q.watch(channel, closes)
+
+	return nil
+}
+
+// watch makes producers wait once the channel is closed. A channel closed
+// alone is reopened here, after a lost connection the manager reopens it.
+func (q *RabbitQueueConnection) watch(channel brokerChannel, closes chan *amqp.Error) {
+	reason := <-closes
+
+	// the manager has reopened the queue on a new connection already
+	if !q.markDown(channel) {
+		return
+	}
+
+	delay := q.manager.minDelay
+	for {
+		q.manager.mu.Lock()
+		// the manager may have restored the queue while we waited for the lock
+		if q.manager.closed || q.manager.connection.IsClosed() || !q.isCurrent(channel) {
+			q.manager.mu.Unlock()
+			return
+		}
+		err := q.open(q.manager.connection)
+		q.manager.mu.Unlock()
+		if err == nil {
+			q.manager.logger.Info("Reopened channel of queue %s closed by: %v", q.Name, reason)
+			return
+		}
+
+		q.manager.logger.Warning("Error reopening channel of queue %s: %v", q.Name, err)
+		select {
+		case <-time.After(delay):
+		case <-q.manager.done:
+			return
+		}
+		delay = nextDelay(delay, q.manager.maxDelay)
+	}
+}
+
+// markDown makes producers wait for the channel to be reopened. It reports
+// whether the channel is still the queue's one.
+func (q *RabbitQueueConnection) markDown(channel brokerChannel) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.channel != channel {
+		return false
+	}
+	select {
+	case <-q.ready:
+		q.ready = make(chan struct{})
+	default:
+	}
+
+	return true
+}
+
+func (q *RabbitQueueConnection) isCurrent(channel brokerChannel) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.channel == channel
+}
+
+// wait returns the open channel, waiting for it to be reopened if it is not.
+func (q *RabbitQueueConnection) wait(ctx context.Context) (brokerChannel, error) {
+	for {
+		q.mu.Lock()
+		channel, ready := q.channel, q.ready
+		q.mu.Unlock()
+
+		select {
+		case <-ready:
+			if !channel.IsClosed() {
+				return channel, nil
+			}
+			// the channel is closed, but watch hasn't marked it down yet, so
+			// mark it here and wait for the reopened one instead of spinning
+			q.markDown(channel)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-q.manager.done:
+			return nil, ErrManagerClosed
+		}
+	}
+}
+
+func (q *RabbitQueueConnection) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.channel == nil {
+		return nil
+	}
+
+	return ignoreClosed(q.channel.Close())
+}
+
+func (q *RabbitQueueConnection) Produce(ctx context.Context, jsonMsg []byte) error {
+	return q.ProduceType(ctx, "", jsonMsg)
+}
+
+// ProduceType publishes a message of msgType, consumers read it from the
+// delivery's Type. While the broker is unreachable it waits for reconnection
+// until ctx is done.
+func (q *RabbitQueueConnection) ProduceType(ctx context.Context, msgType string, jsonMsg []byte) error {
+	for {
+		channel, err := q.wait(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = channel.PublishWithContext(
+			ctx,
+			"",
+			q.Name,
+			false,
+			false,
+			amqp.Publishing{
+				ContentType: "application/json",
+				Type:        msgType,
+				Body:        jsonMsg,
+			},
+		)
+		// the channel was closed under us, publish again once it is reopened
+		if !errors.Is(err, amqp.ErrClosed) {
+			return err
+		}
+	}
+}
+
+// Consume returns deliveries of the queue, they have to be acked. Unacked
+// ones are delivered again once their channel is closed, so messages in
+// flight at shutdown aren't lost. The consumer is subscribed again after
+// reconnects, the returned channel is closed only by the manager's Close.
+func (q *RabbitQueueConnection) Consume() (<-chan amqp.Delivery, error) {
+	q.mu.Lock()
+	channel := q.channel
+	q.mu.Unlock()
+
+	deliveries, err := q.consume(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make(chan amqp.Delivery)
+	go q.forward(deliveries, messages)
+
+	return messages, nil
+}
+
+func (q *RabbitQueueConnection) consume(channel brokerChannel) (<-chan amqp.Delivery, error) {
+	return channel.Consume(
+		q.Name,
+		"",
+		false,
+		false,
+		false,
+		false,
+		nil,
+	)
+}
+
+func (q *RabbitQueueConnection) forward(deliveries <-chan amqp.Delivery, messages chan<- amqp.Delivery) {
+	defer close(messages)
+
+	for {
+		for delivery := range deliveries {
+			select {
+			case messages <- delivery:
+			case <-q.manager.done:
+				return
+			}
+		}
+
+		// deliveries end with the channel, subscribe again on the reopened one
+		for {
+			channel, err := q.wait(context.Background())
+			if err != nil {
+				return
+			}
+			if deliveries, err = q.consume(channel); err == nil {
+				q.manager.logger.Info("Subscribed to queue %s again.", q.Name)
+				break
+			}
+
+			q.manager.logger.Warning("Error subscribing to queue %s again: %v", q.Name, err)
+			select {
+			case <-time.After(q.manager.minDelay):
+			case <-q.manager.done:
+				return
+			}
+		}
+	}
+}
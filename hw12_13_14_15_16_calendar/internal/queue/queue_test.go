@@ -0,0 +1,386 @@
+package queue
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+const waitFor = 2 * time.Second
+
+// fakeBroker hands out fake connections, published messages are sent to
+// published.
+type fakeBroker struct {
+	published chan amqp.Publishing
+	// allow blocks dials until it is received from, nil lets them through.
+	allow chan struct{}
+
+	mu          sync.Mutex
+	connections []*fakeConnection
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{published: make(chan amqp.Publishing, 10)}
+}
+
+func (b *fakeBroker) dial() (brokerConnection, error) {
+	b.mu.Lock()
+	allow := b.allow
+	b.mu.Unlock()
+	if allow != nil {
+		<-allow
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := &fakeConnection{broker: b}
+	b.connections = append(b.connections, c)
+
+	return c, nil
+}
+
+func (b *fakeBroker) dials() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.connections)
+}
+
+func (b *fakeBroker) lastConnection() *fakeConnection {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.connections[len(b.connections)-1]
+}
+
+type closer struct {
+	mu     sync.Mutex
+	closed bool
+	closes []chan *amqp.Error
+}
+
+func (c *closer) NotifyClose(ch chan *amqp.Error) chan *amqp.Error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		close(ch)
+	} else {
+		c.closes = append(c.closes, ch)
+	}
+
+	return ch
+}
+
+func (c *closer) IsClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.closed
+}
+
+// shutdown marks it closed and notifies listeners as amqp does, the reason is
+// sent only for closes caused by errors. It reports whether it was open.
+func (c *closer) shutdown(reason *amqp.Error) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return false
+	}
+	c.closed = true
+	for _, ch := range c.closes {
+		if reason != nil {
+			ch <- reason
+		}
+		close(ch)
+	}
+
+	return true
+}
+
+type fakeConnection struct {
+	closer
+	broker *fakeBroker
+
+	channelsMu sync.Mutex
+	channels   []*fakeChannel
+}
+
+func (c *fakeConnection) Channel() (brokerChannel, error) {
+	if c.IsClosed() {
+		return nil, amqp.ErrClosed
+	}
+
+	c.channelsMu.Lock()
+	defer c.channelsMu.Unlock()
+
+	ch := &fakeChannel{connection: c}
+	c.channels = append(c.channels, ch)
+
+	return ch, nil
+}
+
+func (c *fakeConnection) lastChannel() *fakeChannel {
+	c.channelsMu.Lock()
+	defer c.channelsMu.Unlock()
+
+	return c.channels[len(c.channels)-1]
+}
+
+func (c *fakeConnection) channelCount() int {
+	c.channelsMu.Lock()
+	defer c.channelsMu.Unlock()
+
+	return len(c.channels)
+}
+
+func (c *fakeConnection) Close() error {
+	c.drop(nil)
+
+	return nil
+}
+
+// drop closes the connection with its channels, a nil reason is a graceful
+// close.
+func (c *fakeConnection) drop(reason *amqp.Error) {
+	if !c.shutdown(reason) {
+		return
+	}
+
+	c.channelsMu.Lock()
+	defer c.channelsMu.Unlock()
+
+	for _, ch := range c.channels {
+		ch.drop(reason)
+	}
+}
+
+type fakeChannel struct {
+	closer
+	connection *fakeConnection
+	// closedChecks counts IsClosed calls made after the channel was closed.
+	closedChecks atomic.Int64
+
+	consumersMu sync.Mutex
+	consumers   []chan amqp.Delivery
+}
+
+func (c *fakeChannel) QueueDeclare(name string, _, _, _, _ bool, _ amqp.Table) (amqp.Queue, error) {
+	if c.IsClosed() {
+		return amqp.Queue{}, amqp.ErrClosed
+	}
+
+	return amqp.Queue{Name: name}, nil
+}
+
+func (c *fakeChannel) PublishWithContext(
+	ctx context.Context,
+	_, _ string,
+	_, _ bool,
+	msg amqp.Publishing,
+) error {
+	if c.IsClosed() {
+		return amqp.ErrClosed
+	}
+
+	select {
+	case c.connection.broker.published <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *fakeChannel) Consume(_, _ string, _, _, _, _ bool, _ amqp.Table) (<-chan amqp.Delivery, error) {
+	if c.IsClosed() {
+		return nil, amqp.ErrClosed
+	}
+
+	c.consumersMu.Lock()
+	defer c.consumersMu.Unlock()
+
+	deliveries := make(chan amqp.Delivery, 10)
+	c.consumers = append(c.consumers, deliveries)
+
+	return deliveries, nil
+}
+
+func (c *fakeChannel) IsClosed() bool {
+	closed := c.closer.IsClosed()
+	if closed {
+		c.closedChecks.Add(1)
+	}
+
+	return closed
+}
+
+func (c *fakeChannel) Close() error {
+	c.drop(nil)
+
+	return nil
+}
+
+func (c *fakeChannel) drop(reason *amqp.Error) {
+	if !c.shutdown(reason) {
+		return
+	}
+
+	c.consumersMu.Lock()
+	defer c.consumersMu.Unlock()
+
+	for _, deliveries := range c.consumers {
+		close(deliveries)
+	}
+}
+
+func (c *fakeChannel) hasConsumer() bool {
+	c.consumersMu.Lock()
+	defer c.consumersMu.Unlock()
+
+	return len(c.consumers) > 0
+}
+
+func (c *fakeChannel) deliver(body string) {
+	c.consumersMu.Lock()
+	defer c.consumersMu.Unlock()
+
+	for _, deliveries := range c.consumers {
+		deliveries <- amqp.Delivery{Body: []byte(body)}
+	}
+}
+
+func newTestQueue(t *testing.T, broker *fakeBroker) (*RabbitManager, *RabbitQueueConnection) {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.RMQ.Reconnect.MinDelay = time.Millisecond
+	cfg.RMQ.Reconnect.MaxDelay = 10 * time.Millisecond
+
+	manager := NewRabbitManager(logger.New(logger.Debug, io.Discard))
+	manager.dialer = broker.dial
+	require.NoError(t, manager.Connect(cfg.WithContext(context.Background())))
+	t.Cleanup(func() { require.NoError(t, manager.Close()) })
+
+	queue, err := manager.CreateQueue("events")
+	require.NoError(t, err)
+
+	return manager, queue
+}
+
+func produce(t *testing.T, queue *RabbitQueueConnection, broker *fakeBroker, body string) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), waitFor)
+	defer cancel()
+
+	require.NoError(t, queue.Produce(ctx, []byte(body)))
+	select {
+	case msg := <-broker.published:
+		require.Equal(t, body, string(msg.Body))
+	case <-ctx.Done():
+		t.Fatal("message is not published")
+	}
+}
+
+func TestQueueConnectionLoss(t *testing.T) {
+	broker := newFakeBroker()
+	manager, queue := newTestQueue(t, broker)
+	produce(t, queue, broker, "1")
+
+	// hold the reconnect, so producers have to wait
+	allow := make(chan struct{})
+	broker.mu.Lock()
+	broker.allow = allow
+	broker.mu.Unlock()
+	lost := broker.lastConnection()
+	closed := lost.lastChannel()
+	lost.drop(&amqp.Error{Code: amqp.ConnectionForced, Reason: "broker restarted"})
+	require.ErrorIs(t, manager.Ping(context.Background()), ErrConnectionClosed)
+
+	published := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), waitFor)
+		defer cancel()
+		published <- queue.Produce(ctx, []byte("2"))
+	}()
+
+	// the producer waits without polling the closed channel
+	time.Sleep(50 * time.Millisecond)
+	require.Less(t, closed.closedChecks.Load(), int64(10))
+	require.Empty(t, published)
+
+	close(allow)
+	require.NoError(t, <-published)
+	require.Equal(t, "2", string((<-broker.published).Body))
+	require.Equal(t, 2, broker.dials())
+	require.NoError(t, manager.Ping(context.Background()))
+	require.False(t, broker.lastConnection().lastChannel().IsClosed())
+}
+
+func TestQueueChannelClose(t *testing.T) {
+	broker := newFakeBroker()
+	_, queue := newTestQueue(t, broker)
+	connection := broker.lastConnection()
+
+	connection.lastChannel().drop(&amqp.Error{Code: amqp.PreconditionFailed, Reason: "channel error"})
+	require.Eventually(t, func() bool { return connection.channelCount() == 2 }, waitFor, time.Millisecond)
+
+	// the channel is reopened on the same connection
+	produce(t, queue, broker, "1")
+	require.Equal(t, 1, broker.dials())
+	require.False(t, connection.lastChannel().IsClosed())
+}
+
+func TestQueueResubscribe(t *testing.T) {
+	broker := newFakeBroker()
+	_, queue := newTestQueue(t, broker)
+
+	messages, err := queue.Consume()
+	require.NoError(t, err)
+
+	receive := func(body string) {
+		t.Helper()
+
+		select {
+		case delivery, ok := <-messages:
+			require.True(t, ok)
+			require.Equal(t, body, string(delivery.Body))
+		case <-time.After(waitFor):
+			t.Fatal("message is not received")
+		}
+	}
+
+	broker.lastConnection().lastChannel().deliver("1")
+	receive("1")
+
+	t.Run("after channel close", func(t *testing.T) {
+		connection := broker.lastConnection()
+		connection.lastChannel().drop(&amqp.Error{Code: amqp.PreconditionFailed, Reason: "channel error"})
+		require.Eventually(t, func() bool {
+			return connection.channelCount() == 2 && connection.lastChannel().hasConsumer()
+		}, waitFor, time.Millisecond)
+
+		connection.lastChannel().deliver("2")
+		receive("2")
+	})
+
+	t.Run("after connection loss", func(t *testing.T) {
+		broker.lastConnection().drop(&amqp.Error{Code: amqp.ConnectionForced, Reason: "broker restarted"})
+		require.Eventually(t, func() bool {
+			return broker.dials() == 2 && broker.lastConnection().lastChannel().hasConsumer()
+		}, waitFor, time.Millisecond)
+
+		broker.lastConnection().lastChannel().deliver("3")
+		receive("3")
+	})
+}
@@ -0,0 +1,91 @@
+// Package ratelimit limits the rate of requests by key with token buckets.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many calls pass between removals of full buckets.
+const sweepEvery = 1024
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter keeps a token bucket per key. Buckets are filled at rate tokens per
+// second up to burst, a request takes a token.
+type Limiter struct {
+	rate  float64
+	burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+}
+
+// New returns a limiter allowing rate requests per second by key with bursts
+// of up to burst requests. A zero rate allows everything.
+func New(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:    rate,
+		burst:   float64(max(burst, 1)),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow takes a token of the key's bucket at now. If there is none, it returns
+// false and how long to wait for one.
+func (l *Limiter) Allow(key string, now time.Time) (bool, time.Duration) {
+	if l.rate <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.calls++
+	if l.calls%sweepEvery == 0 {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	l.fill(b, now)
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+
+	return true, 0
+}
+
+func (l *Limiter) fill(b *bucket, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(l.burst, b.tokens+elapsed.Seconds()*l.rate)
+		b.last = now
+	}
+}
+
+// sweep removes buckets which have filled up, they are the same as new ones.
+func (l *Limiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if l.fill(b, now); b.tokens >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Len returns the number of tracked keys.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.buckets)
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	limiter := New(2, 3)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	for range 3 {
+		ok, _ := limiter.Allow("user", now)
+		require.True(t, ok)
+	}
+	ok, wait := limiter.Allow("user", now)
+	require.False(t, ok)
+	require.Equal(t, 500*time.Millisecond, wait)
+
+	// other keys have their own buckets
+	ok, _ = limiter.Allow("other", now)
+	require.True(t, ok)
+
+	ok, _ = limiter.Allow("user", now.Add(500*time.Millisecond))
+	require.True(t, ok)
+	ok, wait = limiter.Allow("user", now.Add(600*time.Millisecond))
+	require.False(t, ok)
+	require.Equal(t, 400*time.Millisecond, wait)
+
+	// buckets don't fill above the burst
+	for range 3 {
+		ok, _ = limiter.Allow("user", now.Add(time.Hour))
+		require.True(t, ok)
+	}
+	ok, _ = limiter.Allow("user", now.Add(time.Hour))
+	require.False(t, ok)
+}
+
+func TestLimiter_Unlimited(t *testing.T) {
+	limiter := New(0, 0)
+	for range 100 {
+		ok, _ := limiter.Allow("user", time.Now())
+		require.True(t, ok)
+	}
+	require.Zero(t, limiter.Len())
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	limiter := New(10, 1)
+	now := time.Now()
+	for i := range sweepEvery - 1 {
+		limiter.Allow(strconv.Itoa(i), now)
+	}
+	require.Equal(t, sweepEvery-1, limiter.Len())
+
+	// full buckets are dropped, the calling key's bucket is made again
+	limiter.Allow("late", now.Add(time.Second))
+	require.Equal(t, 1, limiter.Len())
+}
@@ -0,0 +1,70 @@
+package errcode
+
+import (
+	"context"
+	"errors"
+
+	eventapp "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/app/event"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// codeErrors lists application errors by the status code they are returned
+// with.
+var codeErrors = []struct {
+	code   codes.Code
+	errors []error
+}{
+	{codes.Unauthenticated, []error{eventapp.ErrNoUser}},
+	{codes.PermissionDenied, []error{eventapp.ErrForbidden}},
+	{codes.NotFound, []error{
+		eventapp.ErrNotFound,
+		entity.ErrEventNotFound,
+		entity.ErrCalendarNotFound,
+		entity.ErrShareNotFound,
+		entity.ErrTagNotFound,
+		entity.ErrDigestNotFound,
+	}},
+	{codes.InvalidArgument, []error{
+		eventapp.ErrInvalidTag,
+		eventapp.ErrInvalidColor,
+		eventapp.ErrUnknownTag,
+		eventapp.ErrEmptyQuery,
+		eventapp.ErrInvalidCalendar,
+		eventapp.ErrInvalidRole,
+		eventapp.ErrShareToOwner,
+		eventapp.ErrInvalidDigestTime,
+		eventapp.ErrInvalidTimeZone,
+	}},
+	{codes.AlreadyExists, []error{eventapp.ErrDateBusy, entity.ErrEventUIDExists}},
+	{codes.FailedPrecondition, []error{eventapp.ErrEventIsActive}},
+}
+
+// New returns application errors as statuses with matching codes, so the
+// gateway answers with matching HTTP codes. Other errors are returned as is.
+func New() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+
+		return resp, Status(err)
+	}
+}
+
+// Status returns the status error for err, err itself if it is not an
+// application error.
+func Status(err error) error {
+	for _, group := range codeErrors {
+		for _, target := range group.errors {
+			if errors.Is(err, target) {
+				return status.Error(group.code, err.Error())
+			}
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,45 @@
+package errcode
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	eventapp "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/app/event"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestInterceptor(t *testing.T) {
+	interceptor := New()
+	for err, code := range map[error]codes.Code{
+		eventapp.ErrNoUser:                                 codes.Unauthenticated,
+		eventapp.ErrForbidden:                              codes.PermissionDenied,
+		entity.ErrEventNotFound:                            codes.NotFound,
+		fmt.Errorf("get: %w", entity.ErrCalendarNotFound):  codes.NotFound,
+		eventapp.ErrInvalidColor:                           codes.InvalidArgument,
+		eventapp.ErrInvalidDigestTime:                      codes.InvalidArgument,
+		eventapp.ErrDateBusy:                               codes.AlreadyExists,
+		status.Error(codes.ResourceExhausted, "slow down"): codes.ResourceExhausted,
+	} {
+		_, got := interceptor(context.Background(), nil, nil, func(context.Context, any) (any, error) {
+			return nil, err
+		})
+		require.Equal(t, code, status.Code(got), err)
+	}
+
+	// other errors are kept for outer interceptors
+	quotaErr := entity.QuotaError{Quota: "events per user", Limit: 1}
+	_, got := interceptor(context.Background(), nil, nil, func(context.Context, any) (any, error) {
+		return nil, quotaErr
+	})
+	require.ErrorIs(t, got, entity.ErrQuotaExceeded)
+
+	resp, got := interceptor(context.Background(), nil, nil, func(context.Context, any) (any, error) {
+		return "ok", nil
+	})
+	require.NoError(t, got)
+	require.Equal(t, "ok", resp)
+}
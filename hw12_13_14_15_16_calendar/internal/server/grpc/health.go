@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	proto "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/api"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/http/health"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// watchPeriod is how often watched statuses are checked.
+const watchPeriod = 5 * time.Second
+
+// healthServer implements grpc.health.v1 by the readiness checks, so the
+// server is serving when its dependencies are. The whole server, named by an
+// empty string, and the event service are known.
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+	checker *health.Checker
+}
+
+func newHealthServer(checker *health.Checker) *healthServer {
+	if checker == nil {
+		checker = health.NewChecker(health.DefaultTimeout)
+	}
+
+	return &healthServer{checker: checker}
+}
+
+func (h *healthServer) Check(
+	ctx context.Context,
+	req *healthpb.HealthCheckRequest,
+) (*healthpb.HealthCheckResponse, error) {
+	if !known(req.GetService()) {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.GetService())
+	}
+
+	return &healthpb.HealthCheckResponse{Status: h.status(ctx)}, nil
+}
+
+// Watch sends the status whenever it changes until the client leaves.
+func (h *healthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ctx := stream.Context()
+	if !known(req.GetService()) {
+		err := stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVICE_UNKNOWN})
+		if err != nil {
+			return err
+		}
+		<-ctx.Done()
+		return nil
+	}
+
+	ticker := time.NewTicker(watchPeriod)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	for {
+		if current := h.status(ctx); current != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: current}); err != nil {
+				return err
+			}
+			last = current
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (h *healthServer) status(ctx context.Context) healthpb.HealthCheckResponse_ServingStatus {
+	if h.checker.Check(ctx).OK() {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
+
+func known(service string) bool {
+	return service == "" || service == proto.EventService_ServiceDesc.ServiceName
+}
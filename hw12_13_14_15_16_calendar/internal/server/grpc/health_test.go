@@ -0,0 +1,33 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/http/health"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestHealthServer(t *testing.T) {
+	ctx := context.Background()
+	checker := health.NewChecker(health.DefaultTimeout)
+	server := newHealthServer(checker)
+
+	for _, service := range []string{"", "event.EventService"} {
+		resp, err := server.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+	}
+
+	checker.Add("storage", func(context.Context) error { return errors.New("down") })
+	resp, err := server.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.GetStatus())
+
+	_, err = server.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
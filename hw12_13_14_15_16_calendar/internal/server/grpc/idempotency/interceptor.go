@@ -0,0 +1,46 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/idempotency"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Header is the metadata key holding the client's idempotency key.
+const Header = "idempotency-key"
+
+// maxKeyLength bounds stored keys, clients usually send UUIDs.
+const maxKeyLength = 255
+
+// New puts the key passed in Header into the request context. A key reused
+// for another payload fails with FailedPrecondition, a retry of a request in
+// progress with Aborted.
+func New() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		headers, ok := metadata.FromIncomingContext(ctx)
+		if !ok || len(headers.Get(Header)) == 0 {
+			return handler(ctx, req)
+		}
+
+		key := headers.Get(Header)[0]
+		if len(key) > maxKeyLength {
+			return nil, status.Errorf(codes.InvalidArgument, "%s must be at most %d bytes", Header, maxKeyLength)
+		}
+
+		resp, err := handler(idempotency.WithKey(ctx, key), req)
+		switch {
+		case errors.Is(err, entity.ErrIdempotencyKeyReused):
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		case errors.Is(err, entity.ErrIdempotencyKeyInProgress):
+			return nil, status.Error(codes.Aborted, err.Error())
+		}
+
+		return resp, err
+	}
+}
@@ -0,0 +1,50 @@
+package idempotency
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/idempotency"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func withKey(key string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(Header, key))
+}
+
+func TestInterceptor(t *testing.T) {
+	interceptor := New()
+	key := func(ctx context.Context, _ any) (any, error) {
+		key, _ := idempotency.Key(ctx)
+		return key, nil
+	}
+
+	resp, err := interceptor(withKey("key-1"), nil, nil, key)
+	require.NoError(t, err)
+	require.Equal(t, "key-1", resp)
+
+	resp, err = interceptor(context.Background(), nil, nil, key)
+	require.NoError(t, err)
+	require.Empty(t, resp)
+
+	_, err = interceptor(withKey(strings.Repeat("k", maxKeyLength+1)), nil, nil, key)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestInterceptor_Errors(t *testing.T) {
+	interceptor := New()
+	for err, code := range map[error]codes.Code{
+		entity.ErrIdempotencyKeyReused:     codes.FailedPrecondition,
+		entity.ErrIdempotencyKeyInProgress: codes.Aborted,
+	} {
+		_, got := interceptor(withKey("key-1"), nil, nil, func(context.Context, any) (any, error) {
+			return nil, err
+		})
+		require.Equal(t, code, status.Code(got))
+	}
+}
@@ -0,0 +1,33 @@
+package identity
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Header is the metadata key holding the requesting user ID.
+const Header = "x-user-id"
+
+// New puts the user ID passed in Header into the request context. Requests
+// without the header are passed as is and rejected by the application.
+func New() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		headers, ok := metadata.FromIncomingContext(ctx)
+		if !ok || len(headers.Get(Header)) == 0 {
+			return handler(ctx, req)
+		}
+
+		userID, err := strconv.Atoi(headers.Get(Header)[0])
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "invalid %s: %v", Header, err)
+		}
+
+		return handler(identity.WithUserID(ctx, userID), req)
+	}
+}
@@ -0,0 +1,162 @@
+// Package inproc calls gRPC services in process, without serializing messages
+// or a network hop, through the same interceptors as the server.
+package inproc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// loopback is the peer of calls without one. In process calls come from the
+// gateway, which is trusted to forward client addresses as over loopback.
+var loopback = &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}}
+
+type service struct {
+	impl    any
+	methods map[string]grpc.MethodDesc
+}
+
+// Conn is a client connection to the services registered on it. Only unary
+// methods are supported.
+type Conn struct {
+	interceptor grpc.UnaryServerInterceptor
+	services    map[string]service
+}
+
+var (
+	_ grpc.ClientConnInterface = (*Conn)(nil)
+	_ grpc.ServiceRegistrar    = (*Conn)(nil)
+)
+
+// New returns a connection calling services through the interceptors, the
+// first one is the outermost as in grpc.ChainUnaryInterceptor.
+func New(interceptors ...grpc.UnaryServerInterceptor) *Conn {
+	return &Conn{
+		interceptor: chain(interceptors),
+		services:    make(map[string]service),
+	}
+}
+
+// RegisterService registers the implementation of the service, it must be
+// called before the connection is used.
+func (c *Conn) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	methods := make(map[string]grpc.MethodDesc, len(desc.Methods))
+	for _, method := range desc.Methods {
+		methods[method.MethodName] = method
+	}
+	c.services[desc.ServiceName] = service{impl: impl, methods: methods}
+}
+
+// Invoke calls the method as the server would: outgoing metadata of ctx is
+// incoming to the service, the peer is loopback unless ctx has one, headers
+// and trailers it sets are returned to grpc.Header and grpc.Trailer options,
+// errors are statuses.
+func (c *Conn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	serviceName, methodName, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	svc, ok := c.services[serviceName]
+	if !ok {
+		return status.Errorf(codes.Unimplemented, "unknown service %s", serviceName)
+	}
+	desc, ok := svc.methods[methodName]
+	if !ok {
+		return status.Errorf(codes.Unimplemented, "unknown method %s for service %s", methodName, serviceName)
+	}
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	ctx = metadata.NewIncomingContext(metadata.NewOutgoingContext(ctx, metadata.MD{}), md.Copy())
+	if _, ok := peer.FromContext(ctx); !ok {
+		ctx = peer.NewContext(ctx, loopback)
+	}
+	stream := &transportStream{method: method}
+	ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+
+	decode := func(in any) error {
+		proto.Merge(in.(proto.Message), args.(proto.Message))
+		return nil
+	}
+	resp, err := desc.Handler(svc.impl, ctx, decode, c.interceptor)
+
+	for _, opt := range opts {
+		switch opt := opt.(type) {
+		case grpc.HeaderCallOption:
+			*opt.HeaderAddr = stream.header
+		case grpc.TrailerCallOption:
+			*opt.TrailerAddr = stream.trailer
+		}
+	}
+
+	if err != nil {
+		if _, ok := status.FromError(err); !ok {
+			err = status.FromContextError(err).Err()
+		}
+		return err
+	}
+	proto.Merge(reply.(proto.Message), resp.(proto.Message))
+
+	return nil
+}
+
+// NewStream fails, streaming methods aren't supported.
+func (c *Conn) NewStream(context.Context, *grpc.StreamDesc, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, status.Error(codes.Unimplemented, "streams are not supported in process")
+}
+
+// chain nests the interceptors into one, the first one is the outermost.
+func chain(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		next := handler
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			interceptor, inner := interceptors[i], next
+			next = func(ctx context.Context, req any) (any, error) {
+				return interceptor(ctx, req, info, inner)
+			}
+		}
+
+		return next(ctx, req)
+	}
+}
+
+// transportStream collects metadata set by the service with grpc.SetHeader,
+// grpc.SendHeader and grpc.SetTrailer.
+type transportStream struct {
+	method string
+
+	mu      sync.Mutex
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (s *transportStream) Method() string {
+	return s.method
+}
+
+func (s *transportStream) SetHeader(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.header = metadata.Join(s.header, md)
+
+	return nil
+}
+
+func (s *transportStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+func (s *transportStream) SetTrailer(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.trailer = metadata.Join(s.trailer, md)
+
+	return nil
+}
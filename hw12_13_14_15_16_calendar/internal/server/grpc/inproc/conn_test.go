@@ -0,0 +1,68 @@
+package inproc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestConn(t *testing.T) {
+	var calls []string
+	record := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			calls = append(calls, name+" "+info.FullMethod)
+			return handler(ctx, req)
+		}
+	}
+	echo := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if err := grpc.SetHeader(ctx, metadata.Pairs("x-echo", md.Get("x-user-id")[0])); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+
+	conn := New(record("first"), record("second"), echo)
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(conn, healthServer)
+	client := healthpb.NewHealthClient(conn)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-user-id", "1")
+	var header metadata.MD
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+	require.Equal(t, []string{"1"}, header.Get("x-echo"))
+	require.Equal(t, []string{
+		"first /grpc.health.v1.Health/Check",
+		"second /grpc.health.v1.Health/Check",
+	}, calls)
+
+	// errors are statuses as over the network
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	err = conn.Invoke(ctx, "/grpc.health.v1.Health/Missing", &healthpb.HealthCheckRequest{}, &healthpb.HealthCheckResponse{})
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+	_, err = client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestConn_PlainErrors(t *testing.T) {
+	conn := New(func(context.Context, any, *grpc.UnaryServerInfo, grpc.UnaryHandler) (any, error) {
+		return nil, errors.New("time not available")
+	})
+	healthpb.RegisterHealthServer(conn, health.NewServer())
+
+	_, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.Equal(t, codes.Unknown, status.Code(err))
+	require.Equal(t, "time not available", status.Convert(err).Message())
+}
@@ -39,8 +39,8 @@ func New(logger logger.Logger) grpc.UnaryServerInterceptor {
 		}
 
 		userAgent := unknown
-		if ok {
-			userAgent = headers.Get("user-agent")[0]
+		if userAgents := headers.Get("user-agent"); len(userAgents) > 0 {
+			userAgent = userAgents[0]
 		}
 
 		statusCode := codes.Unknown
@@ -0,0 +1,23 @@
+package ratelimit
+
+import "net/http"
+
+// Handler limits HTTP requests served apart from the gateway by the same
+// buckets as gRPC calls, exhausted requests fail with Too Many Requests and
+// Retry-After. The user is taken from the request context, so it must wrap
+// handlers after authentication.
+func (l *Limiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := forwardedIP(hostOf(r.RemoteAddr), r.Header.Values("X-Forwarded-For"), l.trusted)
+		if ok, wait, message := l.allow(r.Context(), ip); !ok {
+			if wait > 0 {
+				w.Header().Set(Header, seconds(wait))
+			}
+			http.Error(w, message, http.StatusTooManyRequests)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,43 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/identity"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_Handler(t *testing.T) {
+	proxies, err := ParseProxies([]string{"10.1.0.0/16"})
+	require.NoError(t, err)
+	limiter := NewLimiter(Options{UserRate: 0.001, UserBurst: 1, IPRate: 0.001, IPBurst: 2, TrustedProxies: proxies})
+	handler := limiter.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	get := func(addr, forwarded string, userID int) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/dav/", nil)
+		request.RemoteAddr = addr
+		if forwarded != "" {
+			request.Header.Set("X-Forwarded-For", forwarded)
+		}
+		request = request.WithContext(identity.WithUserID(context.Background(), userID))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	// gRPC calls and HTTP requests of a user take tokens of the same bucket
+	_, err = limiter.Interceptor()(identity.WithUserID(withPeer(context.Background(), "192.0.2.1"), 1), nil, info, ok)
+	require.NoError(t, err)
+	recorder := get("192.0.2.2:40000", "", 1)
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "1000", recorder.Header().Get("Retry-After"))
+
+	// clients behind trusted proxies are limited by their addresses
+	require.Equal(t, http.StatusOK, get("10.1.0.1:40000", "198.51.100.1", 2).Code)
+	require.Equal(t, http.StatusOK, get("10.1.0.1:40000", "198.51.100.1", 3).Code)
+	require.Equal(t, http.StatusTooManyRequests, get("10.1.0.1:40000", "198.51.100.1", 4).Code)
+	require.Equal(t, http.StatusOK, get("10.1.0.1:40000", "198.51.100.2", 5).Code)
+}
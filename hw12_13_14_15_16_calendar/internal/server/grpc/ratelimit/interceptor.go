@@ -0,0 +1,205 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/identity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Header is the metadata key telling in seconds when to retry an exhausted
+// request.
+const Header = "retry-after"
+
+// exempt are method prefixes which aren't limited, probes must pass.
+var exempt = []string{"/grpc.health.v1."}
+
+type Options struct {
+	// UserRate and UserBurst limit requests of a user per second.
+	UserRate  float64
+	UserBurst int
+	// IPRate and IPBurst limit requests from an address per second.
+	IPRate  float64
+	IPBurst int
+	// TrustedProxies are networks of proxies in front of the HTTP gateway,
+	// addresses they forward are taken as the client's. The gateway itself
+	// connects over loopback, which is always trusted.
+	TrustedProxies []netip.Prefix
+}
+
+// ParseProxies parses CIDRs of trusted proxies.
+func ParseProxies(cidrs []string) ([]netip.Prefix, error) {
+	proxies := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy: %w", err)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+
+	return proxies, nil
+}
+
+// Limiter limits requests per user and per client address. It is shared by
+// gRPC calls and HTTP requests served apart from the gateway, so they take
+// tokens of the same buckets.
+type Limiter struct {
+	users, ips *ratelimit.Limiter
+	trusted    []netip.Prefix
+}
+
+func NewLimiter(options Options) *Limiter {
+	return &Limiter{
+		users:   ratelimit.New(options.UserRate, options.UserBurst),
+		ips:     ratelimit.New(options.IPRate, options.IPBurst),
+		trusted: options.TrustedProxies,
+	}
+}
+
+// New limits requests per user and per client address, exhausted requests
+// and requests exceeding entity quotas fail with ResourceExhausted and
+// Header. It must follow the identity interceptor.
+func New(options Options) grpc.UnaryServerInterceptor {
+	return NewLimiter(options).Interceptor()
+}
+
+// Interceptor limits gRPC calls as New does.
+func (l *Limiter) Interceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		for _, prefix := range exempt {
+			if strings.HasPrefix(info.FullMethod, prefix) {
+				return handler(ctx, req)
+			}
+		}
+
+		if ok, wait, message := l.allow(ctx, clientIP(ctx, l.trusted)); !ok {
+			return nil, exhausted(ctx, wait, message)
+		}
+
+		resp, err := handler(ctx, req)
+
+		var quotaErr entity.QuotaError
+		if errors.As(err, &quotaErr) {
+			return nil, exhausted(ctx, quotaErr.RetryAfter, quotaErr.Error())
+		}
+
+		return resp, err
+	}
+}
+
+// allow takes tokens of the address's bucket and of the bucket of the user
+// in ctx, if any. If either is empty, it returns false, how long to wait and
+// why.
+func (l *Limiter) allow(ctx context.Context, ip string) (bool, time.Duration, string) {
+	now := time.Now()
+	if ok, wait := l.ips.Allow(ip, now); !ok {
+		return false, wait, "too many requests from the address"
+	}
+	if userID, ok := identity.UserID(ctx); ok {
+		if ok, wait := l.users.Allow(strconv.Itoa(userID), now); !ok {
+			return false, wait, "too many requests of the user"
+		}
+	}
+
+	return true, 0, ""
+}
+
+// clientIP returns the address the request came from. Starting with the peer,
+// x-forwarded-for entries are taken from the last one while the address they
+// were received from is a trusted proxy, so entries set by clients are
+// ignored.
+func clientIP(ctx context.Context, trusted []netip.Prefix) string {
+	var forwarded []string
+	if headers, ok := metadata.FromIncomingContext(ctx); ok {
+		forwarded = headers.Get("x-forwarded-for")
+	}
+
+	return forwardedIP(peerIP(ctx), forwarded, trusted)
+}
+
+// forwardedIP returns the client address given the address the request came
+// from and x-forwarded-for values, as clientIP describes.
+func forwardedIP(addr string, forwarded []string, trusted []netip.Prefix) string {
+	var hops []string
+	for _, value := range forwarded {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0 && IsTrusted(addr, trusted); i-- {
+		addr = hops[i]
+	}
+
+	return addr
+}
+
+func peerIP(ctx context.Context) string {
+	peerInfo, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	return hostOf(peerInfo.Addr.String())
+}
+
+// hostOf returns the host of a host:port address, the address itself if it
+// has no port.
+func hostOf(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return addr
+}
+
+// IsTrusted reports whether the address is loopback or in a trusted proxy
+// network.
+func IsTrusted(addr string, trusted []netip.Prefix) bool {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+	ip = ip.Unmap()
+	if ip.IsLoopback() {
+		return true
+	}
+	for _, prefix := range trusted {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// exhausted returns ResourceExhausted, setting Header in whole seconds unless
+// retrying doesn't help.
+func exhausted(ctx context.Context, retryAfter time.Duration, message string) error {
+	if retryAfter > 0 {
+		_ = grpc.SetHeader(ctx, metadata.Pairs(Header, seconds(retryAfter)))
+	}
+
+	return status.Error(codes.ResourceExhausted, message)
+}
+
+// seconds returns the duration in whole seconds, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,127 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/identity"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var info = &grpc.UnaryServerInfo{FullMethod: "/event.EventService/GetMonthEvents"}
+
+func ok(context.Context, any) (any, error) {
+	return "ok", nil
+}
+
+func withPeer(ctx context.Context, ip string) context.Context {
+	return peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 40000}})
+}
+
+func TestInterceptor_User(t *testing.T) {
+	interceptor := New(Options{UserRate: 1, UserBurst: 2})
+
+	ctx := identity.WithUserID(withPeer(context.Background(), "10.0.0.1"), 1)
+	for range 2 {
+		_, err := interceptor(ctx, nil, info, ok)
+		require.NoError(t, err)
+	}
+	_, err := interceptor(ctx, nil, info, ok)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// other users and probes aren't limited
+	_, err = interceptor(identity.WithUserID(ctx, 2), nil, info, ok)
+	require.NoError(t, err)
+	_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, ok)
+	require.NoError(t, err)
+}
+
+func TestInterceptor_IP(t *testing.T) {
+	interceptor := New(Options{IPRate: 1, IPBurst: 1})
+
+	gateway := withPeer(context.Background(), "127.0.0.1")
+	// the gateway appends the address it got the request from to entries set
+	// by the client
+	forwarded := func(ip string) context.Context {
+		return metadata.NewIncomingContext(gateway, metadata.Pairs("x-forwarded-for", "10.0.0.9, "+ip))
+	}
+
+	_, err := interceptor(forwarded("192.0.2.1"), nil, info, ok)
+	require.NoError(t, err)
+	_, err = interceptor(forwarded("192.0.2.1"), nil, info, ok)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// clients behind the gateway are told apart by the entry it appended
+	_, err = interceptor(forwarded("192.0.2.2"), nil, info, ok)
+	require.NoError(t, err)
+	_, err = interceptor(gateway, nil, info, ok)
+	require.NoError(t, err)
+
+	// gRPC clients can't pose as others by setting the header
+	spoofed := func(ip string) context.Context {
+		direct := withPeer(context.Background(), "192.0.2.3")
+		return metadata.NewIncomingContext(direct, metadata.Pairs("x-forwarded-for", ip))
+	}
+	_, err = interceptor(spoofed("192.0.2.4"), nil, info, ok)
+	require.NoError(t, err)
+	_, err = interceptor(spoofed("192.0.2.5"), nil, info, ok)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestInterceptor_Quota(t *testing.T) {
+	interceptor := New(Options{})
+
+	_, err := interceptor(context.Background(), nil, info, func(context.Context, any) (any, error) {
+		return nil, entity.QuotaError{Quota: "events per day", Limit: 10, RetryAfter: time.Hour}
+	})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Contains(t, err.Error(), "events per day is 10")
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseProxies([]string{"10.1.0.0/16", "2001:db8::/32"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		peer      string
+		forwarded []string
+		expected  string
+	}{
+		{name: "peer", peer: "192.0.2.1", expected: "192.0.2.1"},
+		{name: "untrusted peer", peer: "192.0.2.1", forwarded: []string{"198.51.100.1"}, expected: "192.0.2.1"},
+		{name: "gateway", peer: "127.0.0.1", forwarded: []string{"198.51.100.1, 192.0.2.1"}, expected: "192.0.2.1"},
+		{name: "ipv6 gateway", peer: "::1", forwarded: []string{"192.0.2.1"}, expected: "192.0.2.1"},
+		{
+			name:      "trusted proxies",
+			peer:      "127.0.0.1",
+			forwarded: []string{"198.51.100.1, 192.0.2.1", "10.1.2.3"},
+			expected:  "192.0.2.1",
+		},
+		{name: "ipv6 proxy", peer: "127.0.0.1", forwarded: []string{"192.0.2.1, 2001:db8::1"}, expected: "192.0.2.1"},
+		{name: "only proxies", peer: "127.0.0.1", forwarded: []string{"10.1.0.1, 10.1.0.2"}, expected: "10.1.0.1"},
+		{name: "empty header", peer: "127.0.0.1", forwarded: []string{""}, expected: "127.0.0.1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := withPeer(context.Background(), tc.peer)
+			if tc.forwarded != nil {
+				ctx = metadata.NewIncomingContext(ctx, metadata.MD{"x-forwarded-for": tc.forwarded})
+			}
+			require.Equal(t, tc.expected, clientIP(ctx, proxies))
+		})
+	}
+
+	require.Empty(t, clientIP(context.Background(), nil))
+
+	_, err = ParseProxies([]string{"10.1.0.0"})
+	require.Error(t, err)
+}
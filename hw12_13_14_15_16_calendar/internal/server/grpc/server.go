@@ -2,51 +2,122 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
+	"net/http"
 	"time"
 
 	proto "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/api"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/grpc/errcode"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/grpc/idempotency"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/grpc/identity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/grpc/inproc"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/grpc/log"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/grpc/ratelimit"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/http/health"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 type Options struct {
 	Host, Port     string
 	ConnectTimeout time.Duration
+	// Health checks dependencies for grpc.health.v1, the server is always
+	// serving without it.
+	Health *health.Checker
+	// RateLimit limits requests per user and address, zero rates don't.
+	RateLimit ratelimit.Options
+	// TLS serves over TLS, plaintext is served without it.
+	TLS *tls.Config
 }
 
 type Server interface {
 	Start(context.Context) error
 	Stop(context.Context) error
+	// ServeHTTP serves gRPC requests of an HTTP/2 server, for sharing its
+	// listener.
+	http.Handler
+	// Conn calls the event service in process through the server's
+	// interceptors.
+	Conn() grpc.ClientConnInterface
+	// Limit limits HTTP requests served apart from the gateway by the
+	// server's rate limits.
+	Limit(http.Handler) http.Handler
 }
 
 type server struct {
 	*grpc.Server
-	logger logger.Logger
+	logger  logger.Logger
+	conn    *inproc.Conn
+	limiter *ratelimit.Limiter
 }
 
 type Application interface {
-	GetEvent(id string) (*entity.Event, error)
-	CreateEvent(event entity.Event) (string, error)
-	UpdateEvent(id string, event entity.Event) error
-	DeleteEvent(id string) error
-	GetDayEvents(day time.Time) (*entity.Events, error)
-	GetWeekEvents(weekStart time.Time) (*entity.Events, error)
-	GetMonthEvents(monthStart time.Time) (*entity.Events, error)
+	GetEvent(ctx context.Context, id string) (*entity.Event, error)
+	CreateEvent(ctx context.Context, event entity.Event) (string, error)
+	UpdateEvent(ctx context.Context, id string, event entity.Event) error
+	DeleteEvent(ctx context.Context, id string) error
+	GetDayEvents(ctx context.Context, day time.Time, tags ...string) (*entity.Events, error)
+	GetWeekEvents(ctx context.Context, weekStart time.Time, tags ...string) (*entity.Events, error)
+	GetMonthEvents(ctx context.Context, monthStart time.Time, tags ...string) (*entity.Events, error)
+	SearchEvents(
+		ctx context.Context,
+		query string,
+		start, end time.Time,
+		limit int,
+		tags ...string,
+	) (*entity.Events, error)
+	SaveTag(ctx context.Context, tag entity.Tag) error
+	DeleteTag(ctx context.Context, userID int, name string) error
+	GetTags(ctx context.Context, userID int) (*entity.Tags, error)
+	CreateCalendar(ctx context.Context, calendar entity.Calendar) (string, error)
+	UpdateCalendar(ctx context.Context, calendar entity.Calendar) error
+	DeleteCalendar(ctx context.Context, id string) error
+	GetCalendars(ctx context.Context) (*entity.Calendars, error)
+	ShareCalendar(ctx context.Context, share entity.Share) error
+	UnshareCalendar(ctx context.Context, calendarID string, userID int) error
+	GetShares(ctx context.Context, calendarID string) (*entity.Shares, error)
+	SaveDigest(ctx context.Context, digest entity.Digest) error
+	DeleteDigest(ctx context.Context, userID int) error
+	GetDigest(ctx context.Context, userID int) (*entity.Digest, error)
 }
 
 func New(options Options, logger logger.Logger, app Application) Server {
-	serverGRPC := grpc.NewServer(
+	limiter := ratelimit.NewLimiter(options.RateLimit)
+	interceptors := []grpc.UnaryServerInterceptor{
+		log.New(logger),
+		identity.New(),
+		limiter.Interceptor(),
+		idempotency.New(),
+		errcode.New(),
+	}
+	serverOptions := []grpc.ServerOption{
 		grpc.ConnectionTimeout(options.ConnectTimeout),
-		grpc.ChainUnaryInterceptor(
-			log.New(logger),
-		),
-	)
-	proto.RegisterEventServiceServer(serverGRPC, NewService(app, logger))
-	return &server{serverGRPC, logger}
+		grpc.ChainUnaryInterceptor(interceptors...),
+	}
+	if options.TLS != nil {
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(options.TLS)))
+	}
+	serverGRPC := grpc.NewServer(serverOptions...)
+	conn := inproc.New(interceptors...)
+
+	service := NewService(app, logger)
+	proto.RegisterEventServiceServer(serverGRPC, service)
+	proto.RegisterEventServiceServer(conn, service)
+	healthpb.RegisterHealthServer(serverGRPC, newHealthServer(options.Health))
+	return &server{serverGRPC, logger, conn, limiter}
+}
+
+func (s *server) Conn() grpc.ClientConnInterface {
+	return s.conn
+}
+
+func (s *server) Limit(next http.Handler) http.Handler {
+	return s.limiter.Handler(next)
 }
 
 func (s *server) Start(ctx context.Context) error {
@@ -73,7 +144,20 @@ func (s *server) Start(ctx context.Context) error {
 	return nil
 }
 
-func (s *server) Stop(_ context.Context) error {
-	s.GracefulStop()
-	return nil
+// Stop waits for in-flight RPCs to finish, when ctx is done first they are
+// cancelled.
+func (s *server) Stop(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.Server.Stop()
+		return ctx.Err()
+	}
 }
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"time"
 
 	proto "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/api"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
@@ -9,6 +10,20 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+var protoRoles = map[proto.Role]entity.Role{
+	proto.Role_ROLE_FREE_BUSY: entity.RoleFreeBusy,
+	proto.Role_ROLE_VIEWER:    entity.RoleViewer,
+	proto.Role_ROLE_EDITOR:    entity.RoleEditor,
+	proto.Role_ROLE_OWNER:     entity.RoleOwner,
+}
+
+var entityRoles = map[entity.Role]proto.Role{
+	entity.RoleFreeBusy: proto.Role_ROLE_FREE_BUSY,
+	entity.RoleViewer:   proto.Role_ROLE_VIEWER,
+	entity.RoleEditor:   proto.Role_ROLE_EDITOR,
+	entity.RoleOwner:    proto.Role_ROLE_OWNER,
+}
+
 type Service struct {
 	proto.UnimplementedEventServiceServer
 	app    Application
@@ -22,8 +37,8 @@ func NewService(app Application, logger logger.Logger) *Service {
 	}
 }
 
-func (s Service) CreateEvent(_ context.Context, request *proto.CreateRequest) (*proto.CreateResponse, error) {
-	id, err := s.app.CreateEvent(s.proto2entity(request.GetEventData()))
+func (s Service) CreateEvent(ctx context.Context, request *proto.CreateRequest) (*proto.CreateResponse, error) {
+	id, err := s.app.CreateEvent(ctx, s.proto2entity(request.GetEventData()))
 	if err != nil {
 		s.logger.Error(err.Error())
 
@@ -33,8 +48,9 @@ func (s Service) CreateEvent(_ context.Context, request *proto.CreateRequest) (*
 	return &proto.CreateResponse{EventId: &(proto.EventId{Id: id})}, nil
 }
 
-func (s Service) UpdateEvent(_ context.Context, request *proto.UpdateRequest) (*proto.UpdateResponse, error) {
+func (s Service) UpdateEvent(ctx context.Context, request *proto.UpdateRequest) (*proto.UpdateResponse, error) {
 	err := s.app.UpdateEvent(
+		ctx,
 		request.GetEventId().GetId(),
 		s.proto2entity(request.GetEventData()),
 	)
@@ -47,8 +63,8 @@ func (s Service) UpdateEvent(_ context.Context, request *proto.UpdateRequest) (*
 	return &proto.UpdateResponse{}, nil
 }
 
-func (s Service) DeleteEvent(_ context.Context, request *proto.DeleteRequest) (*proto.DeleteResponse, error) {
-	err := s.app.DeleteEvent(request.GetEventId().GetId())
+func (s Service) DeleteEvent(ctx context.Context, request *proto.DeleteRequest) (*proto.DeleteResponse, error) {
+	err := s.app.DeleteEvent(ctx, request.GetEventId().GetId())
 	if err != nil {
 		s.logger.Error(err.Error())
 
@@ -58,8 +74,8 @@ func (s Service) DeleteEvent(_ context.Context, request *proto.DeleteRequest) (*
 	return &proto.DeleteResponse{}, nil
 }
 
-func (s Service) GetWeekEvents(_ context.Context, date *proto.StartDate) (*proto.Events, error) {
-	events, err := s.app.GetWeekEvents(date.GetStartDate().AsTime())
+func (s Service) GetWeekEvents(ctx context.Context, date *proto.StartDate) (*proto.Events, error) {
+	events, err := s.app.GetWeekEvents(ctx, date.GetStartDate().AsTime(), date.GetTags()...)
 	if err != nil {
 		s.logger.Error(err.Error())
 
@@ -69,8 +85,8 @@ func (s Service) GetWeekEvents(_ context.Context, date *proto.StartDate) (*proto
 	return s.entities2Proto(events), nil
 }
 
-func (s Service) GetMonthEvents(_ context.Context, date *proto.StartDate) (*proto.Events, error) {
-	events, err := s.app.GetMonthEvents(date.GetStartDate().AsTime())
+func (s Service) GetMonthEvents(ctx context.Context, date *proto.StartDate) (*proto.Events, error) {
+	events, err := s.app.GetMonthEvents(ctx, date.GetStartDate().AsTime(), date.GetTags()...)
 	if err != nil {
 		s.logger.Error(err.Error())
 
@@ -80,8 +96,8 @@ func (s Service) GetMonthEvents(_ context.Context, date *proto.StartDate) (*prot
 	return s.entities2Proto(events), nil
 }
 
-func (s Service) GetDayEvents(_ context.Context, date *proto.StartDate) (*proto.Events, error) {
-	events, err := s.app.GetDayEvents(date.GetStartDate().AsTime())
+func (s Service) GetDayEvents(ctx context.Context, date *proto.StartDate) (*proto.Events, error) {
+	events, err := s.app.GetDayEvents(ctx, date.GetStartDate().AsTime(), date.GetTags()...)
 	if err != nil {
 		s.logger.Error(err.Error())
 
@@ -91,8 +107,27 @@ func (s Service) GetDayEvents(_ context.Context, date *proto.StartDate) (*proto.
 	return s.entities2Proto(events), nil
 }
 
-func (s Service) GetEvent(_ context.Context, req *proto.EventId) (*proto.Event, error) {
-	event, err := s.app.GetEvent(req.GetId())
+func (s Service) SearchEvents(ctx context.Context, req *proto.SearchRequest) (*proto.Events, error) {
+	var start, end time.Time
+	if req.GetStart() != nil {
+		start = req.GetStart().AsTime()
+	}
+	if req.GetEnd() != nil {
+		end = req.GetEnd().AsTime()
+	}
+
+	events, err := s.app.SearchEvents(ctx, req.GetQuery(), start, end, int(req.GetLimit()), req.GetTags()...)
+	if err != nil {
+		s.logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	return s.entities2Proto(events), nil
+}
+
+func (s Service) GetEvent(ctx context.Context, req *proto.EventId) (*proto.Event, error) {
+	event, err := s.app.GetEvent(ctx, req.GetId())
 	if err != nil {
 		s.logger.Error(err.Error())
 
@@ -102,6 +137,192 @@ func (s Service) GetEvent(_ context.Context, req *proto.EventId) (*proto.Event,
 	return s.entity2Proto(event), nil
 }
 
+func (s Service) SaveTag(ctx context.Context, tag *proto.Tag) (*proto.SaveTagResponse, error) {
+	err := s.app.SaveTag(ctx, entity.Tag{UserID: int(tag.GetUserId()), Name: tag.GetName(), Color: tag.GetColor()})
+	if err != nil {
+		s.logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	return &proto.SaveTagResponse{}, nil
+}
+
+func (s Service) DeleteTag(ctx context.Context, request *proto.DeleteTagRequest) (*proto.DeleteTagResponse, error) {
+	err := s.app.DeleteTag(ctx, int(request.GetUserId()), request.GetName())
+	if err != nil {
+		s.logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	return &proto.DeleteTagResponse{}, nil
+}
+
+func (s Service) GetTags(ctx context.Context, req *proto.UserId) (*proto.Tags, error) {
+	tags, err := s.app.GetTags(ctx, int(req.GetId()))
+	if err != nil {
+		s.logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	protoTags := make([]*proto.Tag, 0, len(*tags))
+	for _, tag := range *tags {
+		protoTags = append(protoTags, &proto.Tag{UserId: int64(tag.UserID), Name: tag.Name, Color: tag.Color})
+	}
+
+	return &proto.Tags{Tags: protoTags}, nil
+}
+
+func (s Service) SaveDigest(ctx context.Context, digest *proto.Digest) (*proto.SaveDigestResponse, error) {
+	err := s.app.SaveDigest(ctx, entity.Digest{
+		UserID:   int(digest.GetUserId()),
+		Time:     digest.GetTime(),
+		TimeZone: digest.GetTimeZone(),
+	})
+	if err != nil {
+		s.logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	return &proto.SaveDigestResponse{}, nil
+}
+
+func (s Service) DeleteDigest(ctx context.Context, req *proto.UserId) (*proto.DeleteDigestResponse, error) {
+	if err := s.app.DeleteDigest(ctx, int(req.GetId())); err != nil {
+		s.logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	return &proto.DeleteDigestResponse{}, nil
+}
+
+func (s Service) GetDigest(ctx context.Context, req *proto.UserId) (*proto.Digest, error) {
+	digest, err := s.app.GetDigest(ctx, int(req.GetId()))
+	if err != nil {
+		s.logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	return &proto.Digest{
+		UserId:   int64(digest.UserID),
+		Time:     digest.Time,
+		TimeZone: digest.TimeZone,
+		LastSent: digest.LastSent,
+	}, nil
+}
+
+func (s Service) CreateCalendar(
+	ctx context.Context,
+	calendar *proto.Calendar,
+) (*proto.CreateCalendarResponse, error) {
+	id, err := s.app.CreateCalendar(ctx, s.proto2calendar(calendar))
+	if err != nil {
+		s.logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	return &proto.CreateCalendarResponse{CalendarId: &proto.CalendarId{Id: id}}, nil
+}
+
+func (s Service) UpdateCalendar(
+	ctx context.Context,
+	calendar *proto.Calendar,
+) (*proto.UpdateCalendarResponse, error) {
+	err := s.app.UpdateCalendar(ctx, s.proto2calendar(calendar))
+	if err != nil {
+		s.logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	return &proto.UpdateCalendarResponse{}, nil
+}
+
+func (s Service) DeleteCalendar(ctx context.Context, req *proto.CalendarId) (*proto.DeleteCalendarResponse, error) {
+	err := s.app.DeleteCalendar(ctx, req.GetId())
+	if err != nil {
+		s.logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	return &proto.DeleteCalendarResponse{}, nil
+}
+
+func (s Service) GetCalendars(ctx context.Context, _ *proto.GetCalendarsRequest) (*proto.Calendars, error) {
+	calendars, err := s.app.GetCalendars(ctx)
+	if err != nil {
+		s.logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	protoCalendars := make([]*proto.Calendar, 0, len(*calendars))
+	for _, calendar := range *calendars {
+		protoCalendars = append(protoCalendars, &proto.Calendar{
+			CalendarId: &proto.CalendarId{Id: calendar.ID},
+			OwnerId:    int64(calendar.OwnerID),
+			Name:       calendar.Name,
+			CreatedAt:  timestamppb.New(calendar.CreatedAt),
+			UpdatedAt:  timestamppb.New(calendar.UpdatedAt),
+		})
+	}
+
+	return &proto.Calendars{Calendars: protoCalendars}, nil
+}
+
+func (s Service) ShareCalendar(ctx context.Context, share *proto.Share) (*proto.ShareResponse, error) {
+	err := s.app.ShareCalendar(ctx, entity.Share{
+		CalendarID: share.GetCalendarId().GetId(),
+		UserID:     int(share.GetUserId()),
+		Role:       protoRoles[share.GetRole()],
+	})
+	if err != nil {
+		s.logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	return &proto.ShareResponse{}, nil
+}
+
+func (s Service) UnshareCalendar(ctx context.Context, req *proto.UnshareRequest) (*proto.UnshareResponse, error) {
+	err := s.app.UnshareCalendar(ctx, req.GetCalendarId().GetId(), int(req.GetUserId()))
+	if err != nil {
+		s.logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	return &proto.UnshareResponse{}, nil
+}
+
+func (s Service) GetShares(ctx context.Context, req *proto.CalendarId) (*proto.Shares, error) {
+	shares, err := s.app.GetShares(ctx, req.GetId())
+	if err != nil {
+		s.logger.Error(err.Error())
+
+		return nil, err
+	}
+
+	protoShares := make([]*proto.Share, 0, len(*shares))
+	for _, share := range *shares {
+		protoShares = append(protoShares, &proto.Share{
+			CalendarId: &proto.CalendarId{Id: share.CalendarID},
+			UserId:     int64(share.UserID),
+			Role:       entityRoles[share.Role],
+		})
+	}
+
+	return &proto.Shares{Shares: protoShares}, nil
+}
+
 func (s Service) entities2Proto(entityEvents *entity.Events) *proto.Events {
 	protoEvents := make([]*proto.Event, 0, len(*entityEvents))
 
@@ -128,6 +349,8 @@ func (s Service) entity2Proto(entityEvent *entity.Event) *proto.Event {
 			CreatedAt:      timestamppb.New(entityEvent.CreatedAt),
 			UpdatedAt:      timestamppb.New(entityEvent.CreatedAt),
 			RemindSentTime: timestamppb.New(entityEvent.RemindSentTime),
+			Tags:           entityEvent.Tags,
+			CalendarId:     entityEvent.CalendarID,
 		},
 	})
 }
@@ -140,5 +363,14 @@ func (s Service) proto2entity(protoEvent *proto.EventData) entity.Event {
 		Duration:    protoEvent.Duration,
 		RemindTime:  protoEvent.RemindTime.AsTime(),
 		UserID:      int(protoEvent.UserId),
+		Tags:        protoEvent.Tags,
+		CalendarID:  protoEvent.CalendarId,
+	}
+}
+
+func (s Service) proto2calendar(protoCalendar *proto.Calendar) entity.Calendar {
+	return entity.Calendar{
+		ID:   protoCalendar.GetCalendarId().GetId(),
+		Name: protoCalendar.GetName(),
 	}
 }
@@ -0,0 +1,723 @@
+// Package caldav serves calendars of the requesting user over CalDAV (RFC
+// 4791), so desktop and mobile calendar clients can sync events both ways.
+//
+// Resources are laid out as
+//
+//	/dav/principals/{user}/                     the user
+//	/dav/calendars/{user}/                      calendars available to the user
+//	/dav/calendars/{user}/{calendar}/           a calendar
+//	/dav/calendars/{user}/{calendar}/{uid}.ics  an event
+//
+// The requesting user is passed in the x-user-id header, which is taken only
+// from loopback and trusted proxies: users must be authenticated by a proxy in
+// front of the service. Other requests are asked for basic authentication, so
+// clients prompt for credentials the proxy checks.
+//
+// Events are single VEVENTs, recurring ones are rejected. Tags are served as
+// categories but can't be changed over CalDAV.
+package caldav
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	eventapp "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/app/event"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/identity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+	grpcidentity "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/grpc/identity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/grpc/ratelimit"
+)
+
+const (
+	// Prefix is the path all CalDAV resources are served under.
+	Prefix = "/dav/"
+	// WellKnownPath is redirected to Prefix for clients discovering the
+	// service (RFC 6764).
+	WellKnownPath = "/.well-known/caldav"
+
+	MethodPropfind = "PROPFIND"
+	MethodReport   = "REPORT"
+
+	// maxBodySize limits request bodies.
+	maxBodySize = 1 << 20
+	contentType = "text/calendar; charset=utf-8; component=VEVENT"
+	realm       = `Basic realm="calendar"`
+	// rangeLookback is how long before a time range events are loaded to find
+	// the ones overlapping it, longer events starting earlier aren't matched.
+	rangeLookback = 7 * 24 * time.Hour
+)
+
+// Methods are the methods served under Prefix.
+var Methods = []string{
+	http.MethodOptions, http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, MethodPropfind, MethodReport,
+}
+
+var (
+	errNotFound         = errors.New("resource not found")
+	errMethod           = errors.New("method not allowed")
+	errInvalidRequest   = errors.New("invalid request")
+	errUIDMismatch      = errors.New("UID of the resource can't be changed")
+	errPrecondition     = errors.New("precondition failed")
+	errUnsupportedMedia = errors.New("content type must be text/calendar")
+	errUnsupportedQuery = errors.New("unsupported report")
+)
+
+// Application is the part of the event application served over CalDAV.
+type Application interface {
+	GetCalendars(ctx context.Context) (*entity.Calendars, error)
+	GetCalendarEvents(ctx context.Context, calendarID string, start, end time.Time) (*entity.Events, error)
+	GetEvent(ctx context.Context, id string) (*entity.Event, error)
+	GetEventByUID(ctx context.Context, calendarID, uid string) (*entity.Event, error)
+	CreateEvent(ctx context.Context, event entity.Event) (string, error)
+	UpdateEvent(ctx context.Context, id string, event entity.Event) error
+	DeleteEvent(ctx context.Context, id string) error
+}
+
+// Options tell which requests are trusted and how they are limited.
+type Options struct {
+	// TrustedProxies are networks of proxies authenticating users, x-user-id
+	// is taken only from them and loopback.
+	TrustedProxies []netip.Prefix
+	// Limit wraps serving of identified requests, for rate limiting. They
+	// aren't limited without it.
+	Limit func(http.Handler) http.Handler
+}
+
+type Handler struct {
+	app     Application
+	logger  logger.Logger
+	trusted []netip.Prefix
+	serve   http.Handler
+}
+
+func NewHandler(app Application, logger logger.Logger, options Options) *Handler {
+	h := &Handler{app: app, logger: logger, trusted: options.TrustedProxies}
+	h.serve = http.HandlerFunc(h.serveUser)
+	if options.Limit != nil {
+		h.serve = options.Limit(h.serve)
+	}
+
+	return h
+}
+
+type kind int
+
+const (
+	kindRoot kind = iota
+	kindPrincipal
+	kindHome
+	kindCalendar
+	kindObject
+)
+
+// resource is a parsed request path. name is the UID of an event, unescaped
+// and without the .ics extension.
+type resource struct {
+	kind     kind
+	user     int
+	calendar string
+	name     string
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == WellKnownPath {
+		http.Redirect(w, r, Prefix, http.StatusMovedPermanently)
+		return
+	}
+	if r.Method == http.MethodOptions {
+		w.Header().Set("DAV", "1, 3, calendar-access")
+		w.Header().Set("Allow", strings.Join(Methods, ", "))
+		return
+	}
+
+	userID, ok := h.requestUser(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", realm)
+		http.Error(w, eventapp.ErrNoUser.Error(), http.StatusUnauthorized)
+		return
+	}
+	h.serve.ServeHTTP(w, r.WithContext(identity.WithUserID(r.Context(), userID)))
+}
+
+// serveUser serves a request of the user in its context.
+func (h *Handler) serveUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, _ := identity.UserID(ctx)
+	res, err := parsePath(r.URL.EscapedPath())
+	if err == nil && res.kind != kindRoot && res.user != userID {
+		err = eventapp.ErrForbidden
+	}
+	if err == nil {
+		switch r.Method {
+		case MethodPropfind:
+			err = h.propfind(ctx, w, r, userID, res)
+		case MethodReport:
+			err = h.report(ctx, w, r, res)
+		case http.MethodGet, http.MethodHead:
+			err = h.get(ctx, w, res)
+		case http.MethodPut:
+			err = h.put(ctx, w, r, res)
+		case http.MethodDelete:
+			err = h.delete(ctx, w, r, res)
+		default:
+			err = errMethod
+		}
+	}
+	if err != nil {
+		h.fail(w, r, err)
+	}
+}
+
+// requestUser returns the user of the x-user-id header of a request from
+// loopback or a trusted proxy.
+func (h *Handler) requestUser(r *http.Request) (int, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil || !ratelimit.IsTrusted(host, h.trusted) {
+		return 0, false
+	}
+	userID, err := strconv.Atoi(r.Header.Get(grpcidentity.Header))
+	if err != nil {
+		return 0, false
+	}
+
+	return userID, true
+}
+
+func parsePath(path string) (resource, error) {
+	if !strings.HasPrefix(path+"/", Prefix) {
+		return resource{}, errNotFound
+	}
+	path = strings.Trim(strings.TrimPrefix(path+"/", Prefix), "/")
+	if path == "" {
+		return resource{kind: kindRoot}, nil
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil || unescaped == "" {
+			return resource{}, errNotFound
+		}
+		segments[i] = unescaped
+	}
+	if len(segments) < 2 {
+		return resource{}, errNotFound
+	}
+	userID, err := strconv.Atoi(segments[1])
+	if err != nil {
+		return resource{}, errNotFound
+	}
+
+	res := resource{user: userID}
+	switch {
+	case segments[0] == "principals" && len(segments) == 2:
+		res.kind = kindPrincipal
+	case segments[0] == "calendars" && len(segments) == 2:
+		res.kind = kindHome
+	case segments[0] == "calendars" && len(segments) == 3:
+		res.kind, res.calendar = kindCalendar, segments[2]
+	case segments[0] == "calendars" && len(segments) == 4 && strings.HasSuffix(segments[3], ".ics"):
+		res.kind, res.calendar, res.name = kindObject, segments[2], strings.TrimSuffix(segments[3], ".ics")
+	default:
+		return resource{}, errNotFound
+	}
+
+	return res, nil
+}
+
+func principalHref(userID int) string {
+	return Prefix + "principals/" + strconv.Itoa(userID) + "/"
+}
+
+func homeHref(userID int) string {
+	return Prefix + "calendars/" + strconv.Itoa(userID) + "/"
+}
+
+func calendarHref(userID int, calendarID string) string {
+	return homeHref(userID) + url.PathEscape(calendarID) + "/"
+}
+
+func objectHref(userID int, event *entity.Event) string {
+	return calendarHref(userID, event.CalendarID) + url.PathEscape(uidOf(event)) + ".ics"
+}
+
+func (h *Handler) propfind(ctx context.Context, w http.ResponseWriter, r *http.Request, userID int, res resource) error {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return err
+	}
+	var (
+		names     []xml.Name
+		namesOnly bool
+	)
+	if len(strings.TrimSpace(string(body))) > 0 {
+		var req propfindRequest
+		if err = xml.Unmarshal(body, &req); err != nil {
+			return errInvalidRequest
+		}
+		names, namesOnly = req.Prop.names(), req.PropName != nil
+	}
+	children := r.Header.Get("Depth") != "0"
+
+	var responses []response
+	add := func(href string, available []property) {
+		found, missing := selectProperties(available, names, namesOnly)
+		responses = append(responses, response{href: href, found: found, missing: missing})
+	}
+	switch res.kind {
+	case kindRoot:
+		add(Prefix, rootProperties(userID))
+		if children {
+			add(principalHref(userID), principalProperties(userID))
+			add(homeHref(userID), homeProperties(userID))
+		}
+	case kindPrincipal:
+		add(principalHref(userID), principalProperties(userID))
+	case kindHome:
+		add(homeHref(userID), homeProperties(userID))
+		if children {
+			calendars, err := h.app.GetCalendars(ctx)
+			if err != nil {
+				return err
+			}
+			// events are loaded only for the CTags of the calendars
+			loadEvents := !namesOnly && (names == nil || slices.Contains(names, propCTag))
+			for _, calendar := range *calendars {
+				events := &entity.Events{}
+				if loadEvents {
+					if events, err = h.app.GetCalendarEvents(ctx, calendar.ID, time.Time{}, time.Time{}); err != nil {
+						return err
+					}
+				}
+				add(calendarHref(userID, calendar.ID), calendarProperties(userID, calendar, *events))
+			}
+		}
+	case kindCalendar:
+		calendar, err := h.findCalendar(ctx, res.calendar)
+		if err != nil {
+			return err
+		}
+		events, err := h.app.GetCalendarEvents(ctx, calendar.ID, time.Time{}, time.Time{})
+		if err != nil {
+			return err
+		}
+		add(calendarHref(userID, calendar.ID), calendarProperties(userID, calendar, *events))
+		if children {
+			for _, event := range *events {
+				add(objectHref(userID, event), objectProperties(event))
+			}
+		}
+	case kindObject:
+		event, err := h.findEvent(ctx, res.calendar, res.name)
+		if err != nil {
+			return err
+		}
+		add(objectHref(userID, event), objectProperties(event))
+	}
+
+	writeMultistatus(w, responses)
+
+	return nil
+}
+
+func (h *Handler) report(ctx context.Context, w http.ResponseWriter, r *http.Request, res resource) error {
+	if res.kind != kindCalendar {
+		return errUnsupportedQuery
+	}
+	calendar, err := h.findCalendar(ctx, res.calendar)
+	if err != nil {
+		return err
+	}
+
+	var req reportRequest
+	if err = xml.NewDecoder(io.LimitReader(r.Body, maxBodySize)).Decode(&req); err != nil {
+		return errInvalidRequest
+	}
+	names := req.Prop.names()
+	if names == nil {
+		names = []xml.Name{propETag}
+	}
+
+	var responses []response
+	add := func(href string, event *entity.Event) {
+		found, missing := selectProperties(objectProperties(event), names, false)
+		responses = append(responses, response{href: href, found: found, missing: missing})
+	}
+	switch req.XMLName {
+	case xml.Name{Space: nsCalDAV, Local: "calendar-query"}:
+		var filter *timeRange
+		if req.Filter != nil {
+			filter = req.Filter.CompFilter.eventTimeRange()
+		}
+		start, end, err := filter.bounds()
+		if err != nil {
+			return err
+		}
+		from := start
+		if !from.IsZero() {
+			from = from.Add(-rangeLookback)
+		}
+		events, err := h.app.GetCalendarEvents(ctx, calendar.ID, from, end)
+		if err != nil {
+			return err
+		}
+		for _, event := range *events {
+			if overlaps(event, start, end) {
+				add(objectHref(res.user, event), event)
+			}
+		}
+	case xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}:
+		for _, href := range req.Hrefs {
+			event, err := h.eventOfHref(ctx, href, res)
+			if errors.Is(err, errNotFound) || errors.Is(err, entity.ErrEventNotFound) {
+				responses = append(responses, response{href: href, status: http.StatusNotFound})
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			add(href, event)
+		}
+	default:
+		return errUnsupportedQuery
+	}
+
+	writeMultistatus(w, responses)
+
+	return nil
+}
+
+// eventOfHref returns the event of a multiget href, which must be in the
+// calendar of the request.
+func (h *Handler) eventOfHref(ctx context.Context, href string, calendar resource) (*entity.Event, error) {
+	parsed, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return nil, errNotFound
+	}
+	res, err := parsePath(parsed.EscapedPath())
+	if err != nil || res.kind != kindObject || res.user != calendar.user || res.calendar != calendar.calendar {
+		return nil, errNotFound
+	}
+
+	return h.findEvent(ctx, res.calendar, res.name)
+}
+
+// bounds returns the start and end of the time range, zero ones are open.
+func (r *timeRange) bounds() (time.Time, time.Time, error) {
+	var start, end time.Time
+	if r == nil {
+		return start, end, nil
+	}
+
+	var err error
+	if r.Start != "" {
+		if start, err = time.Parse(dateTimeUTC, r.Start); err != nil {
+			return start, end, errInvalidRequest
+		}
+	}
+	if r.End != "" {
+		if end, err = time.Parse(dateTimeUTC, r.End); err != nil {
+			return start, end, errInvalidRequest
+		}
+	}
+
+	return start, end, nil
+}
+
+// overlaps reports whether the event overlaps [start, end), zero times leave
+// it open. Events without a duration overlap at their start.
+func overlaps(event *entity.Event, start, end time.Time) bool {
+	duration, err := parseEventDuration(event.Duration)
+	if err != nil {
+		duration = 0
+	}
+	eventStart, eventEnd := event.DateTime, event.DateTime.Add(duration)
+
+	if !start.IsZero() && !eventEnd.After(start) && !(duration == 0 && eventStart.Equal(start)) {
+		return false
+	}
+
+	return end.IsZero() || eventStart.Before(end)
+}
+
+func (h *Handler) get(ctx context.Context, w http.ResponseWriter, res resource) error {
+	if res.kind != kindObject {
+		return errMethod
+	}
+	event, err := h.findEvent(ctx, res.calendar, res.name)
+	if err != nil {
+		return err
+	}
+
+	data := encodeEvent(event)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("ETag", etagOf(event))
+	_, _ = w.Write(data)
+
+	return nil
+}
+
+// put creates or replaces the event. The stored event may differ from the
+// body, e.g. by dropped properties, so no ETag is returned and clients fetch
+// it again. Events are served under their UID: an event put under another
+// name is found by its UID and its Location is returned when it is created.
+func (h *Handler) put(ctx context.Context, w http.ResponseWriter, r *http.Request, res resource) error {
+	if res.kind != kindObject {
+		return errMethod
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil ||
+		mediaType != "text/calendar" {
+		return errUnsupportedMedia
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return err
+	}
+	event, err := decodeEvent(body)
+	if err != nil {
+		return err
+	}
+	if _, err = h.findCalendar(ctx, res.calendar); err != nil {
+		return err
+	}
+
+	existing, err := h.findEvent(ctx, res.calendar, res.name)
+	if errors.Is(err, entity.ErrEventNotFound) && event.UID != res.name {
+		existing, err = h.findEvent(ctx, res.calendar, event.UID)
+	}
+	if err != nil && !errors.Is(err, entity.ErrEventNotFound) {
+		return err
+	}
+	if existing != nil && uidOf(existing) != event.UID {
+		return errUIDMismatch
+	}
+	if !preconditionsMet(r, existing) {
+		return errPrecondition
+	}
+
+	event.CalendarID = res.calendar
+	if existing != nil {
+		event.Tags = existing.Tags
+		if err = h.app.UpdateEvent(ctx, existing.ID, event); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+		return nil
+	}
+
+	if event.ID, err = h.app.CreateEvent(ctx, event); err != nil {
+		return err
+	}
+	if event.UID != res.name {
+		w.Header().Set("Location", objectHref(res.user, &event))
+	}
+	w.WriteHeader(http.StatusCreated)
+
+	return nil
+}
+
+func (h *Handler) delete(ctx context.Context, w http.ResponseWriter, r *http.Request, res resource) error {
+	if res.kind != kindObject {
+		return errMethod
+	}
+	event, err := h.findEvent(ctx, res.calendar, res.name)
+	if err != nil {
+		return err
+	}
+	if !preconditionsMet(r, event) {
+		return errPrecondition
+	}
+	if err = h.app.DeleteEvent(ctx, event.ID); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// preconditionsMet checks If-Match and If-None-Match of the request against
+// the current event, nil if there is none.
+func preconditionsMet(r *http.Request, event *entity.Event) bool {
+	if match := r.Header.Get("If-Match"); match != "" {
+		if event == nil || (strings.TrimSpace(match) != "*" && !etagListed(match, etagOf(event))) {
+			return false
+		}
+	}
+	if noneMatch := r.Header.Get("If-None-Match"); noneMatch != "" && event != nil {
+		if strings.TrimSpace(noneMatch) == "*" || etagListed(noneMatch, etagOf(event)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func etagListed(list, etag string) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findCalendar returns the calendar if it is available to the requesting
+// user.
+func (h *Handler) findCalendar(ctx context.Context, id string) (*entity.Calendar, error) {
+	calendars, err := h.app.GetCalendars(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, calendar := range *calendars {
+		if calendar.ID == id {
+			return calendar, nil
+		}
+	}
+
+	return nil, entity.ErrCalendarNotFound
+}
+
+// findEvent returns the event of the calendar by resource name, its UID or
+// the ID of events created without one.
+func (h *Handler) findEvent(ctx context.Context, calendarID, name string) (*entity.Event, error) {
+	event, err := h.app.GetEventByUID(ctx, calendarID, name)
+	if !errors.Is(err, entity.ErrEventNotFound) {
+		return event, err
+	}
+	if uuid.Validate(name) != nil {
+		return nil, entity.ErrEventNotFound
+	}
+
+	event, err = h.app.GetEvent(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if event.UID != "" || event.CalendarID != calendarID {
+		return nil, entity.ErrEventNotFound
+	}
+
+	return event, nil
+}
+
+func rootProperties(userID int) []property {
+	return []property{
+		{propResourceType, "<d:collection/>"},
+		{propPrincipal, hrefElement(principalHref(userID))},
+		{propHomeSet, hrefElement(homeHref(userID))},
+	}
+}
+
+func principalProperties(userID int) []property {
+	return []property{
+		{propResourceType, "<d:principal/>"},
+		{propDisplayName, escape("User " + strconv.Itoa(userID))},
+		{propPrincipal, hrefElement(principalHref(userID))},
+		{propPrincipalURL, hrefElement(principalHref(userID))},
+		{propHomeSet, hrefElement(homeHref(userID))},
+	}
+}
+
+func homeProperties(userID int) []property {
+	return []property{
+		{propResourceType, "<d:collection/>"},
+		{propDisplayName, "Calendars"},
+		{propPrincipal, hrefElement(principalHref(userID))},
+	}
+}
+
+func calendarProperties(userID int, calendar *entity.Calendar, events entity.Events) []property {
+	return []property{
+		{propResourceType, "<d:collection/><c:calendar/>"},
+		{propDisplayName, escape(calendar.Name)},
+		{propPrincipal, hrefElement(principalHref(userID))},
+		{propComponentSet, `<c:comp name="VEVENT"/>`},
+		{propSupportedReport, "<d:supported-report><d:report><c:calendar-query/></d:report></d:supported-report>" +
+			"<d:supported-report><d:report><c:calendar-multiget/></d:report></d:supported-report>"},
+		{propCTag, ctagOf(userID, events)},
+	}
+}
+
+func objectProperties(event *entity.Event) []property {
+	data := encodeEvent(event)
+
+	return []property{
+		{propResourceType, ""},
+		{propETag, escape(etagOf(event))},
+		{propContentType, contentType},
+		{propContentLength, strconv.Itoa(len(data))},
+		{propCalendarData, escape(string(data))},
+	}
+}
+
+// ctagOf returns the collection tag of a calendar, which changes with any of
+// its events.
+func ctagOf(userID int, events entity.Events) string {
+	tags := make([]string, 0, len(events))
+	for _, event := range events {
+		tags = append(tags, objectHref(userID, event)+" "+etagOf(event))
+	}
+	sort.Strings(tags)
+
+	sum := sha256.Sum256([]byte(strings.Join(tags, "\n")))
+
+	return hex.EncodeToString(sum[:16])
+}
+
+func (h *Handler) fail(w http.ResponseWriter, r *http.Request, err error) {
+	var quotaErr entity.QuotaError
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, eventapp.ErrNoUser):
+		w.Header().Set("WWW-Authenticate", realm)
+		code = http.StatusUnauthorized
+	case errors.Is(err, eventapp.ErrForbidden), errors.Is(err, ErrRecurrence), errors.Is(err, errUnsupportedQuery):
+		code = http.StatusForbidden
+	case errors.Is(err, errNotFound), errors.Is(err, eventapp.ErrNotFound),
+		errors.Is(err, entity.ErrEventNotFound), errors.Is(err, entity.ErrCalendarNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, errMethod):
+		w.Header().Set("Allow", strings.Join(Methods, ", "))
+		code = http.StatusMethodNotAllowed
+	case errors.Is(err, eventapp.ErrDateBusy), errors.Is(err, eventapp.ErrEventIsActive),
+		errors.Is(err, entity.ErrEventUIDExists):
+		code = http.StatusConflict
+	case errors.Is(err, errPrecondition):
+		code = http.StatusPreconditionFailed
+	case errors.Is(err, errUnsupportedMedia):
+		code = http.StatusUnsupportedMediaType
+	case errors.Is(err, ErrInvalidCalendarData), errors.Is(err, errInvalidRequest), errors.Is(err, errUIDMismatch),
+		errors.Is(err, eventapp.ErrUnknownTag), errors.Is(err, eventapp.ErrInvalidTag):
+		code = http.StatusBadRequest
+	case errors.As(err, &quotaErr):
+		if quotaErr.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(quotaErr.RetryAfter.Round(time.Second).Seconds())))
+		}
+		code = http.StatusInsufficientStorage
+	default:
+		h.logger.Error("caldav %s %s: %s", r.Method, r.URL.Path, err)
+		http.Error(w, http.StatusText(code), code)
+
+		return
+	}
+
+	http.Error(w, err.Error(), code)
+}
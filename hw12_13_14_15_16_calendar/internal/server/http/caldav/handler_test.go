@@ -0,0 +1,354 @@
+package caldav
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/app"
+	eventapp "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/app/event"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/identity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/grpc/ratelimit"
+	memorystorage "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+const eventICS = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:test\r\nBEGIN:VEVENT\r\nUID:standup@example.com\r\n" +
+	"DTSTAMP:20261019T100000Z\r\nDTSTART;TZID=Europe/Berlin:20261020T100000\r\nDURATION:PT30M\r\n" +
+	"SUMMARY:Standup\r\nBEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER:-PT10M\r\nEND:VALARM\r\n" +
+	"END:VEVENT\r\nEND:VCALENDAR\r\n"
+
+// proxyAddr is the address of the proxy identifying users.
+const proxyAddr = "127.0.0.1:40000"
+
+type fixture struct {
+	app      *app.App
+	handler  *Handler
+	calendar string
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	a := app.New(logger.New(logger.Debug, io.Discard), memorystorage.New())
+	calendarID, err := a.CreateCalendar(identity.WithUserID(context.Background(), 1), entity.Calendar{Name: "Work"})
+	require.NoError(t, err)
+
+	return &fixture{app: a, handler: NewHandler(a, a.Logger, Options{}), calendar: calendarID}
+}
+
+func (f *fixture) do(method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.RemoteAddr = proxyAddr
+	request.Header.Set("x-user-id", "1")
+	for i := 0; i+1 < len(headers); i += 2 {
+		request.Header.Set(headers[i], headers[i+1])
+	}
+	recorder := httptest.NewRecorder()
+	f.handler.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func (f *fixture) calendarPath() string {
+	return Prefix + "calendars/1/" + f.calendar + "/"
+}
+
+func (f *fixture) put(t *testing.T, name, body string, headers ...string) int {
+	t.Helper()
+
+	headers = append(headers, "Content-Type", "text/calendar; charset=utf-8")
+
+	return f.do(http.MethodPut, f.calendarPath()+name, body, headers...).Code
+}
+
+func TestHandler_Discovery(t *testing.T) {
+	f := newFixture(t)
+
+	recorder := f.do(http.MethodGet, WellKnownPath, "")
+	require.Equal(t, http.StatusMovedPermanently, recorder.Code)
+	require.Equal(t, Prefix, recorder.Header().Get("Location"))
+
+	recorder = f.do(http.MethodOptions, Prefix, "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Header().Get("DAV"), "calendar-access")
+
+	recorder = f.do(MethodPropfind, Prefix, `<?xml version="1.0"?><d:propfind xmlns:d="DAV:"><d:prop>`+
+		`<d:current-user-principal/><d:owner/></d:prop></d:propfind>`, "Depth", "0")
+	require.Equal(t, http.StatusMultiStatus, recorder.Code)
+	body := recorder.Body.String()
+	require.Contains(t, body, "<d:current-user-principal><d:href>/dav/principals/1/</d:href>")
+	require.Contains(t, body, "<d:owner/></d:prop><d:status>HTTP/1.1 404 Not Found")
+	require.NotContains(t, body, "calendar-home-set")
+
+	recorder = f.do(MethodPropfind, Prefix+"principals/1/", "", "Depth", "0")
+	require.Equal(t, http.StatusMultiStatus, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "<c:calendar-home-set><d:href>/dav/calendars/1/</d:href>")
+
+	recorder = f.do(MethodPropfind, Prefix+"calendars/1/", "", "Depth", "1")
+	require.Equal(t, http.StatusMultiStatus, recorder.Code)
+	body = recorder.Body.String()
+	require.Contains(t, body, "<d:href>"+f.calendarPath()+"</d:href>")
+	require.Contains(t, body, "<d:displayname>Work</d:displayname>")
+	require.Contains(t, body, "<c:calendar/>")
+	require.Contains(t, body, "<cs:getctag>")
+
+	// events are loaded only for CTags
+	app := &periodApp{Application: f.app}
+	f.handler = NewHandler(app, f.app.Logger, Options{})
+	recorder = f.do(MethodPropfind, Prefix+"calendars/1/", `<?xml version="1.0"?>`+
+		`<d:propfind xmlns:d="DAV:"><d:prop><d:displayname/></d:prop></d:propfind>`, "Depth", "1")
+	require.Equal(t, http.StatusMultiStatus, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "<d:displayname>Work</d:displayname>")
+	require.Empty(t, app.periods)
+}
+
+func TestHandler_Identity(t *testing.T) {
+	f := newFixture(t)
+
+	request := httptest.NewRequest(MethodPropfind, Prefix, nil)
+	request.RemoteAddr = proxyAddr
+	recorder := httptest.NewRecorder()
+	f.handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	require.NotEmpty(t, recorder.Header().Get("WWW-Authenticate"))
+
+	// credentials are checked by the proxy, basic authentication isn't
+	// trusted
+	request = httptest.NewRequest(MethodPropfind, Prefix+"principals/1/", nil)
+	request.RemoteAddr = proxyAddr
+	request.SetBasicAuth("1", "any")
+	recorder = httptest.NewRecorder()
+	f.handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	// x-user-id is taken only from trusted proxies
+	proxies, err := ratelimit.ParseProxies([]string{"10.1.0.0/16"})
+	require.NoError(t, err)
+	f.handler = NewHandler(f.app, f.app.Logger, Options{TrustedProxies: proxies})
+	for addr, code := range map[string]int{
+		"192.0.2.1:40000": http.StatusUnauthorized,
+		"10.1.2.3:40000":  http.StatusMultiStatus,
+		proxyAddr:         http.StatusMultiStatus,
+	} {
+		request = httptest.NewRequest(MethodPropfind, Prefix+"principals/1/", nil)
+		request.RemoteAddr = addr
+		request.Header.Set("x-user-id", "1")
+		recorder = httptest.NewRecorder()
+		f.handler.ServeHTTP(recorder, request)
+		require.Equal(t, code, recorder.Code, addr)
+	}
+
+	// other users' resources aren't served, calendars are served only to
+	// users they are available to
+	recorder = f.do(MethodPropfind, Prefix+"calendars/2/", "", "x-user-id", "1")
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+	recorder = f.do(MethodPropfind, Prefix+"calendars/2/"+f.calendar+"/", "", "x-user-id", "2")
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestHandler_RateLimit(t *testing.T) {
+	f := newFixture(t)
+	limiter := ratelimit.NewLimiter(ratelimit.Options{UserRate: 0.001, UserBurst: 1})
+	f.handler = NewHandler(f.app, f.app.Logger, Options{Limit: limiter.Handler})
+
+	require.Equal(t, http.StatusMultiStatus, f.do(MethodPropfind, Prefix+"principals/1/", "").Code)
+	recorder := f.do(MethodPropfind, Prefix+"principals/1/", "")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+
+	// users are limited apart
+	recorder = f.do(MethodPropfind, Prefix+"principals/2/", "", "x-user-id", "2")
+	require.Equal(t, http.StatusMultiStatus, recorder.Code)
+}
+
+func TestHandler_Event(t *testing.T) {
+	f := newFixture(t)
+	name := "standup%40example.com.ics"
+
+	require.Equal(t, http.StatusCreated, f.put(t, name, eventICS, "If-None-Match", "*"))
+	require.Equal(t, http.StatusPreconditionFailed, f.put(t, name, eventICS, "If-None-Match", "*"))
+	// events put under another name are found by their UID
+	require.Equal(t, http.StatusPreconditionFailed, f.put(t, "other.ics", eventICS, "If-None-Match", "*"))
+	changedUID := strings.Replace(eventICS, "UID:standup@", "UID:other@", 1)
+	require.Equal(t, http.StatusBadRequest, f.put(t, name, changedUID))
+
+	ctx := identity.WithUserID(context.Background(), 1)
+	event, err := f.app.GetEventByUID(ctx, f.calendar, "standup@example.com")
+	require.NoError(t, err)
+	require.Equal(t, "Standup", event.Title)
+	require.Equal(t, time.Date(2026, 10, 20, 8, 0, 0, 0, time.UTC), event.DateTime)
+	require.Equal(t, "00:30:00", event.Duration)
+	require.Equal(t, time.Date(2026, 10, 20, 7, 50, 0, 0, time.UTC), event.RemindTime)
+
+	recorder := f.do(http.MethodGet, f.calendarPath()+name, "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, contentType, recorder.Header().Get("Content-Type"))
+	etag := recorder.Header().Get("ETag")
+	require.Equal(t, etagOf(event), etag)
+	require.Contains(t, recorder.Body.String(), "SUMMARY:Standup\r\n")
+
+	updated := strings.Replace(eventICS, "SUMMARY:Standup", "SUMMARY:Daily", 1)
+	require.Equal(t, http.StatusPreconditionFailed, f.put(t, name, updated, "If-Match", `"stale"`))
+	require.Equal(t, http.StatusNoContent, f.put(t, name, updated, "If-Match", etag))
+	event, err = f.app.GetEventByUID(ctx, f.calendar, "standup@example.com")
+	require.NoError(t, err)
+	require.Equal(t, "Daily", event.Title)
+
+	recurring := strings.Replace(eventICS, "SUMMARY:", "RRULE:FREQ=DAILY\r\nSUMMARY:", 1)
+	require.Equal(t, http.StatusForbidden, f.put(t, name, recurring))
+
+	recorder = f.do(http.MethodDelete, f.calendarPath()+name, "", "If-Match", etag)
+	require.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+	recorder = f.do(http.MethodDelete, f.calendarPath()+name, "")
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+	recorder = f.do(http.MethodGet, f.calendarPath()+name, "")
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestHandler_EventName(t *testing.T) {
+	f := newFixture(t)
+
+	// clients may name resources apart from their UID
+	recorder := f.do(http.MethodPut, f.calendarPath()+"client-name.ics", eventICS,
+		"Content-Type", "text/calendar", "If-None-Match", "*")
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	location := f.calendarPath() + "standup@example.com.ics"
+	require.Equal(t, location, recorder.Header().Get("Location"))
+	require.Equal(t, http.StatusOK, f.do(http.MethodGet, location, "").Code)
+
+	updated := strings.Replace(eventICS, "SUMMARY:Standup", "SUMMARY:Daily", 1)
+	require.Equal(t, http.StatusNoContent, f.put(t, "client-name.ics", updated))
+	event, err := f.app.GetEventByUID(identity.WithUserID(context.Background(), 1), f.calendar, "standup@example.com")
+	require.NoError(t, err)
+	require.Equal(t, "Daily", event.Title)
+
+	// concurrent creates of a UID conflict
+	recorder = httptest.NewRecorder()
+	f.handler.fail(recorder, httptest.NewRequest(http.MethodPut, location, nil), entity.ErrEventUIDExists)
+	require.Equal(t, http.StatusConflict, recorder.Code)
+}
+
+func TestHandler_SharedEventTags(t *testing.T) {
+	f := newFixture(t)
+	owner := identity.WithUserID(context.Background(), 1)
+	require.NoError(t, f.app.SaveTag(owner, entity.Tag{UserID: 1, Name: "work"}))
+	require.NoError(t, f.app.ShareCalendar(owner, entity.Share{CalendarID: f.calendar, UserID: 2, Role: entity.RoleEditor}))
+	require.Equal(t, http.StatusCreated, f.put(t, "standup%40example.com.ics", eventICS))
+	event, err := f.app.GetEventByUID(owner, f.calendar, "standup@example.com")
+	require.NoError(t, err)
+	event.Tags = []string{"work"}
+	require.NoError(t, f.app.UpdateEvent(owner, event.ID, *event))
+
+	// editors keep the owner's tags
+	path := Prefix + "calendars/2/" + f.calendar + "/standup%40example.com.ics"
+	updated := strings.Replace(eventICS, "SUMMARY:Standup", "SUMMARY:Daily", 1)
+	recorder := f.do(http.MethodPut, path, updated, "x-user-id", "2", "Content-Type", "text/calendar")
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+	event, err = f.app.GetEventByUID(owner, f.calendar, "standup@example.com")
+	require.NoError(t, err)
+	require.Equal(t, "Daily", event.Title)
+	require.Equal(t, []string{"work"}, event.Tags)
+
+	recorder = httptest.NewRecorder()
+	f.handler.fail(recorder, httptest.NewRequest(http.MethodPut, path, nil), eventapp.ErrUnknownTag)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestHandler_EventWithoutUID(t *testing.T) {
+	f := newFixture(t)
+	ctx := identity.WithUserID(context.Background(), 1)
+	id, err := f.app.CreateEvent(ctx, entity.Event{
+		CalendarID: f.calendar,
+		Title:      "Created over the API",
+		DateTime:   time.Date(2026, 10, 22, 9, 0, 0, 0, time.UTC),
+		Duration:   "01:00:00",
+	})
+	require.NoError(t, err)
+
+	recorder := f.do(MethodPropfind, f.calendarPath(), "", "Depth", "1")
+	require.Contains(t, recorder.Body.String(), "<d:href>"+f.calendarPath()+id+".ics</d:href>")
+
+	recorder = f.do(http.MethodGet, f.calendarPath()+id+".ics", "")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	data := strings.Replace(recorder.Body.String(), "SUMMARY:Created", "SUMMARY:Edited", 1)
+	require.Equal(t, http.StatusNoContent, f.put(t, id+".ics", data))
+
+	event, err := f.app.GetEvent(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "Edited over the API", event.Title)
+}
+
+// periodApp records the periods of calendar events requested from the
+// application.
+type periodApp struct {
+	Application
+	periods [][2]time.Time
+}
+
+func (a *periodApp) GetCalendarEvents(
+	ctx context.Context,
+	calendarID string,
+	start time.Time,
+	end time.Time,
+) (*entity.Events, error) {
+	a.periods = append(a.periods, [2]time.Time{start, end})
+
+	return a.Application.GetCalendarEvents(ctx, calendarID, start, end)
+}
+
+func TestHandler_Report(t *testing.T) {
+	f := newFixture(t)
+	app := &periodApp{Application: f.app}
+	f.handler = NewHandler(app, f.app.Logger, Options{})
+	require.Equal(t, http.StatusCreated, f.put(t, "standup%40example.com.ics", eventICS))
+	next := strings.NewReplacer("standup@", "retro@", "20261020T", "20261027T").Replace(eventICS)
+	require.Equal(t, http.StatusCreated, f.put(t, "retro%40example.com.ics", next))
+
+	query := func(start, end string) string {
+		return `<?xml version="1.0"?><c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">` +
+			`<d:prop><d:getetag/></d:prop><c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VEVENT">` +
+			`<c:time-range start="` + start + `" end="` + end + `"/></c:comp-filter></c:comp-filter></c:filter>` +
+			`</c:calendar-query>`
+	}
+	recorder := f.do(MethodReport, f.calendarPath(), query("20261020T000000Z", "20261021T000000Z"))
+	require.Equal(t, http.StatusMultiStatus, recorder.Code)
+	body := recorder.Body.String()
+	require.Contains(t, body, "standup@example.com.ics")
+	require.NotContains(t, body, "retro@example.com.ics")
+	require.NotContains(t, body, "calendar-data")
+	rangeStart := time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, [][2]time.Time{{rangeStart.Add(-rangeLookback), rangeStart.AddDate(0, 0, 1)}}, app.periods)
+
+	// events started before the range overlap it
+	recorder = f.do(MethodReport, f.calendarPath(), query("20261020T081500Z", "20261020T090000Z"))
+	require.Contains(t, recorder.Body.String(), "standup@example.com.ics")
+
+	// events ending at the start of the range don't overlap it
+	recorder = f.do(MethodReport, f.calendarPath(), query("20261020T083000Z", "20261028T000000Z"))
+	body = recorder.Body.String()
+	require.NotContains(t, body, "standup@example.com.ics")
+	require.Contains(t, body, "retro@example.com.ics")
+
+	recorder = f.do(MethodReport, f.calendarPath(), `<?xml version="1.0"?>`+
+		`<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`+
+		`<d:prop><d:getetag/><c:calendar-data/></d:prop>`+
+		`<d:href>`+f.calendarPath()+`retro%40example.com.ics</d:href>`+
+		`<d:href>`+f.calendarPath()+`missing.ics</d:href>`+
+		`</c:calendar-multiget>`)
+	require.Equal(t, http.StatusMultiStatus, recorder.Code)
+	body = recorder.Body.String()
+	require.Contains(t, body, "<c:calendar-data>BEGIN:VCALENDAR")
+	require.Contains(t, body, "UID:retro@example.com")
+	require.Contains(t, body, "missing.ics</d:href><d:status>HTTP/1.1 404 Not Found</d:status>")
+
+	recorder = f.do(MethodReport, f.calendarPath(), `<?xml version="1.0"?>`+
+		`<c:free-busy-query xmlns:c="urn:ietf:params:xml:ns:caldav"/>`)
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+}
@@ -0,0 +1,493 @@
+package caldav
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+)
+
+var (
+	ErrInvalidCalendarData = errors.New("invalid iCalendar data")
+	ErrRecurrence          = errors.New("recurring events are not supported")
+)
+
+const (
+	prodID       = "-//otus_golang_hw//calendar//EN"
+	dateTimeUTC  = "20060102T150405Z"
+	dateTimeTZ   = "20060102T150405"
+	dateOnly     = "20060102"
+	maxLineOctet = 75
+)
+
+// uidOf returns the iCalendar UID of the event, events created without one
+// are identified by ID.
+func uidOf(event *entity.Event) string {
+	if event.UID != "" {
+		return event.UID
+	}
+
+	return event.ID
+}
+
+// encodeEvent returns the event as an iCalendar object with a single VEVENT.
+// The reminder is an absolute VALARM trigger, tags are categories.
+func encodeEvent(event *entity.Event) []byte {
+	var w icalWriter
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", prodID)
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", escapeText(uidOf(event)))
+
+	// DTSTAMP must be set, times of events restricted to free/busy are
+	// unknown, so the start is used to keep ETags stable
+	stamp := event.DateTime
+	switch {
+	case !event.UpdatedAt.IsZero():
+		stamp = event.UpdatedAt
+	case !event.CreatedAt.IsZero():
+		stamp = event.CreatedAt
+	}
+	w.line("DTSTAMP", stamp.UTC().Format(dateTimeUTC))
+	if !event.CreatedAt.IsZero() {
+		w.line("CREATED", event.CreatedAt.UTC().Format(dateTimeUTC))
+	}
+	if !event.UpdatedAt.IsZero() {
+		w.line("LAST-MODIFIED", event.UpdatedAt.UTC().Format(dateTimeUTC))
+	}
+
+	w.line("DTSTART", event.DateTime.UTC().Format(dateTimeUTC))
+	if duration, err := parseEventDuration(event.Duration); err == nil && duration > 0 {
+		w.line("DURATION", formatDuration(duration))
+	}
+	if event.Title != "" {
+		w.line("SUMMARY", escapeText(event.Title))
+	} else {
+		w.line("SUMMARY", "Busy")
+		w.line("CLASS", "CONFIDENTIAL")
+	}
+	if event.Description != "" {
+		w.line("DESCRIPTION", escapeText(event.Description))
+	}
+	if len(event.Tags) > 0 {
+		categories := make([]string, 0, len(event.Tags))
+		for _, tag := range event.Tags {
+			categories = append(categories, escapeText(tag))
+		}
+		w.line("CATEGORIES", strings.Join(categories, ","))
+	}
+
+	if !event.RemindTime.IsZero() {
+		w.line("BEGIN", "VALARM")
+		w.line("ACTION", "DISPLAY")
+		w.line("DESCRIPTION", escapeText(event.Title))
+		w.line("TRIGGER;VALUE=DATE-TIME", event.RemindTime.UTC().Format(dateTimeUTC))
+		w.line("END", "VALARM")
+	}
+
+	w.line("END", "VEVENT")
+	w.line("END", "VCALENDAR")
+
+	return w.buf.Bytes()
+}
+
+// etagOf returns the strong ETag of the event's iCalendar object.
+func etagOf(event *entity.Event) string {
+	sum := sha256.Sum256(encodeEvent(event))
+
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+type icalWriter struct {
+	buf bytes.Buffer
+}
+
+// line writes a content line folded at 75 octets, without splitting UTF-8
+// sequences.
+func (w *icalWriter) line(name, value string) {
+	line := name + ":" + value
+	for first := true; len(line) > 0; first = false {
+		limit := maxLineOctet
+		if !first {
+			limit--
+			w.buf.WriteByte(' ')
+		}
+		if len(line) <= limit {
+			w.buf.WriteString(line)
+			break
+		}
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.buf.WriteString(line[:cut])
+		w.buf.WriteString("\r\n")
+		line = line[cut:]
+	}
+	w.buf.WriteString("\r\n")
+}
+
+func escapeText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(text)
+}
+
+func unescapeText(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i == len(text)-1 {
+			b.WriteByte(text[i])
+			continue
+		}
+		i++
+		switch text[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(text[i])
+		}
+	}
+
+	return b.String()
+}
+
+// contentLine is a property of an iCalendar component.
+type contentLine struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// parseLines unfolds and parses the content lines of an iCalendar object.
+func parseLines(data []byte) ([]contentLine, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\n ", "")
+	text = strings.ReplaceAll(text, "\n\t", "")
+
+	var lines []contentLine
+	for _, raw := range strings.Split(text, "\n") {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		line, err := parseLine(raw)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// parseLine splits a content line into the name, parameters and value.
+// Parameter values may be quoted and contain ':' and ';' then.
+func parseLine(raw string) (contentLine, error) {
+	line := contentLine{params: make(map[string]string)}
+
+	quoted := false
+	valueAt := -1
+	for i, r := range raw {
+		if r == '"' {
+			quoted = !quoted
+		}
+		if r == ':' && !quoted {
+			valueAt = i
+			break
+		}
+	}
+	if valueAt < 0 {
+		return line, fmt.Errorf("%w: line without a value: %q", ErrInvalidCalendarData, raw)
+	}
+	line.value = raw[valueAt+1:]
+
+	parts := splitUnquoted(raw[:valueAt], ';')
+	line.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		name, value, _ := strings.Cut(param, "=")
+		line.params[strings.ToUpper(name)] = strings.Trim(value, `"`)
+	}
+
+	return line, nil
+}
+
+func splitUnquoted(s string, sep rune) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// decodeEvent returns the event of an iCalendar object with a single
+// non-recurring VEVENT. Times with a TZID must be in IANA time zones, floating
+// times are UTC. Date-only events start at UTC midnight.
+func decodeEvent(data []byte) (entity.Event, error) {
+	lines, err := parseLines(data)
+	if err != nil {
+		return entity.Event{}, err
+	}
+
+	var (
+		event    entity.Event
+		path     []string
+		events   int
+		start    *contentLine
+		end      *contentLine
+		duration *contentLine
+		trigger  *contentLine
+	)
+	for i := range lines {
+		line := &lines[i]
+		switch line.name {
+		case "BEGIN":
+			path = append(path, strings.ToUpper(line.value))
+			if strings.EqualFold(line.value, "VEVENT") {
+				events++
+			}
+			continue
+		case "END":
+			if len(path) == 0 {
+				return event, fmt.Errorf("%w: unexpected END:%s", ErrInvalidCalendarData, line.value)
+			}
+			path = path[:len(path)-1]
+			continue
+		}
+
+		component := strings.Join(path, "/")
+		switch component {
+		case "VCALENDAR/VEVENT":
+			switch line.name {
+			case "UID":
+				event.UID = unescapeText(line.value)
+			case "SUMMARY":
+				event.Title = unescapeText(line.value)
+			case "DESCRIPTION":
+				event.Description = unescapeText(line.value)
+			case "DTSTART":
+				start = line
+			case "DTEND":
+				end = line
+			case "DURATION":
+				duration = line
+			case "RRULE", "RDATE", "RECURRENCE-ID":
+				return event, ErrRecurrence
+			}
+		case "VCALENDAR/VEVENT/VALARM":
+			if line.name == "TRIGGER" && trigger == nil {
+				trigger = line
+			}
+		}
+	}
+
+	switch {
+	case events == 0:
+		return event, fmt.Errorf("%w: no VEVENT", ErrInvalidCalendarData)
+	case events > 1:
+		return event, ErrRecurrence
+	case event.UID == "":
+		return event, fmt.Errorf("%w: VEVENT without UID", ErrInvalidCalendarData)
+	case start == nil:
+		return event, fmt.Errorf("%w: VEVENT without DTSTART", ErrInvalidCalendarData)
+	}
+
+	if event.DateTime, err = parseTime(start); err != nil {
+		return event, err
+	}
+
+	var length time.Duration
+	switch {
+	case end != nil:
+		endTime, err := parseTime(end)
+		if err != nil {
+			return event, err
+		}
+		length = endTime.Sub(event.DateTime)
+	case duration != nil:
+		if length, err = parseDuration(duration.value); err != nil {
+			return event, err
+		}
+	case start.params["VALUE"] == "DATE":
+		length = 24 * time.Hour
+	}
+	if length < 0 {
+		return event, fmt.Errorf("%w: event ends before it starts", ErrInvalidCalendarData)
+	}
+	if length > 0 {
+		event.Duration = formatEventDuration(length)
+	}
+
+	if trigger != nil {
+		if event.RemindTime, err = parseTrigger(trigger, event.DateTime, event.DateTime.Add(length)); err != nil {
+			return event, err
+		}
+	}
+
+	return event, nil
+}
+
+func parseTime(line *contentLine) (time.Time, error) {
+	value := line.value
+	if line.params["VALUE"] == "DATE" || len(value) == len(dateOnly) {
+		t, err := time.Parse(dateOnly, value)
+		if err != nil {
+			return t, fmt.Errorf("%w: %s: %w", ErrInvalidCalendarData, line.name, err)
+		}
+		return t, nil
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(dateTimeUTC, value)
+		if err != nil {
+			return t, fmt.Errorf("%w: %s: %w", ErrInvalidCalendarData, line.name, err)
+		}
+		return t, nil
+	}
+
+	location := time.UTC
+	if tzid := line.params["TZID"]; tzid != "" {
+		var err error
+		if location, err = time.LoadLocation(strings.TrimPrefix(tzid, "/")); err != nil {
+			return time.Time{}, fmt.Errorf("%w: %s: unknown time zone %q", ErrInvalidCalendarData, line.name, tzid)
+		}
+	}
+	t, err := time.ParseInLocation(dateTimeTZ, value, location)
+	if err != nil {
+		return t, fmt.Errorf("%w: %s: %w", ErrInvalidCalendarData, line.name, err)
+	}
+
+	return t.UTC(), nil
+}
+
+// parseTrigger returns the time of an absolute trigger or of one relative to
+// the start or end of the event.
+func parseTrigger(line *contentLine, start, end time.Time) (time.Time, error) {
+	if line.params["VALUE"] == "DATE-TIME" {
+		return parseTime(line)
+	}
+
+	offset, err := parseDuration(line.value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if line.params["RELATED"] == "END" {
+		return end.Add(offset), nil
+	}
+
+	return start.Add(offset), nil
+}
+
+// parseDuration parses an RFC 5545 duration such as -PT15M or P1DT2H.
+func parseDuration(value string) (time.Duration, error) {
+	invalid := fmt.Errorf("%w: duration %q", ErrInvalidCalendarData, value)
+
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(value, "-"):
+		sign, value = -1, value[1:]
+	case strings.HasPrefix(value, "+"):
+		value = value[1:]
+	}
+	if !strings.HasPrefix(value, "P") || len(value) < 3 {
+		return 0, invalid
+	}
+
+	var (
+		duration time.Duration
+		number   string
+		inTime   bool
+	)
+	units := map[bool]map[byte]time.Duration{
+		false: {'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour},
+		true:  {'H': time.Hour, 'M': time.Minute, 'S': time.Second},
+	}
+	for i := 1; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+		case c == 'T' && !inTime && number == "":
+			inTime = true
+		default:
+			unit, ok := units[inTime][c]
+			n, err := strconv.Atoi(number)
+			if !ok || err != nil {
+				return 0, invalid
+			}
+			duration += time.Duration(n) * unit
+			number = ""
+		}
+	}
+	if number != "" {
+		return 0, invalid
+	}
+
+	return sign * duration, nil
+}
+
+func formatDuration(duration time.Duration) string {
+	seconds := int64(duration / time.Second)
+	days, seconds := seconds/86400, seconds%86400
+	hours, seconds := seconds/3600, seconds%3600
+	minutes, seconds := seconds/60, seconds%60
+
+	var b strings.Builder
+	b.WriteString("P")
+	if days > 0 {
+		fmt.Fprintf(&b, "%dD", days)
+	}
+	if hours+minutes+seconds > 0 {
+		b.WriteString("T")
+		if hours > 0 {
+			fmt.Fprintf(&b, "%dH", hours)
+		}
+		if minutes > 0 {
+			fmt.Fprintf(&b, "%dM", minutes)
+		}
+		if seconds > 0 {
+			fmt.Fprintf(&b, "%dS", seconds)
+		}
+	}
+
+	return b.String()
+}
+
+// parseEventDuration parses durations of events, HH:MM:SS with hours
+// possibly over 24.
+func parseEventDuration(value string) (time.Duration, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid event duration %q", value)
+	}
+
+	var duration time.Duration
+	for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid event duration %q", value)
+		}
+		duration += time.Duration(n) * unit
+	}
+
+	return duration, nil
+}
+
+func formatEventDuration(duration time.Duration) string {
+	seconds := int64(duration / time.Second)
+
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds%3600/60, seconds%60)
+}
@@ -0,0 +1,200 @@
+package caldav
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+func ics(lines ...string) []byte {
+	return []byte(strings.Join(lines, "\r\n") + "\r\n")
+}
+
+func TestEncodeDecodeEvent(t *testing.T) {
+	event := &entity.Event{
+		ID:          "3f2b8a52-5f0e-4a59-9d53-51ab4f4c0b5e",
+		UID:         "meeting@example.com",
+		Title:       "Planning; Q4, review",
+		Description: "Agenda:\nbudget\\plans",
+		DateTime:    time.Date(2026, 10, 20, 8, 0, 0, 0, time.UTC),
+		Duration:    "01:30:00",
+		RemindTime:  time.Date(2026, 10, 20, 7, 45, 0, 0, time.UTC),
+		Tags:        []string{"work", "q4"},
+		CreatedAt:   time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	data := encodeEvent(event)
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\r\n"), "\r\n") {
+		require.LessOrEqual(t, len(line), maxLineOctet)
+	}
+	require.Contains(t, string(data), "UID:meeting@example.com\r\n")
+	require.Contains(t, string(data), "DTSTART:20261020T080000Z\r\n")
+	require.Contains(t, string(data), "DURATION:PT1H30M\r\n")
+	require.Contains(t, string(data), "CATEGORIES:work,q4\r\n")
+
+	decoded, err := decodeEvent(data)
+	require.NoError(t, err)
+	require.Equal(t, event.UID, decoded.UID)
+	require.Equal(t, event.Title, decoded.Title)
+	require.Equal(t, event.Description, decoded.Description)
+	require.Equal(t, event.DateTime, decoded.DateTime)
+	require.Equal(t, event.Duration, decoded.Duration)
+	require.Equal(t, event.RemindTime, decoded.RemindTime)
+	require.Empty(t, decoded.Tags)
+
+	// events without a UID are identified by ID, without a title they are
+	// free/busy only
+	data = encodeEvent(&entity.Event{ID: event.ID, DateTime: event.DateTime})
+	require.Contains(t, string(data), "UID:"+event.ID+"\r\n")
+	require.Contains(t, string(data), "CLASS:CONFIDENTIAL\r\n")
+	require.NotContains(t, string(data), "VALARM")
+}
+
+func TestEncodeEvent_Folding(t *testing.T) {
+	event := &entity.Event{
+		UID:         "long",
+		DateTime:    time.Date(2026, 10, 20, 8, 0, 0, 0, time.UTC),
+		Title:       strings.Repeat("событие ", 30),
+		Description: strings.Repeat("x", 200),
+	}
+
+	data := encodeEvent(event)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\r\n"), "\r\n")
+	folded := 0
+	for _, line := range lines {
+		require.LessOrEqual(t, len(line), maxLineOctet)
+		require.True(t, strings.ToValidUTF8(line, "") == line, "line %q splits a character", line)
+		if strings.HasPrefix(line, " ") {
+			folded++
+		}
+	}
+	require.Positive(t, folded)
+
+	decoded, err := decodeEvent(data)
+	require.NoError(t, err)
+	require.Equal(t, event.Title, decoded.Title)
+	require.Equal(t, event.Description, decoded.Description)
+}
+
+func TestDecodeEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		start    time.Time
+		duration string
+		remind   time.Time
+	}{
+		{
+			name: "time zone",
+			data: ics("BEGIN:VCALENDAR", "BEGIN:VEVENT", "UID:1",
+				"DTSTART;TZID=Europe/Berlin:20261020T100000", "DTEND;TZID=Europe/Berlin:20261020T113000",
+				"BEGIN:VALARM", "TRIGGER:-PT15M", "END:VALARM", "END:VEVENT", "END:VCALENDAR"),
+			start:    time.Date(2026, 10, 20, 8, 0, 0, 0, time.UTC),
+			duration: "01:30:00",
+			remind:   time.Date(2026, 10, 20, 7, 45, 0, 0, time.UTC),
+		},
+		{
+			name: "all day",
+			data: ics("BEGIN:VCALENDAR", "BEGIN:VEVENT", "UID:1", "DTSTART;VALUE=DATE:20261020",
+				"END:VEVENT", "END:VCALENDAR"),
+			start:    time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC),
+			duration: "24:00:00",
+		},
+		{
+			name: "trigger related to end",
+			data: ics("BEGIN:VCALENDAR", "BEGIN:VEVENT", "UID:1", "DTSTART:20261020T100000Z", "DURATION:P1DT2H",
+				"BEGIN:VALARM", "TRIGGER;RELATED=END:PT5M", "END:VALARM", "END:VEVENT", "END:VCALENDAR"),
+			start:    time.Date(2026, 10, 20, 10, 0, 0, 0, time.UTC),
+			duration: "26:00:00",
+			remind:   time.Date(2026, 10, 21, 12, 5, 0, 0, time.UTC),
+		},
+		{
+			name: "floating time and folded lines",
+			data: ics("BEGIN:VCALENDAR", "BEGIN:VTIMEZONE", "TZID:Custom", "END:VTIMEZONE", "BEGIN:VEVENT",
+				"UID:1", "DTSTART:20261020", " T100000", "END:VEVENT", "END:VCALENDAR"),
+			start: time.Date(2026, 10, 20, 10, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			event, err := decodeEvent(tc.data)
+			require.NoError(t, err)
+			require.Equal(t, tc.start, event.DateTime)
+			require.Equal(t, tc.duration, event.Duration)
+			require.Equal(t, tc.remind, event.RemindTime)
+		})
+	}
+}
+
+func TestDecodeEvent_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{
+			name: "recurring",
+			data: ics("BEGIN:VCALENDAR", "BEGIN:VEVENT", "UID:1", "DTSTART:20261020T100000Z",
+				"RRULE:FREQ=DAILY", "END:VEVENT", "END:VCALENDAR"),
+			err: ErrRecurrence,
+		},
+		{
+			name: "overridden occurrence",
+			data: ics("BEGIN:VCALENDAR", "BEGIN:VEVENT", "UID:1", "DTSTART:20261020T100000Z", "END:VEVENT",
+				"BEGIN:VEVENT", "UID:1", "DTSTART:20261021T100000Z", "END:VEVENT", "END:VCALENDAR"),
+			err: ErrRecurrence,
+		},
+		{
+			name: "no event",
+			data: ics("BEGIN:VCALENDAR", "BEGIN:VTODO", "UID:1", "END:VTODO", "END:VCALENDAR"),
+			err:  ErrInvalidCalendarData,
+		},
+		{
+			name: "no UID",
+			data: ics("BEGIN:VCALENDAR", "BEGIN:VEVENT", "DTSTART:20261020T100000Z", "END:VEVENT", "END:VCALENDAR"),
+			err:  ErrInvalidCalendarData,
+		},
+		{
+			name: "unknown time zone",
+			data: ics("BEGIN:VCALENDAR", "BEGIN:VEVENT", "UID:1", "DTSTART;TZID=Mars/Olympus:20261020T100000",
+				"END:VEVENT", "END:VCALENDAR"),
+			err: ErrInvalidCalendarData,
+		},
+		{
+			name: "ends before start",
+			data: ics("BEGIN:VCALENDAR", "BEGIN:VEVENT", "UID:1", "DTSTART:20261020T100000Z",
+				"DTEND:20261020T090000Z", "END:VEVENT", "END:VCALENDAR"),
+			err: ErrInvalidCalendarData,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := decodeEvent(tc.data)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"PT15M":    15 * time.Minute,
+		"-PT15M":   -15 * time.Minute,
+		"+P1W":     7 * 24 * time.Hour,
+		"P1DT2H3S": 26*time.Hour + 3*time.Second,
+	}
+	for value, expected := range tests {
+		duration, err := parseDuration(value)
+		require.NoError(t, err, value)
+		require.Equal(t, expected, duration, value)
+	}
+
+	for _, value := range []string{"", "P", "PT", "15M", "PT15", "P1H"} {
+		_, err := parseDuration(value)
+		require.ErrorIs(t, err, ErrInvalidCalendarData, value)
+	}
+
+	require.Equal(t, "P1DT2H3S", formatDuration(26*time.Hour+3*time.Second))
+}
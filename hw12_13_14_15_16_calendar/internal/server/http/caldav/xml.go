@@ -0,0 +1,226 @@
+package caldav
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	nsDAV            = "DAV:"
+	nsCalDAV         = "urn:ietf:params:xml:ns:caldav"
+	nsCalendarServer = "http://calendarserver.org/ns/"
+)
+
+// prefixes of namespaces declared on multistatus responses.
+var prefixes = map[string]string{
+	nsDAV:            "d",
+	nsCalDAV:         "c",
+	nsCalendarServer: "cs",
+}
+
+var (
+	propResourceType    = xml.Name{Space: nsDAV, Local: "resourcetype"}
+	propDisplayName     = xml.Name{Space: nsDAV, Local: "displayname"}
+	propPrincipal       = xml.Name{Space: nsDAV, Local: "current-user-principal"}
+	propPrincipalURL    = xml.Name{Space: nsDAV, Local: "principal-URL"}
+	propETag            = xml.Name{Space: nsDAV, Local: "getetag"}
+	propContentType     = xml.Name{Space: nsDAV, Local: "getcontenttype"}
+	propContentLength   = xml.Name{Space: nsDAV, Local: "getcontentlength"}
+	propSupportedReport = xml.Name{Space: nsDAV, Local: "supported-report-set"}
+	propHomeSet         = xml.Name{Space: nsCalDAV, Local: "calendar-home-set"}
+	propComponentSet    = xml.Name{Space: nsCalDAV, Local: "supported-calendar-component-set"}
+	propCalendarData    = xml.Name{Space: nsCalDAV, Local: "calendar-data"}
+	propCTag            = xml.Name{Space: nsCalendarServer, Local: "getctag"}
+)
+
+// propfindRequest is a PROPFIND body, an empty body requests all properties.
+type propfindRequest struct {
+	XMLName  xml.Name  `xml:"DAV: propfind"`
+	AllProp  *struct{} `xml:"DAV: allprop"`
+	PropName *struct{} `xml:"DAV: propname"`
+	Prop     *propList `xml:"DAV: prop"`
+}
+
+type propList struct {
+	Names []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+func (l *propList) names() []xml.Name {
+	if l == nil {
+		return nil
+	}
+
+	names := make([]xml.Name, 0, len(l.Names))
+	for _, name := range l.Names {
+		names = append(names, name.XMLName)
+	}
+
+	return names
+}
+
+// reportRequest is a calendar-query or calendar-multiget REPORT body.
+type reportRequest struct {
+	XMLName xml.Name
+	Prop    *propList `xml:"DAV: prop"`
+	Hrefs   []string  `xml:"DAV: href"`
+	Filter  *struct {
+		CompFilter *compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	} `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+// compFilter selects components, only time ranges of VEVENTs are applied.
+type compFilter struct {
+	Name        string       `xml:"name,attr"`
+	TimeRange   *timeRange   `xml:"urn:ietf:params:xml:ns:caldav time-range"`
+	CompFilters []compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+type timeRange struct {
+	Start string `xml:"start,attr"`
+	End   string `xml:"end,attr"`
+}
+
+// eventTimeRange returns the time range of the VEVENT filter, nil if there is
+// none.
+func (f *compFilter) eventTimeRange() *timeRange {
+	if f == nil || !strings.EqualFold(f.Name, "VCALENDAR") {
+		return nil
+	}
+	for _, filter := range f.CompFilters {
+		if strings.EqualFold(filter.Name, "VEVENT") {
+			return filter.TimeRange
+		}
+	}
+
+	return nil
+}
+
+// property is a found property with its value as inner XML, written with
+// the prefixes of namespaces.
+type property struct {
+	name  xml.Name
+	value string
+}
+
+// response is a resource of a multistatus response. A status is set for
+// resources without properties, e.g. missing ones.
+type response struct {
+	href    string
+	status  int
+	found   []property
+	missing []xml.Name
+}
+
+// selectProperties splits the requested properties of a resource into found
+// and missing ones. Without names, all properties but calendar data are
+// returned, with names only set empty values.
+func selectProperties(available []property, names []xml.Name, namesOnly bool) ([]property, []xml.Name) {
+	if names == nil {
+		found := make([]property, 0, len(available))
+		for _, prop := range available {
+			if prop.name == propCalendarData {
+				continue
+			}
+			if namesOnly {
+				prop.value = ""
+			}
+			found = append(found, prop)
+		}
+
+		return found, nil
+	}
+
+	var (
+		found   []property
+		missing []xml.Name
+	)
+	for _, name := range names {
+		i := indexOf(available, name)
+		if i < 0 {
+			missing = append(missing, name)
+			continue
+		}
+		found = append(found, available[i])
+	}
+
+	return found, missing
+}
+
+func indexOf(properties []property, name xml.Name) int {
+	for i, prop := range properties {
+		if prop.name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// element returns an XML element with the inner XML, elements of unknown
+// namespaces declare them.
+func element(name xml.Name, inner string) string {
+	tag, declaration := name.Local, ""
+	if prefix, ok := prefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+	} else if name.Space != "" {
+		tag = "x:" + name.Local
+		declaration = ` xmlns:x="` + escape(name.Space) + `"`
+	}
+	if inner == "" {
+		return "<" + tag + declaration + "/>"
+	}
+
+	return "<" + tag + declaration + ">" + inner + "</" + tag + ">"
+}
+
+func escape(text string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(text))
+
+	return b.String()
+}
+
+func hrefElement(href string) string {
+	return "<d:href>" + escape(href) + "</d:href>"
+}
+
+func statusLine(code int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", code, http.StatusText(code))
+}
+
+func writeMultistatus(w http.ResponseWriter, responses []response) {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="` + nsCalDAV + `" xmlns:cs="` + nsCalendarServer + `">`)
+	for _, resp := range responses {
+		b.WriteString("<d:response>")
+		b.WriteString(hrefElement(resp.href))
+		if resp.status != 0 {
+			b.WriteString("<d:status>" + statusLine(resp.status) + "</d:status>")
+		}
+		if len(resp.found) > 0 || (resp.status == 0 && len(resp.missing) == 0) {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, prop := range resp.found {
+				b.WriteString(element(prop.name, prop.value))
+			}
+			b.WriteString("</d:prop><d:status>" + statusLine(http.StatusOK) + "</d:status></d:propstat>")
+		}
+		if len(resp.missing) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, name := range resp.missing {
+				b.WriteString(element(name, ""))
+			}
+			b.WriteString("</d:prop><d:status>" + statusLine(http.StatusNotFound) + "</d:status></d:propstat>")
+		}
+		b.WriteString("</d:response>")
+	}
+	b.WriteString("</d:multistatus>")
+
+	w.Header().Set("Content-Type", `application/xml; charset=utf-8`)
+	w.WriteHeader(http.StatusMultiStatus)
+	_, _ = w.Write([]byte(b.String()))
+}
@@ -0,0 +1,35 @@
+// Package docs serves the OpenAPI document of the gateway and a page to
+// browse and call the API, which works offline.
+package docs
+
+import (
+	_ "embed"
+	"net/http"
+
+	proto "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/api"
+)
+
+const (
+	SpecPath     = "/openapi.json"
+	ExplorerPath = "/docs"
+)
+
+//go:embed explorer.html
+var explorer []byte
+
+// NewSpecHandler writes the OpenAPI document.
+func NewSpecHandler() http.HandlerFunc {
+	return func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write(proto.OpenAPI)
+	}
+}
+
+// NewExplorerHandler writes the explorer page, it loads the document from
+// SpecPath.
+func NewExplorerHandler() http.HandlerFunc {
+	return func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = writer.Write(explorer)
+	}
+}
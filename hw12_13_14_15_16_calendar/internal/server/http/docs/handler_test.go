@@ -0,0 +1,60 @@
+package docs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	proto "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/api"
+	"github.com/stretchr/testify/require"
+)
+
+type spec struct {
+	Info struct {
+		Title string `json:"title"`
+	} `json:"info"`
+	Paths map[string]map[string]struct {
+		OperationID string `json:"operationId"`
+	} `json:"paths"`
+}
+
+func TestSpecHandler(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	NewSpecHandler()(recorder, httptest.NewRequest(http.MethodGet, SpecPath, nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var served spec
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&served))
+	require.Equal(t, "Calendar API", served.Info.Title)
+
+	// every method has a route, methods without HTTP annotations have none
+	operations := make(map[string]bool)
+	for path, item := range served.Paths {
+		require.True(t, strings.HasPrefix(path, "/v1/"), path)
+		for _, operation := range item {
+			operations[operation.OperationID] = true
+		}
+	}
+	methods := proto.File_api_EventService_proto.Services().ByName("EventService").Methods()
+	for i := range methods.Len() {
+		name := string(methods.Get(i).Name())
+		require.True(t, operations["EventService_"+name], "%s has no route", name)
+	}
+	require.Len(t, operations, methods.Len())
+}
+
+func TestExplorerHandler(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	NewExplorerHandler()(recorder, httptest.NewRequest(http.MethodGet, ExplorerPath, nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+	// the page loads nothing but the document
+	page := recorder.Body.String()
+	require.Contains(t, page, `fetch("`+SpecPath+`")`)
+	require.NotContains(t, page, "<script src")
+	require.NotContains(t, page, "https://")
+}
@@ -0,0 +1,138 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds a single dependency check.
+const DefaultTimeout = 2 * time.Second
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Check reports whether a dependency is usable, nil means it is.
+type Check func(ctx context.Context) error
+
+// CheckStatus is the result of a single check.
+type CheckStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Duration is in milliseconds.
+	Duration int64 `json:"durationMs"`
+}
+
+// Status is the readiness of the service with a breakdown by dependency.
+type Status struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckStatus `json:"checks"`
+}
+
+func (s Status) OK() bool {
+	return s.Status == StatusOK
+}
+
+// Checker runs dependency checks for the readiness probe.
+type Checker struct {
+	timeout time.Duration
+
+	mu     sync.Mutex
+	checks map[string]Check
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		checks:  make(map[string]Check),
+	}
+}
+
+// Add registers a check, a check of the same name is replaced.
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks[name] = check
+}
+
+// Check runs all checks concurrently, each bounded by the timeout.
+func (c *Checker) Check(ctx context.Context) Status {
+	c.mu.Lock()
+	checks := make(map[string]Check, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.Unlock()
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		status = Status{Status: StatusOK, Checks: make(map[string]CheckStatus, len(checks))}
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result := c.run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			status.Checks[name] = result
+			if result.Status != StatusOK {
+				status.Status = StatusFail
+			}
+		}()
+	}
+	wg.Wait()
+
+	return status
+}
+
+func (c *Checker) run(ctx context.Context, check Check) CheckStatus {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	started := time.Now()
+	err := check(ctx)
+	result := CheckStatus{Status: StatusOK, Duration: time.Since(started).Milliseconds()}
+	if err != nil {
+		result.Status, result.Error = StatusFail, err.Error()
+	}
+
+	return result
+}
+
+// ReadyHandler answers 200 when every check passes and 503 otherwise, with
+// the status of each check as JSON.
+func (c *Checker) ReadyHandler() http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		status := c.Check(request.Context())
+
+		writer.Header().Set("Content-Type", "application/json")
+		if !status.OK() {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(writer).Encode(status)
+	}
+}
+
+// Routes adds the liveness and readiness probes to mux of a binary without an
+// API server.
+func (c *Checker) Routes(mux *http.ServeMux) {
+	mux.Handle("GET /health/live", NewLiveHandler())
+	mux.Handle("GET /health/ready", c.ReadyHandler())
+}
+
+// NewLiveHandler answers 200 while the process serves requests, dependencies
+// aren't checked so their outages don't get the process restarted.
+func NewLiveHandler() http.HandlerFunc {
+	return NewStatusHandler(func() any {
+		return Status{Status: StatusOK, Checks: map[string]CheckStatus{}}
+	})
+}
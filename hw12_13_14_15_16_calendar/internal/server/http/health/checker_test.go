@@ -0,0 +1,48 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker(t *testing.T) {
+	checker := NewChecker(20 * time.Millisecond)
+	checker.Add("storage", func(context.Context) error { return nil })
+	checker.Add("broker", func(context.Context) error { return errors.New("connection is closed") })
+	checker.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	status := checker.Check(context.Background())
+	require.False(t, status.OK())
+	require.Equal(t, StatusOK, status.Checks["storage"].Status)
+	require.Equal(t, StatusFail, status.Checks["broker"].Status)
+	require.Equal(t, "connection is closed", status.Checks["broker"].Error)
+	require.Equal(t, context.DeadlineExceeded.Error(), status.Checks["slow"].Error)
+
+	recorder := httptest.NewRecorder()
+	checker.ReadyHandler()(recorder, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	var served Status
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&served))
+	require.Len(t, served.Checks, 3)
+
+	checker.Add("broker", func(context.Context) error { return nil })
+	checker.Add("slow", func(context.Context) error { return nil })
+	recorder = httptest.NewRecorder()
+	checker.ReadyHandler()(recorder, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	NewLiveHandler()(recorder, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"status":"ok","checks":{}}`, recorder.Body.String())
+}
@@ -1,11 +1,14 @@
 package health
 
 import (
+	"encoding/json"
 	"net/http"
 )
 
-func NewHandler() http.HandlerFunc {
+// NewStatusHandler writes the status returned by status as JSON.
+func NewStatusHandler(status func() any) http.HandlerFunc {
 	return func(writer http.ResponseWriter, _ *http.Request) {
-		_, _ = writer.Write([]byte(""))
+		writer.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(writer).Encode(status())
 	}
 }
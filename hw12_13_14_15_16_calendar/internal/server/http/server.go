@@ -2,23 +2,52 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"expvar"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	proto "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/api"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/grpc/idempotency"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/grpc/identity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/grpc/ratelimit"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/http/caldav"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/http/docs"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/http/health"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/http/log"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/http/web"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
 type Options struct {
 	Host, Port                string
 	ReadTimeout, WriteTimeout time.Duration
+	// Health checks dependencies for the readiness probe, it always passes
+	// without it.
+	Health *health.Checker
+	// TLS serves over TLS, plaintext is served without it.
+	TLS *tls.Config
+	// GatewayTLS dials the gRPC server over TLS, plaintext is dialed without
+	// it.
+	GatewayTLS *tls.Config
+	// Conn is called by the gateway instead of dialing the gRPC server.
+	Conn grpc.ClientConnInterface
+	// GRPC serves requests with the gRPC content type on this listener, over
+	// HTTP/2 with TLS or h2c without it.
+	GRPC http.Handler
+	// CalDAV serves CalDAV requests under caldav.Prefix, they aren't served
+	// without it.
+	CalDAV http.Handler
 }
 
 type Server interface {
@@ -28,7 +57,56 @@ type Server interface {
 
 type server struct {
 	*http.Server
-	logger logger.Logger
+	logger     logger.Logger
+	health     *health.Checker
+	tls        bool
+	gatewayTLS *tls.Config
+	conn       grpc.ClientConnInterface
+	grpc       http.Handler
+	caldav     http.Handler
+	// grpcCalls tracks gRPC requests in flight, Shutdown doesn't wait for
+	// them on h2c connections.
+	grpcCalls calls
+}
+
+// calls counts requests in flight, idle is closed when the last one is done.
+type calls struct {
+	mu    sync.Mutex
+	count int
+	idle  chan struct{}
+}
+
+func (c *calls) start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.count == 0 {
+		c.idle = make(chan struct{})
+	}
+	c.count++
+}
+
+func (c *calls) done() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.count--
+	if c.count == 0 {
+		close(c.idle)
+	}
+}
+
+// wait returns a channel closed when no requests are in flight.
+func (c *calls) wait() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.count == 0 {
+		idle := make(chan struct{})
+		close(idle)
+		return idle
+	}
+	return c.idle
 }
 
 func New(options Options, logger logger.Logger) Server {
@@ -36,55 +114,190 @@ func New(options Options, logger logger.Logger) Server {
 		Addr:         net.JoinHostPort(options.Host, options.Port),
 		ReadTimeout:  options.ReadTimeout,
 		WriteTimeout: options.WriteTimeout,
+		TLSConfig:    options.TLS,
+	}
+	checker := options.Health
+	if checker == nil {
+		checker = health.NewChecker(health.DefaultTimeout)
 	}
 	return &server{
-		serverHTTP,
-		logger,
+		Server:     serverHTTP,
+		logger:     logger,
+		health:     checker,
+		tls:        options.TLS != nil,
+		gatewayTLS: options.GatewayTLS,
+		conn:       options.Conn,
+		grpc:       options.GRPC,
+		caldav:     options.CalDAV,
 	}
 }
 
 func (s *server) Start(ctx context.Context) error {
+	err := s.setup(ctx)
+	if err != nil {
+		return err
+	}
+
+	if s.tls {
+		// certificates are taken from TLSConfig
+		err = s.ListenAndServeTLS("", "")
+	} else {
+		err = s.ListenAndServe()
+	}
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// setup builds the handler of the server.
+func (s *server) setup(ctx context.Context) error {
 	cfg := config.GetFromContext(ctx)
 	if cfg == nil {
 		return config.ErrNoConfigInContext
 	}
 
-	conn, err := grpc.NewClient(
-		net.JoinHostPort(cfg.GRPC.Host, cfg.GRPC.Port),
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	conn := s.conn
+	if conn == nil {
+		creds := insecure.NewCredentials()
+		if s.gatewayTLS != nil {
+			creds = credentials.NewTLS(s.gatewayTLS)
+		}
+		dialed, err := grpc.NewClient(
+			net.JoinHostPort(cfg.GRPC.Host, cfg.GRPC.Port),
+			grpc.WithTransportCredentials(creds),
+		)
+		if err != nil {
+			return err
+		}
+		conn = dialed
+	}
+
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(headerMatcher),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
 	)
+	err := proto.RegisterEventServiceHandlerClient(ctx, mux, proto.NewEventServiceClient(conn))
 	if err != nil {
 		return err
 	}
-
-	mux := runtime.NewServeMux()
-	for _, f := range []func(context.Context, *runtime.ServeMux, *grpc.ClientConn) error{
-		proto.RegisterEventServiceHandler,
-	} {
-		if err = f(ctx, mux, conn); err != nil {
+	s.Handler = log.NewHandler(s.logger, mux)
+	if s.grpc != nil {
+		if err = s.shareWithGRPC(); err != nil {
 			return err
 		}
 	}
-	s.Handler = log.NewHandler(s.logger, mux)
-	err = mux.HandlePath("GET", "/health",
-		func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-			health.NewHandler()(w, r)
+	// /health is kept for liveness probes configured before /health/live
+	for path, handler := range map[string]http.HandlerFunc{
+		"/health":              health.NewLiveHandler(),
+		"/health/live":         health.NewLiveHandler(),
+		"/health/ready":        s.health.ReadyHandler(),
+		docs.SpecPath:          docs.NewSpecHandler(),
+		docs.ExplorerPath:      docs.NewExplorerHandler(),
+		"/debug/vars":          expvar.Handler().ServeHTTP,
+		"/":                    web.NewRedirectHandler(),
+		web.Path + "{path=**}": web.NewHandler().ServeHTTP,
+	} {
+		err = mux.HandlePath("GET", path, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			handler(w, r)
 		})
-	if err != nil {
-		return err
+		if err != nil {
+			return err
+		}
 	}
 
-	err = s.ListenAndServe()
-	if err != nil {
+	if s.caldav != nil {
+		return s.handleCalDAV(mux)
+	}
+
+	return nil
+}
+
+// handleCalDAV routes CalDAV methods under caldav.Prefix and service discovery
+// to the CalDAV handler.
+func (s *server) handleCalDAV(mux *runtime.ServeMux) error {
+	handler := func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		s.caldav.ServeHTTP(w, r)
+	}
+	for _, method := range caldav.Methods {
+		if err := mux.HandlePath(method, caldav.Prefix+"{path=**}", handler); err != nil {
+			return err
+		}
+	}
+	for _, method := range []string{http.MethodGet, caldav.MethodPropfind} {
+		if err := mux.HandlePath(method, caldav.WellKnownPath, handler); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// shareWithGRPC routes gRPC requests to the gRPC handler, other ones to the
+// gateway. Without TLS, HTTP/2 is served as h2c.
+func (s *server) shareWithGRPC() error {
+	h2 := &http2.Server{}
+	// registers h2c connections to get GOAWAY on Shutdown
+	if err := http2.ConfigureServer(s.Server, h2); err != nil {
 		return err
 	}
+
+	gateway := s.Handler
+	s.Handler = h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			s.grpcCalls.start()
+			defer s.grpcCalls.done()
+			s.grpc.ServeHTTP(w, r)
+			return
+		}
+		gateway.ServeHTTP(w, r)
+	}), h2)
+
 	return nil
 }
 
+// headerMatcher forwards the requesting user and idempotency key headers to
+// the gRPC server along with the default headers.
+func headerMatcher(key string) (string, bool) {
+	for _, header := range []string{identity.Header, idempotency.Header} {
+		if strings.EqualFold(key, header) {
+			return header, true
+		}
+	}
+
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// outgoingHeaderMatcher returns the retry delay of exhausted requests as the
+// Retry-After header, other metadata as Grpc-Metadata- headers.
+func outgoingHeaderMatcher(key string) (string, bool) {
+	if key == ratelimit.Header {
+		return "Retry-After", true
+	}
+
+	return runtime.MetadataHeaderPrefix + key, true
+}
+
+// Stop waits for in-flight requests to finish, when ctx is done first their
+// connections are closed.
 func (s *server) Stop(ctx context.Context) error {
 	err := s.Shutdown(ctx)
+	if err == nil {
+		err = s.waitGRPC(ctx)
+	}
 	if err != nil {
+		_ = s.Close()
 		return err
 	}
 	return nil
 }
+
+// waitGRPC waits for gRPC requests in flight until ctx is done.
+func (s *server) waitGRPC(ctx context.Context) error {
+	select {
+	case <-s.grpcCalls.wait():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
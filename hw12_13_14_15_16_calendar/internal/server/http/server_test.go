@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	proto "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/api"
+	eventapp "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/app/event"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/grpc/errcode"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/grpc/inproc"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/grpc/ratelimit"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const waitFor = 2 * time.Second
+
+// eventErrors are returned by eventService for the ids.
+var eventErrors = map[string]error{
+	"anonymous": eventapp.ErrNoUser,
+	"forbidden": eventapp.ErrForbidden,
+	"missing":   entity.ErrEventNotFound,
+	"invalid":   eventapp.ErrInvalidTag,
+}
+
+// eventService returns an event with the requested id or its error from
+// eventErrors, calls wait for release when it is set.
+type eventService struct {
+	proto.UnimplementedEventServiceServer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *eventService) GetEvent(_ context.Context, id *proto.EventId) (*proto.Event, error) {
+	if s.release != nil {
+		s.started <- struct{}{}
+		<-s.release
+	}
+	if err, ok := eventErrors[id.GetId()]; ok {
+		return nil, err
+	}
+
+	return &proto.Event{EventId: id, EventData: &proto.EventData{Title: "planning"}}, nil
+}
+
+// newCertificate returns a self-signed certificate for 127.0.0.1 and a pool
+// trusting it.
+func newCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "calendar"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}, pool
+}
+
+type sharedServer struct {
+	server *server
+	conn   *grpc.ClientConn
+	client *http.Client
+	url    string
+}
+
+// newSharedServer serves the gateway and service on one listener, the gateway
+// and the returned gRPC client dial it.
+func newSharedServer(t *testing.T, service *eventService, withTLS bool) sharedServer {
+	t.Helper()
+
+	ts := httptest.NewUnstartedServer(nil)
+	creds := insecure.NewCredentials()
+	client := &http.Client{}
+	var serverTLS *tls.Config
+	if withTLS {
+		cert, pool := newCertificate(t)
+		serverTLS = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+		clientTLS := &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+		creds = credentials.NewTLS(clientTLS)
+		client.Transport = &http2.Transport{TLSClientConfig: clientTLS}
+	}
+	conn, err := grpc.NewClient(ts.Listener.Addr().String(), grpc.WithTransportCredentials(creds))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(errcode.New()))
+	proto.RegisterEventServiceServer(grpcServer, service)
+	srv := New(Options{TLS: serverTLS, Conn: conn, GRPC: grpcServer}, logger.New(logger.Debug, io.Discard)).(*server)
+	require.NoError(t, srv.setup((&config.Config{}).WithContext(context.Background())))
+
+	ts.Config = srv.Server
+	if withTLS {
+		ts.TLS = serverTLS
+		ts.EnableHTTP2 = true
+		ts.StartTLS()
+	} else {
+		ts.Start()
+	}
+	t.Cleanup(ts.Close)
+
+	return sharedServer{server: srv, conn: conn, client: client, url: ts.URL}
+}
+
+func TestServer_SharedListener(t *testing.T) {
+	for _, withTLS := range []bool{false, true} {
+		t.Run(map[bool]string{false: "h2c", true: "tls"}[withTLS], func(t *testing.T) {
+			shared := newSharedServer(t, &eventService{}, withTLS)
+			ctx, cancel := context.WithTimeout(context.Background(), waitFor)
+			defer cancel()
+
+			event, err := proto.NewEventServiceClient(shared.conn).GetEvent(ctx, &proto.EventId{Id: "1"})
+			require.NoError(t, err)
+			require.Equal(t, "planning", event.GetEventData().GetTitle())
+
+			// the gateway calls the service through the same listener
+			request, err := http.NewRequestWithContext(ctx, http.MethodGet, shared.url+"/v1/events/2", nil)
+			require.NoError(t, err)
+			response, err := shared.client.Do(request)
+			require.NoError(t, err)
+			defer response.Body.Close()
+			require.Equal(t, http.StatusOK, response.StatusCode)
+
+			var body struct {
+				EventID   struct{ ID string } `json:"eventId"`
+				EventData struct{ Title string }
+			}
+			require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+			require.Equal(t, "2", body.EventID.ID)
+			require.Equal(t, "planning", body.EventData.Title)
+
+			// runtime metrics are served next to the API
+			request, err = http.NewRequestWithContext(ctx, http.MethodGet, shared.url+"/debug/vars", nil)
+			require.NoError(t, err)
+			vars, err := shared.client.Do(request)
+			require.NoError(t, err)
+			defer vars.Body.Close()
+			require.Equal(t, http.StatusOK, vars.StatusCode)
+			require.Equal(t, "application/json; charset=utf-8", vars.Header.Get("Content-Type"))
+		})
+	}
+}
+
+func TestServer_ErrorCodes(t *testing.T) {
+	shared := newSharedServer(t, &eventService{}, false)
+	for id, code := range map[string]int{
+		"anonymous": http.StatusUnauthorized,
+		"forbidden": http.StatusForbidden,
+		"missing":   http.StatusNotFound,
+		"invalid":   http.StatusBadRequest,
+	} {
+		t.Run(id, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), waitFor)
+			defer cancel()
+
+			request, err := http.NewRequestWithContext(ctx, http.MethodGet, shared.url+"/v1/events/"+id, nil)
+			require.NoError(t, err)
+			response, err := shared.client.Do(request)
+			require.NoError(t, err)
+			defer response.Body.Close()
+			require.Equal(t, code, response.StatusCode)
+		})
+	}
+}
+
+func TestServer_InprocRateLimit(t *testing.T) {
+	conn := inproc.New(ratelimit.New(ratelimit.Options{IPRate: 0.001, IPBurst: 1}))
+	proto.RegisterEventServiceServer(conn, &eventService{})
+	srv := New(Options{Conn: conn}, logger.New(logger.Debug, io.Discard)).(*server)
+	require.NoError(t, srv.setup((&config.Config{}).WithContext(context.Background())))
+	ts := httptest.NewServer(srv.Handler)
+	t.Cleanup(ts.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), waitFor)
+	defer cancel()
+	get := func(client string) int {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/v1/events/1", nil)
+		require.NoError(t, err)
+		request.Header.Set("X-Forwarded-For", client)
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+
+		return response.StatusCode
+	}
+
+	// clients behind the loopback proxy are limited apart
+	require.Equal(t, http.StatusOK, get("203.0.113.1"))
+	require.Equal(t, http.StatusTooManyRequests, get("203.0.113.1"))
+	require.Equal(t, http.StatusOK, get("203.0.113.2"))
+}
+
+func TestServer_StopWaitsForGRPC(t *testing.T) {
+	service := &eventService{started: make(chan struct{}), release: make(chan struct{})}
+	shared := newSharedServer(t, service, false)
+
+	called := make(chan error, 1)
+	go func() {
+		_, err := proto.NewEventServiceClient(shared.conn).GetEvent(context.Background(), &proto.EventId{Id: "1"})
+		called <- err
+	}()
+	<-service.started
+
+	stopped := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), waitFor)
+		defer cancel()
+		stopped <- shared.server.Stop(ctx)
+	}()
+
+	select {
+	case err := <-stopped:
+		t.Fatalf("stopped with a call in flight: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(service.release)
+	require.NoError(t, <-called)
+	require.NoError(t, <-stopped)
+
+	// ctx bounds the wait
+	shared.server.grpcCalls.start()
+	defer shared.server.grpcCalls.done()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, shared.server.waitGRPC(ctx), context.Canceled)
+}
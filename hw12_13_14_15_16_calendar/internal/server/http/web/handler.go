@@ -0,0 +1,28 @@
+// Package web serves the calendar page, which shows and edits events through
+// the gateway REST API.
+package web
+
+import (
+	"embed"
+	"net/http"
+)
+
+// Path is the path the page is served at, its files are served below it.
+const Path = "/ui/"
+
+// files are embedded in the ui directory, so their paths are request paths.
+//
+//go:embed ui
+var files embed.FS
+
+// NewHandler serves the files of the page below Path.
+func NewHandler() http.Handler {
+	return http.FileServerFS(files)
+}
+
+// NewRedirectHandler redirects to the page.
+func NewRedirectHandler() http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		http.Redirect(writer, request, Path, http.StatusFound)
+	}
+}
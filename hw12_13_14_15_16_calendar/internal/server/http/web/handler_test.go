@@ -0,0 +1,60 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	handler := NewHandler()
+
+	tests := []struct {
+		path        string
+		code        int
+		contentType string
+	}{
+		{path: Path, code: http.StatusOK, contentType: "text/html; charset=utf-8"},
+		{path: Path + "app.js", code: http.StatusOK, contentType: "text/javascript; charset=utf-8"},
+		{path: Path + "style.css", code: http.StatusOK, contentType: "text/css; charset=utf-8"},
+		{path: Path + "missing.js", code: http.StatusNotFound},
+		{path: "/web.go", code: http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			require.Equal(t, tc.code, recorder.Code)
+			if tc.contentType != "" {
+				require.Equal(t, tc.contentType, recorder.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestHandler_Assets(t *testing.T) {
+	handler := NewHandler()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, Path, nil))
+	page := recorder.Body.String()
+
+	// assets of the page are served, nothing is loaded from elsewhere
+	require.NotContains(t, page, "//")
+	assets := regexp.MustCompile(`(?:src|href)="([^"]+)"`).FindAllStringSubmatch(page, -1)
+	require.NotEmpty(t, assets)
+	for _, asset := range assets {
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, Path+asset[1], nil))
+		require.Equal(t, http.StatusOK, recorder.Code, asset[1])
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	NewRedirectHandler()(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusFound, recorder.Code)
+	require.Equal(t, Path, recorder.Header().Get("Location"))
+}
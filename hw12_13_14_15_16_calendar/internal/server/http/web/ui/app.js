@@ -0,0 +1,389 @@
+"use strict";
+
+// The page talks to the gateway REST API on behalf of the user entered in the
+// header, events are shown and edited in the browser's time zone.
+
+const DAY = 24 * 60 * 60 * 1000;
+// a remind time before this one means the event has no reminder, the gateway
+// returns the Unix epoch for events created without one
+const NO_REMINDER = "0001-01-01T00:00:00Z";
+const NO_TIME_BEFORE = Date.UTC(1971, 0, 1);
+const WEEKDAYS = ["Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"];
+
+const state = {
+  view: localStorage.getItem("view") || "week",
+  anchor: startOfDay(new Date()),
+  userId: localStorage.getItem("userId") || "",
+  calendars: [],
+  editing: null,
+};
+
+function $(id) {
+  return document.getElementById(id);
+}
+
+function element(tag, attributes, ...children) {
+  const node = document.createElement(tag);
+  Object.assign(node, attributes);
+  node.append(...children);
+  return node;
+}
+
+function startOfDay(date) {
+  return new Date(date.getFullYear(), date.getMonth(), date.getDate());
+}
+
+function addDays(date, days) {
+  return new Date(date.getFullYear(), date.getMonth(), date.getDate() + days);
+}
+
+// startOfWeek returns the Monday of the week of the date.
+function startOfWeek(date) {
+  return addDays(date, -((date.getDay() + 6) % 7));
+}
+
+function pad(number) {
+  return String(number).padStart(2, "0");
+}
+
+function dateValue(date) {
+  return date.getFullYear() + "-" + pad(date.getMonth() + 1) + "-" + pad(date.getDate());
+}
+
+function timeValue(date) {
+  return pad(date.getHours()) + ":" + pad(date.getMinutes());
+}
+
+function hasTime(value) {
+  return value && Date.parse(value) >= NO_TIME_BEFORE;
+}
+
+function showMessage(text, isError) {
+  const message = $("message");
+  message.textContent = text;
+  message.className = isError ? "error" : "";
+}
+
+// newKey returns a random UUID. crypto.randomUUID is only available in secure
+// contexts, so the page served over plain HTTP builds it from random bytes.
+function newKey() {
+  if (crypto.randomUUID) {
+    return crypto.randomUUID();
+  }
+  const bytes = crypto.getRandomValues(new Uint8Array(16));
+  bytes[6] = (bytes[6] & 0x0f) | 0x40;
+  bytes[8] = (bytes[8] & 0x3f) | 0x80;
+  const hex = Array.from(bytes, (b) => b.toString(16).padStart(2, "0")).join("");
+  return [hex.slice(0, 8), hex.slice(8, 12), hex.slice(12, 16), hex.slice(16, 20), hex.slice(20)].join("-");
+}
+
+async function api(method, path, body, headers) {
+  const init = { method, headers: Object.assign({ "x-user-id": state.userId }, headers) };
+  if (body !== undefined) {
+    init.headers["Content-Type"] = "application/json";
+    init.body = JSON.stringify(body);
+  }
+  const response = await fetch(path, init);
+  const text = await response.text();
+  const data = text ? JSON.parse(text) : {};
+  if (!response.ok) {
+    throw new Error(data.message || response.status + " " + response.statusText);
+  }
+  return data;
+}
+
+// period returns the days shown by the view around the anchor.
+function period() {
+  const anchor = state.anchor;
+  switch (state.view) {
+  case "day":
+    return { start: anchor, end: addDays(anchor, 1), label: anchor.toLocaleDateString(undefined, { dateStyle: "full" }) };
+  case "week": {
+    const start = startOfWeek(anchor);
+    const end = addDays(start, 7);
+    return { start, end, label: start.toLocaleDateString() + " – " + addDays(end, -1).toLocaleDateString() };
+  }
+  default: {
+    const first = new Date(anchor.getFullYear(), anchor.getMonth(), 1);
+    const start = startOfWeek(first);
+    let end = startOfWeek(new Date(anchor.getFullYear(), anchor.getMonth() + 1, 1));
+    if (end < new Date(anchor.getFullYear(), anchor.getMonth() + 1, 1)) {
+      end = addDays(end, 7);
+    }
+    return { start, end, label: first.toLocaleDateString(undefined, { month: "long", year: "numeric" }) };
+  }
+  }
+}
+
+// fetchEvents returns events starting within [start, end). The API periods
+// start at UTC midnight, so months from the UTC day before start are
+// requested until end is covered.
+async function fetchEvents(start, end) {
+  const events = new Map();
+  let cursor = new Date(Date.UTC(start.getUTCFullYear(), start.getUTCMonth(), start.getUTCDate() - 1));
+  while (cursor < end) {
+    const query = new URLSearchParams({ startDate: cursor.toISOString() });
+    const data = await api("GET", "/v1/events:month?" + query);
+    for (const event of data.events || []) {
+      events.set(event.eventId.id, event);
+    }
+    cursor = new Date(Date.UTC(cursor.getUTCFullYear(), cursor.getUTCMonth() + 1, cursor.getUTCDate()));
+  }
+
+  return [...events.values()]
+    .filter((event) => {
+      const at = new Date(event.eventData.dateTime);
+      return at >= start && at < end;
+    })
+    .sort((a, b) => Date.parse(a.eventData.dateTime) - Date.parse(b.eventData.dateTime));
+}
+
+function eventButton(event) {
+  const data = event.eventData;
+  const at = new Date(data.dateTime);
+  // events of free/busy calendars come without details
+  const busy = !data.title;
+  const button = element("button", {
+    type: "button",
+    className: busy ? "event busy" : "event",
+    title: busy ? "Busy" : data.title + (data.description ? "\n" + data.description : ""),
+  }, timeValue(at) + " " + (busy ? "Busy" : data.title));
+  if (hasTime(data.remindTime) && !hasTime(data.remindSentTime)) {
+    button.append(element("span", { className: "reminder", title: "Reminder set" }));
+  }
+  if (!busy) {
+    button.addEventListener("click", () => openForm(event));
+  }
+  return button;
+}
+
+function dayCell(day, events, outside) {
+  const cell = element("div", { className: "cell" });
+  if (outside) {
+    cell.classList.add("outside");
+  }
+  if (day.getTime() === startOfDay(new Date()).getTime()) {
+    cell.classList.add("today");
+  }
+  const date = element("span", { className: "date", title: "Show the day" }, String(day.getDate()));
+  date.addEventListener("click", () => {
+    state.anchor = day;
+    setView("day");
+  });
+  cell.append(date);
+  for (const event of events) {
+    if (startOfDay(new Date(event.eventData.dateTime)).getTime() === day.getTime()) {
+      cell.append(eventButton(event));
+    }
+  }
+  return cell;
+}
+
+async function render() {
+  for (const button of document.querySelectorAll(".views button")) {
+    button.setAttribute("aria-pressed", String(button.dataset.view === state.view));
+  }
+  const { start, end, label } = period();
+  $("period").textContent = label;
+
+  const calendar = $("calendar");
+  if (!state.userId) {
+    calendar.replaceChildren();
+    showMessage("Enter your user ID to see the calendar.", true);
+    return;
+  }
+
+  let events;
+  try {
+    events = await fetchEvents(start, end);
+  } catch (e) {
+    showMessage("Error loading events: " + e.message, true);
+    return;
+  }
+  showMessage(events.length === 0 ? "No events." : "");
+
+  const grid = element("div", { className: "grid " + state.view });
+  if (state.view !== "day") {
+    grid.append(...WEEKDAYS.map((name) => element("div", { className: "weekday" }, name)));
+  }
+  for (let day = start; day < end; day = addDays(day, 1)) {
+    const outside = state.view === "month" && day.getMonth() !== state.anchor.getMonth();
+    grid.append(dayCell(day, events, outside));
+  }
+  calendar.replaceChildren(grid);
+}
+
+function setView(view) {
+  state.view = view;
+  localStorage.setItem("view", view);
+  render();
+}
+
+function move(direction) {
+  const anchor = state.anchor;
+  switch (state.view) {
+  case "day":
+    state.anchor = addDays(anchor, direction);
+    break;
+  case "week":
+    state.anchor = addDays(anchor, 7 * direction);
+    break;
+  default:
+    state.anchor = new Date(anchor.getFullYear(), anchor.getMonth() + direction, 1);
+  }
+  render();
+}
+
+async function loadCalendars() {
+  const select = $("event-form").elements.calendar;
+  select.replaceChildren(element("option", { value: "" }, "Default"));
+  try {
+    const data = await api("GET", "/v1/calendars");
+    state.calendars = data.calendars || [];
+  } catch (e) {
+    state.calendars = [];
+  }
+  for (const calendar of state.calendars) {
+    select.append(element("option", { value: calendar.calendarId.id }, calendar.name));
+  }
+}
+
+function showReminder(form) {
+  form.elements["remind-at"].hidden = form.elements.reminder.value !== "custom";
+}
+
+// openForm edits the event or creates a new one on the anchor day if it's
+// null.
+async function openForm(event) {
+  if (!state.userId) {
+    showMessage("Enter your user ID to create events.", true);
+    return;
+  }
+  await loadCalendars();
+
+  const form = $("event-form");
+  const fields = form.elements;
+  state.editing = event;
+  form.reset();
+  $("form-error").textContent = "";
+  $("remind-sent").textContent = "";
+  $("form-title").textContent = event ? "Edit event" : "New event";
+  $("delete-event").hidden = !event;
+
+  if (event) {
+    const data = event.eventData;
+    const at = new Date(data.dateTime);
+    fields.title.value = data.title;
+    fields.date.value = dateValue(at);
+    fields.time.value = timeValue(at);
+    fields.duration.value = (data.duration || "01:00").slice(0, 5);
+    fields.calendar.value = data.calendarId || "";
+    fields.description.value = data.description || "";
+    fields.tags.value = (data.tags || []).join(", ");
+    if (hasTime(data.remindTime)) {
+      const remindAt = new Date(data.remindTime);
+      const minutes = String(Math.round((at - remindAt) / 60000));
+      const preset = [...fields.reminder.options].some((option) => option.value === minutes);
+      fields.reminder.value = preset ? minutes : "custom";
+      fields["remind-at"].value = dateValue(remindAt) + "T" + timeValue(remindAt);
+    }
+    if (hasTime(data.remindSentTime)) {
+      $("remind-sent").textContent = "Sent " + new Date(data.remindSentTime).toLocaleString();
+    }
+  } else {
+    fields.date.value = dateValue(state.anchor);
+    fields.time.value = "09:00";
+    fields.reminder.value = "15";
+  }
+  showReminder(form);
+  $("event-dialog").showModal();
+}
+
+function formData(form) {
+  const fields = form.elements;
+  const at = new Date(fields.date.value + "T" + fields.time.value);
+  let remindTime = NO_REMINDER;
+  if (fields.reminder.value === "custom") {
+    if (fields["remind-at"].value) {
+      remindTime = new Date(fields["remind-at"].value).toISOString();
+    }
+  } else if (fields.reminder.value !== "") {
+    remindTime = new Date(at.getTime() - Number(fields.reminder.value) * 60000).toISOString();
+  }
+
+  return {
+    userId: state.userId,
+    title: fields.title.value.trim(),
+    dateTime: at.toISOString(),
+    duration: fields.duration.value + ":00",
+    description: fields.description.value,
+    remindTime,
+    tags: fields.tags.value.split(",").map((tag) => tag.trim()).filter((tag) => tag !== ""),
+    calendarId: fields.calendar.value,
+  };
+}
+
+async function save(submitEvent) {
+  submitEvent.preventDefault();
+  const form = $("event-form");
+  const data = formData(form);
+  try {
+    if (state.editing) {
+      await api("PUT", "/v1/events/" + encodeURIComponent(state.editing.eventId.id), data);
+    } else {
+      // a retried submit doesn't create the event twice
+      await api("POST", "/v1/events", data, { "Idempotency-Key": newKey() });
+    }
+  } catch (e) {
+    $("form-error").textContent = e.message;
+    return;
+  }
+  $("event-dialog").close();
+  state.anchor = startOfDay(new Date(data.dateTime));
+  render();
+}
+
+async function remove() {
+  if (!state.editing || !confirm("Delete the event?")) {
+    return;
+  }
+  try {
+    await api("DELETE", "/v1/events/" + encodeURIComponent(state.editing.eventId.id));
+  } catch (e) {
+    $("form-error").textContent = e.message;
+    return;
+  }
+  $("event-dialog").close();
+  render();
+}
+
+function main() {
+  const userId = $("user-id");
+  userId.value = state.userId;
+  userId.addEventListener("change", () => {
+    state.userId = userId.value.trim();
+    localStorage.setItem("userId", state.userId);
+    render();
+  });
+
+  for (const button of document.querySelectorAll(".views button")) {
+    button.addEventListener("click", () => setView(button.dataset.view));
+  }
+  $("previous").addEventListener("click", () => move(-1));
+  $("next").addEventListener("click", () => move(1));
+  $("today").addEventListener("click", () => {
+    state.anchor = startOfDay(new Date());
+    render();
+  });
+  $("new-event").addEventListener("click", () => openForm(null));
+
+  const form = $("event-form");
+  form.addEventListener("submit", save);
+  form.elements.reminder.addEventListener("change", () => showReminder(form));
+  $("cancel").addEventListener("click", () => $("event-dialog").close());
+  $("delete-event").addEventListener("click", remove);
+
+  render();
+}
+
+main();
@@ -1,17 +1,33 @@
 package server
 
 import (
+	"context"
 	"time"
 
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
 	serverGRPC "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/grpc"
 	serverHTTP "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/http"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server/http/caldav"
+)
+
+const (
+	// ListenSeparate serves gRPC and HTTP on their own ports, the gateway
+	// dials the gRPC server.
+	ListenSeparate = "separate"
+	// ListenSingle serves gRPC and HTTP on the HTTP port, the gateway calls
+	// the service in process.
+	ListenSingle = "single"
 )
 
 type Options struct {
 	HTTP serverHTTP.Options
 	GRPC serverGRPC.Options
+	// ListenMode is ListenSeparate or ListenSingle, separate by default.
+	ListenMode string
+	// CalDAV serves the application over CalDAV on the HTTP server, to users
+	// identified by proxies trusted by the rate limits and as limited by them.
+	CalDAV bool
 }
 
 type Server struct {
@@ -22,13 +38,35 @@ type Server struct {
 }
 
 type ApplicationEvent interface {
-	GetEvent(id string) (*entity.Event, error)
-	CreateEvent(event entity.Event) (string, error)
-	UpdateEvent(id string, event entity.Event) error
-	DeleteEvent(id string) error
-	GetDayEvents(day time.Time) (*entity.Events, error)
-	GetWeekEvents(weekStart time.Time) (*entity.Events, error)
-	GetMonthEvents(monthStart time.Time) (*entity.Events, error)
+	GetEvent(ctx context.Context, id string) (*entity.Event, error)
+	CreateEvent(ctx context.Context, event entity.Event) (string, error)
+	UpdateEvent(ctx context.Context, id string, event entity.Event) error
+	DeleteEvent(ctx context.Context, id string) error
+	GetDayEvents(ctx context.Context, day time.Time, tags ...string) (*entity.Events, error)
+	GetWeekEvents(ctx context.Context, weekStart time.Time, tags ...string) (*entity.Events, error)
+	GetMonthEvents(ctx context.Context, monthStart time.Time, tags ...string) (*entity.Events, error)
+	SearchEvents(
+		ctx context.Context,
+		query string,
+		start, end time.Time,
+		limit int,
+		tags ...string,
+	) (*entity.Events, error)
+	SaveTag(ctx context.Context, tag entity.Tag) error
+	DeleteTag(ctx context.Context, userID int, name string) error
+	GetTags(ctx context.Context, userID int) (*entity.Tags, error)
+	CreateCalendar(ctx context.Context, calendar entity.Calendar) (string, error)
+	UpdateCalendar(ctx context.Context, calendar entity.Calendar) error
+	DeleteCalendar(ctx context.Context, id string) error
+	GetCalendars(ctx context.Context) (*entity.Calendars, error)
+	ShareCalendar(ctx context.Context, share entity.Share) error
+	UnshareCalendar(ctx context.Context, calendarID string, userID int) error
+	GetShares(ctx context.Context, calendarID string) (*entity.Shares, error)
+	SaveDigest(ctx context.Context, digest entity.Digest) error
+	DeleteDigest(ctx context.Context, userID int) error
+	GetDigest(ctx context.Context, userID int) (*entity.Digest, error)
+	GetEventByUID(ctx context.Context, calendarID, uid string) (*entity.Event, error)
+	GetCalendarEvents(ctx context.Context, calendarID string, start, end time.Time) (*entity.Events, error)
 }
 
 type Application interface {
@@ -41,6 +79,16 @@ func New(options Options, logger logger.Logger, app Application) *Server {
 		logger,
 		app,
 	)
+	if options.ListenMode == ListenSingle {
+		options.HTTP.Conn = grpcServer.Conn()
+		options.HTTP.GRPC = grpcServer
+	}
+	if options.CalDAV {
+		options.HTTP.CalDAV = caldav.NewHandler(app, logger, caldav.Options{
+			TrustedProxies: options.GRPC.RateLimit.TrustedProxies,
+			Limit:          grpcServer.Limit,
+		})
+	}
 	httpServer := serverHTTP.New(
 		options.HTTP,
 		logger,
@@ -52,3 +100,8 @@ func New(options Options, logger logger.Logger, app Application) *Server {
 		options: options,
 	}
 }
+
+// Single reports whether gRPC is served on the HTTP listener.
+func (s *Server) Single() bool {
+	return s.options.ListenMode == ListenSingle
+}
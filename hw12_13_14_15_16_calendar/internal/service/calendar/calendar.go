@@ -4,10 +4,8 @@ import (
 	"context"
 	"errors"
 	"net/http"
-	"sync"
-	"time"
 
-	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/lifecycle"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/server"
 )
@@ -27,53 +25,32 @@ func New(
 	}
 }
 
-func (s *Calendar) Run(ctx context.Context) error {
-	cfg := config.GetFromContext(ctx)
-	if cfg == nil {
-		return config.ErrNoConfigInContext
+// Components returns the servers in start order. HTTP proxies to gRPC, so it
+// starts after and stops before the gRPC server, draining requests first.
+// Sharing the HTTP listener, gRPC has no component of its own.
+func (s *Calendar) Components() []lifecycle.Component {
+	httpServer := lifecycle.Component{
+		Name: "HTTP server",
+		Run: func(ctx context.Context) error {
+			err := (*s.server.HTTP).Start(ctx)
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		},
+		Stop: (*s.server.HTTP).Stop,
+	}
+	if s.server.Single() {
+		httpServer.Name = "HTTP and GRPC server"
+		return []lifecycle.Component{httpServer}
 	}
-	var err error
-	wg := sync.WaitGroup{}
-	wg.Add(3)
-	go func() {
-		defer wg.Done()
-
-		s.logger.Info("GRPC server starting...")
-		err = (*s.server.GRPC).Start(ctx)
-		if err != nil {
-			s.logger.Error("Failed to start GRPC server: %v", err)
-			// cancel()
-		}
-	}()
-	go func() {
-		defer wg.Done()
-
-		s.logger.Info("HTTP server starting...")
-		err = (*s.server.HTTP).Start(ctx)
-		if err != nil && !errors.Is(err, http.ErrServerClosed) {
-			s.logger.Error("Failed to start HTTP server: %v", err)
-			// cancel()
-		}
-	}()
-	go func() {
-		defer wg.Done()
-		<-ctx.Done()
-
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
-		defer cancel()
-
-		s.logger.Info("GRPC server stopping...")
-		if err = (*s.server.GRPC).Stop(ctx); err != nil {
-			s.logger.Error("Failed to stop GRPC server: %v", err)
-		}
-
-		s.logger.Info("HTTP server stopping...")
-		if err = (*s.server.HTTP).Stop(ctx); err != nil {
-			s.logger.Error("Failed to stop HTTP server: %v", err)
-		}
 
-		s.logger.Info("Calendar stopped")
-	}()
-	wg.Wait()
-	return err
+	return []lifecycle.Component{
+		{
+			Name: "GRPC server",
+			Run:  (*s.server.GRPC).Start,
+			Stop: (*s.server.GRPC).Stop,
+		},
+		httpServer,
+	}
 }
@@ -0,0 +1,63 @@
+package retention
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+)
+
+// FileArchiver appends purged events to a JSON lines file.
+type FileArchiver struct {
+	mu   sync.Mutex
+	path string
+}
+
+type archiveRecord struct {
+	ArchivedAt time.Time     `json:"archivedAt"`
+	Policy     string        `json:"policy"`
+	Event      *entity.Event `json:"event"`
+}
+
+func NewFileArchiver(path string) *FileArchiver {
+	return &FileArchiver{path: path}
+}
+
+// Archive writes the events and syncs the file, so they survive a crash
+// before they are deleted.
+func (a *FileArchiver) Archive(_ context.Context, policy string, events entity.Events) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o750); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(a.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+
+	err = write(f, policy, events)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func write(f *os.File, policy string, events entity.Events) error {
+	now := time.Now().UTC()
+	encoder := json.NewEncoder(f)
+	for _, event := range events {
+		if err := encoder.Encode(archiveRecord{ArchivedAt: now, Policy: policy, Event: event}); err != nil {
+			return err
+		}
+	}
+
+	return f.Sync()
+}
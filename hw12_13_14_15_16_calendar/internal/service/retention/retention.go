@@ -0,0 +1,175 @@
+// Package retention purges old events according to per-user and per-calendar
+// policies, optionally archiving them first.
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+)
+
+var ErrNoArchiver = errors.New("policy archives events, but no archiver is set")
+
+// Policy decides how long events are kept. Zero UserID and empty CalendarID
+// match any event.
+type Policy struct {
+	Name       string
+	UserID     int
+	CalendarID string
+	// Keep is how long events are kept after they start, zero keeps them
+	// forever.
+	Keep    time.Duration
+	Archive bool
+}
+
+// specificity orders matching policies, a calendar policy beats a user one.
+func (p Policy) specificity() int {
+	score := 0
+	if p.CalendarID != "" {
+		score += 2
+	}
+	if p.UserID != 0 {
+		score++
+	}
+
+	return score
+}
+
+func (p Policy) matches(event *entity.Event) bool {
+	return (p.CalendarID == "" || p.CalendarID == event.CalendarID) &&
+		(p.UserID == 0 || p.UserID == event.UserID)
+}
+
+// Events is the part of the app the engine works with.
+type Events interface {
+	GetEventsOlderThan(ctx context.Context, t time.Time) (*entity.Events, error)
+	PurgeEvent(ctx context.Context, id string) error
+}
+
+// Archiver keeps purged events somewhere else.
+type Archiver interface {
+	Archive(ctx context.Context, policy string, events entity.Events) error
+}
+
+// Report tells what a run purged.
+type Report struct {
+	Started  time.Time
+	Checked  int
+	Archived int
+	// Purged counts purged events by policy name.
+	Purged map[string]int
+}
+
+func (r Report) Total() int {
+	total := 0
+	for _, count := range r.Purged {
+		total += count
+	}
+
+	return total
+}
+
+type Engine struct {
+	events   Events
+	archiver Archiver
+	policies []Policy
+}
+
+// New returns an engine applying policies over the default one, which matches
+// any event. The archiver may be nil if no policy archives.
+func New(events Events, archiver Archiver, defaultPolicy Policy, policies ...Policy) (*Engine, error) {
+	defaultPolicy.UserID, defaultPolicy.CalendarID = 0, ""
+	if defaultPolicy.Name == "" {
+		defaultPolicy.Name = "default"
+	}
+
+	all := make([]Policy, 0, len(policies)+1)
+	for _, policy := range slices.Concat(policies, []Policy{defaultPolicy}) {
+		if policy.Name == "" {
+			policy.Name = fmt.Sprintf("user %d calendar %q", policy.UserID, policy.CalendarID)
+		}
+		if policy.Archive && archiver == nil {
+			return nil, fmt.Errorf("%w: %s", ErrNoArchiver, policy.Name)
+		}
+		all = append(all, policy)
+	}
+
+	return &Engine{
+		events:   events,
+		archiver: archiver,
+		policies: all,
+	}, nil
+}
+
+// policyFor returns the most specific policy matching the event, the first
+// one of equally specific policies.
+func (e *Engine) policyFor(event *entity.Event) int {
+	best := -1
+	for i, policy := range e.policies {
+		if policy.matches(event) && (best < 0 || policy.specificity() > e.policies[best].specificity()) {
+			best = i
+		}
+	}
+
+	return best
+}
+
+// Run purges events expired at now. Events of a policy are archived before
+// any of them is deleted, so a failed archive keeps them in the storage.
+func (e *Engine) Run(ctx context.Context, now time.Time) (Report, error) {
+	report := Report{Started: now, Purged: make(map[string]int)}
+
+	// no event may be purged after the shortest period
+	var shortest time.Duration
+	for _, policy := range e.policies {
+		if policy.Keep > 0 && (shortest == 0 || policy.Keep < shortest) {
+			shortest = policy.Keep
+		}
+	}
+	if shortest == 0 {
+		return report, nil
+	}
+
+	candidates, err := e.events.GetEventsOlderThan(ctx, now.Add(-shortest))
+	if err != nil {
+		return report, err
+	}
+	report.Checked = len(*candidates)
+
+	expired := make([]entity.Events, len(e.policies))
+	for _, event := range *candidates {
+		i := e.policyFor(event)
+		keep := e.policies[i].Keep
+		if keep > 0 && event.DateTime.Before(now.Add(-keep)) {
+			expired[i] = append(expired[i], event)
+		}
+	}
+
+	for i, events := range expired {
+		if len(events) == 0 {
+			continue
+		}
+		policy := e.policies[i]
+
+		if policy.Archive {
+			if err = e.archiver.Archive(ctx, policy.Name, events); err != nil {
+				return report, fmt.Errorf("archive events of %s: %w", policy.Name, err)
+			}
+			report.Archived += len(events)
+		}
+
+		for _, event := range events {
+			err = e.events.PurgeEvent(ctx, event.ID)
+			if err != nil && !errors.Is(err, entity.ErrEventNotFound) {
+				return report, fmt.Errorf("purge event %s: %w", event.ID, err)
+			}
+			report.Purged[policy.Name]++
+		}
+	}
+
+	return report, nil
+}
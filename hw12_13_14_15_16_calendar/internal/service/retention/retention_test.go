@@ -0,0 +1,111 @@
+package retention
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+	memorystorage "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2025, 12, 5, 0, 0, 0, 0, time.UTC)
+
+func createApp(t *testing.T, events ...entity.Event) *app.App {
+	t.Helper()
+
+	st := memorystorage.New()
+	for _, event := range events {
+		_, err := st.Create(context.Background(), event)
+		require.NoError(t, err)
+	}
+
+	return app.New(logger.New(logger.Debug, io.Discard), st)
+}
+
+func titles(t *testing.T, a *app.App) []string {
+	t.Helper()
+
+	events, err := a.GetEventsOlderThan(context.Background(), now.AddDate(1, 0, 0))
+	require.NoError(t, err)
+
+	result := make([]string, 0, len(*events))
+	for _, event := range *events {
+		result = append(result, event.Title)
+	}
+
+	return result
+}
+
+type failingArchiver struct{}
+
+func (failingArchiver) Archive(context.Context, string, entity.Events) error {
+	return errors.New("disk is full")
+}
+
+func TestEngine(t *testing.T) {
+	ctx := context.Background()
+	a := createApp(t,
+		entity.Event{Title: "old", UserID: 1, DateTime: now.AddDate(0, 0, -40)},
+		entity.Event{Title: "recent", UserID: 1, DateTime: now.AddDate(0, 0, -20)},
+		entity.Event{Title: "kept user", UserID: 2, DateTime: now.AddDate(0, 0, -40)},
+		entity.Event{Title: "short calendar", UserID: 2, CalendarID: "c1", DateTime: now.AddDate(0, 0, -20)},
+		entity.Event{Title: "forever", UserID: 3, DateTime: now.AddDate(-5, 0, 0)},
+	)
+
+	archive := filepath.Join(t.TempDir(), "archive", "events.jsonl")
+	engine, err := New(a, NewFileArchiver(archive),
+		Policy{Keep: 30 * 24 * time.Hour},
+		Policy{Name: "user 2", UserID: 2, Keep: 60 * 24 * time.Hour},
+		Policy{Name: "calendar c1", CalendarID: "c1", Keep: 10 * 24 * time.Hour, Archive: true},
+		Policy{Name: "user 3", UserID: 3},
+	)
+	require.NoError(t, err)
+
+	report, err := engine.Run(ctx, now)
+	require.NoError(t, err)
+	require.Equal(t, 5, report.Checked)
+	require.Equal(t, 1, report.Archived)
+	require.Equal(t, map[string]int{"default": 1, "calendar c1": 1}, report.Purged)
+	require.Equal(t, 2, report.Total())
+	require.Equal(t, []string{"forever", "kept user", "recent"}, titles(t, a))
+
+	f, err := os.Open(archive)
+	require.NoError(t, err)
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	require.True(t, scanner.Scan())
+	var record archiveRecord
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+	require.Equal(t, "calendar c1", record.Policy)
+	require.Equal(t, "short calendar", record.Event.Title)
+	require.False(t, scanner.Scan())
+
+	// nothing more expires at the same time
+	report, err = engine.Run(ctx, now)
+	require.NoError(t, err)
+	require.Zero(t, report.Total())
+}
+
+func TestEngineArchiveFailure(t *testing.T) {
+	a := createApp(t, entity.Event{Title: "old", UserID: 1, DateTime: now.AddDate(-2, 0, 0)})
+
+	engine, err := New(a, failingArchiver{}, Policy{Keep: 24 * time.Hour, Archive: true})
+	require.NoError(t, err)
+
+	_, err = engine.Run(context.Background(), now)
+	require.Error(t, err)
+	require.Equal(t, []string{"old"}, titles(t, a))
+
+	_, err = New(a, nil, Policy{Keep: 24 * time.Hour, Archive: true})
+	require.ErrorIs(t, err, ErrNoArchiver)
+}
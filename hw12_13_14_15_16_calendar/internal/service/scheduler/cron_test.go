@@ -0,0 +1,48 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCron(t *testing.T) {
+	// friday
+	now := time.Date(2025, 12, 5, 10, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2025, 12, 5, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 12, 5, 10, 45, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2025, 12, 6, 3, 0, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2025, 12, 6, 10, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * 1-5", time.Date(2025, 12, 5, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2025, 12, 7, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 12, 7, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// either of restricted day fields fits
+		{"0 0 1 * 6", time.Date(2025, 12, 6, 0, 0, 0, 0, time.UTC)},
+		// as in cron, a day field starting with * makes both fields apply
+		{"0 0 */2 * 1", time.Date(2025, 12, 15, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr)
+			require.NoError(t, err)
+			require.Equal(t, tt.next, cron.Next(now))
+		})
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		_, err := ParseCron(expr)
+		require.ErrorIs(t, err, ErrInvalidCron, expr)
+	}
+
+	cron, err := ParseCron("0 0 30 2 *")
+	require.NoError(t, err)
+	require.True(t, cron.Next(now).IsZero())
+}
@@ -0,0 +1,147 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/identity"
+)
+
+// defaultDigestTemplate renders a digest when no template file is configured.
+const defaultDigestTemplate = `Your agenda for {{.Date}}:
+{{range .Events}}- {{.Time}} {{.Title}}{{if .Duration}} ({{.Duration}}){{end}}
+{{else}}No events today.
+{{end}}`
+
+// digestData is passed to the digest template, times are in the user's time
+// zone.
+type digestData struct {
+	UserID int
+	Date   string
+	Events []digestEvent
+}
+
+type digestEvent struct {
+	Time     string
+	Title    string
+	Duration string
+}
+
+// newDigestTemplate parses the template file, the default template is used if
+// path is empty.
+func newDigestTemplate(path string) (*template.Template, error) {
+	text := defaultDigestTemplate
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read digest template: %w", err)
+		}
+		text = string(data)
+	}
+
+	return template.New("digest").Parse(text)
+}
+
+// sendDigests publishes the day's digest of every subscribed user whose local
+// digest time has come and who hasn't got today's digest yet.
+func (s *Scheduler) sendDigests(ctx context.Context) error {
+	digests, err := s.app.GetDigests(ctx)
+	if err != nil {
+		return fmt.Errorf("get digests: %w", err)
+	}
+
+	now := time.Now()
+	var errs []error
+	for _, digest := range *digests {
+		location, err := time.LoadLocation(digest.TimeZone)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("digest of user %d: %w", digest.UserID, err))
+			continue
+		}
+
+		local := now.In(location)
+		date := local.Format(time.DateOnly)
+		if digest.LastSent == date || local.Format("15:04") < digest.Time {
+			continue
+		}
+
+		if err = s.sendDigest(ctx, digest.UserID, local); err != nil {
+			errs = append(errs, fmt.Errorf("digest of user %d: %w", digest.UserID, err))
+			continue
+		}
+		if err = s.app.MarkDigestSent(ctx, digest.UserID, date); err != nil {
+			errs = append(errs, fmt.Errorf("mark digest of user %d: %w", digest.UserID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// sendDigest gathers the user's events of the local day and publishes them to
+// the reminder queue.
+func (s *Scheduler) sendDigest(ctx context.Context, userID int, local time.Time) error {
+	msg, err := s.buildDigest(ctx, userID, local)
+	if err != nil {
+		return err
+	}
+
+	jsonMsg, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if err = s.qScheduler.ProduceType(ctx, entity.DigestMsgType, jsonMsg); err != nil {
+		return err
+	}
+
+	s.logger.Info("Digest for %s sent to user #%d", msg.Date, userID)
+
+	return nil
+}
+
+// buildDigest renders the digest of the user's local day, from local midnight
+// to the next one.
+func (s *Scheduler) buildDigest(ctx context.Context, userID int, local time.Time) (*entity.DigestMsg, error) {
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+
+	events, err := s.app.GetPeriodEvents(identity.WithUserID(ctx, userID), day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	// storages don't order period queries
+	sorted := slices.SortedFunc(slices.Values(*events), func(a, b *entity.Event) int {
+		return a.DateTime.Compare(b.DateTime)
+	})
+
+	data := digestData{UserID: userID, Date: day.Format(time.DateOnly)}
+	msg := &entity.DigestMsg{UserID: userID, Date: data.Date, Events: make([]entity.EventMsg, 0, len(sorted))}
+	for _, event := range sorted {
+		title := event.Title
+		if title == "" {
+			// free/busy access hides the details
+			title = "Busy"
+		}
+		data.Events = append(data.Events, digestEvent{
+			Time:     event.DateTime.In(local.Location()).Format("15:04"),
+			Title:    title,
+			Duration: event.Duration,
+		})
+		msg.Events = append(msg.Events, event.ToMsg())
+	}
+
+	var text strings.Builder
+	if err = s.digestTemplate.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("render digest: %w", err)
+	}
+	msg.Text = text.String()
+
+	return msg, nil
+}
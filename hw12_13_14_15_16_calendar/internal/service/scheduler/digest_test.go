@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/app"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/identity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+	memorystorage "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildDigest(t *testing.T) {
+	logg := logger.New(logger.Debug, io.Discard)
+	application := app.New(logg, memorystorage.New())
+	ctx := identity.WithUserID(context.Background(), 1)
+	day := time.Date(2025, 12, 5, 0, 0, 0, 0, time.UTC)
+
+	// the digest covers the Moscow day, which is 21:00 to 21:00 UTC
+	for _, event := range []entity.Event{
+		{Title: "night flight", DateTime: day.Add(-2 * time.Hour)},
+		{Title: "standup", DateTime: day.Add(7 * time.Hour), Duration: "00:15:00"},
+		{Title: "lunch", DateTime: day.Add(10 * time.Hour)},
+		{Title: "tomorrow", DateTime: day.Add(21*time.Hour + 30*time.Minute)},
+	} {
+		_, err := application.CreateEvent(ctx, event)
+		require.NoError(t, err)
+	}
+
+	s := New(application, logg, nil, nil)
+	var err error
+	s.digestTemplate, err = newDigestTemplate("")
+	require.NoError(t, err)
+
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+	local := time.Date(2025, 12, 5, 8, 0, 0, 0, moscow)
+
+	msg, err := s.buildDigest(context.Background(), 1, local)
+	require.NoError(t, err)
+	require.Equal(t, 1, msg.UserID)
+	require.Equal(t, "2025-12-05", msg.Date)
+	require.Len(t, msg.Events, 3)
+	require.Equal(
+		t,
+		"Your agenda for 2025-12-05:\n- 01:00 night flight\n- 10:00 standup (00:15:00)\n- 13:00 lunch\n",
+		msg.Text,
+	)
+
+	msg, err = s.buildDigest(context.Background(), 2, local)
+	require.NoError(t, err)
+	require.Equal(t, "Your agenda for 2025-12-05:\nNo events today.\n", msg.Text)
+
+	path := filepath.Join(t.TempDir(), "digest.tmpl")
+	require.NoError(t, os.WriteFile(path, []byte("{{len .Events}} events on {{.Date}}"), 0o640))
+	s.digestTemplate, err = newDigestTemplate(path)
+	require.NoError(t, err)
+	msg, err = s.buildDigest(context.Background(), 1, local)
+	require.NoError(t, err)
+	require.Equal(t, "3 events on 2025-12-05", msg.Text)
+}
@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
+)
+
+// Dispatch modes. In the leader mode only the leader sends reminders, in the
+// claim mode every worker of every replica claims batches of due reminders.
+const (
+	DispatchLeader = "leader"
+	DispatchClaim  = "claim"
+)
+
+// Metrics are published by expvar as "scheduler".
+var (
+	metrics = expvar.NewMap("scheduler")
+	backlog = new(expvar.Int)
+)
+
+func init() {
+	metrics.Set("remind_backlog", backlog)
+}
+
+// dispatchReminders claims and sends due reminders with every worker until
+// the backlog is drained. A claimed reminder stays leased until its ack marks
+// it as reminded, a failed send releases it for other workers.
+func (s *Scheduler) dispatchReminders(ctx context.Context) error {
+	cfg := config.GetFromContext(ctx)
+
+	var wg sync.WaitGroup
+	errs := make([]error, cfg.Scheduler.Dispatch.Workers)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.drain(ctx, cfg.Scheduler.Dispatch.BatchSize, cfg.Scheduler.Dispatch.Lease)
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// drain claims batches until one isn't full, a full batch means more
+// reminders may be due.
+func (s *Scheduler) drain(ctx context.Context, batchSize int, lease time.Duration) error {
+	for ctx.Err() == nil {
+		events, err := s.app.ClaimEventsForRemind(ctx, batchSize, lease)
+		if err != nil {
+			return fmt.Errorf("claim events for reminder: %w", err)
+		}
+		metrics.Add("reminds_claimed", int64(len(*events)))
+
+		var sendErr error
+		for _, event := range *events {
+			if err = s.sendEvent(ctx, event, s.qScheduler); err == nil {
+				metrics.Add("reminds_sent", 1)
+				continue
+			}
+
+			sendErr = fmt.Errorf("send event %s: %w", event.ID, err)
+			metrics.Add("reminds_failed", 1)
+			if err = s.app.ReleaseEventClaim(context.WithoutCancel(ctx), event.ID); err != nil {
+				s.logger.Error("Error releasing claimed event \"%s\": %v", event.ID, err)
+			}
+		}
+
+		// released reminders would be claimed again at once, so wait
+		if sendErr != nil {
+			return sendErr
+		}
+		if len(*events) < batchSize {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// measureBacklog updates the number of due reminders.
+func (s *Scheduler) measureBacklog(ctx context.Context) error {
+	count, err := s.app.CountEventsForRemind(ctx)
+	if err != nil {
+		return fmt.Errorf("count events for reminder: %w", err)
+	}
+	backlog.Set(int64(count))
+
+	return nil
+}
@@ -0,0 +1,283 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+)
+
+var (
+	ErrDuplicateJob  = errors.New("job is already registered")
+	ErrUnknownJob    = errors.New("job is not registered")
+	ErrJobNotRunning = errors.New("job is not running")
+	ErrJobStale      = errors.New("job is stale")
+)
+
+// Schedule tells when a job runs after t.
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// Every runs a job at multiples of the interval, so slow runs don't shift the
+// following ones.
+type Every time.Duration
+
+func (e Every) Next(t time.Time) time.Time {
+	return t.Truncate(time.Duration(e)).Add(time.Duration(e))
+}
+
+// ParseSchedule parses a cron expression or "@every <duration>".
+func ParseSchedule(expr string) (Schedule, error) {
+	if interval, ok := strings.CutPrefix(expr, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCron, expr)
+		}
+
+		return Every(d), nil
+	}
+
+	return ParseCron(expr)
+}
+
+// JobState is what the admin endpoint shows about a job.
+type JobState struct {
+	Name       string    `json:"name"`
+	Schedule   string    `json:"schedule"`
+	LeaderOnly bool      `json:"leaderOnly"`
+	Running    bool      `json:"running"`
+	Runs       int       `json:"runs"`
+	Failures   int       `json:"failures"`
+	Skipped    int       `json:"skipped"`
+	LastRun    time.Time `json:"lastRun"`
+	// LastDuration is in milliseconds.
+	LastDuration int64     `json:"lastDurationMs"`
+	LastError    string    `json:"lastError,omitempty"`
+	NextRun      time.Time `json:"nextRun"`
+}
+
+type job struct {
+	schedule   Schedule
+	leaderOnly bool
+	run        func(ctx context.Context) error
+	state      JobState
+	// rescheduled wakes the job's loop up to follow a new schedule.
+	rescheduled chan struct{}
+}
+
+// Jobs runs registered jobs on their schedules. Each job runs in its own
+// goroutine one run at a time, runs missed while the previous one is still
+// going are skipped.
+type Jobs struct {
+	logger logger.Logger
+
+	mu    sync.Mutex
+	jobs  map[string]*job
+	names []string
+}
+
+func NewJobs(logg logger.Logger) *Jobs {
+	return &Jobs{
+		logger: logg,
+		jobs:   make(map[string]*job),
+	}
+}
+
+// Register adds a job. Leader only jobs are run by RunLeader.
+func (j *Jobs) Register(name, expr string, leaderOnly bool, run func(ctx context.Context) error) error {
+	schedule, err := ParseSchedule(expr)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, ok := j.jobs[name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateJob, name)
+	}
+	j.jobs[name] = &job{
+		schedule:   schedule,
+		leaderOnly: leaderOnly,
+		run:        run,
+		state:      JobState{Name: name, Schedule: expr, LeaderOnly: leaderOnly},
+
+		rescheduled: make(chan struct{}, 1),
+	}
+	j.names = append(j.names, name)
+
+	return nil
+}
+
+// Reschedule changes the schedule of a registered job, a running loop follows
+// it from the next run.
+func (j *Jobs) Reschedule(name, expr string) error {
+	schedule, err := ParseSchedule(expr)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	jb, ok := j.jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownJob, name)
+	}
+	if jb.state.Schedule == expr {
+		return nil
+	}
+	jb.schedule = schedule
+	jb.state.Schedule = expr
+
+	select {
+	case jb.rescheduled <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Run runs jobs for every replica until ctx is done.
+func (j *Jobs) Run(ctx context.Context) {
+	j.runAll(ctx, false)
+}
+
+// RunLeader runs leader only jobs until ctx, usually the leadership, is done.
+func (j *Jobs) RunLeader(ctx context.Context) {
+	j.runAll(ctx, true)
+}
+
+func (j *Jobs) runAll(ctx context.Context, leaderOnly bool) {
+	var wg sync.WaitGroup
+
+	j.mu.Lock()
+	for _, name := range j.names {
+		if jb := j.jobs[name]; jb.leaderOnly == leaderOnly {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				j.loop(ctx, jb)
+			}()
+		}
+	}
+	j.mu.Unlock()
+
+	wg.Wait()
+}
+
+func (j *Jobs) loop(ctx context.Context, jb *job) {
+	defer j.update(jb, func(state *JobState) { state.NextRun = time.Time{} })
+
+	next := j.scheduleOf(jb).Next(time.Now())
+	for !next.IsZero() {
+		j.update(jb, func(state *JobState) { state.NextRun = next })
+
+		select {
+		case <-time.After(time.Until(next)):
+		case <-jb.rescheduled:
+			next = j.scheduleOf(jb).Next(time.Now())
+			continue
+		case <-ctx.Done():
+			return
+		}
+
+		j.runOnce(ctx, jb)
+
+		// runs which should have started while this one was running are skipped
+		schedule := j.scheduleOf(jb)
+		now := time.Now()
+		skipped := 0
+		for next = schedule.Next(next); !next.IsZero() && next.Before(now); next = schedule.Next(next) {
+			skipped++
+		}
+		if skipped > 0 {
+			j.update(jb, func(state *JobState) { state.Skipped += skipped })
+			j.logger.Warning("Job %s overran its schedule, %d runs skipped", jb.state.Name, skipped)
+		}
+	}
+}
+
+// runOnce runs the job to the end even if ctx is done meanwhile, so shutdown
+// or a lost leadership don't interrupt publishing half way.
+func (j *Jobs) runOnce(ctx context.Context, jb *job) {
+	started := time.Now()
+	j.update(jb, func(state *JobState) {
+		state.Running = true
+		state.LastRun = started.UTC()
+	})
+
+	err := jb.run(context.WithoutCancel(ctx))
+
+	j.update(jb, func(state *JobState) {
+		state.Running = false
+		state.Runs++
+		state.LastDuration = time.Since(started).Milliseconds()
+		state.LastError = ""
+		if err != nil {
+			state.Failures++
+			state.LastError = err.Error()
+		}
+	})
+	if err != nil {
+		j.logger.Error("Job %s failed: %v", jb.state.Name, err)
+	}
+}
+
+func (j *Jobs) scheduleOf(jb *job) Schedule {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return jb.schedule
+}
+
+func (j *Jobs) update(jb *job, change func(*JobState)) {
+	j.mu.Lock()
+	change(&jb.state)
+	j.mu.Unlock()
+}
+
+// Check reports jobs which should be running on every replica but aren't, and
+// jobs which are staleAfter late for their next run, being stuck in a run.
+func (j *Jobs) Check(now time.Time, staleAfter time.Duration) error {
+	var errs []error
+	for _, state := range j.State() {
+		switch {
+		case state.NextRun.IsZero() && !state.LeaderOnly:
+			errs = append(errs, fmt.Errorf("%w: %s", ErrJobNotRunning, state.Name))
+		case !state.NextRun.IsZero() && now.Sub(state.NextRun) > staleAfter:
+			errs = append(errs, fmt.Errorf("%w: %s is %s late", ErrJobStale, state.Name,
+				now.Sub(state.NextRun).Truncate(time.Second)))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// State returns states of jobs in registration order.
+func (j *Jobs) State() []JobState {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	states := make([]JobState, 0, len(j.names))
+	for _, name := range j.names {
+		states = append(states, j.jobs[name].state)
+	}
+
+	return states
+}
+
+// Handler writes job states as JSON.
+func (j *Jobs) Handler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(writer).Encode(j.State())
+	})
+}
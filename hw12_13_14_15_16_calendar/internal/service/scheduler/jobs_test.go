@@ -0,0 +1,159 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvery(t *testing.T) {
+	now := time.Date(2025, 12, 5, 10, 30, 15, 0, time.UTC)
+
+	require.Equal(t, time.Date(2025, 12, 5, 10, 30, 20, 0, time.UTC), Every(10*time.Second).Next(now))
+	require.Equal(t, time.Date(2025, 12, 5, 11, 0, 0, 0, time.UTC), Every(time.Hour).Next(now))
+
+	schedule, err := ParseSchedule("@every 10s")
+	require.NoError(t, err)
+	require.Equal(t, Every(10*time.Second), schedule)
+
+	_, err = ParseSchedule("@every never")
+	require.ErrorIs(t, err, ErrInvalidCron)
+}
+
+func TestJobs(t *testing.T) {
+	jobs := NewJobs(logger.New(logger.Debug, io.Discard))
+
+	var runs, running, overlaps atomic.Int32
+	slow := func(context.Context) error {
+		if running.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		defer running.Add(-1)
+		runs.Add(1)
+		time.Sleep(25 * time.Millisecond)
+
+		return nil
+	}
+	require.NoError(t, jobs.Register("slow", "@every 10ms", false, slow))
+	require.NoError(t, jobs.Register("failing", "@every 10ms", false, func(context.Context) error {
+		return errors.New("broken")
+	}))
+	require.NoError(t, jobs.Register("leader", "@every 10ms", true, func(context.Context) error {
+		return nil
+	}))
+	require.ErrorIs(t, jobs.Register("slow", "@every 1s", false, slow), ErrDuplicateJob)
+	require.ErrorIs(t, jobs.Register("bad", "* *", false, slow), ErrInvalidCron)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	jobs.Run(ctx)
+
+	require.Zero(t, overlaps.Load())
+	states := jobs.State()
+	require.Len(t, states, 3)
+
+	require.Equal(t, "slow", states[0].Name)
+	require.EqualValues(t, runs.Load(), states[0].Runs)
+	require.Positive(t, states[0].Runs)
+	require.Positive(t, states[0].Skipped)
+	require.False(t, states[0].Running)
+	require.True(t, states[0].NextRun.IsZero())
+
+	require.Equal(t, states[1].Runs, states[1].Failures)
+	require.Positive(t, states[1].Failures)
+	require.Equal(t, "broken", states[1].LastError)
+
+	// leader only jobs are left to RunLeader
+	require.Zero(t, states[2].Runs)
+
+	recorder := httptest.NewRecorder()
+	jobs.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/admin/jobs", nil))
+	var served []JobState
+	require.NoError(t, json.NewDecoder(recorder.Body).Decode(&served))
+	require.Len(t, served, 3)
+	require.Equal(t, "@every 10ms", served[0].Schedule)
+}
+
+func TestJobsReschedule(t *testing.T) {
+	jobs := NewJobs(logger.New(logger.Debug, io.Discard))
+
+	var runs atomic.Int32
+	require.NoError(t, jobs.Register("scan", "@every 1h", false, func(context.Context) error {
+		runs.Add(1)
+		return nil
+	}))
+	require.ErrorIs(t, jobs.Reschedule("missing", "@every 1s"), ErrUnknownJob)
+	require.ErrorIs(t, jobs.Reschedule("scan", "@every soon"), ErrInvalidCron)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = jobs.Reschedule("scan", "@every 10ms")
+	}()
+	jobs.Run(ctx)
+
+	require.Positive(t, runs.Load())
+	require.Equal(t, "@every 10ms", jobs.State()[0].Schedule)
+}
+
+func TestCheckConfig(t *testing.T) {
+	cfg, err := config.New(strings.NewReader("scheduler:\n  period: 5s\n"))
+	require.NoError(t, err)
+	require.NoError(t, CheckConfig(cfg))
+
+	cfg.Scheduler.Digest.Schedule = "* *"
+	cfg.Scheduler.Retention.ArchiveFile = ""
+	cfg.Scheduler.Retention.Archive = true
+
+	err = CheckConfig(cfg)
+	require.ErrorIs(t, err, config.ErrInvalid)
+	require.Contains(t, err.Error(), "scheduler.digest.schedule:")
+	require.Contains(t, err.Error(), "scheduler.retention:")
+}
+
+func TestJobsCheck(t *testing.T) {
+	jobs := NewJobs(logger.New(logger.Debug, io.Discard))
+
+	release := make(chan struct{})
+	require.NoError(t, jobs.Register("stuck", "@every 10ms", false, func(ctx context.Context) error {
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil
+	}))
+	require.NoError(t, jobs.Register("leader", "@every 10ms", true, func(context.Context) error {
+		return nil
+	}))
+
+	err := jobs.Check(time.Now(), time.Minute)
+	require.ErrorIs(t, err, ErrJobNotRunning)
+	require.NotContains(t, err.Error(), "leader", "leader only jobs don't run on every replica")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		jobs.Run(ctx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return !jobs.State()[0].NextRun.IsZero() }, time.Second, time.Millisecond)
+	require.NoError(t, jobs.Check(time.Now(), time.Minute))
+
+	require.Eventually(t, func() bool { return jobs.State()[0].Running }, time.Second, time.Millisecond)
+	require.ErrorIs(t, jobs.Check(time.Now().Add(time.Minute), 30*time.Second), ErrJobStale)
+
+	close(release)
+	cancel()
+	<-done
+}
@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/service/retention"
+)
+
+const day = 24 * time.Hour
+
+// newRetention builds the retention engine, RetentionPeriod is the default
+// policy.
+func newRetention(events retention.Events, cfg *config.Config) (*retention.Engine, error) {
+	settings := cfg.Scheduler.Retention
+
+	var archiver retention.Archiver
+	if settings.ArchiveFile != "" {
+		archiver = retention.NewFileArchiver(settings.ArchiveFile)
+	}
+
+	policies := make([]retention.Policy, 0, len(settings.Policies))
+	for _, policy := range settings.Policies {
+		policies = append(policies, retention.Policy{
+			Name:       policy.Name,
+			UserID:     policy.UserID,
+			CalendarID: policy.CalendarID,
+			Keep:       time.Duration(policy.KeepDays) * day,
+			Archive:    policy.Archive,
+		})
+	}
+
+	return retention.New(
+		events,
+		archiver,
+		retention.Policy{Keep: cfg.Scheduler.RetentionPeriod, Archive: settings.Archive},
+		policies...,
+	)
+}
+
+// purge applies retention policies and deletes expired idempotency keys once.
+func (s *Scheduler) purge(ctx context.Context) error {
+	now := time.Now().UTC()
+	report, err := s.retention.Load().Run(ctx, now)
+	s.logReport(report)
+
+	if keysErr := s.app.PurgeIdempotencyKeys(ctx, now); keysErr != nil {
+		err = errors.Join(err, fmt.Errorf("purge idempotency keys: %w", keysErr))
+	}
+
+	return err
+}
+
+func (s *Scheduler) logReport(report retention.Report) {
+	metrics.Add("retention_purged", int64(report.Total()))
+	metrics.Add("retention_archived", int64(report.Archived))
+
+	byPolicy := make([]string, 0, len(report.Purged))
+	for _, name := range slices.Sorted(maps.Keys(report.Purged)) {
+		byPolicy = append(byPolicy, name+": "+strconv.Itoa(report.Purged[name]))
+	}
+
+	s.logger.Info("Retention purged %d of %d checked events, %d archived (%s)",
+		report.Total(), report.Checked, report.Archived, strings.Join(byPolicy, ", "))
+}
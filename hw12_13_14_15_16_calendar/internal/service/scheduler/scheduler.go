@@ -3,45 +3,90 @@ package scheduler
 import (
 	"context"
 	"encoding/json"
-	"time"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"text/template"
 
+	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/app"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/leader"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/queue"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/service/retention"
 )
 
 type Scheduler struct {
 	app      *app.App
 	logger   logger.Logger
 	qManager *queue.RabbitManager
+	elector  leader.Elector
+	isLeader atomic.Bool
+
+	jobs           *Jobs
+	retention      atomic.Pointer[retention.Engine]
+	digestTemplate *template.Template
+	qScheduler     *queue.RabbitQueueConnection
 }
 
 func New(
 	app *app.App,
 	logg logger.Logger,
 	qManager *queue.RabbitManager,
+	elector leader.Elector,
 ) *Scheduler {
 	return &Scheduler{
 		app:      app,
 		qManager: qManager,
 		logger:   logg,
+		elector:  elector,
+		jobs:     NewJobs(logg),
 	}
 }
 
+// Jobs returns the scheduler's jobs, they are registered by Run.
+func (s *Scheduler) Jobs() *Jobs {
+	return s.jobs
+}
+
+// IsLeader reports whether this replica currently runs the remind and
+// retention loops.
+func (s *Scheduler) IsLeader() bool {
+	return s.isLeader.Load()
+}
+
 func (s *Scheduler) Run(ctx context.Context) error {
 	cfg := config.GetFromContext(ctx)
 	if cfg == nil {
 		return config.ErrNoConfigInContext
 	}
 
-	qScheduler, err := s.qManager.CreateQueue(cfg.Scheduler.Queue)
+	engine, err := newRetention(s.app, cfg)
+	if err != nil {
+		s.logger.Error("Error configuring retention: %v", err)
+		return err
+	}
+	s.retention.Store(engine)
+
+	if s.digestTemplate, err = newDigestTemplate(cfg.Scheduler.Digest.Template); err != nil {
+		s.logger.Error("Error configuring digest: %v", err)
+		return err
+	}
+
+	s.qScheduler, err = s.qManager.CreateQueue(cfg.Scheduler.Queue)
 	if err != nil {
 		s.logger.Error("Error declaring scheduler queue: %v", err)
 		return err
 	}
 
+	if err = s.registerJobs(cfg); err != nil {
+		s.logger.Error("Error registering scheduler jobs: %v", err)
+		return err
+	}
+
 	qSchedulerAck, err := s.qManager.CreateQueue(cfg.Scheduler.Queue + "_ACK")
 	if err != nil {
 		s.logger.Error("Error declaring scheduler_ack queue: %v", err)
@@ -54,74 +99,188 @@ func (s *Scheduler) Run(ctx context.Context) error {
 		return err
 	}
 
+	// acks are handled by every replica, marking an event twice is harmless
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	wg.Add(2)
 	go func() {
-		for {
-			select {
-			case msg, ok := <-channel:
-				if !ok {
-					return
-				}
-				eventMsg := entity.EventMsg{}
-				err := json.Unmarshal(msg.Body, &eventMsg)
-				if err != nil {
-					s.logger.Error("Error reading msg from channel: &v", err)
-					continue
-				}
-				err = s.app.MarkEventAsReminded(eventMsg.ID)
-				if err != nil {
-					s.logger.Error("Error ack sent message: %v", err)
-					continue
-				}
-				s.logger.Info("Event \"%s\" marked as sent", eventMsg.ID)
-			case <-ctx.Done():
-				return
-			}
-		}
+		defer wg.Done()
+		s.consumeAcks(ctx, channel)
+	}()
+	go func() {
+		defer wg.Done()
+		s.jobs.Run(ctx)
 	}()
+
+	for {
+		// campaign fails only when ctx is done
+		leaderCtx, err := s.elector.Campaign(ctx)
+		if err != nil {
+			s.logger.Info("Scheduler stopped.")
+			return nil
+		}
+
+		s.isLeader.Store(true)
+		s.logger.Info("Became the scheduler leader.")
+		s.jobs.RunLeader(leaderCtx)
+		s.isLeader.Store(false)
+
+		resignCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.Scheduler.Leader.RetryPeriod)
+		if err := s.elector.Resign(resignCtx); err != nil {
+			s.logger.Error("Error resigning scheduler leadership: %v", err)
+		}
+		cancel()
+
+		if ctx.Err() != nil {
+			s.logger.Info("Scheduler stopped.")
+			return nil
+		}
+		s.logger.Warning("Lost the scheduler leadership, waiting to be elected again.")
+	}
+}
+
+// consumeAcks marks events acked by the sender as reminded until ctx is done.
+// A received ack is handled even if shutdown begins meanwhile.
+func (s *Scheduler) consumeAcks(ctx context.Context, channel <-chan amqp.Delivery) {
 	for {
-		s.logger.Info("Looking for events to remind...")
 		select {
-		case <-time.After(cfg.Scheduler.Period):
-			events := s.getEvents()
-			if events != nil {
-				s.sendEvents(events, qScheduler)
+		case msg, ok := <-channel:
+			if !ok {
+				return
+			}
+			s.markReminded(context.WithoutCancel(ctx), msg.Body)
+			if err := msg.Ack(false); err != nil {
+				s.logger.Error("Error acking msg: %v", err)
 			}
 		case <-ctx.Done():
-			s.logger.Info("Scheduler stopped.")
-			return nil
+			return
 		}
 	}
 }
 
-func (s *Scheduler) getEvents() *entity.Events {
-	events, err := s.app.GetEventsForRemind()
+func (s *Scheduler) markReminded(ctx context.Context, body []byte) {
+	eventMsg := entity.EventMsg{}
+	err := json.Unmarshal(body, &eventMsg)
+	if err != nil {
+		s.logger.Error("Error reading msg from channel: %v", err)
+		return
+	}
+	err = s.app.MarkEventAsReminded(ctx, eventMsg.ID)
+	if err != nil {
+		s.logger.Error("Error ack sent message: %v", err)
+		return
+	}
+	s.logger.Info("Event \"%s\" marked as sent", eventMsg.ID)
+}
+
+// Reload applies the scheduler period and retention settings of a reloaded
+// config to running jobs. Nothing is changed if they are invalid.
+func (s *Scheduler) Reload(cfg *config.Config) error {
+	engine, err := newRetention(s.app, cfg)
 	if err != nil {
-		s.logger.Error("Error getting events for reminder: %v", err)
-		return nil
+		return err
+	}
+	if _, err = ParseSchedule(cfg.Scheduler.Retention.Schedule); err != nil {
+		return err
+	}
+
+	s.retention.Store(engine)
+	every := periodSchedule(cfg)
+
+	return errors.Join(
+		s.jobs.Reschedule(jobReminderScan, every),
+		s.jobs.Reschedule(jobRemindBacklog, every),
+		s.jobs.Reschedule(jobRetentionPurge, cfg.Scheduler.Retention.Schedule),
+	)
+}
+
+// CheckConfig checks scheduler settings the config package can't: job
+// schedules, retention policies and the digest template.
+func CheckConfig(cfg *config.Config) error {
+	var errs []error
+	for _, schedule := range []struct{ field, expr string }{
+		{"scheduler.period", periodSchedule(cfg)},
+		{"scheduler.retention.schedule", cfg.Scheduler.Retention.Schedule},
+		{"scheduler.digest.schedule", cfg.Scheduler.Digest.Schedule},
+	} {
+		if _, err := ParseSchedule(schedule.expr); err != nil {
+			errs = append(errs, config.FieldError{Field: schedule.field, Message: err.Error()})
+		}
+	}
+	if _, err := newRetention(nil, cfg); err != nil {
+		errs = append(errs, config.FieldError{Field: "scheduler.retention", Message: err.Error()})
+	}
+	if _, err := newDigestTemplate(cfg.Scheduler.Digest.Template); err != nil {
+		errs = append(errs, config.FieldError{Field: "scheduler.digest.template", Message: err.Error()})
+	}
+
+	return errors.Join(errs...)
+}
+
+const (
+	jobReminderScan   = "reminder-scan"
+	jobRetentionPurge = "retention-purge"
+	jobDailyDigest    = "daily-digest"
+	jobRemindBacklog  = "remind-backlog"
+)
+
+func periodSchedule(cfg *config.Config) string {
+	return "@every " + cfg.Scheduler.Period.String()
+}
+
+// registerJobs registers the reminder scan, leader only unless reminders are
+// claimed by every replica, the retention purge, the daily digest and the
+// backlog measurement.
+func (s *Scheduler) registerJobs(cfg *config.Config) error {
+	every := periodSchedule(cfg)
+
+	scan, scanLeaderOnly := s.scanReminders, true
+	if cfg.Scheduler.Dispatch.Mode == DispatchClaim {
+		scan, scanLeaderOnly = s.dispatchReminders, false
 	}
 
-	if len(*events) == 0 {
-		return nil
+	return errors.Join(
+		s.jobs.Register(jobReminderScan, every, scanLeaderOnly, scan),
+		s.jobs.Register(jobRetentionPurge, cfg.Scheduler.Retention.Schedule, true, s.purge),
+		s.jobs.Register(jobDailyDigest, cfg.Scheduler.Digest.Schedule, true, s.sendDigests),
+		s.jobs.Register(jobRemindBacklog, every, false, s.measureBacklog),
+	)
+}
+
+// scanReminders sends every due reminder until it is acked.
+func (s *Scheduler) scanReminders(ctx context.Context) error {
+	s.logger.Info("Looking for events to remind...")
+
+	events, err := s.app.GetEventsForRemind(ctx)
+	if err != nil {
+		return fmt.Errorf("get events for reminder: %w", err)
 	}
 
-	return events
+	s.sendEvents(ctx, events, s.qScheduler)
+
+	return nil
 }
 
-func (s *Scheduler) sendEvents(events *entity.Events, queueSend *queue.RabbitQueueConnection) {
+func (s *Scheduler) sendEvents(ctx context.Context, events *entity.Events, queueSend *queue.RabbitQueueConnection) {
 	for _, event := range *events {
-		eventMsg := event.ToMsg()
-		jsonMsg, err := json.Marshal(eventMsg)
-		if err != nil {
+		if err := s.sendEvent(ctx, event, queueSend); err != nil {
 			s.logger.Error("Error sending msg to RabbitMQ: %v", err)
-			continue
 		}
+	}
+}
 
-		err = queueSend.Produce(jsonMsg)
-		if err != nil {
-			s.logger.Error("Error sending msg to RabbitMQ: %v", err)
-			continue
-		}
+func (s *Scheduler) sendEvent(ctx context.Context, event *entity.Event, queueSend *queue.RabbitQueueConnection) error {
+	jsonMsg, err := json.Marshal(event.ToMsg())
+	if err != nil {
+		return err
+	}
 
-		s.logger.Info("Event \"%s\" sent", event.ID)
+	if err = queueSend.Produce(ctx, jsonMsg); err != nil {
+		return err
 	}
+
+	s.logger.Info("Event \"%s\" sent", event.ID)
+
+	return nil
 }
@@ -53,30 +53,64 @@ func (s *Sender) Run(ctx context.Context) error {
 
 		select {
 		case <-ctx.Done():
+			// unacked deliveries are redelivered once the queue is closed
 			s.logger.Info("Sender stopped.")
 			return nil
-		case msg := <-channel:
-			eventMsg := entity.EventMsg{}
-
-			err := json.Unmarshal(msg.Body, &eventMsg)
-			if err != nil {
-				s.logger.Error("Error reading msg from channel: " + err.Error())
-
-				continue
+		case msg, ok := <-channel:
+			// the channel is closed only when the queue manager is closed
+			if !ok {
+				s.logger.Info("Sender stopped, queue is closed.")
+				return nil
 			}
 
-			s.logger.Info(fmt.Sprintf(
-				"Sending reminder about \"%s\" event to #%d user. Event time: %s.",
-				eventMsg.Title,
-				eventMsg.UserID,
-				eventMsg.DateTime.Format(time.RFC822),
-			))
-
-			err = qSchedulerAck.Produce(msg.Body)
-			if err != nil {
-				s.logger.Error("Error sending msg to RabbitMQ: " + err.Error())
-				continue
+			// a received message is sent and acked even if shutdown begins
+			s.handle(context.WithoutCancel(ctx), qSchedulerAck, msg.Type, msg.Body)
+			if err = msg.Ack(false); err != nil {
+				s.logger.Error("Error acking msg: %v", err)
 			}
 		}
 	}
 }
+
+func (s *Sender) handle(ctx context.Context, qSchedulerAck *queue.RabbitQueueConnection, msgType string, body []byte) {
+	if msgType == entity.DigestMsgType {
+		s.sendDigest(body)
+
+		return
+	}
+
+	eventMsg := entity.EventMsg{}
+
+	err := json.Unmarshal(body, &eventMsg)
+	if err != nil {
+		s.logger.Error("Error reading msg from channel: " + err.Error())
+
+		return
+	}
+
+	s.logger.Info(fmt.Sprintf(
+		"Sending reminder about \"%s\" event to #%d user. Event time: %s.",
+		eventMsg.Title,
+		eventMsg.UserID,
+		eventMsg.DateTime.Format(time.RFC822),
+	))
+
+	err = qSchedulerAck.Produce(ctx, body)
+	if err != nil {
+		s.logger.Error("Error sending msg to RabbitMQ: " + err.Error())
+	}
+}
+
+// sendDigest delivers a daily agenda. Digests aren't acked, the scheduler
+// marks them as sent when they are published.
+func (s *Sender) sendDigest(body []byte) {
+	digestMsg := entity.DigestMsg{}
+
+	if err := json.Unmarshal(body, &digestMsg); err != nil {
+		s.logger.Error("Error reading digest from channel: " + err.Error())
+
+		return
+	}
+
+	s.logger.Info(fmt.Sprintf("Sending digest for %s to #%d user:\n%s", digestMsg.Date, digestMsg.UserID, digestMsg.Text))
+}
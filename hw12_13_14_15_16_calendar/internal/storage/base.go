@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage/file"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage/memory"
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage/sql"
 )
@@ -15,6 +16,7 @@ type Type string
 const (
 	Memory Type = "memory"
 	DB     Type = "db"
+	File   Type = "file"
 )
 
 var ErrInvalidStorageValue = errors.New("invalid storage value in config")
@@ -22,31 +24,103 @@ var ErrInvalidStorageValue = errors.New("invalid storage value in config")
 type ConnectionStorage interface {
 	Connect(ctx context.Context) error
 	Close(ctx context.Context) error
+	// Ping checks the storage is connected and reachable.
+	Ping(ctx context.Context) error
 }
 
 type Storage interface {
 	ConnectionStorage
 
-	Create(entity.Event) (string, error)
-	Update(entity.Event) error
-	Delete(string) error
-	GetAll() (*entity.Events, error)
-	GetByID(string) (*entity.Event, error)
-	GetForPeriod(time.Time, time.Time) (*entity.Events, error)
-	GetForTime(time.Time) (*entity.Event, error)
-	GetForRemind() (*entity.Events, error)
-	MarkAsReminded(string) error
-	DeleteOlderThan(time.Time) error
+	Create(context.Context, entity.Event) (string, error)
+	Update(context.Context, entity.Event) error
+	Delete(context.Context, string) error
+	GetAll(context.Context) (*entity.Events, error)
+	GetByID(context.Context, string) (*entity.Event, error)
+	// GetByUID returns the event of the calendar having the iCalendar UID.
+	GetByUID(ctx context.Context, calendarID, uid string) (*entity.Event, error)
+	// GetForPeriod returns events of [start, end) having any of passed tags.
+	GetForPeriod(context.Context, time.Time, time.Time, ...string) (*entity.Events, error)
+	// GetForScope is GetForPeriod limited to events of the scope.
+	GetForScope(context.Context, entity.Scope, time.Time, time.Time, ...string) (*entity.Events, error)
+	// Search returns up to limit events of the scope in [start, end) matching
+	// every word and quoted phrase of the query, best matches first. Zero times
+	// leave the period open, zero limit returns every match.
+	Search(
+		ctx context.Context,
+		scope entity.Scope,
+		query string,
+		start, end time.Time,
+		limit int,
+		tags ...string,
+	) (*entity.Events, error)
+	// GetForTime returns an event of the scope starting at the time.
+	GetForTime(context.Context, entity.Scope, time.Time) (*entity.Event, error)
+	GetForRemind(context.Context) (*entity.Events, error)
+	// ClaimForRemind leases up to limit due reminders for the lease duration,
+	// so concurrent schedulers don't claim the same ones.
+	ClaimForRemind(ctx context.Context, limit int, lease time.Duration) (*entity.Events, error)
+	ReleaseClaim(context.Context, string) error
+	CountForRemind(context.Context) (int, error)
+	// CountUserEvents returns the number of all events the user has created
+	// and of those created since the time.
+	CountUserEvents(ctx context.Context, userID int, since time.Time) (total int, recent int, err error)
+	MarkAsReminded(context.Context, string) error
+	// GetOlderThan returns events starting before t, oldest first.
+	GetOlderThan(context.Context, time.Time) (*entity.Events, error)
+	DeleteOlderThan(context.Context, time.Time) error
+
+	SaveTag(context.Context, entity.Tag) error
+	// DeleteTag removes the tag from the user's catalog and events.
+	DeleteTag(context.Context, int, string) error
+	GetTags(context.Context, int) (*entity.Tags, error)
+	// SaveDigest subscribes the user to the digest or changes the subscription,
+	// the last sent date is kept.
+	SaveDigest(context.Context, entity.Digest) error
+	DeleteDigest(context.Context, int) error
+	GetDigest(context.Context, int) (*entity.Digest, error)
+	// GetDigests returns every subscription ordered by user.
+	GetDigests(context.Context) (*entity.Digests, error)
+	MarkDigestSent(ctx context.Context, userID int, date string) error
+
+	// CreateIdempotencyKey fails with entity.ErrIdempotencyKeyExists if the
+	// user has the key already.
+	CreateIdempotencyKey(context.Context, entity.IdempotencyKey) error
+	// SaveIdempotencyKey replaces the user's key.
+	SaveIdempotencyKey(context.Context, entity.IdempotencyKey) error
+	GetIdempotencyKey(ctx context.Context, userID int, key string) (*entity.IdempotencyKey, error)
+	// DeleteIdempotencyKey deletes the key only if it is stored with the hash
+	// and creation time given, so a key replaced concurrently is kept. It fails
+	// with entity.ErrIdempotencyKeyNotFound otherwise.
+	DeleteIdempotencyKey(context.Context, entity.IdempotencyKey) error
+	// DeleteIdempotencyKeysBefore removes keys created before t.
+	DeleteIdempotencyKeysBefore(context.Context, time.Time) error
+
+	CreateCalendar(context.Context, entity.Calendar) (string, error)
+	UpdateCalendar(context.Context, entity.Calendar) error
+	// DeleteCalendar removes the calendar with its events and shares.
+	DeleteCalendar(context.Context, string) error
+	GetCalendar(context.Context, string) (*entity.Calendar, error)
+	// GetCalendars returns calendars owned by or shared with the user, oldest
+	// first.
+	GetCalendars(context.Context, int) (*entity.Calendars, error)
+	// GetRoles returns the user's roles on calendars owned by or shared with
+	// them by calendar ID.
+	GetRoles(context.Context, int) (map[string]entity.Role, error)
+	// SaveShare grants the role to the user or changes an existing grant.
+	SaveShare(context.Context, entity.Share) error
+	DeleteShare(context.Context, string, int) error
+	GetShares(context.Context, string) (*entity.Shares, error)
 }
 
 func Get(storageType string) (Storage, error) {
-	if Type(storageType) != Memory && Type(storageType) != DB {
-		return nil, ErrInvalidStorageValue
-	}
-
-	if Type(storageType) == Memory {
+	switch Type(storageType) {
+	case Memory:
 		return memorystorage.New(), nil
+	case DB:
+		return sqlstorage.New(), nil
+	case File:
+		return filestorage.New(), nil
+	default:
+		return nil, ErrInvalidStorageValue
 	}
-
-	return sqlstorage.New(), nil
 }
@@ -0,0 +1,431 @@
+package cachestorage
+
+import (
+	"container/list"
+	"context"
+	"expvar"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage"
+)
+
+const day = time.Hour * 24
+
+// maxPeriodDays limits the length of cached periods, longer requests always go
+// to the backend.
+const maxPeriodDays = 62
+
+// Backend is the storage wrapped by the cache.
+type Backend = storage.Storage
+
+type Options struct {
+	TTL  time.Duration
+	Size int
+}
+
+type Stats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+}
+
+// period is a GetForPeriod or GetForScope request, tags holds the sorted tag
+// filter joined by zero bytes, scope is empty for GetForPeriod.
+type period struct {
+	start, end int64
+	tags       string
+	scope      string
+}
+
+type item struct {
+	id      string
+	period  period
+	event   *entity.Event
+	events  entity.Events
+	expires time.Time
+}
+
+// Storage is a read-through cache in front of Backend. Events are cached by
+// ID, GetForPeriod and GetForScope results are cached per requested period and
+// indexed by the days they cover, so that writes drop only the periods they
+// affect.
+type Storage struct {
+	Backend
+
+	mu      sync.Mutex
+	options Options
+	lru     *list.List
+	byID    map[string]*list.Element
+	periods map[period]*list.Element
+	days    map[int64]map[period]struct{}
+	events  map[string]map[period]struct{}
+	now     func() time.Time
+	// gen changes on every write, so results read from Backend concurrently
+	// with a write are not cached.
+	gen uint64
+
+	hits, misses, evictions atomic.Uint64
+}
+
+func New(backend Backend, options Options) *Storage {
+	s := &Storage{
+		Backend: backend,
+		options: options,
+		now:     time.Now,
+	}
+	s.reset()
+
+	return s
+}
+
+// Var returns the current Stats as an expvar variable.
+func (s *Storage) Var() expvar.Var {
+	return expvar.Func(func() any { return s.Stats() })
+}
+
+func (s *Storage) Stats() Stats {
+	s.mu.Lock()
+	entries := s.lru.Len()
+	s.mu.Unlock()
+
+	return Stats{
+		Hits:      s.hits.Load(),
+		Misses:    s.misses.Load(),
+		Evictions: s.evictions.Load(),
+		Entries:   entries,
+	}
+}
+
+func (s *Storage) GetByID(ctx context.Context, id string) (*entity.Event, error) {
+	s.mu.Lock()
+	if el, ok := s.byID[id]; ok && s.alive(el) {
+		s.lru.MoveToFront(el)
+		event := copyEvent(el.Value.(*item).event)
+		s.mu.Unlock()
+		s.hits.Add(1)
+
+		return event, nil
+	}
+	gen := s.gen
+	s.mu.Unlock()
+	s.misses.Add(1)
+
+	event, err := s.Backend.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cached := copyEvent(event)
+	s.mu.Lock()
+	if gen == s.gen {
+		s.put(&item{id: id, event: cached})
+	}
+	s.mu.Unlock()
+
+	return event, nil
+}
+
+func (s *Storage) GetForPeriod(
+	ctx context.Context,
+	start time.Time,
+	end time.Time,
+	tags ...string,
+) (*entity.Events, error) {
+	key := period{start: start.UnixNano(), end: end.UnixNano(), tags: tagsKey(tags)}
+
+	return s.getPeriod(key, end.Sub(start), func() (*entity.Events, error) {
+		return s.Backend.GetForPeriod(ctx, start, end, tags...)
+	})
+}
+
+func (s *Storage) GetForScope(
+	ctx context.Context,
+	scope entity.Scope,
+	start time.Time,
+	end time.Time,
+	tags ...string,
+) (*entity.Events, error) {
+	key := period{start: start.UnixNano(), end: end.UnixNano(), tags: tagsKey(tags), scope: scopeKey(scope)}
+
+	return s.getPeriod(key, end.Sub(start), func() (*entity.Events, error) {
+		return s.Backend.GetForScope(ctx, scope, start, end, tags...)
+	})
+}
+
+func (s *Storage) getPeriod(
+	key period,
+	length time.Duration,
+	load func() (*entity.Events, error),
+) (*entity.Events, error) {
+	s.mu.Lock()
+	if el, ok := s.periods[key]; ok && s.alive(el) {
+		s.lru.MoveToFront(el)
+		events := copyEvents(el.Value.(*item).events)
+		s.mu.Unlock()
+		s.hits.Add(1)
+
+		return &events, nil
+	}
+	gen := s.gen
+	s.mu.Unlock()
+	s.misses.Add(1)
+
+	events, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	if length <= maxPeriodDays*day {
+		s.mu.Lock()
+		if gen == s.gen {
+			s.put(&item{period: key, events: copyEvents(*events)})
+		}
+		s.mu.Unlock()
+	}
+
+	return events, nil
+}
+
+func (s *Storage) Create(ctx context.Context, event entity.Event) (string, error) {
+	id, err := s.Backend.Create(ctx, event)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	s.dropPeriodsAt(event.DateTime)
+	s.mu.Unlock()
+
+	return id, nil
+}
+
+func (s *Storage) Update(ctx context.Context, event entity.Event) error {
+	err := s.Backend.Update(ctx, event)
+
+	s.mu.Lock()
+	s.dropEvent(event.ID)
+	s.dropPeriodsAt(event.DateTime)
+	s.mu.Unlock()
+
+	return err
+}
+
+func (s *Storage) Delete(ctx context.Context, id string) error {
+	err := s.Backend.Delete(ctx, id)
+
+	s.mu.Lock()
+	s.dropEvent(id)
+	s.mu.Unlock()
+
+	return err
+}
+
+func (s *Storage) MarkAsReminded(ctx context.Context, id string) error {
+	err := s.Backend.MarkAsReminded(ctx, id)
+
+	s.mu.Lock()
+	s.dropEvent(id)
+	s.mu.Unlock()
+
+	return err
+}
+
+func (s *Storage) DeleteOlderThan(ctx context.Context, t time.Time) error {
+	err := s.Backend.DeleteOlderThan(ctx, t)
+
+	s.mu.Lock()
+	s.reset()
+	s.mu.Unlock()
+
+	return err
+}
+
+// DeleteTag drops cached events the tag is removed from, with the periods
+// containing them.
+func (s *Storage) DeleteTag(ctx context.Context, userID int, name string) error {
+	err := s.Backend.DeleteTag(ctx, userID, name)
+
+	s.mu.Lock()
+	s.dropMatching(func(event *entity.Event) bool {
+		return event.UserID == userID && slices.Contains(event.Tags, name)
+	})
+	s.mu.Unlock()
+
+	return err
+}
+
+// DeleteCalendar drops cached events of the calendar, with the periods
+// containing them.
+func (s *Storage) DeleteCalendar(ctx context.Context, id string) error {
+	err := s.Backend.DeleteCalendar(ctx, id)
+
+	s.mu.Lock()
+	s.dropMatching(func(event *entity.Event) bool { return event.CalendarID == id })
+	s.mu.Unlock()
+
+	return err
+}
+
+func (s *Storage) Close(ctx context.Context) error {
+	s.mu.Lock()
+	s.reset()
+	s.mu.Unlock()
+
+	return s.Backend.Close(ctx)
+}
+
+func (s *Storage) reset() {
+	s.gen++
+	s.lru = list.New()
+	s.byID = make(map[string]*list.Element)
+	s.periods = make(map[period]*list.Element)
+	s.days = make(map[int64]map[period]struct{})
+	s.events = make(map[string]map[period]struct{})
+}
+
+// alive reports whether el is not expired, expired elements are removed.
+func (s *Storage) alive(el *list.Element) bool {
+	if s.options.TTL <= 0 || s.now().Before(el.Value.(*item).expires) {
+		return true
+	}
+	s.remove(el)
+
+	return false
+}
+
+func (s *Storage) put(it *item) {
+	it.expires = s.now().Add(s.options.TTL)
+
+	if it.event != nil {
+		if el, ok := s.byID[it.id]; ok {
+			s.remove(el)
+		}
+		s.byID[it.id] = s.lru.PushFront(it)
+	} else {
+		if el, ok := s.periods[it.period]; ok {
+			s.remove(el)
+		}
+		s.periods[it.period] = s.lru.PushFront(it)
+		for d := it.period.start / int64(day); d <= it.period.end/int64(day); d++ {
+			index(s.days, d, it.period)
+		}
+		for _, event := range it.events {
+			index(s.events, event.ID, it.period)
+		}
+	}
+
+	for s.options.Size > 0 && s.lru.Len() > s.options.Size {
+		s.remove(s.lru.Back())
+		s.evictions.Add(1)
+	}
+}
+
+func (s *Storage) remove(el *list.Element) {
+	it := s.lru.Remove(el).(*item)
+	if it.event != nil {
+		delete(s.byID, it.id)
+		return
+	}
+
+	delete(s.periods, it.period)
+	for d := it.period.start / int64(day); d <= it.period.end/int64(day); d++ {
+		unindex(s.days, d, it.period)
+	}
+	for _, event := range it.events {
+		unindex(s.events, event.ID, it.period)
+	}
+}
+
+// dropEvent removes the event and every cached period containing it.
+func (s *Storage) dropEvent(id string) {
+	s.gen++
+	if el, ok := s.byID[id]; ok {
+		s.dropPeriodsAt(el.Value.(*item).event.DateTime)
+		s.remove(el)
+	}
+	for p := range s.events[id] {
+		if el, ok := s.periods[p]; ok {
+			s.remove(el)
+		}
+	}
+}
+
+// dropMatching removes cached events matching and every cached period
+// containing one of them.
+func (s *Storage) dropMatching(match func(*entity.Event) bool) {
+	s.gen++
+	for el := s.lru.Front(); el != nil; {
+		next := el.Next()
+		it := el.Value.(*item)
+		if it.event != nil && match(it.event) || slices.ContainsFunc(it.events, match) {
+			s.remove(el)
+		}
+		el = next
+	}
+}
+
+// dropPeriodsAt removes cached periods that may include an event at t.
+func (s *Storage) dropPeriodsAt(t time.Time) {
+	s.gen++
+	ts := t.UnixNano()
+	for p := range s.days[ts/int64(day)] {
+		if p.start <= ts && ts <= p.end {
+			s.remove(s.periods[p])
+		}
+	}
+}
+
+func index[K comparable](m map[K]map[period]struct{}, k K, p period) {
+	if m[k] == nil {
+		m[k] = make(map[period]struct{})
+	}
+	m[k][p] = struct{}{}
+}
+
+func unindex[K comparable](m map[K]map[period]struct{}, k K, p period) {
+	delete(m[k], p)
+	if len(m[k]) == 0 {
+		delete(m, k)
+	}
+}
+
+func tagsKey(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	sorted := slices.Clone(tags)
+	slices.Sort(sorted)
+
+	return strings.Join(slices.Compact(sorted), "\x00")
+}
+
+// scopeKey is never empty, so scoped periods differ from unscoped ones.
+func scopeKey(scope entity.Scope) string {
+	ids := slices.Clone(scope.CalendarIDs)
+	slices.Sort(ids)
+
+	return strconv.Itoa(scope.UserID) + "\x00" + strings.Join(slices.Compact(ids), "\x00")
+}
+
+func copyEvent(event *entity.Event) *entity.Event {
+	e := *event
+	e.Tags = slices.Clone(event.Tags)
+
+	return &e
+}
+
+func copyEvents(events entity.Events) entity.Events {
+	result := make(entity.Events, 0, len(events))
+	for _, event := range events {
+		result = append(result, copyEvent(event))
+	}
+
+	return result
+}
@@ -0,0 +1,251 @@
+package cachestorage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	memorystorage "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+var initialDate = time.Date(2025, 12, 5, 12, 0o0, 0, 0, time.UTC)
+
+func createStorage(t *testing.T, options Options) *Storage {
+	t.Helper()
+
+	st := New(memorystorage.New(), options)
+	require.NoError(t, st.Connect(context.Background()))
+
+	return st
+}
+
+func TestCacheGetByID(t *testing.T) {
+	ctx := context.Background()
+	st := createStorage(t, Options{TTL: time.Minute})
+
+	id, err := st.Create(ctx, entity.Event{Title: "1", DateTime: initialDate, UserID: 1})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		event, err := st.GetByID(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, "1", event.Title)
+	}
+	require.Equal(t, uint64(1), st.Stats().Misses)
+	require.Equal(t, uint64(2), st.Stats().Hits)
+	require.JSONEq(t, `{"hits":2,"misses":1,"evictions":0,"entries":1}`, st.Var().String())
+
+	t.Run("returned event is a copy", func(t *testing.T) {
+		event, err := st.GetByID(ctx, id)
+		require.NoError(t, err)
+		event.Title = "changed"
+
+		event, err = st.GetByID(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, "1", event.Title)
+	})
+
+	t.Run("update invalidates", func(t *testing.T) {
+		require.NoError(t, st.Update(ctx, entity.Event{ID: id, Title: "2", DateTime: initialDate, UserID: 1}))
+
+		event, err := st.GetByID(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, "2", event.Title)
+	})
+
+	t.Run("mark as reminded invalidates", func(t *testing.T) {
+		require.NoError(t, st.MarkAsReminded(ctx, id))
+
+		event, err := st.GetByID(ctx, id)
+		require.NoError(t, err)
+		require.False(t, event.RemindSentTime.IsZero())
+	})
+
+	t.Run("delete invalidates", func(t *testing.T) {
+		require.NoError(t, st.Delete(ctx, id))
+
+		_, err := st.GetByID(ctx, id)
+		require.ErrorIs(t, err, entity.ErrEventNotFound)
+	})
+}
+
+func TestCacheGetForPeriod(t *testing.T) {
+	ctx := context.Background()
+	st := createStorage(t, Options{TTL: time.Minute})
+	dayStart := initialDate.Truncate(day)
+	dayEnd := dayStart.Add(day - time.Second)
+
+	id, err := st.Create(ctx, entity.Event{Title: "1", DateTime: initialDate, UserID: 1})
+	require.NoError(t, err)
+
+	events, err := st.GetForPeriod(ctx, dayStart, dayEnd)
+	require.NoError(t, err)
+	require.Len(t, *events, 1)
+	events, err = st.GetForPeriod(ctx, dayStart, dayEnd)
+	require.NoError(t, err)
+	require.Len(t, *events, 1)
+	require.Equal(t, uint64(1), st.Stats().Hits)
+
+	t.Run("create in period invalidates", func(t *testing.T) {
+		_, err := st.Create(ctx, entity.Event{Title: "2", DateTime: initialDate.Add(time.Hour), UserID: 2})
+		require.NoError(t, err)
+
+		events, err := st.GetForPeriod(ctx, dayStart, dayEnd)
+		require.NoError(t, err)
+		require.Len(t, *events, 2)
+	})
+
+	t.Run("create out of period keeps cache", func(t *testing.T) {
+		hits := st.Stats().Hits
+		_, err := st.Create(ctx, entity.Event{Title: "3", DateTime: initialDate.Add(day * 3), UserID: 1})
+		require.NoError(t, err)
+
+		events, err := st.GetForPeriod(ctx, dayStart, dayEnd)
+		require.NoError(t, err)
+		require.Len(t, *events, 2)
+		require.Equal(t, hits+1, st.Stats().Hits)
+	})
+
+	t.Run("moving event out of period invalidates", func(t *testing.T) {
+		err := st.Update(ctx, entity.Event{ID: id, Title: "1", DateTime: initialDate.Add(day * 5), UserID: 1})
+		require.NoError(t, err)
+
+		events, err := st.GetForPeriod(ctx, dayStart, dayEnd)
+		require.NoError(t, err)
+		require.Len(t, *events, 1)
+	})
+
+	t.Run("delete older than invalidates", func(t *testing.T) {
+		require.NoError(t, st.DeleteOlderThan(ctx, initialDate.Add(day)))
+
+		events, err := st.GetForPeriod(ctx, dayStart, dayEnd)
+		require.NoError(t, err)
+		require.Len(t, *events, 0)
+	})
+}
+
+func TestCacheDeleteTagAndCalendar(t *testing.T) {
+	ctx := context.Background()
+	st := createStorage(t, Options{TTL: time.Minute})
+	dayStart := initialDate.Truncate(day)
+	dayEnd := dayStart.Add(day - time.Second)
+	nextDay := dayStart.Add(day)
+
+	work, err := st.CreateCalendar(ctx, entity.Calendar{OwnerID: 1, Name: "work"})
+	require.NoError(t, err)
+	home, err := st.CreateCalendar(ctx, entity.Calendar{OwnerID: 1, Name: "home"})
+	require.NoError(t, err)
+	require.NoError(t, st.SaveTag(ctx, entity.Tag{UserID: 1, Name: "urgent"}))
+
+	tagged, err := st.Create(ctx, entity.Event{
+		Title: "1", DateTime: initialDate, UserID: 1, CalendarID: work, Tags: []string{"urgent"},
+	})
+	require.NoError(t, err)
+	other, err := st.Create(ctx, entity.Event{Title: "2", DateTime: initialDate.Add(day), UserID: 1, CalendarID: home})
+	require.NoError(t, err)
+
+	warm := func() {
+		t.Helper()
+		for _, id := range []string{tagged, other} {
+			_, err := st.GetByID(ctx, id)
+			require.NoError(t, err)
+		}
+		for _, start := range []time.Time{dayStart, nextDay} {
+			_, err := st.GetForPeriod(ctx, start, start.Add(day-time.Second))
+			require.NoError(t, err)
+		}
+	}
+	warm()
+
+	t.Run("delete tag drops only events having it", func(t *testing.T) {
+		require.NoError(t, st.DeleteTag(ctx, 1, "urgent"))
+
+		misses := st.Stats().Misses
+		event, err := st.GetByID(ctx, tagged)
+		require.NoError(t, err)
+		require.Empty(t, event.Tags)
+		events, err := st.GetForPeriod(ctx, dayStart, dayEnd)
+		require.NoError(t, err)
+		require.Empty(t, (*events)[0].Tags)
+		require.Equal(t, misses+2, st.Stats().Misses)
+
+		hits := st.Stats().Hits
+		_, err = st.GetByID(ctx, other)
+		require.NoError(t, err)
+		_, err = st.GetForPeriod(ctx, nextDay, nextDay.Add(day-time.Second))
+		require.NoError(t, err)
+		require.Equal(t, hits+2, st.Stats().Hits)
+	})
+
+	t.Run("delete calendar drops only its events", func(t *testing.T) {
+		warm()
+		require.NoError(t, st.DeleteCalendar(ctx, work))
+
+		_, err := st.GetByID(ctx, tagged)
+		require.ErrorIs(t, err, entity.ErrEventNotFound)
+		events, err := st.GetForPeriod(ctx, dayStart, dayEnd)
+		require.NoError(t, err)
+		require.Empty(t, *events)
+
+		hits := st.Stats().Hits
+		_, err = st.GetByID(ctx, other)
+		require.NoError(t, err)
+		_, err = st.GetForPeriod(ctx, nextDay, nextDay.Add(day-time.Second))
+		require.NoError(t, err)
+		require.Equal(t, hits+2, st.Stats().Hits)
+	})
+}
+
+func TestCacheLimits(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ttl", func(t *testing.T) {
+		st := createStorage(t, Options{TTL: time.Minute})
+		now := time.Now()
+		st.now = func() time.Time { return now }
+
+		id, err := st.Create(ctx, entity.Event{Title: "1", DateTime: initialDate, UserID: 1})
+		require.NoError(t, err)
+		_, err = st.GetByID(ctx, id)
+		require.NoError(t, err)
+		_, err = st.GetByID(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), st.Stats().Hits)
+
+		now = now.Add(time.Minute)
+		_, err = st.GetByID(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), st.Stats().Hits)
+		require.Equal(t, uint64(2), st.Stats().Misses)
+	})
+
+	t.Run("size", func(t *testing.T) {
+		st := createStorage(t, Options{TTL: time.Minute, Size: 2})
+
+		ids := make([]string, 0, 3)
+		for i := 0; i < 3; i++ {
+			id, err := st.Create(ctx, entity.Event{Title: "1", DateTime: initialDate.Add(time.Hour * time.Duration(i))})
+			require.NoError(t, err)
+			_, err = st.GetByID(ctx, id)
+			require.NoError(t, err)
+			ids = append(ids, id)
+		}
+		require.Equal(t, uint64(1), st.Stats().Evictions)
+		require.Equal(t, 2, st.Stats().Entries)
+
+		_, err := st.GetByID(ctx, ids[0])
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), st.Stats().Hits)
+	})
+}
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		t.Helper()
+
+		return createStorage(t, Options{TTL: time.Minute, Size: 50})
+	})
+}
@@ -0,0 +1,30 @@
+package filestorage_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
+	filestorage "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage/file"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConformance is in an external package, storagetest imports this one
+// through the storage package.
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		t.Helper()
+
+		cfg := &config.Config{}
+		cfg.File.Dir = t.TempDir()
+		cfg.File.SnapshotEvery = 10
+		ctx := cfg.WithContext(context.Background())
+
+		st := filestorage.New()
+		require.NoError(t, st.Connect(ctx))
+		t.Cleanup(func() { st.Close(ctx) })
+
+		return st
+	})
+}
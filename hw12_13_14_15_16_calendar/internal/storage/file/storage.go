@@ -0,0 +1,811 @@
+package filestorage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	memorystorage "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage/memory"
+)
+
+const (
+	snapshotFile = "snapshot.json"
+	logFile      = "events.log"
+)
+
+const (
+	opPut       = "put"
+	opDelete    = "delete"
+	opPurge     = "purge"
+	opPutTag    = "put_tag"
+	opDeleteTag = "delete_tag"
+
+	opPutCalendar    = "put_calendar"
+	opDeleteCalendar = "delete_calendar"
+	opPutShare       = "put_share"
+	opDeleteShare    = "delete_share"
+
+	opPutDigest    = "put_digest"
+	opDeleteDigest = "delete_digest"
+
+	opPutKey    = "put_key"
+	opDeleteKey = "delete_key"
+	opPurgeKeys = "purge_keys"
+)
+
+var (
+	ErrNotConnected    = errors.New("file storage is not connected")
+	ErrCorruptSnapshot = errors.New("corrupt snapshot")
+)
+
+// record is a single line of the append-only log. Records are idempotent, so
+// replaying a log over a snapshot that already contains it is safe.
+type record struct {
+	Op     string        `json:"op"`
+	Event  *entity.Event `json:"event,omitempty"`
+	ID     string        `json:"id,omitempty"`
+	Before time.Time     `json:"before,omitempty"`
+	Tag    *entity.Tag   `json:"tag,omitempty"`
+
+	Calendar *entity.Calendar `json:"calendar,omitempty"`
+	Share    *entity.Share    `json:"share,omitempty"`
+	Digest   *entity.Digest   `json:"digest,omitempty"`
+
+	Key *entity.IdempotencyKey `json:"key,omitempty"`
+}
+
+// snapshotData is the snapshot file content. Snapshots written before tags
+// were added are a plain array of events.
+type snapshotData struct {
+	Events    []*entity.Event         `json:"events"`
+	Tags      []entity.Tag            `json:"tags"`
+	Calendars []*entity.Calendar      `json:"calendars"`
+	Shares    []entity.Share          `json:"shares"`
+	Digests   []entity.Digest         `json:"digests"`
+	Keys      []entity.IdempotencyKey `json:"keys"`
+}
+
+type tagKey struct {
+	userID int
+	name   string
+}
+
+type shareKey struct {
+	calendarID string
+	userID     int
+}
+
+type idempotencyKey struct {
+	userID int
+	key    string
+}
+
+// state is the storage content being restored on Connect.
+type state struct {
+	events    map[string]*entity.Event
+	tags      map[tagKey]entity.Tag
+	calendars map[string]*entity.Calendar
+	shares    map[shareKey]entity.Share
+	digests   map[int]entity.Digest
+	keys      map[idempotencyKey]entity.IdempotencyKey
+}
+
+func (st state) memoryState() memorystorage.State {
+	result := memorystorage.State{}
+	for _, event := range st.events {
+		result.Events = append(result.Events, event)
+	}
+	for _, tag := range st.tags {
+		result.Tags = append(result.Tags, tag)
+	}
+	for _, calendar := range st.calendars {
+		result.Calendars = append(result.Calendars, calendar)
+	}
+	for _, share := range st.shares {
+		result.Shares = append(result.Shares, share)
+	}
+	for _, digest := range st.digests {
+		result.Digests = append(result.Digests, digest)
+	}
+	for _, key := range st.keys {
+		result.Keys = append(result.Keys, key)
+	}
+
+	return result
+}
+
+// Storage keeps events in memory and persists every change to an append-only
+// log in Dir. Every SnapshotEvery records the whole state is written to a
+// snapshot and the log is truncated.
+type Storage struct {
+	*memorystorage.Storage
+
+	mu            sync.Mutex
+	dir           string
+	snapshotEvery int
+	log           *os.File
+	records       int
+}
+
+func New() *Storage {
+	return &Storage{Storage: memorystorage.New()}
+}
+
+func (s *Storage) Create(ctx context.Context, event entity.Event) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return "", ErrNotConnected
+	}
+
+	id, err := s.Storage.Create(ctx, event)
+	if err != nil {
+		return "", err
+	}
+
+	if err = s.appendEvent(ctx, id); err != nil {
+		_ = s.Storage.Delete(ctx, id)
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (s *Storage) Update(ctx context.Context, event entity.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return ErrNotConnected
+	}
+
+	if err := s.Storage.Update(ctx, event); err != nil {
+		return err
+	}
+
+	return s.appendEvent(ctx, event.ID)
+}
+
+func (s *Storage) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return ErrNotConnected
+	}
+
+	if err := s.Storage.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	return s.append(record{Op: opDelete, ID: id})
+}
+
+func (s *Storage) MarkAsReminded(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return ErrNotConnected
+	}
+
+	if err := s.Storage.MarkAsReminded(ctx, id); err != nil {
+		return err
+	}
+
+	return s.appendEvent(ctx, id)
+}
+
+func (s *Storage) DeleteOlderThan(ctx context.Context, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return ErrNotConnected
+	}
+
+	if err := s.Storage.DeleteOlderThan(ctx, t); err != nil {
+		return err
+	}
+
+	return s.append(record{Op: opPurge, Before: t})
+}
+
+func (s *Storage) SaveTag(ctx context.Context, tag entity.Tag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return ErrNotConnected
+	}
+
+	if err := s.Storage.SaveTag(ctx, tag); err != nil {
+		return err
+	}
+
+	return s.append(record{Op: opPutTag, Tag: &tag})
+}
+
+func (s *Storage) DeleteTag(ctx context.Context, userID int, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return ErrNotConnected
+	}
+
+	if err := s.Storage.DeleteTag(ctx, userID, name); err != nil {
+		return err
+	}
+
+	return s.append(record{Op: opDeleteTag, Tag: &entity.Tag{UserID: userID, Name: name}})
+}
+
+func (s *Storage) CreateCalendar(ctx context.Context, calendar entity.Calendar) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return "", ErrNotConnected
+	}
+
+	id, err := s.Storage.CreateCalendar(ctx, calendar)
+	if err != nil {
+		return "", err
+	}
+
+	if err = s.appendCalendar(ctx, id); err != nil {
+		_ = s.Storage.DeleteCalendar(ctx, id)
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (s *Storage) UpdateCalendar(ctx context.Context, calendar entity.Calendar) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return ErrNotConnected
+	}
+
+	if err := s.Storage.UpdateCalendar(ctx, calendar); err != nil {
+		return err
+	}
+
+	return s.appendCalendar(ctx, calendar.ID)
+}
+
+func (s *Storage) DeleteCalendar(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return ErrNotConnected
+	}
+
+	if err := s.Storage.DeleteCalendar(ctx, id); err != nil {
+		return err
+	}
+
+	return s.append(record{Op: opDeleteCalendar, ID: id})
+}
+
+func (s *Storage) SaveShare(ctx context.Context, share entity.Share) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return ErrNotConnected
+	}
+
+	if err := s.Storage.SaveShare(ctx, share); err != nil {
+		return err
+	}
+
+	return s.append(record{Op: opPutShare, Share: &share})
+}
+
+func (s *Storage) DeleteShare(ctx context.Context, calendarID string, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return ErrNotConnected
+	}
+
+	if err := s.Storage.DeleteShare(ctx, calendarID, userID); err != nil {
+		return err
+	}
+
+	return s.append(record{Op: opDeleteShare, Share: &entity.Share{CalendarID: calendarID, UserID: userID}})
+}
+
+func (s *Storage) SaveDigest(ctx context.Context, digest entity.Digest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return ErrNotConnected
+	}
+
+	if err := s.Storage.SaveDigest(ctx, digest); err != nil {
+		return err
+	}
+
+	return s.appendDigest(ctx, digest.UserID)
+}
+
+func (s *Storage) DeleteDigest(ctx context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return ErrNotConnected
+	}
+
+	if err := s.Storage.DeleteDigest(ctx, userID); err != nil {
+		return err
+	}
+
+	return s.append(record{Op: opDeleteDigest, Digest: &entity.Digest{UserID: userID}})
+}
+
+func (s *Storage) MarkDigestSent(ctx context.Context, userID int, date string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return ErrNotConnected
+	}
+
+	if err := s.Storage.MarkDigestSent(ctx, userID, date); err != nil {
+		return err
+	}
+
+	return s.appendDigest(ctx, userID)
+}
+
+// Connect restores the state from the snapshot and the log. A torn or corrupt
+// tail of the log, left by a crash in the middle of a write, is cut off.
+func (s *Storage) CreateIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return ErrNotConnected
+	}
+
+	if err := s.Storage.CreateIdempotencyKey(ctx, key); err != nil {
+		return err
+	}
+
+	return s.append(record{Op: opPutKey, Key: &key})
+}
+
+func (s *Storage) SaveIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return ErrNotConnected
+	}
+
+	if err := s.Storage.SaveIdempotencyKey(ctx, key); err != nil {
+		return err
+	}
+
+	return s.append(record{Op: opPutKey, Key: &key})
+}
+
+func (s *Storage) DeleteIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return ErrNotConnected
+	}
+
+	if err := s.Storage.DeleteIdempotencyKey(ctx, key); err != nil {
+		return err
+	}
+
+	return s.append(record{Op: opDeleteKey, Key: &key})
+}
+
+func (s *Storage) DeleteIdempotencyKeysBefore(ctx context.Context, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return ErrNotConnected
+	}
+
+	if err := s.Storage.DeleteIdempotencyKeysBefore(ctx, t); err != nil {
+		return err
+	}
+
+	return s.append(record{Op: opPurgeKeys, Before: t})
+}
+
+func (s *Storage) Connect(ctx context.Context) error {
+	cfg := config.GetFromContext(ctx)
+	if cfg == nil {
+		return config.ErrNoConfigInContext
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dir = cfg.File.Dir
+	s.snapshotEvery = cfg.File.SnapshotEvery
+
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return err
+	}
+	_ = os.Remove(filepath.Join(s.dir, snapshotFile+".tmp"))
+
+	st, err := s.readSnapshot()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(s.dir, logFile), os.O_RDWR|os.O_CREATE, 0o640)
+	if err != nil {
+		return err
+	}
+
+	if err = s.replay(f, st); err != nil {
+		f.Close()
+		return err
+	}
+
+	s.Storage = memorystorage.NewWithState(st.memoryState())
+	s.log = f
+
+	return nil
+}
+
+// Ping checks the log is open.
+func (s *Storage) Ping(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return ErrNotConnected
+	}
+
+	return nil
+}
+
+// Close writes a final snapshot, so the next Connect doesn't replay the log.
+func (s *Storage) Close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.log == nil {
+		return nil
+	}
+
+	err := s.snapshot()
+	if closeErr := s.log.Close(); err == nil {
+		err = closeErr
+	}
+	s.log = nil
+
+	if closeErr := s.Storage.Close(ctx); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func (s *Storage) readSnapshot() (state, error) {
+	st := state{
+		events:    make(map[string]*entity.Event),
+		tags:      make(map[tagKey]entity.Tag),
+		calendars: make(map[string]*entity.Calendar),
+		shares:    make(map[shareKey]entity.Share),
+		digests:   make(map[int]entity.Digest),
+		keys:      make(map[idempotencyKey]entity.IdempotencyKey),
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.dir, snapshotFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return st, err
+	}
+
+	var snap snapshotData
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(data, &snap.Events)
+	} else {
+		err = json.Unmarshal(data, &snap)
+	}
+	if err != nil {
+		return st, fmt.Errorf("%w: %w", ErrCorruptSnapshot, err)
+	}
+
+	for _, event := range snap.Events {
+		st.events[event.ID] = event
+	}
+	for _, tag := range snap.Tags {
+		st.tags[tagKey{tag.UserID, tag.Name}] = tag
+	}
+	for _, calendar := range snap.Calendars {
+		st.calendars[calendar.ID] = calendar
+	}
+	for _, share := range snap.Shares {
+		st.shares[shareKey{share.CalendarID, share.UserID}] = share
+	}
+	for _, digest := range snap.Digests {
+		st.digests[digest.UserID] = digest
+	}
+	for _, key := range snap.Keys {
+		st.keys[idempotencyKey{key.UserID, key.Key}] = key
+	}
+
+	return st, nil
+}
+
+func (s *Storage) replay(f *os.File, st state) error {
+	var offset int64
+
+	s.records = 0
+
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		rec, ok := decode(line)
+		if !ok {
+			break
+		}
+		apply(st, rec)
+
+		offset += int64(len(line))
+		s.records++
+	}
+
+	if err := f.Truncate(offset); err != nil {
+		return err
+	}
+	_, err := f.Seek(offset, io.SeekStart)
+
+	return err
+}
+
+func apply(st state, rec record) {
+	switch rec.Op {
+	case opPut:
+		st.events[rec.Event.ID] = rec.Event
+	case opDelete:
+		delete(st.events, rec.ID)
+	case opPurge:
+		for id, event := range st.events {
+			if event.DateTime.Before(rec.Before) {
+				delete(st.events, id)
+			}
+		}
+	case opPutTag:
+		st.tags[tagKey{rec.Tag.UserID, rec.Tag.Name}] = *rec.Tag
+	case opDeleteTag:
+		delete(st.tags, tagKey{rec.Tag.UserID, rec.Tag.Name})
+		for _, event := range st.events {
+			if event.UserID == rec.Tag.UserID {
+				event.Tags = slices.DeleteFunc(event.Tags, func(tag string) bool { return tag == rec.Tag.Name })
+			}
+		}
+	case opPutCalendar:
+		st.calendars[rec.Calendar.ID] = rec.Calendar
+	case opDeleteCalendar:
+		delete(st.calendars, rec.ID)
+		for key := range st.shares {
+			if key.calendarID == rec.ID {
+				delete(st.shares, key)
+			}
+		}
+		for id, event := range st.events {
+			if event.CalendarID == rec.ID {
+				delete(st.events, id)
+			}
+		}
+	case opPutShare:
+		st.shares[shareKey{rec.Share.CalendarID, rec.Share.UserID}] = *rec.Share
+	case opDeleteShare:
+		delete(st.shares, shareKey{rec.Share.CalendarID, rec.Share.UserID})
+	case opPutDigest:
+		st.digests[rec.Digest.UserID] = *rec.Digest
+	case opDeleteDigest:
+		delete(st.digests, rec.Digest.UserID)
+	case opPutKey:
+		st.keys[idempotencyKey{rec.Key.UserID, rec.Key.Key}] = *rec.Key
+	case opDeleteKey:
+		delete(st.keys, idempotencyKey{rec.Key.UserID, rec.Key.Key})
+	case opPurgeKeys:
+		for id, key := range st.keys {
+			if key.CreatedAt.Before(rec.Before) {
+				delete(st.keys, id)
+			}
+		}
+	}
+}
+
+func (s *Storage) appendEvent(ctx context.Context, id string) error {
+	event, err := s.Storage.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	stored := *event
+
+	return s.append(record{Op: opPut, Event: &stored})
+}
+
+func (s *Storage) appendCalendar(ctx context.Context, id string) error {
+	calendar, err := s.Storage.GetCalendar(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.append(record{Op: opPutCalendar, Calendar: calendar})
+}
+
+func (s *Storage) appendDigest(ctx context.Context, userID int) error {
+	digest, err := s.Storage.GetDigest(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.append(record{Op: opPutDigest, Digest: digest})
+}
+
+// append writes rec to the log, syncs it to disk and takes a snapshot when
+// the log gets long enough.
+func (s *Storage) append(rec record) error {
+	line, err := encode(rec)
+	if err != nil {
+		return err
+	}
+
+	if _, err = s.log.Write(line); err != nil {
+		return err
+	}
+	if err = s.log.Sync(); err != nil {
+		return err
+	}
+
+	s.records++
+	if s.snapshotEvery > 0 && s.records >= s.snapshotEvery {
+		return s.snapshot()
+	}
+
+	return nil
+}
+
+// snapshot atomically replaces the snapshot file with the current state and
+// truncates the log. A crash between the two steps only leaves records that
+// are already in the snapshot.
+func (s *Storage) snapshot() error {
+	state := s.Storage.State()
+
+	data, err := json.Marshal(snapshotData{
+		Events:    state.Events,
+		Tags:      state.Tags,
+		Calendars: state.Calendars,
+		Shares:    state.Shares,
+		Digests:   state.Digests,
+		Keys:      state.Keys,
+	})
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(s.dir, snapshotFile+".tmp")
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp, filepath.Join(s.dir, snapshotFile)); err != nil {
+		return err
+	}
+	if err = syncDir(s.dir); err != nil {
+		return err
+	}
+
+	if err = s.log.Truncate(0); err != nil {
+		return err
+	}
+	if _, err = s.log.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	s.records = 0
+
+	return s.log.Sync()
+}
+
+// encode formats rec as a log line: crc32 of the payload, a space, the JSON
+// payload and a newline.
+func encode(rec record) ([]byte, error) {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf("%08x %s\n", crc32.ChecksumIEEE(payload), payload)), nil
+}
+
+func decode(line []byte) (record, bool) {
+	var rec record
+
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	sum, payload, found := bytes.Cut(line, []byte(" "))
+	if !found || fmt.Sprintf("%08x", crc32.ChecksumIEEE(payload)) != string(sum) {
+		return rec, false
+	}
+
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return rec, false
+	}
+	if rec.Op == opPut && rec.Event == nil {
+		return rec, false
+	}
+	if (rec.Op == opPutTag || rec.Op == opDeleteTag) && rec.Tag == nil {
+		return rec, false
+	}
+	if rec.Op == opPutCalendar && rec.Calendar == nil {
+		return rec, false
+	}
+	if (rec.Op == opPutShare || rec.Op == opDeleteShare) && rec.Share == nil {
+		return rec, false
+	}
+	if (rec.Op == opPutDigest || rec.Op == opDeleteDigest) && rec.Digest == nil {
+		return rec, false
+	}
+
+	return rec, true
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
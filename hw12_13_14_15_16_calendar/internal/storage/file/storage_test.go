@@ -0,0 +1,280 @@
+package filestorage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/stretchr/testify/require"
+)
+
+var initialDate = time.Date(2025, 12, 5, 12, 0o0, 0, 0, time.UTC)
+
+func configContext(t *testing.T, dir string, snapshotEvery int) context.Context {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.File.Dir = dir
+	cfg.File.SnapshotEvery = snapshotEvery
+
+	return cfg.WithContext(context.Background())
+}
+
+func connect(t *testing.T, ctx context.Context) *Storage {
+	t.Helper()
+
+	st := New()
+	require.NoError(t, st.Connect(ctx))
+
+	return st
+}
+
+func TestStorageModify(t *testing.T) {
+	ctx := configContext(t, t.TempDir(), 0)
+	st := connect(t, ctx)
+	defer st.Close(ctx)
+
+	id, err := st.Create(ctx, entity.Event{Title: "1", DateTime: initialDate, UserID: 1})
+	require.NoError(t, err)
+
+	require.NoError(t, st.Update(ctx, entity.Event{ID: id, Title: "2", DateTime: initialDate, UserID: 1}))
+	event, err := st.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "2", event.Title)
+
+	err = st.Update(ctx, entity.Event{ID: "unknown", Title: "2", DateTime: initialDate, UserID: 1})
+	require.ErrorIs(t, err, entity.ErrEventNotFound)
+
+	require.NoError(t, st.Delete(ctx, id))
+	_, err = st.GetByID(ctx, id)
+	require.ErrorIs(t, err, entity.ErrEventNotFound)
+}
+
+func TestPing(t *testing.T) {
+	ctx := configContext(t, t.TempDir(), 0)
+	st := New()
+	require.ErrorIs(t, st.Ping(ctx), ErrNotConnected)
+
+	require.NoError(t, st.Connect(ctx))
+	require.NoError(t, st.Ping(ctx))
+
+	require.NoError(t, st.Close(ctx))
+	require.ErrorIs(t, st.Ping(ctx), ErrNotConnected)
+}
+
+func TestStorageRecovery(t *testing.T) {
+	t.Run("replay log", func(t *testing.T) {
+		ctx := configContext(t, t.TempDir(), 0)
+		st := connect(t, ctx)
+
+		id1, err := st.Create(ctx, entity.Event{Title: "1", DateTime: initialDate, UserID: 1})
+		require.NoError(t, err)
+		id2, err := st.Create(ctx, entity.Event{Title: "2", DateTime: initialDate.Add(time.Hour), UserID: 1})
+		require.NoError(t, err)
+		id3, err := st.Create(ctx, entity.Event{Title: "3", DateTime: initialDate.AddDate(-2, 0, 0), UserID: 2})
+		require.NoError(t, err)
+		require.NoError(t, st.Update(ctx, entity.Event{ID: id1, Title: "1.1", DateTime: initialDate, UserID: 1}))
+		require.NoError(t, st.Delete(ctx, id2))
+		require.NoError(t, st.MarkAsReminded(ctx, id1))
+		require.NoError(t, st.DeleteOlderThan(ctx, initialDate.AddDate(-1, 0, 0)))
+
+		// simulate a crash: reopen without Close, so no snapshot is written
+		restored := connect(t, ctx)
+		defer restored.Close(ctx)
+
+		events, err := restored.GetAll(ctx)
+		require.NoError(t, err)
+		require.Len(t, *events, 1)
+
+		event, err := restored.GetByID(ctx, id1)
+		require.NoError(t, err)
+		require.Equal(t, "1.1", event.Title)
+		require.True(t, event.DateTime.Equal(initialDate))
+		require.False(t, event.RemindSentTime.IsZero())
+
+		_, err = restored.GetByID(ctx, id3)
+		require.ErrorIs(t, err, entity.ErrEventNotFound)
+	})
+
+	t.Run("torn tail", func(t *testing.T) {
+		dir := t.TempDir()
+		ctx := configContext(t, dir, 0)
+		st := connect(t, ctx)
+
+		id, err := st.Create(ctx, entity.Event{Title: "1", DateTime: initialDate, UserID: 1})
+		require.NoError(t, err)
+
+		f, err := os.OpenFile(filepath.Join(dir, logFile), os.O_WRONLY|os.O_APPEND, 0o640)
+		require.NoError(t, err)
+		_, err = f.WriteString(`00000000 {"op":"put","ev`)
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		restored := connect(t, ctx)
+		events, err := restored.GetAll(ctx)
+		require.NoError(t, err)
+		require.Len(t, *events, 1)
+		require.Equal(t, id, (*events)[0].ID)
+
+		// new records are appended after the cut off tail
+		id2, err := restored.Create(ctx, entity.Event{Title: "2", DateTime: initialDate.Add(time.Hour), UserID: 1})
+		require.NoError(t, err)
+
+		restored = connect(t, ctx)
+		defer restored.Close(ctx)
+		_, err = restored.GetByID(ctx, id2)
+		require.NoError(t, err)
+	})
+
+	t.Run("snapshot and compaction", func(t *testing.T) {
+		dir := t.TempDir()
+		ctx := configContext(t, dir, 3)
+		st := connect(t, ctx)
+
+		for i := 0; i < 4; i++ {
+			_, err := st.Create(ctx, entity.Event{Title: "1", DateTime: initialDate.Add(time.Hour * time.Duration(i))})
+			require.NoError(t, err)
+		}
+
+		info, err := os.Stat(filepath.Join(dir, snapshotFile))
+		require.NoError(t, err)
+		require.NotZero(t, info.Size())
+		require.Equal(t, 1, st.records)
+
+		require.NoError(t, st.Close(ctx))
+		info, err = os.Stat(filepath.Join(dir, logFile))
+		require.NoError(t, err)
+		require.Zero(t, info.Size())
+
+		restored := connect(t, ctx)
+		defer restored.Close(ctx)
+		events, err := restored.GetAll(ctx)
+		require.NoError(t, err)
+		require.Len(t, *events, 4)
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		ctx := configContext(t, t.TempDir(), 0)
+		st := connect(t, ctx)
+
+		require.NoError(t, st.SaveTag(ctx, entity.Tag{UserID: 1, Name: "work", Color: "#ff0000"}))
+		require.NoError(t, st.SaveTag(ctx, entity.Tag{UserID: 1, Name: "home", Color: "#00ff00"}))
+		id, err := st.Create(ctx, entity.Event{Title: "1", DateTime: initialDate, UserID: 1, Tags: []string{"home", "work"}})
+		require.NoError(t, err)
+		require.NoError(t, st.DeleteTag(ctx, 1, "home"))
+
+		check := func(restored *Storage) {
+			t.Helper()
+
+			tags, err := restored.GetTags(ctx, 1)
+			require.NoError(t, err)
+			require.Equal(t, entity.Tags{{UserID: 1, Name: "work", Color: "#ff0000"}}, *tags)
+
+			event, err := restored.GetByID(ctx, id)
+			require.NoError(t, err)
+			require.Equal(t, []string{"work"}, event.Tags)
+		}
+
+		// from the log
+		restored := connect(t, ctx)
+		check(restored)
+
+		// from the snapshot
+		require.NoError(t, restored.Close(ctx))
+		restored = connect(t, ctx)
+		defer restored.Close(ctx)
+		check(restored)
+	})
+
+	t.Run("digests", func(t *testing.T) {
+		ctx := configContext(t, t.TempDir(), 0)
+		st := connect(t, ctx)
+
+		require.NoError(t, st.SaveDigest(ctx, entity.Digest{UserID: 1, Time: "07:00", TimeZone: "UTC"}))
+		require.NoError(t, st.SaveDigest(ctx, entity.Digest{UserID: 2, Time: "08:00", TimeZone: "UTC"}))
+		require.NoError(t, st.MarkDigestSent(ctx, 1, "2025-12-05"))
+		require.NoError(t, st.DeleteDigest(ctx, 2))
+
+		check := func(restored *Storage) {
+			t.Helper()
+
+			digests, err := restored.GetDigests(ctx)
+			require.NoError(t, err)
+			require.Equal(t, entity.Digests{{UserID: 1, Time: "07:00", TimeZone: "UTC", LastSent: "2025-12-05"}}, *digests)
+		}
+
+		// from the log
+		restored := connect(t, ctx)
+		check(restored)
+
+		// from the snapshot
+		require.NoError(t, restored.Close(ctx))
+		restored = connect(t, ctx)
+		defer restored.Close(ctx)
+		check(restored)
+	})
+
+	t.Run("idempotency keys", func(t *testing.T) {
+		ctx := configContext(t, t.TempDir(), 0)
+		st := connect(t, ctx)
+
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		kept := entity.IdempotencyKey{UserID: 1, Key: "kept", Hash: "a", CreatedAt: now}
+		require.NoError(t, st.CreateIdempotencyKey(ctx, kept))
+		kept.EventID = "event-1"
+		require.NoError(t, st.SaveIdempotencyKey(ctx, kept))
+		deleted := entity.IdempotencyKey{UserID: 1, Key: "deleted", CreatedAt: now}
+		require.NoError(t, st.CreateIdempotencyKey(ctx, deleted))
+		require.NoError(t, st.DeleteIdempotencyKey(ctx, deleted))
+		require.NoError(t, st.CreateIdempotencyKey(ctx,
+			entity.IdempotencyKey{UserID: 1, Key: "expired", CreatedAt: now.Add(-time.Hour)}))
+		require.NoError(t, st.DeleteIdempotencyKeysBefore(ctx, now.Add(-time.Minute)))
+
+		check := func(restored *Storage) {
+			t.Helper()
+
+			key, err := restored.GetIdempotencyKey(ctx, 1, "kept")
+			require.NoError(t, err)
+			require.Equal(t, kept, *key)
+			for _, gone := range []string{"deleted", "expired"} {
+				_, err = restored.GetIdempotencyKey(ctx, 1, gone)
+				require.ErrorIs(t, err, entity.ErrIdempotencyKeyNotFound)
+			}
+		}
+
+		// from the log
+		restored := connect(t, ctx)
+		check(restored)
+
+		// from the snapshot
+		require.NoError(t, restored.Close(ctx))
+		restored = connect(t, ctx)
+		defer restored.Close(ctx)
+		check(restored)
+	})
+
+	t.Run("snapshot of events only", func(t *testing.T) {
+		dir := t.TempDir()
+		snapshot := `[{"ID":"1","Title":"1","DateTime":"2025-12-05T12:00:00Z"}]`
+		require.NoError(t, os.WriteFile(filepath.Join(dir, snapshotFile), []byte(snapshot), 0o640))
+
+		ctx := configContext(t, dir, 0)
+		restored := connect(t, ctx)
+		defer restored.Close(ctx)
+		event, err := restored.GetByID(ctx, "1")
+		require.NoError(t, err)
+		require.Equal(t, "1", event.Title)
+	})
+
+	t.Run("corrupt snapshot", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, snapshotFile), []byte("[{"), 0o640))
+
+		err := New().Connect(configContext(t, dir, 0))
+		require.ErrorIs(t, err, ErrCorruptSnapshot)
+	})
+}
@@ -0,0 +1,18 @@
+package memorystorage_test
+
+import (
+	"testing"
+
+	memorystorage "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage/memory"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage/storagetest"
+)
+
+// TestConformance is in an external package, storagetest imports this one
+// through the storage package.
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		t.Helper()
+
+		return memorystorage.New()
+	})
+}
@@ -0,0 +1,139 @@
+package memorystorage
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+)
+
+// Weights of a match in event fields, the same as Postgres ts_rank uses for
+// the A and B weighted title and description.
+const (
+	titleWeight       = 1.0
+	descriptionWeight = 0.4
+)
+
+// posting holds positions of a term in event fields.
+type posting struct {
+	title, description []int
+}
+
+// index is an inverted index of event titles and descriptions: term -> event
+// ID -> positions.
+type index map[string]map[string]*posting
+
+func (ix index) add(event *entity.Event) {
+	for pos, term := range tokenize(event.Title) {
+		p := ix.posting(term, event.ID)
+		p.title = append(p.title, pos)
+	}
+	for pos, term := range tokenize(event.Description) {
+		p := ix.posting(term, event.ID)
+		p.description = append(p.description, pos)
+	}
+}
+
+func (ix index) remove(event *entity.Event) {
+	for _, text := range []string{event.Title, event.Description} {
+		for _, term := range tokenize(text) {
+			delete(ix[term], event.ID)
+			if len(ix[term]) == 0 {
+				delete(ix, term)
+			}
+		}
+	}
+}
+
+func (ix index) posting(term, id string) *posting {
+	if ix[term] == nil {
+		ix[term] = make(map[string]*posting)
+	}
+	if ix[term][id] == nil {
+		ix[term][id] = &posting{}
+	}
+
+	return ix[term][id]
+}
+
+// search returns scores of events matching every phrase of the query.
+func (ix index) search(query string) map[string]float64 {
+	phrases := parseQuery(query)
+	if len(phrases) == 0 {
+		return nil
+	}
+
+	var scores map[string]float64
+	for _, phrase := range phrases {
+		matched := make(map[string]float64)
+		for id := range ix[phrase[0]] {
+			if _, ok := scores[id]; scores != nil && !ok {
+				continue
+			}
+
+			title := ix.occurrences(phrase, id, func(p *posting) []int { return p.title })
+			description := ix.occurrences(phrase, id, func(p *posting) []int { return p.description })
+			if title+description > 0 {
+				matched[id] = scores[id] + float64(title)*titleWeight + float64(description)*descriptionWeight
+			}
+		}
+		scores = matched
+	}
+
+	return scores
+}
+
+// occurrences counts the phrase in one field of the event.
+func (ix index) occurrences(phrase []string, id string, field func(*posting) []int) int {
+	positions := make([]map[int]struct{}, len(phrase))
+	for i, term := range phrase {
+		p, ok := ix[term][id]
+		if !ok {
+			return 0
+		}
+		positions[i] = make(map[int]struct{}, len(field(p)))
+		for _, pos := range field(p) {
+			positions[i][pos] = struct{}{}
+		}
+	}
+
+	count := 0
+	for start := range positions[0] {
+		found := true
+		for i := 1; i < len(phrase) && found; i++ {
+			_, found = positions[i][start+i]
+		}
+		if found {
+			count++
+		}
+	}
+
+	return count
+}
+
+// parseQuery splits the query into phrases: quoted text is a phrase, any
+// other word is a phrase of its own.
+func parseQuery(query string) [][]string {
+	phrases := make([][]string, 0)
+	for i, part := range strings.Split(query, `"`) {
+		terms := tokenize(part)
+		if i%2 == 1 {
+			if len(terms) > 0 {
+				phrases = append(phrases, terms)
+			}
+			continue
+		}
+		for _, term := range terms {
+			phrases = append(phrases, []string{term})
+		}
+	}
+
+	return phrases
+}
+
+// tokenize lowercases text and splits it into words of letters and digits.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -3,6 +3,7 @@ package memorystorage
 import (
 	"context"
 	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,20 +11,91 @@ import (
 	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
 )
 
+// Storage keeps events in a map. Read methods return copies of stored events,
+// so callers can't modify them bypassing Update.
 type Storage struct {
-	mu   sync.RWMutex
-	data map[string]*entity.Event
+	mu        sync.RWMutex
+	data      map[string]*entity.Event
+	tags      map[int]map[string]entity.Tag
+	calendars map[string]*entity.Calendar
+	shares    map[string]map[int]entity.Share
+	digests   map[int]*entity.Digest
+	keys      map[keyID]entity.IdempotencyKey
+	index     index
+	// leases holds remind claims by event id, they aren't part of the state.
+	leases map[string]time.Time
+}
+
+// State is the whole content of a storage, used to persist and restore it.
+type State struct {
+	Events    []*entity.Event
+	Tags      []entity.Tag
+	Calendars []*entity.Calendar
+	Shares    []entity.Share
+	Digests   []entity.Digest
+	Keys      []entity.IdempotencyKey
+}
+
+type keyID struct {
+	userID int
+	key    string
 }
 
 func New() *Storage {
-	return &Storage{}
+	return NewWithEvents(make(map[string]*entity.Event))
 }
 
 func NewWithEvents(events map[string]*entity.Event) *Storage {
-	return &Storage{data: events}
+	s := &Storage{
+		data:      events,
+		tags:      make(map[int]map[string]entity.Tag),
+		calendars: make(map[string]*entity.Calendar),
+		shares:    make(map[string]map[int]entity.Share),
+		digests:   make(map[int]*entity.Digest),
+		keys:      make(map[keyID]entity.IdempotencyKey),
+		index:     make(index),
+		leases:    make(map[string]time.Time),
+	}
+	for _, event := range events {
+		s.index.add(event)
+	}
+
+	return s
+}
+
+func NewWithState(state State) *Storage {
+	events := make(map[string]*entity.Event, len(state.Events))
+	for _, event := range state.Events {
+		events[event.ID] = event
+	}
+
+	s := NewWithEvents(events)
+	for _, tag := range state.Tags {
+		if s.tags[tag.UserID] == nil {
+			s.tags[tag.UserID] = make(map[string]entity.Tag)
+		}
+		s.tags[tag.UserID][tag.Name] = tag
+	}
+	for _, calendar := range state.Calendars {
+		s.calendars[calendar.ID] = calendar
+	}
+	for _, share := range state.Shares {
+		if s.shares[share.CalendarID] == nil {
+			s.shares[share.CalendarID] = make(map[int]entity.Share)
+		}
+		s.shares[share.CalendarID][share.UserID] = share
+	}
+	for _, digest := range state.Digests {
+		s.digests[digest.UserID] = &digest
+	}
+	for _, key := range state.Keys {
+		s.keys[keyID{key.UserID, key.Key}] = key
+	}
+
+	return s
 }
 
-func (s *Storage) GetByID(id string) (*entity.Event, error) {
+func (s *Storage) GetByID(_ context.Context, id string) (*entity.Event, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -32,122 +104,598 @@ func (s *Storage) GetByID(id string) (*entity.Event, error) {
 		return nil, entity.ErrEventNotFound
 	}
 
-	return event, nil
+	return copyEvent(event), nil
 }
 
-func (s *Storage) GetAll() (*entity.Events, error) {
+func (s *Storage) GetAll(_ context.Context) (*entity.Events, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	events := make(entity.Events, 0, len(s.data))
 
 	for _, event := range s.data {
-		events = append(events, event)
+		events = append(events, copyEvent(event))
 	}
 
 	return &events, nil
 }
 
-func (s *Storage) Create(event entity.Event) (string, error) {
+func (s *Storage) Create(_ context.Context, event entity.Event) (string, error) {
 	event.ID = uuid.New().String()
+	event.CreatedAt = time.Now().UTC()
+	event.UpdatedAt = event.CreatedAt
+	event.RemindSentTime = time.Time{}
 
 	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.uidTaken(event) {
+		return "", entity.ErrEventUIDExists
+	}
 	s.data[event.ID] = &event
-	s.mu.Unlock()
+	s.index.add(&event)
 
 	return event.ID, nil
 }
 
-func (s *Storage) Update(event entity.Event) error {
-	_, err := s.GetByID(event.ID)
-	if err != nil {
-		return err
+// uidTaken reports whether another event of the calendar has the UID of the
+// event.
+func (s *Storage) uidTaken(event entity.Event) bool {
+	if event.CalendarID == "" || event.UID == "" {
+		return false
+	}
+	for _, other := range s.data {
+		if other.ID != event.ID && other.CalendarID == event.CalendarID && other.UID == event.UID {
+			return true
+		}
 	}
 
+	return false
+}
+
+// Update replaces event data, creation and remind sent times are kept.
+func (s *Storage) Update(_ context.Context, event entity.Event) error {
 	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, has := s.data[event.ID]
+	if !has {
+		return entity.ErrEventNotFound
+	}
+	if s.uidTaken(event) {
+		return entity.ErrEventUIDExists
+	}
+
+	event.CreatedAt = existing.CreatedAt
+	event.RemindSentTime = existing.RemindSentTime
+	event.UpdatedAt = time.Now().UTC()
+	s.index.remove(existing)
 	s.data[event.ID] = &event
-	s.mu.Unlock()
+	s.index.add(&event)
 
 	return nil
 }
 
-func (s *Storage) Delete(id string) error {
+func (s *Storage) Delete(_ context.Context, id string) error {
 	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, has := s.data[id]
+	if !has {
+		return entity.ErrEventNotFound
+	}
+	s.index.remove(event)
 	delete(s.data, id)
-	s.mu.Unlock()
+	delete(s.leases, id)
 
 	return nil
 }
 
-func (s *Storage) GetForTime(t time.Time) (*entity.Event, error) {
+func (s *Storage) GetByUID(_ context.Context, calendarID, uid string) (*entity.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, event := range s.data {
+		if uid != "" && event.UID == uid && event.CalendarID == calendarID {
+			return copyEvent(event), nil
+		}
+	}
+
+	return nil, entity.ErrEventNotFound
+}
+
+func (s *Storage) GetForTime(_ context.Context, scope entity.Scope, t time.Time) (*entity.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	for _, event := range s.data {
-		if event.DateTime == t {
-			return event, nil
+		if event.DateTime.Equal(t) && scope.Contains(event) {
+			return copyEvent(event), nil
 		}
 	}
 
 	return nil, entity.ErrEventNotFound
 }
 
-func (s *Storage) GetForPeriod(periodStart time.Time, periodEnd time.Time) (*entity.Events, error) {
+// GetForPeriod returns events in the half-open period [periodStart, periodEnd)
+// having any of passed tags.
+func (s *Storage) GetForPeriod(
+	_ context.Context,
+	periodStart time.Time,
+	periodEnd time.Time,
+	tags ...string,
+) (*entity.Events, error) {
+	return s.getForPeriod(periodStart, periodEnd, tags, func(*entity.Event) bool { return true })
+}
+
+func (s *Storage) GetForScope(
+	_ context.Context,
+	scope entity.Scope,
+	periodStart time.Time,
+	periodEnd time.Time,
+	tags ...string,
+) (*entity.Events, error) {
+	return s.getForPeriod(periodStart, periodEnd, tags, scope.Contains)
+}
+
+func (s *Storage) getForPeriod(
+	periodStart time.Time,
+	periodEnd time.Time,
+	tags []string,
+	match func(*entity.Event) bool,
+) (*entity.Events, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	periodEvents := make(entity.Events, 0)
 
 	for _, event := range s.data {
-		if event.DateTime.After(periodStart) && event.DateTime.Before(periodEnd) {
-			periodEvents = append(periodEvents, event)
+		if !event.DateTime.Before(periodStart) && event.DateTime.Before(periodEnd) && event.HasAnyTag(tags) &&
+			match(event) {
+			periodEvents = append(periodEvents, copyEvent(event))
 		}
 	}
 
 	return &periodEvents, nil
 }
 
-func (s *Storage) GetForRemind() (*entity.Events, error) {
+// Search returns up to limit events of the scope in [start, end) matching every
+// word and quoted phrase of the query and having any of the tags, best matches
+// first. Zero start or end leaves the period open, zero limit returns every
+// match.
+func (s *Storage) Search(
+	_ context.Context,
+	scope entity.Scope,
+	query string,
+	start, end time.Time,
+	limit int,
+	tags ...string,
+) (*entity.Events, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	scores := s.index.search(query)
+	events := make(entity.Events, 0, len(scores))
+	for id := range scores {
+		event := s.data[id]
+		if (start.IsZero() || !event.DateTime.Before(start)) && (end.IsZero() || event.DateTime.Before(end)) &&
+			scope.Contains(event) && event.HasAnyTag(tags) {
+			events = append(events, copyEvent(event))
+		}
+	}
+	slices.SortFunc(events, func(a, b *entity.Event) int {
+		if scores[a.ID] != scores[b.ID] {
+			if scores[a.ID] > scores[b.ID] {
+				return -1
+			}
+			return 1
+		}
+
+		return a.DateTime.Compare(b.DateTime)
+	})
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+
+	return &events, nil
+}
+
+func (s *Storage) GetForRemind(_ context.Context) (*entity.Events, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now().UTC()
 	remindEvents := make(entity.Events, 0)
 
 	for _, event := range s.data {
-		if event.RemindSentTime.IsZero() && slices.Contains(
-			[]int{-1, 0},
-			event.RemindTime.Compare(time.Now().UTC()),
-		) {
-			remindEvents = append(remindEvents, event)
+		if isDue(event, now) {
+			remindEvents = append(remindEvents, copyEvent(event))
 		}
 	}
 
 	return &remindEvents, nil
 }
 
-func (s *Storage) MarkAsReminded(id string) error {
-	event, err := s.GetByID(id)
-	if err != nil {
-		return nil //nolint:nilerr
+// ClaimForRemind leases up to limit due reminders, which aren't leased or
+// whose lease has expired, earliest first.
+func (s *Storage) ClaimForRemind(_ context.Context, limit int, lease time.Duration) (*entity.Events, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	due := make(entity.Events, 0)
+
+	for _, event := range s.data {
+		if isDue(event, now) && !s.leases[event.ID].After(now) {
+			due = append(due, event)
+		}
+	}
+	slices.SortFunc(due, func(a, b *entity.Event) int {
+		return a.RemindTime.Compare(b.RemindTime)
+	})
+	if len(due) > limit {
+		due = due[:limit]
 	}
+
+	claimed := make(entity.Events, 0, len(due))
+	for _, event := range due {
+		s.leases[event.ID] = now.Add(lease)
+		claimed = append(claimed, copyEvent(event))
+	}
+
+	return &claimed, nil
+}
+
+// ReleaseClaim drops the event's lease, so the reminder can be claimed again.
+func (s *Storage) ReleaseClaim(_ context.Context, id string) error {
 	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, has := s.data[id]; !has {
+		return entity.ErrEventNotFound
+	}
+	delete(s.leases, id)
+
+	return nil
+}
+
+// CountForRemind returns the number of due reminders, leased ones included.
+func (s *Storage) CountForRemind(_ context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now().UTC()
+	count := 0
+
+	for _, event := range s.data {
+		if isDue(event, now) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (s *Storage) CountUserEvents(_ context.Context, userID int, since time.Time) (int, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	total, recent := 0, 0
+	for _, event := range s.data {
+		if event.UserID != userID {
+			continue
+		}
+		total++
+		if !event.CreatedAt.Before(since) {
+			recent++
+		}
+	}
+
+	return total, recent, nil
+}
+
+func (s *Storage) MarkAsReminded(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	event, has := s.data[id]
+	if !has {
+		return entity.ErrEventNotFound
+	}
+
 	event.RemindSentTime = time.Now().UTC()
-	s.mu.Unlock()
+	event.UpdatedAt = event.RemindSentTime
+	delete(s.leases, id)
 
 	return nil
 }
 
-func (s *Storage) DeleteOlderThan(t time.Time) error {
+func (s *Storage) GetOlderThan(_ context.Context, t time.Time) (*entity.Events, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := make(entity.Events, 0)
+	for _, event := range s.data {
+		if event.DateTime.Before(t) {
+			events = append(events, copyEvent(event))
+		}
+	}
+	slices.SortFunc(events, func(a, b *entity.Event) int {
+		return a.DateTime.Compare(b.DateTime)
+	})
+
+	return &events, nil
+}
+
+func (s *Storage) DeleteOlderThan(_ context.Context, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, event := range s.data {
+		if event.DateTime.Before(t) {
+			s.index.remove(event)
+			delete(s.data, id)
+			delete(s.leases, id)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveTag(_ context.Context, tag entity.Tag) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tags[tag.UserID] == nil {
+		s.tags[tag.UserID] = make(map[string]entity.Tag)
+	}
+	s.tags[tag.UserID][tag.Name] = tag
+
+	return nil
+}
+
+func (s *Storage) DeleteTag(_ context.Context, userID int, name string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	var err error
+	if _, has := s.tags[userID][name]; !has {
+		return entity.ErrTagNotFound
+	}
+	delete(s.tags[userID], name)
+
 	for _, event := range s.data {
-		if event.DateTime.Compare(t) == -1 {
-			err = s.Delete(event.ID)
-			if err != nil {
-				return err
-			}
+		if event.UserID == userID && slices.Contains(event.Tags, name) {
+			event.Tags = slices.DeleteFunc(slices.Clone(event.Tags), func(tag string) bool { return tag == name })
 		}
 	}
 
 	return nil
 }
 
+// GetTags returns the user's tags ordered by name.
+func (s *Storage) GetTags(_ context.Context, userID int) (*entity.Tags, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tags := make(entity.Tags, 0, len(s.tags[userID]))
+	for _, tag := range s.tags[userID] {
+		tags = append(tags, &tag)
+	}
+	slices.SortFunc(tags, func(a, b *entity.Tag) int { return strings.Compare(a.Name, b.Name) })
+
+	return &tags, nil
+}
+
+func (s *Storage) CreateCalendar(_ context.Context, calendar entity.Calendar) (string, error) {
+	calendar.ID = uuid.New().String()
+	calendar.CreatedAt = time.Now().UTC()
+	calendar.UpdatedAt = calendar.CreatedAt
+
+	s.mu.Lock()
+	s.calendars[calendar.ID] = &calendar
+	s.mu.Unlock()
+
+	return calendar.ID, nil
+}
+
+// UpdateCalendar renames the calendar or passes it to another owner.
+func (s *Storage) UpdateCalendar(_ context.Context, calendar entity.Calendar) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, has := s.calendars[calendar.ID]
+	if !has {
+		return entity.ErrCalendarNotFound
+	}
+
+	calendar.CreatedAt = existing.CreatedAt
+	calendar.UpdatedAt = time.Now().UTC()
+	s.calendars[calendar.ID] = &calendar
+
+	return nil
+}
+
+func (s *Storage) DeleteCalendar(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, has := s.calendars[id]; !has {
+		return entity.ErrCalendarNotFound
+	}
+	delete(s.calendars, id)
+	delete(s.shares, id)
+
+	for eventID, event := range s.data {
+		if event.CalendarID == id {
+			s.index.remove(event)
+			delete(s.data, eventID)
+			delete(s.leases, eventID)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) GetCalendar(_ context.Context, id string) (*entity.Calendar, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	calendar, has := s.calendars[id]
+	if !has {
+		return nil, entity.ErrCalendarNotFound
+	}
+	c := *calendar
+
+	return &c, nil
+}
+
+func (s *Storage) GetCalendars(_ context.Context, userID int) (*entity.Calendars, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	calendars := make(entity.Calendars, 0)
+	for id, calendar := range s.calendars {
+		if _, shared := s.shares[id][userID]; calendar.OwnerID == userID || shared {
+			c := *calendar
+			calendars = append(calendars, &c)
+		}
+	}
+	slices.SortFunc(calendars, func(a, b *entity.Calendar) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return &calendars, nil
+}
+
+func (s *Storage) GetRoles(_ context.Context, userID int) (map[string]entity.Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	roles := make(map[string]entity.Role)
+	for id, calendar := range s.calendars {
+		if calendar.OwnerID == userID {
+			roles[id] = entity.RoleOwner
+		} else if share, shared := s.shares[id][userID]; shared {
+			roles[id] = share.Role
+		}
+	}
+
+	return roles, nil
+}
+
+func (s *Storage) SaveShare(_ context.Context, share entity.Share) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, has := s.calendars[share.CalendarID]; !has {
+		return entity.ErrCalendarNotFound
+	}
+	if s.shares[share.CalendarID] == nil {
+		s.shares[share.CalendarID] = make(map[int]entity.Share)
+	}
+	s.shares[share.CalendarID][share.UserID] = share
+
+	return nil
+}
+
+func (s *Storage) DeleteShare(_ context.Context, calendarID string, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, has := s.shares[calendarID][userID]; !has {
+		return entity.ErrShareNotFound
+	}
+	delete(s.shares[calendarID], userID)
+
+	return nil
+}
+
+// GetShares returns shares of the calendar ordered by user.
+func (s *Storage) GetShares(_ context.Context, calendarID string) (*entity.Shares, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	shares := make(entity.Shares, 0, len(s.shares[calendarID]))
+	for _, share := range s.shares[calendarID] {
+		shares = append(shares, &share)
+	}
+	slices.SortFunc(shares, func(a, b *entity.Share) int { return a.UserID - b.UserID })
+
+	return &shares, nil
+}
+
+// State returns copies of everything the storage holds.
+func (s *Storage) State() State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state := State{
+		Events:    make([]*entity.Event, 0, len(s.data)),
+		Tags:      make([]entity.Tag, 0),
+		Calendars: make([]*entity.Calendar, 0, len(s.calendars)),
+		Shares:    make([]entity.Share, 0),
+		Digests:   make([]entity.Digest, 0, len(s.digests)),
+		Keys:      make([]entity.IdempotencyKey, 0, len(s.keys)),
+	}
+	for _, event := range s.data {
+		state.Events = append(state.Events, copyEvent(event))
+	}
+	for _, userTags := range s.tags {
+		for _, tag := range userTags {
+			state.Tags = append(state.Tags, tag)
+		}
+	}
+	for _, calendar := range s.calendars {
+		c := *calendar
+		state.Calendars = append(state.Calendars, &c)
+	}
+	for _, calendarShares := range s.shares {
+		for _, share := range calendarShares {
+			state.Shares = append(state.Shares, share)
+		}
+	}
+	for _, digest := range s.digests {
+		state.Digests = append(state.Digests, *digest)
+	}
+	for _, key := range s.keys {
+		state.Keys = append(state.Keys, key)
+	}
+
+	return state
+}
+
 func (s *Storage) Connect(_ context.Context) error {
-	s.data = make(map[string]*entity.Event)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data == nil {
+		s.data = make(map[string]*entity.Event)
+	}
+	if s.tags == nil {
+		s.tags = make(map[int]map[string]entity.Tag)
+	}
+	if s.calendars == nil {
+		s.calendars = make(map[string]*entity.Calendar)
+	}
+	if s.shares == nil {
+		s.shares = make(map[string]map[int]entity.Share)
+	}
+	if s.index == nil {
+		s.index = make(index)
+		for _, event := range s.data {
+			s.index.add(event)
+		}
+	}
+
+	return nil
+}
 
+// Ping always succeeds, the data is in the process.
+func (s *Storage) Ping(_ context.Context) error {
 	return nil
 }
 
@@ -156,6 +704,149 @@ func (s *Storage) Close(_ context.Context) error {
 	defer s.mu.Unlock()
 
 	s.data = nil
+	s.tags = nil
+	s.calendars = nil
+	s.shares = nil
+	s.index = nil
+
+	return nil
+}
+
+func copyEvent(event *entity.Event) *entity.Event {
+	e := *event
+	e.Tags = slices.Clone(event.Tags)
+
+	return &e
+}
+
+// isDue reports whether the event's reminder should be sent at now.
+func isDue(event *entity.Event, now time.Time) bool {
+	return !event.RemindTime.IsZero() && event.RemindSentTime.IsZero() && !event.RemindTime.After(now)
+}
+
+func (s *Storage) SaveDigest(_ context.Context, digest entity.Digest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, has := s.digests[digest.UserID]; has {
+		digest.LastSent = existing.LastSent
+	}
+	s.digests[digest.UserID] = &digest
+
+	return nil
+}
+
+func (s *Storage) DeleteDigest(_ context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, has := s.digests[userID]; !has {
+		return entity.ErrDigestNotFound
+	}
+	delete(s.digests, userID)
+
+	return nil
+}
+
+func (s *Storage) GetDigest(_ context.Context, userID int) (*entity.Digest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	digest, has := s.digests[userID]
+	if !has {
+		return nil, entity.ErrDigestNotFound
+	}
+	result := *digest
+
+	return &result, nil
+}
+
+func (s *Storage) GetDigests(_ context.Context) (*entity.Digests, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	digests := make(entity.Digests, 0, len(s.digests))
+	for _, digest := range s.digests {
+		result := *digest
+		digests = append(digests, &result)
+	}
+	slices.SortFunc(digests, func(a, b *entity.Digest) int {
+		return a.UserID - b.UserID
+	})
+
+	return &digests, nil
+}
+
+func (s *Storage) MarkDigestSent(_ context.Context, userID int, date string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	digest, has := s.digests[userID]
+	if !has {
+		return entity.ErrDigestNotFound
+	}
+	digest.LastSent = date
+
+	return nil
+}
+
+func (s *Storage) CreateIdempotencyKey(_ context.Context, key entity.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := keyID{key.UserID, key.Key}
+	if _, has := s.keys[id]; has {
+		return entity.ErrIdempotencyKeyExists
+	}
+	s.keys[id] = key
+
+	return nil
+}
+
+func (s *Storage) SaveIdempotencyKey(_ context.Context, key entity.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[keyID{key.UserID, key.Key}] = key
+
+	return nil
+}
+
+func (s *Storage) GetIdempotencyKey(_ context.Context, userID int, key string) (*entity.IdempotencyKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result, has := s.keys[keyID{userID, key}]
+	if !has {
+		return nil, entity.ErrIdempotencyKeyNotFound
+	}
+
+	return &result, nil
+}
+
+func (s *Storage) DeleteIdempotencyKey(_ context.Context, key entity.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := keyID{key.UserID, key.Key}
+	stored, has := s.keys[id]
+	if !has || stored.Hash != key.Hash || !stored.CreatedAt.Equal(key.CreatedAt) {
+		return entity.ErrIdempotencyKeyNotFound
+	}
+	delete(s.keys, id)
+
+	return nil
+}
+
+func (s *Storage) DeleteIdempotencyKeysBefore(_ context.Context, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, key := range s.keys {
+		if key.CreatedAt.Before(t) {
+			delete(s.keys, id)
+		}
+	}
 
 	return nil
 }
@@ -31,8 +31,8 @@ func TestStorageModify(t *testing.T) {
 		memStorage := New()
 		connectErr := memStorage.Connect(context.Background())
 		require.NoError(t, connectErr)
-		_, createErr := memStorage.Create(event)
-		storageEvents, _ := memStorage.GetAll()
+		_, createErr := memStorage.Create(context.Background(), event)
+		storageEvents, _ := memStorage.GetAll(context.Background())
 		require.NoError(t, createErr)
 		require.Len(t, *storageEvents, 1)
 	})
@@ -42,16 +42,16 @@ func TestStorageModify(t *testing.T) {
 		connectErr := memStorage.Connect(context.Background())
 		require.NoError(t, connectErr)
 		// create to init storage
-		id, createErr := memStorage.Create(event)
+		id, createErr := memStorage.Create(context.Background(), event)
 		require.NoError(t, createErr)
 		// read & update
-		event1, readErr := memStorage.GetByID(id)
+		event1, readErr := memStorage.GetByID(context.Background(), id)
 		require.NoError(t, readErr)
 		event1.Title = newTitle
-		updateErr := memStorage.Update(*event1)
+		updateErr := memStorage.Update(context.Background(), *event1)
 		require.NoError(t, updateErr)
 		// assert
-		event2, readErr := memStorage.GetByID(id)
+		event2, readErr := memStorage.GetByID(context.Background(), id)
 		require.NoError(t, readErr)
 		require.Equal(t, newTitle, event2.Title)
 	})
@@ -60,11 +60,11 @@ func TestStorageModify(t *testing.T) {
 		connectErr := memStorage.Connect(context.Background())
 		require.NoError(t, connectErr)
 		// create to init storage
-		_, createErr := memStorage.Create(event)
+		_, createErr := memStorage.Create(context.Background(), event)
 		require.NoError(t, createErr)
 		// generate random ID & try to update
 		event.ID = uuid.New().String()
-		updateErr := memStorage.Update(event)
+		updateErr := memStorage.Update(context.Background(), event)
 		// assert
 		require.ErrorIs(t, updateErr, entity.ErrEventNotFound)
 	})
@@ -73,12 +73,12 @@ func TestStorageModify(t *testing.T) {
 		connectErr := memStorage.Connect(context.Background())
 		require.NoError(t, connectErr)
 		// create to init storage
-		id, createErr := memStorage.Create(event)
+		id, createErr := memStorage.Create(context.Background(), event)
 		require.NoError(t, createErr)
 		// delete
-		deleteErr := memStorage.Delete(id)
+		deleteErr := memStorage.Delete(context.Background(), id)
 		// assert
-		events, _ := memStorage.GetAll()
+		events, _ := memStorage.GetAll(context.Background())
 		require.NoError(t, deleteErr)
 		require.Len(t, *events, 0)
 	})
@@ -90,11 +90,11 @@ func TestStorageRead(t *testing.T) {
 		connectErr := memStorage.Connect(context.Background())
 		require.NoError(t, connectErr)
 		// create to init storage
-		_, createErr := memStorage.Create(event)
+		_, createErr := memStorage.Create(context.Background(), event)
 		require.NoError(t, createErr)
 		// generate random ID & try to read
 		id := uuid.New().String()
-		_, readErr := memStorage.GetByID(id)
+		_, readErr := memStorage.GetByID(context.Background(), id)
 		// assert
 		require.ErrorIs(t, readErr, entity.ErrEventNotFound)
 	})
@@ -106,10 +106,10 @@ func TestStorageRead(t *testing.T) {
 		require.NoError(t, connectErr)
 		// create to init storage
 		for i := 0; i < n; i++ {
-			_, createErr := memStorage.Create(event)
+			_, createErr := memStorage.Create(context.Background(), event)
 			require.NoError(t, createErr)
 		}
-		events, _ := memStorage.GetAll()
+		events, _ := memStorage.GetAll(context.Background())
 		require.Len(t, *events, n)
 	})
 
@@ -129,6 +129,7 @@ func TestStorageRead(t *testing.T) {
 			initialDate.Location(),
 		)
 		events, err := strg.GetForPeriod(
+			context.Background(),
 			dayBeginning,
 			dayBeginning.Add(time.Hour*24-time.Second),
 		)
@@ -146,6 +147,7 @@ func TestStorageRead(t *testing.T) {
 		})
 		weekBeginning := weekStartDate(initialDate)
 		events, err := str.GetForPeriod(
+			context.Background(),
 			weekBeginning,
 			weekBeginning.AddDate(0, 0, 7).Add(-time.Second),
 		)
@@ -164,6 +166,7 @@ func TestStorageRead(t *testing.T) {
 		})
 		monthBeginning := time.Date(initialDate.Year(), initialDate.Month(), 1, 0, 0, 0, 0, initialDate.Location())
 		events, err := str.GetForPeriod(
+			context.Background(),
 			monthBeginning,
 			monthBeginning.AddDate(0, 1, 0).Add(-time.Second),
 		)
@@ -182,7 +185,7 @@ func TestStorageRead(t *testing.T) {
 			"4": {ID: "4", Title: "4", DateTime: date, UserID: 1, RemindTime: now.Add(-time.Hour * 1), RemindSentTime: now},
 		})
 
-		events, err := strg.GetForRemind()
+		events, err := strg.GetForRemind(context.Background())
 
 		require.NoError(t, err)
 		require.Len(t, *events, 2)
@@ -199,7 +202,7 @@ func TestStorageRead(t *testing.T) {
 			"1": &event,
 		})
 
-		err := strg.MarkAsReminded(event.ID)
+		err := strg.MarkAsReminded(context.Background(), event.ID)
 		require.NoError(t, err)
 		require.False(t, event.RemindSentTime.IsZero())
 		require.Less(t, event.RemindTime, event.RemindSentTime)
@@ -0,0 +1,49 @@
+package sqlstorage_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
+	sqlstorage "github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage/sql"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+// TestConformance needs a Postgres instance passed as TEST_DB_DSN, `make test`
+// starts one. It is skipped without it, except on CI. It is in an external
+// package, storagetest imports this one through the storage package.
+func TestConformance(t *testing.T) {
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		if os.Getenv("CI") != "" {
+			t.Fatal("TEST_DB_DSN is not set, run the tests with make test")
+		}
+		t.Skip("TEST_DB_DSN is not set")
+	}
+
+	cfg := &config.Config{}
+	cfg.DB.Dsn = dsn
+	cfg.DB.Migrate = true
+	cfg.DB.MigrationsDir = "../../../migrations"
+	ctx := cfg.WithContext(context.Background())
+
+	db, err := sql.Open("pgx", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	storagetest.Run(t, func(t *testing.T) storagetest.Storage {
+		t.Helper()
+
+		st := sqlstorage.New()
+		require.NoError(t, st.Connect(ctx))
+		t.Cleanup(func() { st.Close(ctx) })
+
+		_, err := db.ExecContext(ctx, "TRUNCATE event, tag, calendar, digest, idempotency_key CASCADE")
+		require.NoError(t, err)
+
+		return st
+	})
+}
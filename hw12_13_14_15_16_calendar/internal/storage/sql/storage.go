@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	_ "github.com/jackc/pgx/v5/stdlib" // driver import
 	"github.com/jmoiron/sqlx"
 	"github.com/pressly/goose/v3"
@@ -15,12 +17,13 @@ import (
 )
 
 type PgStorage struct {
-	db  *sqlx.DB
-	ctx context.Context
+	db           *sqlx.DB
+	queryTimeout time.Duration
 }
 
 type sqlEvent struct {
 	ID             string         `db:"id"`
+	CalendarID     sql.NullString `db:"calendar_id"`
 	UserID         int            `db:"user_id"`
 	Title          string         `db:"title"`
 	DateTime       time.Time      `db:"datetime"`
@@ -28,53 +31,132 @@ type sqlEvent struct {
 	Duration       sql.NullString `db:"duration"`
 	RemindTime     sql.NullTime   `db:"remind_time"`
 	RemindSentTime sql.NullTime   `db:"remind_sent_time"`
+	Tags           tagsArray      `db:"tags"`
+	UID            sql.NullString `db:"uid"`
 	CreatedAt      time.Time      `db:"created_at"`
 	UpdatedAt      time.Time      `db:"updated_at"`
 }
 
-var ErrConnectFailed = errors.New("error connecting to db")
+type sqlCalendar struct {
+	ID        string    `db:"id"`
+	OwnerID   int       `db:"owner_id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+type sqlShare struct {
+	CalendarID string `db:"calendar_id"`
+	UserID     int    `db:"user_id"`
+	Role       string `db:"role"`
+}
+
+type sqlDigest struct {
+	UserID   int    `db:"user_id"`
+	Time     string `db:"time"`
+	TimeZone string `db:"time_zone"`
+	LastSent string `db:"last_sent"`
+}
+
+type sqlIdempotencyKey struct {
+	UserID    int       `db:"user_id"`
+	Key       string    `db:"key"`
+	Hash      string    `db:"hash"`
+	EventID   string    `db:"event_id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type sqlTag struct {
+	UserID int    `db:"user_id"`
+	Name   string `db:"name"`
+	Color  string `db:"color"`
+}
+
+// tagsArray scans a text[] column, which the stdlib driver returns as a string.
+type tagsArray []string
+
+func (a *tagsArray) Scan(src any) error {
+	return pgtype.NewMap().SQLScanner((*[]string)(a)).Scan(src)
+}
+
+var (
+	ErrConnectFailed = errors.New("error connecting to db")
+	ErrNotConnected  = errors.New("db storage is not connected")
+)
+
+// eventColumns lists columns scanned into sqlEvent, the search column is left
+// out.
+const eventColumns = `id, calendar_id, user_id, title, datetime, description, duration, remind_time,
+	remind_sent_time, tags, uid, created_at, updated_at`
+
+// scopeCondition matches events of entity.Scope passed as the user_id and
+// calendars parameters.
+const scopeCondition = `(calendar_id = ANY(CAST(CAST(:calendars AS text[]) AS uuid[]))
+	OR (calendar_id IS NULL AND user_id = :user_id AND :user_id <> 0))`
+
+// foreignKeyViolation is the postgres error code of a missing referenced row.
+const foreignKeyViolation = "23503"
+
+// uniqueViolation is the postgres error code of a duplicate key, eventUIDIndex
+// is the unique index of event UIDs in a calendar.
+const (
+	uniqueViolation = "23505"
+	eventUIDIndex   = "event_calendar_uid_idx"
+)
+
+func (s *PgStorage) Create(ctx context.Context, event entity.Event) (string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 
-func (s *PgStorage) Create(event entity.Event) (string, error) {
 	query := `
 		INSERT INTO event (
-			user_id, title, description, datetime, duration, remind_time
+			calendar_id, user_id, title, description, datetime, duration, remind_time, tags, uid
 		) VALUES (
-			:user_id, :title, :description, :datetime, :duration, :remind_time
+			:calendar_id, :user_id, :title, :description, :datetime, :duration, :remind_time, :tags, :uid
 		)
 		RETURNING id
 	`
 
 	params := map[string]any{
+		"calendar_id": nullString(event.CalendarID),
 		"user_id":     event.UserID,
 		"title":       event.Title,
 		"description": event.Description,
-		"datetime":    event.DateTime,
+		"datetime":    event.DateTime.UTC(),
 		"duration":    event.Duration,
-		"remind_time": event.RemindTime,
+		"remind_time": nullTime(event.RemindTime),
+		"tags":        nonNil(event.Tags),
+		"uid":         nullString(event.UID),
 	}
 
 	var id string
-	stmt, err := s.db.PrepareNamedContext(s.ctx, query)
+	stmt, err := s.db.PrepareNamedContext(ctx, query)
 	if err != nil {
 		return "", err
 	}
 	defer stmt.Close()
 
-	if err = stmt.GetContext(s.ctx, &id, params); err != nil {
+	if err = stmt.GetContext(ctx, &id, params); err != nil {
+		if isUniqueViolation(err, eventUIDIndex) {
+			return "", entity.ErrEventUIDExists
+		}
 		return "", err
 	}
 
 	return id, nil
 }
 
-func (s *PgStorage) GetByID(id string) (*entity.Event, error) {
+func (s *PgStorage) GetByID(ctx context.Context, id string) (*entity.Event, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT *
+		SELECT ` + eventColumns + `
 		FROM event
 		WHERE id = :id
 	`
 
-	stmt, err := s.db.PrepareNamedContext(s.ctx, query)
+	stmt, err := s.db.PrepareNamedContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -82,7 +164,7 @@ func (s *PgStorage) GetByID(id string) (*entity.Event, error) {
 
 	var se sqlEvent
 	err = stmt.GetContext(
-		s.ctx,
+		ctx,
 		&se,
 		map[string]any{"id": id},
 	)
@@ -96,11 +178,35 @@ func (s *PgStorage) GetByID(id string) (*entity.Event, error) {
 	return s.sqlEventToEvent(&se), nil
 }
 
-func (s *PgStorage) GetAll() (*entity.Events, error) {
-	query := `SELECT * FROM event`
+func (s *PgStorage) GetByUID(ctx context.Context, calendarID, uid string) (*entity.Event, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ` + eventColumns + `
+		FROM event
+		WHERE calendar_id = $1 AND uid = $2
+	`
+
+	var se sqlEvent
+	if err := s.db.GetContext(ctx, &se, query, calendarID, uid); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, entity.ErrEventNotFound
+		}
+		return nil, err
+	}
+
+	return s.sqlEventToEvent(&se), nil
+}
+
+func (s *PgStorage) GetAll(ctx context.Context) (*entity.Events, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + eventColumns + ` FROM event`
 
 	var rows []sqlEvent
-	if err := s.db.SelectContext(s.ctx, &rows, query); err != nil {
+	if err := s.db.SelectContext(ctx, &rows, query); err != nil {
 		return nil, err
 	}
 
@@ -112,55 +218,87 @@ func (s *PgStorage) GetAll() (*entity.Events, error) {
 	return &events, nil
 }
 
-func (s *PgStorage) Update(event entity.Event) error {
+func (s *PgStorage) Update(ctx context.Context, event entity.Event) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
 	query := `
 		UPDATE event SET
+			calendar_id = :calendar_id,
 			user_id     = :user_id,
 			title       = :title,
 			description = :description,
 			datetime    = :datetime,
 			duration    = :duration,
 			remind_time = :remind_time,
+			tags        = :tags,
+			uid         = :uid,
 			updated_at  = now()
 		WHERE id = :id
 	`
 
 	params := map[string]any{
 		"id":          event.ID,
+		"calendar_id": nullString(event.CalendarID),
 		"user_id":     event.UserID,
 		"title":       event.Title,
 		"description": event.Description,
-		"datetime":    event.DateTime,
+		"datetime":    event.DateTime.UTC(),
 		"duration":    event.Duration,
-		"remind_time": event.RemindTime,
+		"remind_time": nullTime(event.RemindTime),
+		"tags":        nonNil(event.Tags),
+		"uid":         nullString(event.UID),
 	}
 
-	_, err := s.db.NamedExecContext(s.ctx, query, params)
-	return err
+	result, err := s.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		if isUniqueViolation(err, eventUIDIndex) {
+			return entity.ErrEventUIDExists
+		}
+		return err
+	}
+
+	return s.checkAffected(result, entity.ErrEventNotFound)
 }
 
-func (s *PgStorage) Delete(id string) error {
+func (s *PgStorage) Delete(ctx context.Context, id string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
 	query := `
 		DELETE FROM event
 		WHERE id = :id
 	`
 
-	_, err := s.db.NamedExecContext(
-		s.ctx,
+	result, err := s.db.NamedExecContext(
+		ctx,
 		query,
 		map[string]any{"id": id},
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	return s.checkAffected(result, entity.ErrEventNotFound)
 }
 
-func (s *PgStorage) GetForPeriod(start time.Time, end time.Time) (*entity.Events, error) {
+func (s *PgStorage) GetForPeriod(
+	ctx context.Context,
+	start time.Time,
+	end time.Time,
+	tags ...string,
+) (*entity.Events, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT *
+		SELECT ` + eventColumns + `
 		FROM event
-		WHERE datetime BETWEEN :start AND :end
+		WHERE datetime >= :start AND datetime < :end
+			AND (cardinality(CAST(:tags AS text[])) = 0 OR tags && CAST(:tags AS text[]))
 	`
 
-	stmt, err := s.db.PrepareNamedContext(s.ctx, query)
+	stmt, err := s.db.PrepareNamedContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -168,11 +306,12 @@ func (s *PgStorage) GetForPeriod(start time.Time, end time.Time) (*entity.Events
 
 	var rows []sqlEvent
 	err = stmt.SelectContext(
-		s.ctx,
+		ctx,
 		&rows,
 		map[string]any{
-			"start": start,
-			"end":   end,
+			"start": start.UTC(),
+			"end":   end.UTC(),
+			"tags":  nonNil(tags),
 		},
 	)
 	if err != nil {
@@ -187,14 +326,84 @@ func (s *PgStorage) GetForPeriod(start time.Time, end time.Time) (*entity.Events
 	return &events, nil
 }
 
-func (s *PgStorage) GetForTime(t time.Time) (*entity.Event, error) {
+// GetForScope selects events of the scope's calendars and the user's events
+// having no calendar, zero user_id selects none of the latter.
+func (s *PgStorage) GetForScope(
+	ctx context.Context,
+	scope entity.Scope,
+	start time.Time,
+	end time.Time,
+	tags ...string,
+) (*entity.Events, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ` + eventColumns + `
+		FROM event
+		WHERE datetime >= :start AND datetime < :end
+			AND (cardinality(CAST(:tags AS text[])) = 0 OR tags && CAST(:tags AS text[]))
+			AND ` + scopeCondition + `
+	`
+
+	return s.selectEvents(ctx, query, map[string]any{
+		"start":     start.UTC(),
+		"end":       end.UTC(),
+		"tags":      nonNil(tags),
+		"user_id":   scope.UserID,
+		"calendars": nonNil(scope.CalendarIDs),
+	})
+}
+
+// Search ranks matches with ts_rank, the title weighs more than the
+// description. The query is parsed by websearch_to_tsquery, so quoted phrases
+// are supported.
+func (s *PgStorage) Search(
+	ctx context.Context,
+	scope entity.Scope,
+	query string,
+	start, end time.Time,
+	limit int,
+	tags ...string,
+) (*entity.Events, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	sqlQuery := `
+		SELECT ` + eventColumns + `
+		FROM event, websearch_to_tsquery('simple', :query) AS q
+		WHERE search @@ q
+			AND (CAST(:start AS timestamp) IS NULL OR datetime >= :start)
+			AND (CAST(:end AS timestamp) IS NULL OR datetime < :end)
+			AND (cardinality(CAST(:tags AS text[])) = 0 OR tags && CAST(:tags AS text[]))
+			AND ` + scopeCondition + `
+		ORDER BY ts_rank(search, q) DESC, datetime
+		LIMIT NULLIF(CAST(:limit AS integer), 0)
+	`
+
+	return s.selectEvents(ctx, sqlQuery, map[string]any{
+		"query":     query,
+		"start":     nullTime(start),
+		"end":       nullTime(end),
+		"user_id":   scope.UserID,
+		"calendars": nonNil(scope.CalendarIDs),
+		"limit":     limit,
+		"tags":      nonNil(tags),
+	})
+}
+
+func (s *PgStorage) GetForTime(ctx context.Context, scope entity.Scope, t time.Time) (*entity.Event, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT *
+		SELECT ` + eventColumns + `
 		FROM event
-		WHERE datetime = :datetime
+		WHERE datetime = :datetime AND ` + scopeCondition + `
+		LIMIT 1
 	`
 
-	stmt, err := s.db.PrepareNamedContext(s.ctx, query)
+	stmt, err := s.db.PrepareNamedContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -202,10 +411,12 @@ func (s *PgStorage) GetForTime(t time.Time) (*entity.Event, error) {
 
 	var se sqlEvent
 	err = stmt.GetContext(
-		s.ctx,
+		ctx,
 		&se,
 		map[string]any{
-			"datetime": t,
+			"datetime":  t.UTC(),
+			"user_id":   scope.UserID,
+			"calendars": nonNil(scope.CalendarIDs),
 		},
 	)
 	if err != nil {
@@ -218,16 +429,19 @@ func (s *PgStorage) GetForTime(t time.Time) (*entity.Event, error) {
 	return s.sqlEventToEvent(&se), nil
 }
 
-func (s *PgStorage) GetForRemind() (*entity.Events, error) {
+func (s *PgStorage) GetForRemind(ctx context.Context) (*entity.Events, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT *
+		SELECT ` + eventColumns + `
 		FROM event
-		WHERE remind_sent_time IS NULL AND now() >= remind_time
+		WHERE remind_sent_time IS NULL AND remind_time <= now() AT TIME ZONE 'UTC'
 	`
 
 	var rows []sqlEvent
 	err := s.db.SelectContext(
-		s.ctx,
+		ctx,
 		&rows,
 		query,
 	)
@@ -243,10 +457,98 @@ func (s *PgStorage) GetForRemind() (*entity.Events, error) {
 	return &events, nil
 }
 
-func (s *PgStorage) MarkAsReminded(id string) error {
+// ClaimForRemind leases due reminders in one statement. Rows locked by a
+// concurrent claim are skipped instead of waited for.
+func (s *PgStorage) ClaimForRemind(ctx context.Context, limit int, lease time.Duration) (*entity.Events, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
 	query := `
 		UPDATE event SET
-			remind_sent_time = now(),
+			remind_lease_until = now() AT TIME ZONE 'UTC' + make_interval(secs => CAST(:lease AS double precision))
+		WHERE id IN (
+			SELECT id
+			FROM event
+			WHERE remind_sent_time IS NULL AND remind_time <= now() AT TIME ZONE 'UTC'
+				AND (remind_lease_until IS NULL OR remind_lease_until <= now() AT TIME ZONE 'UTC')
+			ORDER BY remind_time
+			LIMIT :limit
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + eventColumns
+
+	return s.selectEvents(ctx, query, map[string]any{
+		"lease": lease.Seconds(),
+		"limit": limit,
+	})
+}
+
+func (s *PgStorage) ReleaseClaim(ctx context.Context, id string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE event SET remind_lease_until = NULL
+		WHERE id = :id
+	`
+
+	result, err := s.db.NamedExecContext(ctx, query, map[string]any{
+		"id": id,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.checkAffected(result, entity.ErrEventNotFound)
+}
+
+func (s *PgStorage) CountForRemind(ctx context.Context) (int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT count(*)
+		FROM event
+		WHERE remind_sent_time IS NULL AND remind_time <= now() AT TIME ZONE 'UTC'
+	`
+
+	var count int
+	if err := s.db.GetContext(ctx, &count, query); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (s *PgStorage) CountUserEvents(ctx context.Context, userID int, since time.Time) (int, int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT count(*) AS total, count(*) FILTER (WHERE created_at >= $2) AS recent
+		FROM event
+		WHERE user_id = $1
+	`
+
+	var counts struct {
+		Total  int `db:"total"`
+		Recent int `db:"recent"`
+	}
+	if err := s.db.GetContext(ctx, &counts, query, userID, since.UTC()); err != nil {
+		return 0, 0, err
+	}
+
+	return counts.Total, counts.Recent, nil
+}
+
+func (s *PgStorage) MarkAsReminded(ctx context.Context, id string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE event SET
+			remind_sent_time = now() AT TIME ZONE 'UTC',
+			remind_lease_until = NULL,
 			updated_at  = now()
 		WHERE id = :id
 	`
@@ -255,35 +557,520 @@ func (s *PgStorage) MarkAsReminded(id string) error {
 		"id": id,
 	}
 
-	_, err := s.db.NamedExecContext(s.ctx, query, params)
+	result, err := s.db.NamedExecContext(ctx, query, params)
+	if err != nil {
+		return err
+	}
+
+	return s.checkAffected(result, entity.ErrEventNotFound)
+}
+
+func (s *PgStorage) SaveTag(ctx context.Context, tag entity.Tag) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO tag (user_id, name, color)
+		VALUES (:user_id, :name, :color)
+		ON CONFLICT (user_id, name) DO UPDATE SET color = EXCLUDED.color
+	`
+
+	_, err := s.db.NamedExecContext(ctx, query, sqlTag{UserID: tag.UserID, Name: tag.Name, Color: tag.Color})
+	return err
+}
+
+// DeleteTag removes the tag from the catalog and from the user's events in
+// one transaction.
+func (s *PgStorage) DeleteTag(ctx context.Context, userID int, name string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	params := map[string]any{
+		"user_id": userID,
+		"name":    name,
+	}
+
+	result, err := tx.NamedExecContext(ctx, `DELETE FROM tag WHERE user_id = :user_id AND name = :name`, params)
+	if err != nil {
+		return err
+	}
+	if err = s.checkAffected(result, entity.ErrTagNotFound); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE event SET
+			tags       = array_remove(tags, CAST(:name AS text)),
+			updated_at = now()
+		WHERE user_id = :user_id AND CAST(:name AS text) = ANY(tags)
+	`
+	if _, err = tx.NamedExecContext(ctx, query, params); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *PgStorage) GetTags(ctx context.Context, userID int) (*entity.Tags, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT user_id, name, color
+		FROM tag
+		WHERE user_id = $1
+		ORDER BY name
+	`
+
+	var rows []sqlTag
+	if err := s.db.SelectContext(ctx, &rows, query, userID); err != nil {
+		return nil, err
+	}
+
+	tags := make(entity.Tags, 0, len(rows))
+	for _, r := range rows {
+		tags = append(tags, &entity.Tag{UserID: r.UserID, Name: r.Name, Color: r.Color})
+	}
+
+	return &tags, nil
+}
+
+func (s *PgStorage) SaveDigest(ctx context.Context, digest entity.Digest) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO digest (user_id, time, time_zone)
+		VALUES (:user_id, :time, :time_zone)
+		ON CONFLICT (user_id) DO UPDATE SET time = EXCLUDED.time, time_zone = EXCLUDED.time_zone
+	`
+
+	_, err := s.db.NamedExecContext(ctx, query, sqlDigest{
+		UserID:   digest.UserID,
+		Time:     digest.Time,
+		TimeZone: digest.TimeZone,
+	})
+	return err
+}
+
+func (s *PgStorage) DeleteDigest(ctx context.Context, userID int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM digest WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.checkAffected(result, entity.ErrDigestNotFound)
+}
+
+func (s *PgStorage) GetDigest(ctx context.Context, userID int) (*entity.Digest, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT user_id, time, time_zone, last_sent
+		FROM digest
+		WHERE user_id = $1
+	`
+
+	var row sqlDigest
+	if err := s.db.GetContext(ctx, &row, query, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, entity.ErrDigestNotFound
+		}
+		return nil, err
+	}
+
+	return sqlDigestToDigest(row), nil
+}
+
+func (s *PgStorage) GetDigests(ctx context.Context) (*entity.Digests, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT user_id, time, time_zone, last_sent
+		FROM digest
+		ORDER BY user_id
+	`
+
+	var rows []sqlDigest
+	if err := s.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, err
+	}
+
+	digests := make(entity.Digests, 0, len(rows))
+	for _, r := range rows {
+		digests = append(digests, sqlDigestToDigest(r))
+	}
+
+	return &digests, nil
+}
+
+func (s *PgStorage) MarkDigestSent(ctx context.Context, userID int, date string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `UPDATE digest SET last_sent = $2 WHERE user_id = $1`, userID, date)
+	if err != nil {
+		return err
+	}
+
+	return s.checkAffected(result, entity.ErrDigestNotFound)
+}
+
+func sqlDigestToDigest(r sqlDigest) *entity.Digest {
+	return &entity.Digest{UserID: r.UserID, Time: r.Time, TimeZone: r.TimeZone, LastSent: r.LastSent}
+}
+
+func (s *PgStorage) CreateCalendar(ctx context.Context, calendar entity.Calendar) (string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO calendar (owner_id, name)
+		VALUES ($1, $2)
+		RETURNING id
+	`
+
+	var id string
+	if err := s.db.GetContext(ctx, &id, query, calendar.OwnerID, calendar.Name); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (s *PgStorage) UpdateCalendar(ctx context.Context, calendar entity.Calendar) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE calendar SET
+			owner_id   = $2,
+			name       = $3,
+			updated_at = now()
+		WHERE id = $1
+	`
+
+	result, err := s.db.ExecContext(ctx, query, calendar.ID, calendar.OwnerID, calendar.Name)
+	if err != nil {
+		return err
+	}
+
+	return s.checkAffected(result, entity.ErrCalendarNotFound)
+}
+
+// DeleteCalendar relies on foreign keys to remove the calendar's events and
+// shares.
+func (s *PgStorage) DeleteCalendar(ctx context.Context, id string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `DELETE FROM calendar WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	return s.checkAffected(result, entity.ErrCalendarNotFound)
+}
+
+func (s *PgStorage) GetCalendar(ctx context.Context, id string) (*entity.Calendar, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var sc sqlCalendar
+	err := s.db.GetContext(ctx, &sc, `SELECT * FROM calendar WHERE id = $1`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, entity.ErrCalendarNotFound
+		}
+		return nil, err
+	}
+
+	return s.sqlCalendarToCalendar(&sc), nil
+}
+
+func (s *PgStorage) GetCalendars(ctx context.Context, userID int) (*entity.Calendars, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT c.*
+		FROM calendar c
+		WHERE c.owner_id = $1
+			OR EXISTS (SELECT 1 FROM calendar_share cs WHERE cs.calendar_id = c.id AND cs.user_id = $1)
+		ORDER BY c.created_at, c.id
+	`
+
+	var rows []sqlCalendar
+	if err := s.db.SelectContext(ctx, &rows, query, userID); err != nil {
+		return nil, err
+	}
+
+	calendars := make(entity.Calendars, 0, len(rows))
+	for _, r := range rows {
+		calendars = append(calendars, s.sqlCalendarToCalendar(&r))
+	}
+
+	return &calendars, nil
+}
+
+// GetRoles joins shares of the user to the calendars, so the roles are read
+// in a single query.
+func (s *PgStorage) GetRoles(ctx context.Context, userID int) (map[string]entity.Role, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT c.id AS calendar_id,
+			CASE WHEN c.owner_id = $1 THEN 'owner' ELSE cs.role END AS role
+		FROM calendar c
+		LEFT JOIN calendar_share cs ON cs.calendar_id = c.id AND cs.user_id = $1
+		WHERE c.owner_id = $1 OR cs.user_id IS NOT NULL
+	`
+
+	var rows []sqlShare
+	if err := s.db.SelectContext(ctx, &rows, query, userID); err != nil {
+		return nil, err
+	}
+
+	roles := make(map[string]entity.Role, len(rows))
+	for _, r := range rows {
+		roles[r.CalendarID] = entity.Role(r.Role)
+	}
+
+	return roles, nil
+}
+
+func (s *PgStorage) SaveShare(ctx context.Context, share entity.Share) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO calendar_share (calendar_id, user_id, role)
+		VALUES (:calendar_id, :user_id, :role)
+		ON CONFLICT (calendar_id, user_id) DO UPDATE SET role = EXCLUDED.role
+	`
+
+	_, err := s.db.NamedExecContext(ctx, query, sqlShare{
+		CalendarID: share.CalendarID,
+		UserID:     share.UserID,
+		Role:       string(share.Role),
+	})
+	if isForeignKeyViolation(err) {
+		return entity.ErrCalendarNotFound
+	}
+
 	return err
 }
 
+func (s *PgStorage) DeleteShare(ctx context.Context, calendarID string, userID int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `DELETE FROM calendar_share WHERE calendar_id = $1 AND user_id = $2`
+
+	result, err := s.db.ExecContext(ctx, query, calendarID, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.checkAffected(result, entity.ErrShareNotFound)
+}
+
+func (s *PgStorage) GetShares(ctx context.Context, calendarID string) (*entity.Shares, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT calendar_id, user_id, role
+		FROM calendar_share
+		WHERE calendar_id = $1
+		ORDER BY user_id
+	`
+
+	var rows []sqlShare
+	if err := s.db.SelectContext(ctx, &rows, query, calendarID); err != nil {
+		return nil, err
+	}
+
+	shares := make(entity.Shares, 0, len(rows))
+	for _, r := range rows {
+		shares = append(shares, &entity.Share{CalendarID: r.CalendarID, UserID: r.UserID, Role: entity.Role(r.Role)})
+	}
+
+	return &shares, nil
+}
+
 func New() *PgStorage {
 	return &PgStorage{}
 }
 
-func (s *PgStorage) DeleteOlderThan(t time.Time) error {
+func (s *PgStorage) GetOlderThan(ctx context.Context, t time.Time) (*entity.Events, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT ` + eventColumns + `
+		FROM event
+		WHERE datetime < :time
+		ORDER BY datetime
+	`
+
+	return s.selectEvents(ctx, query, map[string]any{
+		"time": t.UTC(),
+	})
+}
+
+func (s *PgStorage) DeleteOlderThan(ctx context.Context, t time.Time) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
 	query := `
-		DELETE event
+		DELETE FROM event
 		WHERE datetime < :time
 	`
 
 	params := map[string]any{
-		"time": t,
+		"time": t.UTC(),
 	}
 
-	_, err := s.db.NamedExecContext(s.ctx, query, params)
+	_, err := s.db.NamedExecContext(ctx, query, params)
 	return err
 }
 
+func (s *PgStorage) CreateIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO idempotency_key (user_id, key, hash, event_id, created_at)
+		VALUES (:user_id, :key, :hash, :event_id, :created_at)
+		ON CONFLICT (user_id, key) DO NOTHING
+	`
+
+	result, err := s.db.NamedExecContext(ctx, query, toSQLIdempotencyKey(key))
+	if err != nil {
+		return err
+	}
+
+	return s.checkAffected(result, entity.ErrIdempotencyKeyExists)
+}
+
+func (s *PgStorage) SaveIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO idempotency_key (user_id, key, hash, event_id, created_at)
+		VALUES (:user_id, :key, :hash, :event_id, :created_at)
+		ON CONFLICT (user_id, key) DO UPDATE
+			SET hash = EXCLUDED.hash, event_id = EXCLUDED.event_id, created_at = EXCLUDED.created_at
+	`
+
+	_, err := s.db.NamedExecContext(ctx, query, toSQLIdempotencyKey(key))
+	return err
+}
+
+func (s *PgStorage) GetIdempotencyKey(ctx context.Context, userID int, key string) (*entity.IdempotencyKey, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT user_id, key, hash, event_id, created_at
+		FROM idempotency_key
+		WHERE user_id = $1 AND key = $2
+	`
+
+	var row sqlIdempotencyKey
+	if err := s.db.GetContext(ctx, &row, query, userID, key); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, entity.ErrIdempotencyKeyNotFound
+		}
+		return nil, err
+	}
+
+	return &entity.IdempotencyKey{
+		UserID:    row.UserID,
+		Key:       row.Key,
+		Hash:      row.Hash,
+		EventID:   row.EventID,
+		CreatedAt: row.CreatedAt.UTC(),
+	}, nil
+}
+
+func (s *PgStorage) DeleteIdempotencyKey(ctx context.Context, key entity.IdempotencyKey) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `
+		DELETE FROM idempotency_key
+		WHERE user_id = :user_id AND key = :key AND hash = :hash AND created_at = :created_at
+	`
+
+	result, err := s.db.NamedExecContext(ctx, query, toSQLIdempotencyKey(key))
+	if err != nil {
+		return err
+	}
+
+	return s.checkAffected(result, entity.ErrIdempotencyKeyNotFound)
+}
+
+func (s *PgStorage) DeleteIdempotencyKeysBefore(ctx context.Context, t time.Time) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_key WHERE created_at < $1`, t.UTC())
+	return err
+}
+
+func toSQLIdempotencyKey(key entity.IdempotencyKey) sqlIdempotencyKey {
+	return sqlIdempotencyKey{
+		UserID:    key.UserID,
+		Key:       key.Key,
+		Hash:      key.Hash,
+		EventID:   key.EventID,
+		CreatedAt: key.CreatedAt.UTC(),
+	}
+}
+
+// selectEvents runs a named query returning event rows.
+func (s *PgStorage) selectEvents(ctx context.Context, query string, params map[string]any) (*entity.Events, error) {
+	stmt, err := s.db.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var rows []sqlEvent
+	if err = stmt.SelectContext(ctx, &rows, params); err != nil {
+		return nil, err
+	}
+
+	events := make(entity.Events, 0, len(rows))
+	for _, r := range rows {
+		events = append(events, s.sqlEventToEvent(&r))
+	}
+
+	return &events, nil
+}
+
 func (s *PgStorage) Connect(ctx context.Context) error {
 	cfg := config.GetFromContext(ctx)
 	if cfg == nil {
 		return config.ErrNoConfigInContext
 	}
 
-	db, err := sqlx.Open("pgx", cfg.DB.Dsn)
+	db, err := sqlx.Open("pgx", cfg.DSN())
 	if err != nil {
 		return fmt.Errorf(ErrConnectFailed.Error()+":%w", err)
 	}
@@ -293,13 +1080,25 @@ func (s *PgStorage) Connect(ctx context.Context) error {
 	}
 
 	s.db = db
-	s.ctx = ctx
+	s.queryTimeout = cfg.DB.QueryTimeout
 	if cfg.DB.Migrate {
 		return s.migrate(cfg.DB.MigrationsDir)
 	}
 	return nil
 }
 
+// Ping checks the database answers within the query timeout.
+func (s *PgStorage) Ping(ctx context.Context) error {
+	if s.db == nil {
+		return ErrNotConnected
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return s.db.PingContext(ctx)
+}
+
 func (s *PgStorage) Close(_ context.Context) error {
 	if s.db == nil {
 		return nil
@@ -307,37 +1106,112 @@ func (s *PgStorage) Close(_ context.Context) error {
 
 	err := s.db.Close()
 	s.db = nil
-	s.ctx = nil
 
 	return err
 }
 
+// withTimeout bounds a single query by the configured DB.QueryTimeout,
+// keeping any earlier deadline of the caller's context.
+func (s *PgStorage) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, s.queryTimeout)
+}
+
+// nullTime stores zero time as NULL. Columns are timestamps without time zone,
+// so times are passed in UTC.
+func nullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// nullString stores an empty string as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation
+}
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == constraint
+}
+
+// nonNil passes an empty array instead of NULL for a nil slice.
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+
+	return values
+}
+
+// checkAffected maps a statement that changed no rows to notFound.
+func (s *PgStorage) checkAffected(result sql.Result, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound
+	}
+
+	return nil
+}
+
 func (s *PgStorage) sqlEventToEvent(se *sqlEvent) *entity.Event {
 	e := &entity.Event{
 		ID:        se.ID,
 		UserID:    se.UserID,
 		Title:     se.Title,
 		DateTime:  se.DateTime,
+		Tags:      se.Tags,
 		CreatedAt: se.CreatedAt,
 		UpdatedAt: se.UpdatedAt,
 	}
 
+	if se.CalendarID.Valid {
+		e.CalendarID = se.CalendarID.String
+	}
 	if se.Description.Valid {
 		e.Description = se.Description.String
 	}
 	if se.Duration.Valid {
 		e.Duration = se.Duration.String
 	}
+	if se.UID.Valid {
+		e.UID = se.UID.String
+	}
 	if se.RemindTime.Valid {
 		e.RemindTime = se.RemindTime.Time
 	}
-	if se.RemindTime.Valid {
+	if se.RemindSentTime.Valid {
 		e.RemindSentTime = se.RemindSentTime.Time
 	}
 
 	return e
 }
 
+func (s *PgStorage) sqlCalendarToCalendar(sc *sqlCalendar) *entity.Calendar {
+	return &entity.Calendar{
+		ID:        sc.ID,
+		OwnerID:   sc.OwnerID,
+		Name:      sc.Name,
+		CreatedAt: sc.CreatedAt,
+		UpdatedAt: sc.UpdatedAt,
+	}
+}
+
 func (s *PgStorage) migrate(migrationDir string) error {
 	if s.db == nil {
 		return fmt.Errorf("database connection is not established")
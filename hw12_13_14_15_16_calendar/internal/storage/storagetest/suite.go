@@ -0,0 +1,779 @@
+// Package storagetest provides the behavioral test suite every event storage
+// backend must pass.
+package storagetest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/entity"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// Storage is the storage the suite runs against.
+type Storage = storage.Storage
+
+// Factory returns a connected storage without events. It is called once per
+// subtest.
+type Factory func(t *testing.T) Storage
+
+// Run runs the suite against storages made by newStorage. Times are kept in
+// UTC with microsecond precision, which every backend can store exactly.
+func Run(t *testing.T, newStorage Factory) {
+	t.Helper()
+
+	t.Run("crud", func(t *testing.T) { testCRUD(t, newStorage(t)) })
+	t.Run("missing ids", func(t *testing.T) { testMissing(t, newStorage(t)) })
+	t.Run("period boundaries", func(t *testing.T) { testPeriod(t, newStorage(t)) })
+	t.Run("get for time", func(t *testing.T) { testForTime(t, newStorage(t)) })
+	t.Run("remind selection", func(t *testing.T) { testRemind(t, newStorage(t)) })
+	t.Run("remind claims", func(t *testing.T) { testClaim(t, newStorage(t)) })
+	t.Run("user counts", func(t *testing.T) { testUserCounts(t, newStorage(t)) })
+	t.Run("retention", func(t *testing.T) { testRetention(t, newStorage(t)) })
+	t.Run("tags", func(t *testing.T) { testTags(t, newStorage(t)) })
+	t.Run("digests", func(t *testing.T) { testDigests(t, newStorage(t)) })
+	t.Run("idempotency keys", func(t *testing.T) { testIdempotencyKeys(t, newStorage(t)) })
+	t.Run("calendars", func(t *testing.T) { testCalendars(t, newStorage(t)) })
+	t.Run("uids", func(t *testing.T) { testUIDs(t, newStorage(t)) })
+	t.Run("search", func(t *testing.T) { testSearch(t, newStorage(t)) })
+	t.Run("concurrency", func(t *testing.T) { testConcurrency(t, newStorage(t)) })
+}
+
+var day = time.Date(2025, 12, 5, 0, 0, 0, 0, time.UTC)
+
+func newEvent(title string, dateTime time.Time) entity.Event {
+	return entity.Event{
+		UserID:      1,
+		Title:       title,
+		DateTime:    dateTime,
+		Description: "description of " + title,
+		Duration:    "01:00:00",
+	}
+}
+
+func create(t *testing.T, st Storage, event entity.Event) string {
+	t.Helper()
+
+	id, err := st.Create(context.Background(), event)
+	require.NoError(t, err)
+	require.NotEmpty(t, id)
+
+	return id
+}
+
+// titlesOf returns a function listing sorted titles of events returned by a
+// storage call, so calls can be passed directly.
+func titlesOf(t *testing.T) func(*entity.Events, error) []string {
+	t.Helper()
+
+	return func(events *entity.Events, err error) []string {
+		t.Helper()
+		require.NoError(t, err)
+
+		return titles(events)
+	}
+}
+
+func titles(events *entity.Events) []string {
+	result := make([]string, 0, len(*events))
+	for _, event := range *events {
+		result = append(result, event.Title)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+func testCRUD(t *testing.T, st Storage) {
+	t.Helper()
+	ctx := context.Background()
+	titles := titlesOf(t)
+
+	event := newEvent("1", day.Add(time.Hour))
+	event.RemindTime = day
+	id := create(t, st, event)
+
+	stored, err := st.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, id, stored.ID)
+	require.Equal(t, event.UserID, stored.UserID)
+	require.Equal(t, event.Title, stored.Title)
+	require.Equal(t, event.Description, stored.Description)
+	require.Equal(t, event.Duration, stored.Duration)
+	require.True(t, event.DateTime.Equal(stored.DateTime))
+	require.True(t, event.RemindTime.Equal(stored.RemindTime))
+	require.True(t, stored.RemindSentTime.IsZero())
+	require.False(t, stored.CreatedAt.IsZero())
+
+	// returned events are not bound to the storage
+	stored.Title = "changed"
+	stored, err = st.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "1", stored.Title)
+
+	update := newEvent("2", day.Add(time.Hour*2))
+	update.ID = id
+	update.UserID = 2
+	require.NoError(t, st.Update(ctx, update))
+
+	stored, err = st.GetByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "2", stored.Title)
+	require.Equal(t, 2, stored.UserID)
+	require.True(t, update.DateTime.Equal(stored.DateTime))
+	require.True(t, stored.RemindTime.IsZero())
+	require.False(t, stored.CreatedAt.IsZero())
+
+	create(t, st, newEvent("3", day.Add(time.Hour*3)))
+	require.Equal(t, []string{"2", "3"}, titles(st.GetAll(ctx)))
+
+	require.NoError(t, st.Delete(ctx, id))
+	_, err = st.GetByID(ctx, id)
+	require.ErrorIs(t, err, entity.ErrEventNotFound)
+	require.Equal(t, []string{"3"}, titles(st.GetAll(ctx)))
+}
+
+func testMissing(t *testing.T, st Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	// a valid ID format, unknown to the storage
+	id := "00000000-0000-0000-0000-000000000000"
+
+	_, err := st.GetByID(ctx, id)
+	require.ErrorIs(t, err, entity.ErrEventNotFound)
+
+	event := newEvent("1", day)
+	event.ID = id
+	require.ErrorIs(t, st.Update(ctx, event), entity.ErrEventNotFound)
+	require.ErrorIs(t, st.Delete(ctx, id), entity.ErrEventNotFound)
+	require.ErrorIs(t, st.MarkAsReminded(ctx, id), entity.ErrEventNotFound)
+	require.ErrorIs(t, st.ReleaseClaim(ctx, id), entity.ErrEventNotFound)
+
+	_, err = st.GetForTime(ctx, entity.Scope{UserID: 1}, day)
+	require.ErrorIs(t, err, entity.ErrEventNotFound)
+}
+
+func testPeriod(t *testing.T, st Storage) {
+	t.Helper()
+	ctx := context.Background()
+	titles := titlesOf(t)
+
+	create(t, st, newEvent("before", day.Add(-time.Microsecond)))
+	create(t, st, newEvent("start", day))
+	create(t, st, newEvent("middle", day.Add(time.Hour*12)))
+	create(t, st, newEvent("last", day.Add(time.Hour*24-time.Microsecond)))
+	create(t, st, newEvent("end", day.Add(time.Hour*24)))
+
+	// periods are half-open: start is included, end is not
+	require.Equal(
+		t,
+		[]string{"last", "middle", "start"},
+		titles(st.GetForPeriod(ctx, day, day.Add(time.Hour*24))),
+	)
+	require.Equal(
+		t,
+		[]string{"end", "last", "middle", "start"},
+		titles(st.GetForPeriod(ctx, day, day.Add(time.Hour*24+time.Microsecond))),
+	)
+	require.Empty(t, titles(st.GetForPeriod(ctx, day, day)))
+	require.Empty(t, titles(st.GetForPeriod(ctx, day.AddDate(0, 1, 0), day.AddDate(0, 2, 0))))
+}
+
+func testForTime(t *testing.T, st Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	create(t, st, newEvent("1", day))
+	create(t, st, newEvent("2", day.Add(time.Hour)))
+	scope := entity.Scope{UserID: 1}
+
+	event, err := st.GetForTime(ctx, scope, day.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, "2", event.Title)
+
+	// the same instant in another location
+	event, err = st.GetForTime(ctx, scope, day.In(time.FixedZone("UTC+3", 3*60*60)))
+	require.NoError(t, err)
+	require.Equal(t, "1", event.Title)
+
+	_, err = st.GetForTime(ctx, scope, day.Add(time.Minute))
+	require.ErrorIs(t, err, entity.ErrEventNotFound)
+
+	// events out of the scope aren't returned
+	calendarID, err := st.CreateCalendar(ctx, entity.Calendar{OwnerID: 2, Name: "work"})
+	require.NoError(t, err)
+	other := newEvent("other", day.Add(time.Hour*2))
+	other.UserID = 2
+	other.CalendarID = calendarID
+	create(t, st, other)
+	_, err = st.GetForTime(ctx, scope, day.Add(time.Hour*2))
+	require.ErrorIs(t, err, entity.ErrEventNotFound)
+	_, err = st.GetForTime(ctx, entity.Scope{UserID: 2}, day)
+	require.ErrorIs(t, err, entity.ErrEventNotFound)
+	event, err = st.GetForTime(ctx, entity.Scope{CalendarIDs: []string{calendarID}}, day.Add(time.Hour*2))
+	require.NoError(t, err)
+	require.Equal(t, "other", event.Title)
+}
+
+func testRemind(t *testing.T, st Storage) {
+	t.Helper()
+	ctx := context.Background()
+	titles := titlesOf(t)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	due := newEvent("due", now.Add(time.Hour))
+	due.RemindTime = now.Add(-time.Minute)
+	dueID := create(t, st, due)
+
+	overdue := newEvent("overdue", now.Add(-time.Hour))
+	overdue.RemindTime = now.Add(-time.Hour * 2)
+	create(t, st, overdue)
+
+	future := newEvent("future", now.Add(time.Hour*2))
+	future.RemindTime = now.Add(time.Hour)
+	create(t, st, future)
+
+	create(t, st, newEvent("without reminder", now.Add(time.Hour)))
+
+	require.Equal(t, []string{"due", "overdue"}, titles(st.GetForRemind(ctx)))
+
+	require.NoError(t, st.MarkAsReminded(ctx, dueID))
+	event, err := st.GetByID(ctx, dueID)
+	require.NoError(t, err)
+	require.False(t, event.RemindSentTime.IsZero())
+	require.Equal(t, []string{"overdue"}, titles(st.GetForRemind(ctx)))
+
+	// updates keep the remind sent time
+	due.ID = dueID
+	due.Title = "due updated"
+	require.NoError(t, st.Update(ctx, due))
+	event, err = st.GetByID(ctx, dueID)
+	require.NoError(t, err)
+	require.False(t, event.RemindSentTime.IsZero())
+	require.Equal(t, []string{"overdue"}, titles(st.GetForRemind(ctx)))
+}
+
+func testUserCounts(t *testing.T, st Storage) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	for i := range 3 {
+		create(t, st, newEvent(fmt.Sprintf("first %d", i), now.Add(time.Duration(i)*time.Hour)))
+	}
+	other := newEvent("other", now)
+	other.UserID = 2
+	create(t, st, other)
+
+	for since, expected := range map[time.Time]int{
+		{}:                  3,
+		now.Add(-time.Hour): 3,
+		now.Add(time.Hour):  0,
+	} {
+		total, recent, err := st.CountUserEvents(ctx, 1, since)
+		require.NoError(t, err)
+		require.Equal(t, 3, total, "since %s", since)
+		require.Equal(t, expected, recent, "since %s", since)
+	}
+
+	total, recent, err := st.CountUserEvents(ctx, 3, time.Time{})
+	require.NoError(t, err)
+	require.Zero(t, total)
+	require.Zero(t, recent)
+}
+
+func testIdempotencyKeys(t *testing.T, st Storage) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	key := entity.IdempotencyKey{UserID: 1, Key: "retry-1", Hash: "abc", CreatedAt: now}
+	require.NoError(t, st.CreateIdempotencyKey(ctx, key))
+	require.ErrorIs(t, st.CreateIdempotencyKey(ctx, key), entity.ErrIdempotencyKeyExists)
+
+	// keys are per user
+	other := key
+	other.UserID = 2
+	other.CreatedAt = now.Add(-time.Hour)
+	require.NoError(t, st.CreateIdempotencyKey(ctx, other))
+
+	key.EventID = "event-1"
+	require.NoError(t, st.SaveIdempotencyKey(ctx, key))
+	saved, err := st.GetIdempotencyKey(ctx, 1, "retry-1")
+	require.NoError(t, err)
+	require.Equal(t, key, *saved)
+
+	require.NoError(t, st.DeleteIdempotencyKeysBefore(ctx, now.Add(-time.Minute)))
+	_, err = st.GetIdempotencyKey(ctx, 2, "retry-1")
+	require.ErrorIs(t, err, entity.ErrIdempotencyKeyNotFound)
+
+	// a replaced key is kept
+	replaced := key
+	replaced.CreatedAt = now.Add(-time.Second)
+	require.ErrorIs(t, st.DeleteIdempotencyKey(ctx, replaced), entity.ErrIdempotencyKeyNotFound)
+	replaced = key
+	replaced.Hash = "def"
+	require.ErrorIs(t, st.DeleteIdempotencyKey(ctx, replaced), entity.ErrIdempotencyKeyNotFound)
+
+	require.NoError(t, st.DeleteIdempotencyKey(ctx, key))
+	require.ErrorIs(t, st.DeleteIdempotencyKey(ctx, key), entity.ErrIdempotencyKeyNotFound)
+	_, err = st.GetIdempotencyKey(ctx, 1, "retry-1")
+	require.ErrorIs(t, err, entity.ErrIdempotencyKeyNotFound)
+}
+
+func testClaim(t *testing.T, st Storage) {
+	t.Helper()
+	ctx := context.Background()
+	titles := titlesOf(t)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	ids := make(map[string]string)
+	for i, title := range []string{"first", "second", "third"} {
+		event := newEvent(title, now.Add(time.Hour))
+		event.RemindTime = now.Add(-time.Minute * time.Duration(3-i))
+		ids[title] = create(t, st, event)
+	}
+
+	count, err := st.CountForRemind(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+
+	// earliest reminders are claimed first, claimed ones are skipped
+	require.Equal(t, []string{"first", "second"}, titles(st.ClaimForRemind(ctx, 2, time.Minute)))
+	require.Equal(t, []string{"third"}, titles(st.ClaimForRemind(ctx, 2, time.Minute)))
+	require.Empty(t, titles(st.ClaimForRemind(ctx, 2, time.Minute)))
+
+	// leased reminders are still due
+	count, err = st.CountForRemind(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+
+	require.NoError(t, st.ReleaseClaim(ctx, ids["second"]))
+	require.Equal(t, []string{"second"}, titles(st.ClaimForRemind(ctx, 2, time.Minute)))
+
+	require.NoError(t, st.MarkAsReminded(ctx, ids["first"]))
+	count, err = st.CountForRemind(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	// expired leases can be claimed again
+	require.NoError(t, st.ReleaseClaim(ctx, ids["second"]))
+	require.Equal(t, []string{"second"}, titles(st.ClaimForRemind(ctx, 1, -time.Second)))
+	require.Equal(t, []string{"second"}, titles(st.ClaimForRemind(ctx, 1, time.Minute)))
+}
+
+func testRetention(t *testing.T, st Storage) {
+	t.Helper()
+	ctx := context.Background()
+	titles := titlesOf(t)
+
+	create(t, st, newEvent("old", day.AddDate(-1, 0, -1)))
+	create(t, st, newEvent("boundary", day.AddDate(-1, 0, 0)))
+	create(t, st, newEvent("new", day))
+
+	events, err := st.GetOlderThan(ctx, day.AddDate(-1, 0, 1))
+	require.NoError(t, err)
+	require.Len(t, *events, 2)
+	require.Equal(t, "old", (*events)[0].Title)
+	require.Equal(t, "boundary", (*events)[1].Title)
+
+	require.NoError(t, st.DeleteOlderThan(ctx, day.AddDate(-1, 0, 0)))
+	require.Equal(t, []string{"boundary", "new"}, titles(st.GetAll(ctx)))
+
+	require.NoError(t, st.DeleteOlderThan(ctx, day.AddDate(-2, 0, 0)))
+	require.Equal(t, []string{"boundary", "new"}, titles(st.GetAll(ctx)))
+}
+
+func testTags(t *testing.T, st Storage) {
+	t.Helper()
+	ctx := context.Background()
+	titles := titlesOf(t)
+
+	require.NoError(t, st.SaveTag(ctx, entity.Tag{UserID: 1, Name: "work", Color: "#ff0000"}))
+	require.NoError(t, st.SaveTag(ctx, entity.Tag{UserID: 1, Name: "home", Color: "#00ff00"}))
+	require.NoError(t, st.SaveTag(ctx, entity.Tag{UserID: 2, Name: "work", Color: "#0000ff"}))
+	// saving an existing tag changes its color
+	require.NoError(t, st.SaveTag(ctx, entity.Tag{UserID: 1, Name: "work", Color: "#ffffff"}))
+
+	tags, err := st.GetTags(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, entity.Tags{
+		{UserID: 1, Name: "home", Color: "#00ff00"},
+		{UserID: 1, Name: "work", Color: "#ffffff"},
+	}, *tags)
+
+	work := newEvent("work", day.Add(time.Hour))
+	work.Tags = []string{"work"}
+	workID := create(t, st, work)
+
+	both := newEvent("both", day.Add(time.Hour*2))
+	both.Tags = []string{"home", "work"}
+	create(t, st, both)
+
+	create(t, st, newEvent("untagged", day.Add(time.Hour*3)))
+
+	other := newEvent("other user", day.Add(time.Hour*4))
+	other.UserID = 2
+	other.Tags = []string{"work"}
+	create(t, st, other)
+
+	stored, err := st.GetByID(ctx, workID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"work"}, stored.Tags)
+
+	end := day.Add(time.Hour * 24)
+	require.Equal(t, []string{"both", "other user", "untagged", "work"}, titles(st.GetForPeriod(ctx, day, end)))
+	require.Equal(t, []string{"both"}, titles(st.GetForPeriod(ctx, day, end, "home")))
+	require.Equal(t, []string{"both", "other user", "work"}, titles(st.GetForPeriod(ctx, day, end, "work")))
+	require.Equal(t, []string{"both", "other user", "work"}, titles(st.GetForPeriod(ctx, day, end, "home", "work")))
+	require.Empty(t, titles(st.GetForPeriod(ctx, day, end, "unknown")))
+
+	// deleting a tag removes it from the user's events only
+	require.NoError(t, st.DeleteTag(ctx, 1, "work"))
+	require.ErrorIs(t, st.DeleteTag(ctx, 1, "work"), entity.ErrTagNotFound)
+	require.Equal(t, []string{"other user"}, titles(st.GetForPeriod(ctx, day, end, "work")))
+
+	stored, err = st.GetByID(ctx, workID)
+	require.NoError(t, err)
+	require.Empty(t, stored.Tags)
+
+	tags, err = st.GetTags(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, *tags, 1)
+}
+
+func testDigests(t *testing.T, st Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	require.NoError(t, st.SaveDigest(ctx, entity.Digest{UserID: 2, Time: "08:00", TimeZone: "UTC"}))
+	require.NoError(t, st.SaveDigest(ctx, entity.Digest{UserID: 1, Time: "07:30", TimeZone: "Europe/Moscow"}))
+
+	require.NoError(t, st.MarkDigestSent(ctx, 1, "2025-12-05"))
+	require.ErrorIs(t, st.MarkDigestSent(ctx, 3, "2025-12-05"), entity.ErrDigestNotFound)
+
+	// changing the subscription keeps the last sent date
+	require.NoError(t, st.SaveDigest(ctx, entity.Digest{UserID: 1, Time: "09:00", TimeZone: "Europe/Moscow"}))
+	digest, err := st.GetDigest(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, entity.Digest{UserID: 1, Time: "09:00", TimeZone: "Europe/Moscow", LastSent: "2025-12-05"}, *digest)
+
+	digests, err := st.GetDigests(ctx)
+	require.NoError(t, err)
+	require.Equal(t, entity.Digests{
+		{UserID: 1, Time: "09:00", TimeZone: "Europe/Moscow", LastSent: "2025-12-05"},
+		{UserID: 2, Time: "08:00", TimeZone: "UTC"},
+	}, *digests)
+
+	require.NoError(t, st.DeleteDigest(ctx, 2))
+	require.ErrorIs(t, st.DeleteDigest(ctx, 2), entity.ErrDigestNotFound)
+	_, err = st.GetDigest(ctx, 2)
+	require.ErrorIs(t, err, entity.ErrDigestNotFound)
+}
+
+func testCalendars(t *testing.T, st Storage) {
+	t.Helper()
+	ctx := context.Background()
+	titles := titlesOf(t)
+
+	workID, err := st.CreateCalendar(ctx, entity.Calendar{OwnerID: 1, Name: "work"})
+	require.NoError(t, err)
+	teamID, err := st.CreateCalendar(ctx, entity.Calendar{OwnerID: 2, Name: "team"})
+	require.NoError(t, err)
+
+	calendar, err := st.GetCalendar(ctx, workID)
+	require.NoError(t, err)
+	require.Equal(t, "work", calendar.Name)
+	require.Equal(t, 1, calendar.OwnerID)
+	require.False(t, calendar.CreatedAt.IsZero())
+
+	require.NoError(t, st.UpdateCalendar(ctx, entity.Calendar{ID: workID, OwnerID: 1, Name: "job"}))
+	calendar, err = st.GetCalendar(ctx, workID)
+	require.NoError(t, err)
+	require.Equal(t, "job", calendar.Name)
+
+	require.NoError(t, st.SaveShare(ctx, entity.Share{CalendarID: teamID, UserID: 1, Role: entity.RoleViewer}))
+	require.NoError(t, st.SaveShare(ctx, entity.Share{CalendarID: teamID, UserID: 3, Role: entity.RoleViewer}))
+	// saving an existing share changes its role
+	require.NoError(t, st.SaveShare(ctx, entity.Share{CalendarID: teamID, UserID: 1, Role: entity.RoleEditor}))
+
+	shares, err := st.GetShares(ctx, teamID)
+	require.NoError(t, err)
+	require.Equal(t, entity.Shares{
+		{CalendarID: teamID, UserID: 1, Role: entity.RoleEditor},
+		{CalendarID: teamID, UserID: 3, Role: entity.RoleViewer},
+	}, *shares)
+
+	calendars, err := st.GetCalendars(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, *calendars, 2)
+	calendars, err = st.GetCalendars(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, *calendars, 1)
+	require.Equal(t, teamID, (*calendars)[0].ID)
+
+	require.NoError(t, st.DeleteShare(ctx, teamID, 3))
+	require.ErrorIs(t, st.DeleteShare(ctx, teamID, 3), entity.ErrShareNotFound)
+	calendars, err = st.GetCalendars(ctx, 3)
+	require.NoError(t, err)
+	require.Empty(t, *calendars)
+
+	work := newEvent("work", day.Add(time.Hour))
+	work.CalendarID = workID
+	workEventID := create(t, st, work)
+	team := newEvent("team", day.Add(time.Hour*2))
+	team.CalendarID = teamID
+	create(t, st, team)
+
+	create(t, st, newEvent("legacy", day.Add(time.Hour*3)))
+	other := newEvent("other legacy", day.Add(time.Hour*4))
+	other.UserID = 2
+	create(t, st, other)
+
+	stored, err := st.GetByID(ctx, workEventID)
+	require.NoError(t, err)
+	require.Equal(t, workID, stored.CalendarID)
+
+	roles, err := st.GetRoles(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, map[string]entity.Role{workID: entity.RoleOwner, teamID: entity.RoleEditor}, roles)
+	roles, err = st.GetRoles(ctx, 3)
+	require.NoError(t, err)
+	require.Empty(t, roles)
+
+	end := day.Add(time.Hour * 24)
+	require.Equal(
+		t,
+		[]string{"legacy", "team"},
+		titles(st.GetForScope(ctx, entity.Scope{UserID: 1, CalendarIDs: []string{teamID}}, day, end)),
+	)
+	// a zero user selects no events without calendar
+	require.Equal(t, []string{"work"}, titles(st.GetForScope(ctx, entity.Scope{CalendarIDs: []string{workID}}, day, end)))
+	require.Empty(t, titles(st.GetForScope(ctx, entity.Scope{UserID: 3}, day, end)))
+
+	// deleting a calendar removes its events and shares
+	require.NoError(t, st.DeleteCalendar(ctx, teamID))
+	require.ErrorIs(t, st.DeleteCalendar(ctx, teamID), entity.ErrCalendarNotFound)
+	_, err = st.GetCalendar(ctx, teamID)
+	require.ErrorIs(t, err, entity.ErrCalendarNotFound)
+	require.Equal(t, []string{"legacy", "other legacy", "work"}, titles(st.GetAll(ctx)))
+	calendars, err = st.GetCalendars(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, *calendars, 1)
+
+	require.ErrorIs(
+		t,
+		st.UpdateCalendar(ctx, entity.Calendar{ID: teamID, OwnerID: 2, Name: "team"}),
+		entity.ErrCalendarNotFound,
+	)
+}
+
+func testUIDs(t *testing.T, st Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	workID, err := st.CreateCalendar(ctx, entity.Calendar{OwnerID: 1, Name: "work"})
+	require.NoError(t, err)
+	homeID, err := st.CreateCalendar(ctx, entity.Calendar{OwnerID: 1, Name: "home"})
+	require.NoError(t, err)
+
+	event := newEvent("planning", day.Add(time.Hour))
+	event.CalendarID = workID
+	event.UID = "planning@example.com"
+	id := create(t, st, event)
+	plain := newEvent("plain", day.Add(time.Hour*2))
+	plain.CalendarID = workID
+	plainID := create(t, st, plain)
+
+	stored, err := st.GetByUID(ctx, workID, "planning@example.com")
+	require.NoError(t, err)
+	require.Equal(t, id, stored.ID)
+	require.Equal(t, "planning@example.com", stored.UID)
+
+	// UIDs are looked up within a calendar
+	_, err = st.GetByUID(ctx, homeID, "planning@example.com")
+	require.ErrorIs(t, err, entity.ErrEventNotFound)
+	_, err = st.GetByUID(ctx, workID, "")
+	require.ErrorIs(t, err, entity.ErrEventNotFound)
+
+	// UIDs are unique within a calendar
+	duplicate := newEvent("duplicate", day.Add(time.Hour*3))
+	duplicate.CalendarID = workID
+	duplicate.UID = "planning@example.com"
+	_, err = st.Create(ctx, duplicate)
+	require.ErrorIs(t, err, entity.ErrEventUIDExists)
+	plainStored, err := st.GetByID(ctx, plainID)
+	require.NoError(t, err)
+	plainStored.UID = "planning@example.com"
+	require.ErrorIs(t, st.Update(ctx, *plainStored), entity.ErrEventUIDExists)
+
+	stored.CalendarID = homeID
+	require.NoError(t, st.Update(ctx, *stored))
+	stored, err = st.GetByUID(ctx, homeID, "planning@example.com")
+	require.NoError(t, err)
+	require.Equal(t, id, stored.ID)
+
+	// the UID is free in the calendar the event has left
+	duplicate.UID = "planning@example.com"
+	create(t, st, duplicate)
+}
+
+func testSearch(t *testing.T, st Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	searchIn := func(scope entity.Scope, query string, limit int) []string {
+		t.Helper()
+
+		events, err := st.Search(ctx, scope, query, time.Time{}, time.Time{}, limit)
+		require.NoError(t, err)
+
+		result := make([]string, 0, len(*events))
+		for _, event := range *events {
+			result = append(result, event.Title)
+		}
+
+		return result
+	}
+	search := func(query string, start, end time.Time) []string {
+		t.Helper()
+
+		events, err := st.Search(ctx, entity.Scope{UserID: 1}, query, start, end, 0)
+		require.NoError(t, err)
+
+		result := make([]string, 0, len(*events))
+		for _, event := range *events {
+			result = append(result, event.Title)
+		}
+
+		return result
+	}
+
+	vendor := newEvent("Vendor call", day.AddDate(0, -6, 0))
+	vendor.Description = "Meeting with the vendor about the contract"
+	vendor.Tags = []string{"sales"}
+	create(t, st, vendor)
+
+	review := newEvent("Contract review", day)
+	review.Description = "Review the vendor contract, meeting notes from the vendor call"
+	review.Tags = []string{"legal", "q4"}
+	reviewID := create(t, st, review)
+
+	standup := newEvent("Standup", day.Add(time.Hour))
+	standup.Description = "Daily meeting"
+	create(t, st, standup)
+
+	calendarID, err := st.CreateCalendar(ctx, entity.Calendar{OwnerID: 2, Name: "vendors"})
+	require.NoError(t, err)
+	shared := newEvent("Vendor dinner", day.Add(time.Hour*2))
+	shared.UserID = 2
+	shared.CalendarID = calendarID
+	create(t, st, shared)
+	private := newEvent("Vendor visit", day.Add(time.Hour*3))
+	private.UserID = 2
+	create(t, st, private)
+
+	// title matches rank higher than description ones
+	require.Equal(t, []string{"Vendor call", "Contract review"}, search("vendor", time.Time{}, time.Time{}))
+
+	// only events of the scope are found, up to the limit
+	require.Equal(
+		t,
+		[]string{"Vendor call", "Vendor dinner", "Contract review"},
+		searchIn(entity.Scope{UserID: 1, CalendarIDs: []string{calendarID}}, "vendor", 0),
+	)
+	require.Equal(t, []string{"Vendor dinner"}, searchIn(entity.Scope{CalendarIDs: []string{calendarID}}, "vendor", 0))
+	require.Equal(t, []string{"Vendor visit"}, searchIn(entity.Scope{UserID: 2}, "vendor", 0))
+	require.Equal(
+		t,
+		[]string{"Vendor call", "Vendor dinner"},
+		searchIn(entity.Scope{UserID: 1, CalendarIDs: []string{calendarID}}, "vendor", 2),
+	)
+	// only events having any of the tags are found
+	found := titlesOf(t)
+	require.Equal(
+		t,
+		[]string{"Contract review"},
+		found(st.Search(ctx, entity.Scope{UserID: 1}, "vendor", time.Time{}, time.Time{}, 0, "legal", "other")),
+	)
+	require.Empty(t, found(st.Search(ctx, entity.Scope{UserID: 1}, "vendor", time.Time{}, time.Time{}, 0, "other")))
+
+	require.Equal(t, []string{"Contract review", "Vendor call"}, search("CONTRACT", time.Time{}, time.Time{}))
+	// every word must match
+	require.Equal(t, []string{"Vendor call", "Contract review"}, search("vendor meeting", time.Time{}, time.Time{}))
+	require.Empty(t, search("vendor lunch", time.Time{}, time.Time{}))
+
+	// phrases match consecutive words only
+	require.Equal(t, []string{"Vendor call"}, search(`"with the vendor"`, time.Time{}, time.Time{}))
+	require.Equal(t, []string{"Contract review"}, search(`"vendor contract"`, time.Time{}, time.Time{}))
+	require.Empty(t, search(`"contract vendor"`, time.Time{}, time.Time{}))
+
+	// date range is half-open
+	require.Equal(t, []string{"Contract review"}, search("vendor", day, day.Add(time.Hour)))
+	require.Equal(t, []string{"Vendor call"}, search("vendor", time.Time{}, day))
+	require.Equal(t, []string{"Contract review"}, search("vendor", day, time.Time{}))
+
+	// the index follows updates and deletes
+	review.ID = reviewID
+	review.Description = "Review the agreement"
+	require.NoError(t, st.Update(ctx, review))
+	require.Equal(t, []string{"Vendor call"}, search("vendor", time.Time{}, time.Time{}))
+	require.Equal(t, []string{"Contract review"}, search("agreement", time.Time{}, time.Time{}))
+
+	require.NoError(t, st.Delete(ctx, reviewID))
+	require.Empty(t, search("agreement", time.Time{}, time.Time{}))
+}
+
+func testConcurrency(t *testing.T, st Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	const workers, perWorker = 8, 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				event := newEvent(fmt.Sprintf("%d-%d", w, i), day.Add(time.Minute*time.Duration(w*perWorker+i)))
+				event.RemindTime = day
+				id, err := st.Create(ctx, event)
+				if err == nil {
+					event.ID = id
+					err = st.Update(ctx, event)
+				}
+				if err == nil {
+					err = st.MarkAsReminded(ctx, id)
+				}
+				if err == nil {
+					_, err = st.GetForPeriod(ctx, day, day.Add(time.Hour*24))
+				}
+				if err == nil && i%2 == 0 {
+					err = st.Delete(ctx, id)
+				}
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	events, err := st.GetForPeriod(ctx, day, day.Add(time.Hour*24))
+	require.NoError(t, err)
+	require.Len(t, *events, workers*perWorker/2)
+	for _, event := range *events {
+		require.False(t, event.RemindSentTime.IsZero())
+	}
+}
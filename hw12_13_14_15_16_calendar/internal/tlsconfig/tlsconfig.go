@@ -0,0 +1,195 @@
+// Package tlsconfig builds TLS configs of servers and clients from config
+// settings. Certificates are read again when their files change, so they are
+// rotated without a restart.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+)
+
+var ErrNoCertificates = errors.New("no certificates found")
+
+// Server returns the config of a listener serving the key pair. With a client
+// CA file, clients must present a certificate signed by its CAs.
+func Server(settings config.ServerTLS, logg logger.Logger) (*tls.Config, error) {
+	pair, err := newKeyPair(settings.CertFile, settings.KeyFile, logg)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return pair.get(), nil
+		},
+	}
+	if settings.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	pool, err := newCertPool(settings.ClientCAFile, logg)
+	if err != nil {
+		return nil, err
+	}
+	// clients are verified by VerifyConnection instead of ClientCAs, so
+	// reloaded CAs apply to configs cloned by servers
+	tlsConfig.ClientAuth = tls.RequireAnyClientCert
+	tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+		return verifyClient(state.PeerCertificates, pool.get())
+	}
+
+	return tlsConfig, nil
+}
+
+func verifyClient(certificates []*x509.Certificate, roots *x509.CertPool) error {
+	if len(certificates) == 0 {
+		return fmt.Errorf("client certificate: %w", ErrNoCertificates)
+	}
+
+	options := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, intermediate := range certificates[1:] {
+		options.Intermediates.AddCert(intermediate)
+	}
+	if _, err := certificates[0].Verify(options); err != nil {
+		return fmt.Errorf("client certificate: %w", err)
+	}
+
+	return nil
+}
+
+// Client returns the config of a connection verifying the server by the CA
+// file, which is read once, and presenting the client certificate if set.
+func Client(settings config.ClientTLS, logg logger.Logger) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: settings.ServerName,
+	}
+
+	if settings.CAFile != "" {
+		pool, err := loadCertPool(settings.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if settings.CertFile != "" {
+		pair, err := newKeyPair(settings.CertFile, settings.KeyFile, logg)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return pair.get(), nil
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// watched is a value loaded from files, it is loaded again on the first get
+// after a file's modification time changes. If loading fails, e.g. a file is
+// being written, the last value is kept and loading is retried by next get.
+type watched[T any] struct {
+	paths  []string
+	load   func() (T, error)
+	logger logger.Logger
+
+	mu     sync.Mutex
+	value  T
+	mtimes []time.Time
+}
+
+// newWatched loads the value, failing if it can't.
+func newWatched[T any](paths []string, load func() (T, error), logg logger.Logger) (*watched[T], error) {
+	w := &watched[T]{paths: paths, load: load, logger: logg}
+
+	mtimes, err := w.modified()
+	if err != nil {
+		return nil, err
+	}
+	if w.value, err = load(); err != nil {
+		return nil, err
+	}
+	w.mtimes = mtimes
+
+	return w, nil
+}
+
+func (w *watched[T]) get() T {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// files being replaced may be missing, they are checked again next time
+	mtimes, err := w.modified()
+	if err != nil || slices.EqualFunc(mtimes, w.mtimes, time.Time.Equal) {
+		return w.value
+	}
+
+	value, err := w.load()
+	if err != nil {
+		w.logger.Warning("Error reloading %v, keeping the loaded one: %v", w.paths, err)
+		return w.value
+	}
+	w.value, w.mtimes = value, mtimes
+	w.logger.Info("Reloaded %v.", w.paths)
+
+	return w.value
+}
+
+func (w *watched[T]) modified() ([]time.Time, error) {
+	mtimes := make([]time.Time, 0, len(w.paths))
+	for _, path := range w.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		mtimes = append(mtimes, info.ModTime())
+	}
+
+	return mtimes, nil
+}
+
+func newKeyPair(certFile, keyFile string, logg logger.Logger) (*watched[*tls.Certificate], error) {
+	return newWatched([]string{certFile, keyFile}, func() (*tls.Certificate, error) {
+		pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load key pair: %w", err)
+		}
+
+		return &pair, nil
+	}, logg)
+}
+
+func newCertPool(file string, logg logger.Logger) (*watched[*x509.CertPool], error) {
+	return newWatched([]string{file}, func() (*x509.CertPool, error) {
+		return loadCertPool(file)
+	}, logg)
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read CA file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("CA file %s: %w", file, ErrNoCertificates)
+	}
+
+	return pool, nil
+}
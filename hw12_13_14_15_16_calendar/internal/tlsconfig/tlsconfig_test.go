@@ -0,0 +1,228 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/config"
+	"github.com/rainb0w-clwn/otus_golang_hw/hw12_13_14_15_calendar/internal/logger"
+	"github.com/stretchr/testify/require"
+)
+
+var logg = logger.New(logger.Debug, io.Discard)
+
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string
+}
+
+var serial int64
+
+// newAuthority writes a self-signed CA to dir.
+func newAuthority(t *testing.T, dir, name string) authority {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial++
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, name+".pem")
+	writePEM(t, file, "CERTIFICATE", der)
+
+	return authority{cert: cert, key: key, file: file}
+}
+
+// issue writes a certificate for localhost signed by the CA and its key to
+// dir, returning their files.
+func (a authority) issue(t *testing.T, dir, name string, usage x509.ExtKeyUsage) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile = filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, file, blockType string, der []byte) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(file, data, 0o600))
+	// files written within a test may get the same modification time
+	later := time.Now().Add(time.Duration(serial) * time.Second)
+	require.NoError(t, os.Chtimes(file, later, later))
+}
+
+// handshake connects the client to the server over loopback, returning the
+// server certificate seen by the client and errors of both sides.
+func handshake(t *testing.T, server, client *tls.Config) (*x509.Certificate, error) {
+	t.Helper()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", server)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		defer conn.Close()
+		serverErr <- conn.(*tls.Conn).Handshake()
+	}()
+
+	conn, err := tls.Dial("tcp", listener.Addr().String(), client)
+	if err != nil {
+		return nil, errors.Join(err, <-serverErr)
+	}
+	defer conn.Close()
+
+	if err = <-serverErr; err != nil {
+		return nil, err
+	}
+
+	return conn.ConnectionState().PeerCertificates[0], nil
+}
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t, dir, "ca")
+	certFile, keyFile := ca.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+
+	server, err := Server(config.ServerTLS{CertFile: certFile, KeyFile: keyFile}, logg)
+	require.NoError(t, err)
+	client, err := Client(config.ClientTLS{CAFile: ca.file, ServerName: "localhost"}, logg)
+	require.NoError(t, err)
+
+	cert, err := handshake(t, server, client)
+	require.NoError(t, err)
+	require.Equal(t, "server", cert.Subject.CommonName)
+
+	// servers are verified by the CA
+	other := newAuthority(t, dir, "other")
+	client, err = Client(config.ClientTLS{CAFile: other.file, ServerName: "localhost"}, logg)
+	require.NoError(t, err)
+	_, err = handshake(t, server, client)
+	require.Error(t, err)
+
+	_, err = Server(config.ServerTLS{CertFile: certFile, KeyFile: filepath.Join(dir, "missing.pem")}, logg)
+	require.Error(t, err)
+	_, err = Client(config.ClientTLS{CAFile: keyFile}, logg)
+	require.ErrorIs(t, err, ErrNoCertificates)
+}
+
+func TestServer_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t, dir, "ca")
+	serverCert, serverKey := ca.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, dir, "client", x509.ExtKeyUsageClientAuth)
+
+	server, err := Server(config.ServerTLS{CertFile: serverCert, KeyFile: serverKey, ClientCAFile: ca.file}, logg)
+	require.NoError(t, err)
+
+	client, err := Client(config.ClientTLS{
+		CAFile: ca.file, CertFile: clientCert, KeyFile: clientKey, ServerName: "localhost",
+	}, logg)
+	require.NoError(t, err)
+	_, err = handshake(t, server, client)
+	require.NoError(t, err)
+
+	// clients without a certificate or with one of another CA are rejected
+	client, err = Client(config.ClientTLS{CAFile: ca.file, ServerName: "localhost"}, logg)
+	require.NoError(t, err)
+	_, err = handshake(t, server, client)
+	require.Error(t, err)
+
+	other := newAuthority(t, dir, "other")
+	otherCert, otherKey := other.issue(t, dir, "intruder", x509.ExtKeyUsageClientAuth)
+	client, err = Client(config.ClientTLS{
+		CAFile: ca.file, CertFile: otherCert, KeyFile: otherKey, ServerName: "localhost",
+	}, logg)
+	require.NoError(t, err)
+	_, err = handshake(t, server, client)
+	require.Error(t, err)
+
+	// a reloaded client CA file trusts the other CA
+	data, err := os.ReadFile(other.file)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(ca.file, data, 0o600))
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(ca.file, later, later))
+	_, err = handshake(t, server, client)
+	require.NoError(t, err)
+}
+
+func TestServer_Reload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newAuthority(t, dir, "ca")
+	certFile, keyFile := ca.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+
+	server, err := Server(config.ServerTLS{CertFile: certFile, KeyFile: keyFile}, logg)
+	require.NoError(t, err)
+	client, err := Client(config.ClientTLS{CAFile: ca.file, ServerName: "localhost"}, logg)
+	require.NoError(t, err)
+
+	first, err := handshake(t, server, client)
+	require.NoError(t, err)
+
+	ca.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	second, err := handshake(t, server, client)
+	require.NoError(t, err)
+	require.NotEqual(t, first.SerialNumber, second.SerialNumber)
+
+	// a broken file keeps the loaded pair
+	require.NoError(t, os.WriteFile(keyFile, []byte("broken"), 0o600))
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+	third, err := handshake(t, server, client)
+	require.NoError(t, err)
+	require.Equal(t, second.SerialNumber, third.SerialNumber)
+}
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE event ADD COLUMN IF NOT EXISTS tags text[] NOT NULL DEFAULT '{}';
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS event_tags_idx ON event USING gin (tags);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS tag
+(
+    user_id integer    not null,
+    name    text       not null,
+    color   varchar(7) not null default '',
+    primary key (user_id, name)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS tag;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP INDEX IF EXISTS event_tags_idx;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE event DROP COLUMN IF EXISTS tags;
+-- +goose StatementEnd
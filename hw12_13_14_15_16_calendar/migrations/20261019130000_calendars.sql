@@ -0,0 +1,53 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS calendar
+(
+    id         uuid               default gen_random_uuid() not null primary key,
+    owner_id   integer   not null,
+    name       text      not null,
+    created_at timestamp not null default now(),
+    updated_at timestamp not null default now()
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS calendar_owner_idx ON calendar (owner_id);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS calendar_share
+(
+    calendar_id uuid        not null references calendar (id) on delete cascade,
+    user_id     integer     not null,
+    role        varchar(16) not null,
+    primary key (calendar_id, user_id)
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS calendar_share_user_idx ON calendar_share (user_id);
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE event ADD COLUMN IF NOT EXISTS calendar_id uuid references calendar (id) on delete cascade;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS event_calendar_idx ON event (calendar_id);
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- every user having events gets a default calendar holding them
+INSERT INTO calendar (owner_id, name)
+SELECT DISTINCT user_id, 'Default' FROM event;
+-- +goose StatementEnd
+-- +goose StatementBegin
+UPDATE event SET calendar_id = calendar.id
+FROM calendar
+WHERE calendar.owner_id = event.user_id AND event.calendar_id IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE event DROP COLUMN IF EXISTS calendar_id;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS calendar_share;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS calendar;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the simple configuration doesn't stem words, matching the in-memory index
+ALTER TABLE event ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+) STORED;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS event_search_idx ON event USING gin (search);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS event_search_idx;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE event DROP COLUMN IF EXISTS search;
+-- +goose StatementEnd
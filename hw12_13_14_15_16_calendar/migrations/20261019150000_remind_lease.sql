@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE event ADD COLUMN IF NOT EXISTS remind_lease_until timestamp;
+-- +goose StatementEnd
+-- +goose StatementBegin
+-- due reminders are looked up by every claim, keep them cheap to find
+CREATE INDEX IF NOT EXISTS event_remind_due_idx ON event (remind_time) WHERE remind_sent_time IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS event_remind_due_idx;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE event DROP COLUMN IF EXISTS remind_lease_until;
+-- +goose StatementEnd
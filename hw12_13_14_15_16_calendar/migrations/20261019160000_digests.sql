@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS digest
+(
+    user_id   integer     not null primary key,
+    time      varchar(5)  not null,
+    time_zone text        not null,
+    last_sent varchar(10) not null default ''
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS digest;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE event ADD COLUMN IF NOT EXISTS uid text;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE UNIQUE INDEX IF NOT EXISTS event_calendar_uid_idx ON event (calendar_id, uid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS event_calendar_uid_idx;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE event DROP COLUMN IF EXISTS uid;
+-- +goose StatementEnd
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"testing"
 	"time"
 
@@ -13,6 +14,9 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// userHeader passes the requesting user to the service.
+const userHeader = "X-User-Id"
+
 type CreateEventRequestData struct {
 	UserID      string `json:"userId"`
 	Title       string `json:"title"`
@@ -65,13 +69,12 @@ func TestCreateEvent_Success(t *testing.T) {
 			RemindTime:  datetime.Add(2 * time.Hour).Format(time.RFC3339),
 		},
 	}
-	body, _ := json.Marshal(req)
+	body, _ := json.Marshal(req.EventData)
 
-	httpReq, err := http.NewRequestWithContext(
-		ctx, "POST", calendarBaseURL+"/event.EventService/CreateEvent", bytes.NewBuffer(body),
-	)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", calendarBaseURL+"/v1/events", bytes.NewBuffer(body))
 	require.NoError(t, err)
 	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(userHeader, "1")
 
 	client := &http.Client{}
 	resp, err := client.Do(httpReq)
@@ -90,36 +93,52 @@ func TestCreateEvent_Error(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	now := time.Now().UTC()
-	datetime := now.Add(1 * time.Hour)
+	// the time is taken by the first event
+	datetime := time.Now().UTC().Add(5 * time.Hour).Truncate(time.Second)
+	data := CreateEventRequestData{
+		UserID:   "1",
+		Title:    "Meeting",
+		DateTime: datetime.Format(time.RFC3339),
+		Duration: "02:00:00",
+	}
 
-	req := CreateEventRequest{
-		EventData: CreateEventRequestData{
-			Title:       "Meeting",
-			Description: "Team meeting",
-			DateTime:    datetime.Format(time.RFC3339),
-			Duration:    "02:00:00",
-			RemindTime:  datetime.Add(2 * time.Hour).Format(time.RFC3339),
-		},
+	require.Equal(t, http.StatusOK, postEvent(ctx, t, "1", data))
+	require.Equal(t, http.StatusConflict, postEvent(ctx, t, "1", data))
+}
+
+func TestCreateEvent_NoUser(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	datetime := time.Now().UTC().Add(6 * time.Hour)
+	data := CreateEventRequestData{
+		Title:    "Meeting",
+		DateTime: datetime.Format(time.RFC3339),
+		Duration: "02:00:00",
 	}
-	body, _ := json.Marshal(req)
 
-	httpReq, err := http.NewRequestWithContext(
-		ctx, "POST", calendarBaseURL+"/event.EventService/CreateEvent", bytes.NewBuffer(body),
-	)
+	require.Equal(t, http.StatusUnauthorized, postEvent(ctx, t, "", data))
+}
+
+// postEvent creates the event on behalf of userID, none if it is empty, and
+// returns the response status.
+func postEvent(ctx context.Context, t *testing.T, userID string, data CreateEventRequestData) int {
+	t.Helper()
+
+	body, _ := json.Marshal(data)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", calendarBaseURL+"/v1/events", bytes.NewBuffer(body))
 	require.NoError(t, err)
 	httpReq.Header.Set("Content-Type", "application/json")
+	if userID != "" {
+		httpReq.Header.Set(userHeader, userID)
+	}
 
 	client := &http.Client{}
 	resp, err := client.Do(httpReq)
 	require.NoError(t, err)
 	defer resp.Body.Close()
 
-	require.NotEqual(t, http.StatusOK, resp.StatusCode)
-}
-
-type GetDateEventRequest struct {
-	StartDate string `json:"startDate"`
+	return resp.StatusCode
 }
 
 func createTestEvent(ctx context.Context, t *testing.T, userID, title string, start, end int64) {
@@ -132,13 +151,12 @@ func createTestEvent(ctx context.Context, t *testing.T, userID, title string, st
 			Duration: time.Time{}.Add(time.Duration(end) * time.Second).Format(time.TimeOnly),
 		},
 	}
-	body, _ := json.Marshal(req)
+	body, _ := json.Marshal(req.EventData)
 
-	httpReq, err := http.NewRequestWithContext(
-		ctx, "POST", calendarBaseURL+"/event.EventService/CreateEvent", bytes.NewBuffer(body),
-	)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", calendarBaseURL+"/v1/events", bytes.NewBuffer(body))
 	require.NoError(t, err)
 	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(userHeader, userID)
 
 	client := &http.Client{}
 	resp, err := client.Do(httpReq)
@@ -157,14 +175,10 @@ func TestListEvents_Day(t *testing.T) {
 
 	createTestEvent(ctx, t, userID, "Today event", now.Unix(), now.Add(time.Hour).Unix())
 
-	req := GetDateEventRequest{
-		StartDate: startOfDay.Format(time.RFC3339),
-	}
-	body, _ := json.Marshal(req)
-
-	url := fmt.Sprintf("%s/event.EventService/GetDayEvents", calendarBaseURL)
+	query := url.Values{"startDate": {startOfDay.Format(time.RFC3339)}}
+	target := fmt.Sprintf("%s/v1/events:day?%s", calendarBaseURL, query.Encode())
 
-	doRangeRequestAndVerify(ctx, t, url, body, func(events []Event) {
+	doRangeRequestAndVerify(ctx, t, userID, target, func(events []Event) {
 		assert.Empty(t, events)
 	})
 }
@@ -188,14 +202,10 @@ func TestListEvents_Week(t *testing.T) {
 	eventTime := weekStart.Add(12 * time.Hour)
 	createTestEvent(ctx, t, userID, "Weekly event", eventTime.Unix(), eventTime.Add(time.Hour).Unix())
 
-	req := GetDateEventRequest{
-		StartDate: weekStart.Format(time.RFC3339),
-	}
-	body, _ := json.Marshal(req)
+	query := url.Values{"startDate": {weekStart.Format(time.RFC3339)}}
+	target := fmt.Sprintf("%s/v1/events:week?%s", calendarBaseURL, query.Encode())
 
-	url := fmt.Sprintf("%s/event.EventService/GetWeekEvents", calendarBaseURL)
-
-	doRangeRequestAndVerify(ctx, t, url, body, func(events []Event) {
+	doRangeRequestAndVerify(ctx, t, userID, target, func(events []Event) {
 		assert.NotEmpty(t, events)
 		for _, e := range events {
 			assert.Equal(t, userID, e.Data.UserID)
@@ -217,14 +227,10 @@ func TestListEvents_Month(t *testing.T) {
 	eventTime := monthStart.Add(24 * time.Hour)
 	createTestEvent(ctx, t, userID, "Monthly event", eventTime.Unix(), eventTime.Add(time.Hour).Unix())
 
-	req := GetDateEventRequest{
-		StartDate: monthStart.Format(time.RFC3339),
-	}
-	body, _ := json.Marshal(req)
-
-	url := fmt.Sprintf("%s/event.EventService/GetMonthEvents", calendarBaseURL)
+	query := url.Values{"startDate": {monthStart.Format(time.RFC3339)}}
+	target := fmt.Sprintf("%s/v1/events:month?%s", calendarBaseURL, query.Encode())
 
-	doRangeRequestAndVerify(ctx, t, url, body, func(events []Event) {
+	doRangeRequestAndVerify(ctx, t, userID, target, func(events []Event) {
 		assert.NotEmpty(t, events)
 		for _, e := range events {
 			assert.Equal(t, userID, e.Data.UserID)
@@ -233,10 +239,13 @@ func TestListEvents_Month(t *testing.T) {
 	})
 }
 
-func doRangeRequestAndVerify(ctx context.Context, t *testing.T, url string, body []byte, verifyFunc func([]Event)) {
+func doRangeRequestAndVerify(
+	ctx context.Context, t *testing.T, userID, target string, verifyFunc func([]Event),
+) {
 	t.Helper()
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
 	require.NoError(t, err)
+	req.Header.Set(userHeader, userID)
 
 	resp, err := http.DefaultClient.Do(req)
 	require.NoError(t, err)
@@ -248,7 +257,7 @@ func doRangeRequestAndVerify(ctx context.Context, t *testing.T, url string, body
 	err = json.NewDecoder(resp.Body).Decode(&result)
 	require.NoError(t, err)
 
-	t.Logf("Found %d events in range: %s", len(result.Events), url)
+	t.Logf("Found %d events in range: %s", len(result.Events), target)
 
 	verifyFunc(result.Events)
 }
@@ -27,13 +27,12 @@ func TestNotificationIsSent(t *testing.T) {
 				RemindTime: now.Add(-6 * time.Hour).Format(time.RFC3339),
 			},
 		}
-		body, _ := json.Marshal(req)
+		body, _ := json.Marshal(req.EventData)
 
-		httpReq, err := http.NewRequestWithContext(
-			ctx, "POST", calendarBaseURL+"/event.EventService/CreateEvent", bytes.NewBuffer(body),
-		)
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", calendarBaseURL+"/v1/events", bytes.NewBuffer(body))
 		require.NoError(t, err)
 		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set(userHeader, userID)
 
 		client := &http.Client{}
 		resp, err := client.Do(httpReq)
@@ -46,15 +45,9 @@ func TestNotificationIsSent(t *testing.T) {
 		require.NotEmpty(t, id)
 
 		<-time.After(10 * time.Second)
-		reqG := EventID{
-			ID: id,
-		}
-		body, _ = json.Marshal(reqG)
-		httpReq, err = http.NewRequestWithContext(
-			ctx, "POST", calendarBaseURL+"/event.EventService/GetEvent", bytes.NewBuffer(body),
-		)
+		httpReq, err = http.NewRequestWithContext(ctx, "GET", calendarBaseURL+"/v1/events/"+id, nil)
 		require.NoError(t, err)
-		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set(userHeader, userID)
 
 		client = &http.Client{}
 		resp, err = client.Do(httpReq)